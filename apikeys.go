@@ -0,0 +1,183 @@
+package main
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Every API key token starts with apiKeyTokenPrefix, which tells them apart from session tokens, and the first apiKeyPrefixLength characters are stored in the clear so keys can be recognized in listings.
+const (
+	apiKeyTokenPrefix   = "sdb_"
+	apiKeyPrefixLength  = 12
+	apiKeyLastUsedDelay = time.Minute // ValidateAPIKey updates LastUsedAt at most this often, so scripts making many requests don't write on each one
+)
+
+// What requests an API key may make. Each scope includes the ones before it, and session tokens and passwords have every scope.
+type apiKeyScope int
+
+const (
+	APIKeyReadOnly    apiKeyScope = iota + 1 // may request rows and plan changes, see requiredScope
+	APIKeyRosterWrite                        // may also change volunteers, schedules, their rows, and rosters
+	APIKeyAdmin                              // may also manage sharing, organizations, passwords, sessions, and API keys
+)
+
+var apiKeyScopeNames = map[apiKeyScope]string{APIKeyReadOnly: "read-only", APIKeyRosterWrite: "roster-write", APIKeyAdmin: "admin"}
+
+func (s apiKeyScope) String() string {
+	if name, ok := apiKeyScopeNames[s]; ok {
+		return name
+	}
+	return "none"
+}
+
+func parseAPIKeyScope(name string) (apiKeyScope, error) {
+	for key, val := range apiKeyScopeNames {
+		if val == name {
+			return key, nil
+		}
+	}
+	return 0, fmt.Errorf("error in parseAPIKeyScope: method failed because `%s` is not one of read-only, roster-write, or admin", name)
+}
+
+// A row of APIKeys. Like a session token, the key itself is only known to whoever created it.
+type apiKey struct {
+	KeyID      int
+	User       string
+	Name       string // what the key is for, chosen by its user
+	Prefix     string // the start of the token, see apiKeyPrefixLength
+	Scope      string // read-only, roster-write, or admin
+	CreatedAt  time.Time
+	ExpiresAt  time.Time // the zero time if the key does not expire
+	LastUsedAt time.Time // the zero time if the key was never used
+	RevokedAt  time.Time // the zero time if the key was not revoked
+}
+
+// Creates an API key for currentUser and returns its token, apiKeyTokenPrefix followed by 32 random bytes in unpadded URL-safe base64. The token is not stored, so it can't be recovered later. A zero expiresAt creates a key that does not expire.
+func (sm SampleModel) CreateAPIKey(currentUser string, name string, scope apiKeyScope, expiresAt time.Time) (string, apiKey, error) {
+	if _, ok := apiKeyScopeNames[scope]; !ok {
+		return "", apiKey{}, fmt.Errorf("error in CreateAPIKey: method failed because scope %d is invalid", scope)
+	}
+	if len(name) == 0 {
+		return "", apiKey{}, errors.New("error in CreateAPIKey: method failed because the key has no name")
+	}
+	now := time.Now().Truncate(time.Second)
+	if !expiresAt.IsZero() && !expiresAt.After(now) {
+		return "", apiKey{}, fmt.Errorf("error in CreateAPIKey: method failed because expiresAt %s is not in the future", expiresAt.Format(time.RFC3339))
+	}
+	_, err := sm.RequestUser(user{UserName: currentUser})
+	if err != nil {
+		return "", apiKey{}, fmt.Errorf("error in CreateAPIKey: %w", err)
+	}
+	random := make([]byte, 32)
+	_, err = rand.Read(random)
+	if err != nil {
+		return "", apiKey{}, fmt.Errorf("error in CreateAPIKey: rand.Read error: %w", err)
+	}
+	token := apiKeyTokenPrefix + base64.RawURLEncoding.EncodeToString(random)
+	result := apiKey{User: currentUser, Name: name, Prefix: token[:apiKeyPrefixLength], Scope: scope.String(), CreatedAt: now, ExpiresAt: expiresAt.Truncate(time.Second)}
+	res, err := sm.execAudited(currentUser, `insert into APIKeys (TokenHash, Prefix, Name, User, Scope, CreatedAt, ExpiresAt) values (?, ?, ?, ?, ?, ?, ?)`,
+		hashSessionToken(token), result.Prefix, result.Name, currentUser, result.Scope, result.CreatedAt.Unix(), nullableUnix(result.ExpiresAt))
+	if err != nil {
+		return "", apiKey{}, fmt.Errorf("error in CreateAPIKey: %w", err)
+	}
+	lastID, err := res.LastInsertId()
+	if err != nil {
+		return "", apiKey{}, fmt.Errorf("error in CreateAPIKey: sql.Result.LastInsertId error: %w", err)
+	}
+	result.KeyID = int(lastID)
+	return token, result, nil
+}
+
+// Returns nil for the zero time and its Unix seconds otherwise, for the nullable time columns of APIKeys.
+func nullableUnix(value time.Time) any {
+	if value.IsZero() {
+		return nil
+	}
+	return value.Unix()
+}
+
+func timeFromNullableUnix(value sql.NullInt64) time.Time {
+	if !value.Valid {
+		return time.Time{}
+	}
+	return time.Unix(value.Int64, 0)
+}
+
+const apiKeyColumns = `KeyID, User, Name, Prefix, Scope, CreatedAt, ExpiresAt, LastUsedAt, RevokedAt`
+
+// Scans a row selected with apiKeyColumns.
+func scanAPIKey(row interface{ Scan(...any) error }) (apiKey, error) {
+	var result apiKey
+	var createdAt int64
+	var expiresAt, lastUsedAt, revokedAt sql.NullInt64
+	err := row.Scan(&result.KeyID, &result.User, &result.Name, &result.Prefix, &result.Scope, &createdAt, &expiresAt, &lastUsedAt, &revokedAt)
+	if err != nil {
+		return apiKey{}, err
+	}
+	result.CreatedAt = time.Unix(createdAt, 0)
+	result.ExpiresAt, result.LastUsedAt, result.RevokedAt = timeFromNullableUnix(expiresAt), timeFromNullableUnix(lastUsedAt), timeFromNullableUnix(revokedAt)
+	return result, nil
+}
+
+// Returns every API key of currentUser including revoked and expired ones, oldest first.
+func (sm SampleModel) RequestAPIKeys(currentUser string) ([]apiKey, error) {
+	rows, err := sm.DB.Query(fmt.Sprintf(`select %s from APIKeys where User = ? order by KeyID`, apiKeyColumns), currentUser)
+	if err != nil {
+		return []apiKey{}, fmt.Errorf("error in RequestAPIKeys: sql.DB.Query error: %w", err)
+	}
+	defer rows.Close()
+	result := []apiKey{}
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return []apiKey{}, fmt.Errorf("error in RequestAPIKeys: sql.Rows.Scan error: %w", err)
+		}
+		result = append(result, key)
+	}
+	err = rows.Err()
+	if err != nil {
+		return []apiKey{}, fmt.Errorf("error in RequestAPIKeys: sql.Rows.Err error: %w", err)
+	}
+	return result, nil
+}
+
+// Returns the API key with token if it has not expired or been revoked, and records that it was used. Unknown, revoked, and expired tokens fail with the same UserAPIKeyInvalid UserError.
+func (sm SampleModel) ValidateAPIKey(token string) (apiKey, error) {
+	now := time.Now().Truncate(time.Second)
+	result, err := scanAPIKey(sm.DB.QueryRow(fmt.Sprintf(`select %s from APIKeys where TokenHash = ? and RevokedAt is null and (ExpiresAt is null or ExpiresAt > ?)`, apiKeyColumns), hashSessionToken(token), now.Unix()))
+	if errors.Is(err, sql.ErrNoRows) {
+		return apiKey{}, fmt.Errorf("error in ValidateAPIKey: %w", &UserError{Kind: UserAPIKeyInvalid})
+	}
+	if err != nil {
+		return apiKey{}, fmt.Errorf("error in ValidateAPIKey: sql.DB.QueryRow error: %w", err)
+	}
+	if now.Sub(result.LastUsedAt) < apiKeyLastUsedDelay {
+		return result, nil
+	}
+	result.LastUsedAt = now
+	_, err = sm.DB.Exec(`update APIKeys set LastUsedAt = ? where KeyID = ?`, result.LastUsedAt.Unix(), result.KeyID)
+	if err != nil {
+		return apiKey{}, fmt.Errorf("error in ValidateAPIKey: sql.DB.Exec error: %w", err)
+	}
+	return result, nil
+}
+
+// Revokes the API key with keyID of currentUser. The row is kept, so the key still shows up in RequestAPIKeys.
+func (sm SampleModel) RevokeAPIKey(currentUser string, keyID int) error {
+	res, err := sm.execAudited(currentUser, `update APIKeys set RevokedAt = ? where KeyID = ? and User = ? and RevokedAt is null`, time.Now().Unix(), keyID, currentUser)
+	if err != nil {
+		return fmt.Errorf("error in RevokeAPIKey: %w", err)
+	}
+	count, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error in RevokeAPIKey: sql.Result.RowsAffected error: %w", err)
+	}
+	if count == 0 {
+		return fmt.Errorf("error in RevokeAPIKey: method failed to locate an unrevoked API key with KeyID %d", keyID)
+	}
+	return nil
+}
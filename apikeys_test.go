@@ -0,0 +1,107 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAPIKeys(t *testing.T) {
+	env, tearDownEnvironment := setUpEnvironment(t)
+	defer tearDownEnvironment(t)
+	token, created, err := env.sample.CreateAPIKey("Seth", "nightly export", APIKeyReadOnly, time.Time{})
+	if err != nil {
+		t.Errorf("Error setting up test (CreateAPIKey failed): %v", err)
+		t.FailNow()
+	}
+	wantInvalid := func(t *testing.T, err error) {
+		t.Helper()
+		var userErr *UserError
+		if !errors.As(err, &userErr) || userErr.Kind != UserAPIKeyInvalid {
+			t.Errorf("got error `%v`, want a UserAPIKeyInvalid UserError", err)
+		}
+	}
+	t.Run("Show the start of the token as the prefix", func(t *testing.T) {
+		checkResults(t, strings.HasPrefix(token, apiKeyTokenPrefix), true, false, nil)
+		checkResults(t, created.Prefix, token[:apiKeyPrefixLength], "", nil)
+	})
+	t.Run("Fail to create a key with an invalid scope or expiry", func(t *testing.T) {
+		_, _, err := env.sample.CreateAPIKey("Seth", "broken", apiKeyScope(7), time.Time{})
+		if err == nil {
+			t.Errorf("CreateAPIKey accepted scope 7")
+		}
+		_, _, err = env.sample.CreateAPIKey("Seth", "broken", APIKeyAdmin, time.Now().Add(-time.Hour))
+		if err == nil {
+			t.Errorf("CreateAPIKey accepted an expiry in the past")
+		}
+	})
+	t.Run("Validate a key", func(t *testing.T) {
+		ans, err := env.sample.ValidateAPIKey(token)
+		checkResultsSlice(t, []string{ans.User, ans.Scope}, []string{"Seth", "read-only"}, nil, err)
+		checkResults(t, ans.LastUsedAt.IsZero(), false, false, nil)
+	})
+	t.Run("Fail to validate a session token as a key", func(t *testing.T) {
+		sessionToken, _, err := env.sample.IssueSession("Seth")
+		if err != nil {
+			t.Errorf("Error setting up test (IssueSession failed): %v", err)
+		}
+		_, err = env.sample.ValidateAPIKey(sessionToken)
+		wantInvalid(t, err)
+	})
+	t.Run("Fail to validate an expired key", func(t *testing.T) {
+		expiring, expiringKey, err := env.sample.CreateAPIKey("Seth", "short lived", APIKeyRosterWrite, time.Now().Add(time.Hour))
+		if err != nil {
+			t.Errorf("Error setting up test (CreateAPIKey failed): %v", err)
+		}
+		_, err = env.sample.DB.Exec(`update APIKeys set ExpiresAt = ? where KeyID = ?`, time.Now().Add(-time.Minute).Unix(), expiringKey.KeyID)
+		if err != nil {
+			t.Errorf("Error setting up test (sql.DB.Exec failed): %v", err)
+		}
+		_, err = env.sample.ValidateAPIKey(expiring)
+		wantInvalid(t, err)
+	})
+	t.Run("Revoke a key", func(t *testing.T) {
+		err := env.sample.RevokeAPIKey("Seth", created.KeyID)
+		if err != nil {
+			t.Errorf("RevokeAPIKey failed: %v", err)
+		}
+		_, err = env.sample.ValidateAPIKey(token)
+		wantInvalid(t, err)
+		err = env.sample.RevokeAPIKey("Seth", created.KeyID)
+		if err == nil {
+			t.Errorf("RevokeAPIKey revoked a key twice")
+		}
+	})
+	t.Run("List revoked and expired keys too", func(t *testing.T) {
+		ans, err := env.sample.RequestAPIKeys("Seth")
+		checkResults(t, len(ans), 2, 0, err)
+		checkResultsSlice(t, []bool{ans[0].RevokedAt.IsZero(), ans[1].RevokedAt.IsZero(), ans[1].ExpiresAt.IsZero()}, []bool{false, true, false}, nil, nil)
+	})
+	t.Run("Log creating and revoking keys but not using them", func(t *testing.T) {
+		ans, err := env.sample.RequestAuditLog("Seth", auditFilter{TableName: "APIKeys"})
+		var operations []string
+		for _, val := range ans {
+			operations = append(operations, val.Operation)
+			if strings.Contains(val.Before+val.After, "TokenHash") || strings.Contains(val.Before+val.After, "LastUsedAt") {
+				t.Errorf("got AuditLog row %+v, want it without TokenHash and LastUsedAt", val)
+			}
+		}
+		checkResultsSlice(t, operations, []string{"insert", "insert", "update", "update"}, nil, err) // the updates set ExpiresAt and RevokedAt
+		checkResults(t, ans[3].User, "Seth", "", nil)
+	})
+	t.Run("Delete the keys with their user", func(t *testing.T) {
+		err := env.sample.CreateUser(user{UserName: "Ann"})
+		if err != nil {
+			t.Errorf("Error setting up test (CreateUser failed): %v", err)
+		}
+		_, _, err = env.sample.CreateAPIKey("Ann", "sync", APIKeyAdmin, time.Time{})
+		if err != nil {
+			t.Errorf("Error setting up test (CreateAPIKey failed): %v", err)
+		}
+		err = env.sample.DeleteUser("Ann")
+		if err != nil {
+			t.Errorf("DeleteUser failed: %v", err)
+		}
+	})
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// A row of AuditLog, written by the audit triggers for every insert, update, and delete of an audited table.
+type auditEntry struct {
+	AuditID    int
+	User       string // the user the change was made for, see beginAudited. Empty if it was made outside of beginAudited
+	CreatedAt  time.Time
+	TableName  string
+	PrimaryKey string // the primary key of the changed row, as text
+	Operation  string // insert, update, or delete
+	Owner      string // the User column of the changed row, which for rows of a shared schedule is its owner
+	Schedule   int    // the schedule the row belongs to, or 0
+	Volunteer  int    // the volunteer the row belongs to, or 0
+	Before     string // the row as JSON before the change, or empty for inserts
+	After      string // the row as JSON after the change, or empty for deletes
+}
+
+// Which rows of AuditLog RequestAuditLog returns. Fields with their zero value match every row.
+type auditFilter struct {
+	Schedule  int
+	Volunteer int
+	User      string // the user the change was made for
+	TableName string
+	Limit     int // if > 0, only the newest Limit matching rows are returned
+}
+
+// How the audit triggers of a table describe its rows. In the SQL expressions ROW stands for new or old.
+type auditedTable struct {
+	name       string
+	primaryKey string
+	columns    []string // the columns copied into Before and After
+	updateOf   []string // if set, only updates of these columns are logged
+	owner      string
+	schedule   string
+	volunteer  string
+}
+
+// The tables the audit triggers of migration 5 are created for. Sessions is left out because it holds credentials and changes on every request, and Dates, Weekdays, and Months hold reference data.
+// Once the migration has been released this must not change; add a new migration that replaces the triggers instead.
+var auditedTables = []auditedTable{
+	{name: "Users", primaryKey: "UserName", columns: []string{"UserName"}, owner: "ROW.UserName", schedule: "null", volunteer: "null"}, // Password is never copied into the log
+	{name: "Volunteers", primaryKey: "VolunteerID", columns: []string{"VolunteerID", "VolunteerName", "User"}, owner: "ROW.User", schedule: "null", volunteer: "ROW.VolunteerID"},
+	{name: "Schedules", primaryKey: "ScheduleID", columns: []string{"ScheduleID", "ScheduleName", "ShiftsOff", "VolunteersPerShift", "User", "StartDate", "EndDate"},
+		updateOf: []string{"ScheduleName", "ShiftsOff", "VolunteersPerShift", "User", "StartDate", "EndDate"}, owner: "ROW.User", schedule: "ROW.ScheduleID", volunteer: "null"}, // Revision bumps are not changes of their own
+	{name: "WeekdaysForSchedule", primaryKey: "WFSID", columns: []string{"WFSID", "User", "Weekday", "Schedule"}, owner: "ROW.User", schedule: "ROW.Schedule", volunteer: "null"},
+	{name: "VolunteersForSchedule", primaryKey: "VFSID", columns: []string{"VFSID", "User", "Schedule", "Volunteer"}, owner: "ROW.User", schedule: "ROW.Schedule", volunteer: "ROW.Volunteer"},
+	{name: "UnavailabilitiesForSchedule", primaryKey: "UFSID", columns: []string{"UFSID", "User", "VolunteerForSchedule", "Date"}, owner: "ROW.User",
+		schedule:  "(select Schedule from VolunteersForSchedule where VFSID = ROW.VolunteerForSchedule)",
+		volunteer: "(select Volunteer from VolunteersForSchedule where VFSID = ROW.VolunteerForSchedule)"},
+	{name: "CompletedSchedules", primaryKey: "CScheduleID", columns: []string{"CScheduleID", "ScheduleData", "User", "Schedule"}, owner: "ROW.User", schedule: "ROW.Schedule", volunteer: "null"},
+	{name: "ScheduleMembers", primaryKey: "MemberID", columns: []string{"MemberID", "Schedule", "User", "Role"}, owner: "(select User from Schedules where ScheduleID = ROW.Schedule)", schedule: "ROW.Schedule", volunteer: "null"},
+	{name: "Organizations", primaryKey: "OrganizationID", columns: []string{"OrganizationID", "OrganizationName"}, owner: "null", schedule: "null", volunteer: "null"},
+	{name: "OrganizationMembers", primaryKey: "MemberID", columns: []string{"MemberID", "Organization", "User"}, owner: "ROW.User", schedule: "null", volunteer: "null"},
+	{name: "OrganizationVolunteers", primaryKey: "Volunteer", columns: []string{"Volunteer", "Organization"}, owner: "(select User from Volunteers where VolunteerID = ROW.Volunteer)", schedule: "null", volunteer: "ROW.Volunteer"},
+}
+
+// Returns the SQL of migration 5: AuditLog, AuditActor, and an insert, update, and delete trigger for each of auditedTables.
+func auditMigration() string {
+	var b strings.Builder
+	b.WriteString(`
+	create table AuditLog (
+		AuditID integer primary key autoincrement,
+		User text,
+		CreatedAt integer not null,
+		TableName text not null,
+		PrimaryKey text not null,
+		Operation text not null check (Operation in ('insert', 'update', 'delete')),
+		Owner text,
+		Schedule integer,
+		Volunteer integer,
+		Before text,
+		After text
+	);
+	create index AuditLogBySchedule on AuditLog (Schedule);
+	create index AuditLogByVolunteer on AuditLog (Volunteer);
+	create index AuditLogByUser on AuditLog (User);
+	create table AuditActor (
+		ActorID integer primary key check (ActorID = 1),
+		User text not null
+	);
+	`)
+	for _, table := range auditedTables {
+		b.WriteString(auditTriggers(table))
+	}
+	return b.String()
+}
+
+// Returns the SQL creating the insert, update, and delete audit triggers of table.
+func auditTriggers(table auditedTable) string {
+	var b strings.Builder
+	rowJSON := func(row string) string {
+		var pairs []string
+		for _, column := range table.columns {
+			pairs = append(pairs, fmt.Sprintf(`'%s', %s.%s`, column, row, column))
+		}
+		return fmt.Sprintf(`json_object(%s)`, strings.Join(pairs, ", "))
+	}
+	for _, operation := range []string{"insert", "update", "delete"} {
+		row, before, after := "new", "null", rowJSON("new")
+		event := operation
+		switch operation {
+		case "update":
+			before = rowJSON("old")
+			if len(table.updateOf) > 0 {
+				event = fmt.Sprintf(`update of %s`, strings.Join(table.updateOf, ", "))
+			}
+		case "delete":
+			row, before, after = "old", rowJSON("old"), "null"
+		}
+		expr := func(sql string) string {
+			return strings.ReplaceAll(sql, "ROW.", row+".")
+		}
+		fmt.Fprintf(&b, `
+	create trigger %sAudit%s after %s on %s begin
+		insert into AuditLog (User, CreatedAt, TableName, PrimaryKey, Operation, Owner, Schedule, Volunteer, Before, After)
+		values ((select User from AuditActor), unixepoch(), '%s', %s.%s, '%s', %s, %s, %s, %s, %s);
+	end;
+	`, table.name, strings.ToUpper(operation[:1])+operation[1:], event, table.name,
+			table.name, row, table.primaryKey, operation, expr(table.owner), expr(table.schedule), expr(table.volunteer), before, after)
+	}
+	return b.String()
+}
+
+// Returns the SQL dropping the audit triggers of the table named table.name and creating them again from table, for migrations that change which columns are logged.
+func replaceAuditTriggers(table auditedTable) string {
+	var b strings.Builder
+	for _, operation := range []string{"Insert", "Update", "Delete"} {
+		fmt.Fprintf(&b, `
+	drop trigger %sAudit%s;`, table.name, operation)
+	}
+	b.WriteString(auditTriggers(table))
+	return b.String()
+}
+
+// A write transaction whose changes the audit triggers attribute to the user it was begun for, see beginAudited.
+type auditedTx struct {
+	*sql.Tx
+}
+
+// Begins a transaction and records currentUser in AuditActor, where the audit triggers look up who made each change. SQLite runs one write transaction at a time, so the row can't be seen by the changes of another one.
+func (sm SampleModel) beginAudited(currentUser string) (auditedTx, error) {
+	tx, err := sm.DB.Begin()
+	if err != nil {
+		return auditedTx{}, fmt.Errorf("error in beginAudited: sql.DB.Begin error: %w", err)
+	}
+	_, err = tx.Exec(`insert or replace into AuditActor (ActorID, User) values (1, ?)`, currentUser)
+	if err != nil {
+		tx.Rollback()
+		return auditedTx{}, fmt.Errorf("error in beginAudited: sql.Tx.Exec error: %w", err)
+	}
+	return auditedTx{Tx: tx}, nil
+}
+
+// Clears AuditActor, so changes made later outside of beginAudited are not attributed to the same user, and commits.
+func (tx auditedTx) Commit() error {
+	_, err := tx.Exec(`delete from AuditActor`)
+	if err != nil {
+		return fmt.Errorf("sql.Tx.Exec error: %w", err)
+	}
+	return tx.Tx.Commit()
+}
+
+// Runs a single statement in its own audited transaction, for methods that don't need one otherwise.
+func (sm SampleModel) execAudited(currentUser string, query string, args ...any) (sql.Result, error) {
+	tx, err := sm.beginAudited(currentUser)
+	if err != nil {
+		return nil, fmt.Errorf("error in execAudited: %w", err)
+	}
+	defer tx.Rollback()
+	res, err := tx.Exec(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error in execAudited: sql.Tx.Exec error: %w", err)
+	}
+	err = tx.Commit()
+	if err != nil {
+		return nil, fmt.Errorf("error in execAudited: sql.Tx.Commit error: %w", err)
+	}
+	return res, nil
+}
+
+// Returns the rows of AuditLog that match filter, oldest first. currentUser sees the changes it made, the changes of rows it owns, and the changes of schedules it may view, see ShareSchedule.
+func (sm SampleModel) RequestAuditLog(currentUser string, filter auditFilter) ([]auditEntry, error) {
+	conditions := []string{fmt.Sprintf(`(User = "%s" or Owner = "%s" or %s)`, currentUser, currentUser, scheduleAccess("Schedule", currentUser, ScheduleViewer))}
+	var args []any
+	if filter.Schedule > 0 {
+		conditions = append(conditions, `Schedule = ?`)
+		args = append(args, filter.Schedule)
+	}
+	if filter.Volunteer > 0 {
+		conditions = append(conditions, `Volunteer = ?`)
+		args = append(args, filter.Volunteer)
+	}
+	if len(filter.User) > 0 {
+		conditions = append(conditions, `User = ?`)
+		args = append(args, filter.User)
+	}
+	if len(filter.TableName) > 0 {
+		conditions = append(conditions, `TableName = ?`)
+		args = append(args, filter.TableName)
+	}
+	auditQuery := fmt.Sprintf(`select AuditID, coalesce(User, ''), CreatedAt, TableName, PrimaryKey, Operation, coalesce(Owner, ''), coalesce(Schedule, 0), coalesce(Volunteer, 0), coalesce(Before, ''), coalesce(After, '') from AuditLog where %s order by AuditID desc`, strings.Join(conditions, " and "))
+	if filter.Limit > 0 {
+		auditQuery = fmt.Sprintf(`%s limit %d`, auditQuery, filter.Limit)
+	}
+	rows, err := sm.DB.Query(auditQuery, args...)
+	if err != nil {
+		return []auditEntry{}, fmt.Errorf("error in RequestAuditLog: sql.DB.Query error: %w. Value of auditQuery is `%s`", err, auditQuery)
+	}
+	defer rows.Close()
+	result := []auditEntry{}
+	for rows.Next() {
+		var entry auditEntry
+		var createdAt int64
+		err = rows.Scan(&entry.AuditID, &entry.User, &createdAt, &entry.TableName, &entry.PrimaryKey, &entry.Operation, &entry.Owner, &entry.Schedule, &entry.Volunteer, &entry.Before, &entry.After)
+		if err != nil {
+			return []auditEntry{}, fmt.Errorf("error in RequestAuditLog: sql.Rows.Scan error: %w", err)
+		}
+		entry.CreatedAt = time.Unix(createdAt, 0)
+		result = append(result, entry)
+	}
+	err = rows.Err()
+	if err != nil {
+		return []auditEntry{}, fmt.Errorf("error in RequestAuditLog: sql.Rows.Err error: %w", err)
+	}
+	slices.Reverse(result)
+	return result, nil
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestAuditLog(t *testing.T) {
+	env, tearDownEnvironment := setUpEnvironment(t)
+	defer tearDownEnvironment(t)
+	setUpSampleData(t, env)
+	err := env.sample.CreateUser(user{UserName: "Ann", Password: []byte("not a real hash")})
+	if err != nil {
+		t.Errorf("Error setting up test (CreateUser failed): %v", err)
+		t.FailNow()
+	}
+	test0 := Must(env.sample.RequestSchedule("Seth", schedule{ScheduleName: "test0"}))
+	test1 := Must(env.sample.RequestSchedule("Seth", schedule{ScheduleName: "test1"}))
+	tim := Must(env.sample.RequestVolunteer("Seth", volunteer{VolunteerName: "Tim"}))
+	t.Run("Log the sample data", func(t *testing.T) {
+		ans, err := env.sample.RequestAuditLog("Seth", auditFilter{Schedule: test1.ScheduleID})
+		checkResults(t, len(ans) > 0, true, false, err)
+		for _, val := range ans {
+			checkResults(t, val.User, "Seth", "", nil)
+			checkResults(t, val.Operation, "insert", "", nil)
+		}
+	})
+	t.Run("Log before and after values of an update", func(t *testing.T) {
+		err := env.sample.UpdateVolunteers("Seth", []volunteer{{VolunteerID: tim.VolunteerID, VolunteerName: "Timothy"}})
+		if err != nil {
+			t.Errorf("UpdateVolunteers failed: %v", err)
+		}
+		ans, err := env.sample.RequestAuditLog("Seth", auditFilter{Volunteer: tim.VolunteerID, TableName: "Volunteers", Limit: 1})
+		checkResultsSlice(t, []auditEntry{{TableName: ans[0].TableName, PrimaryKey: ans[0].PrimaryKey, Operation: ans[0].Operation, Before: ans[0].Before, After: ans[0].After}}, []auditEntry{{
+			TableName:  "Volunteers",
+			PrimaryKey: "1",
+			Operation:  "update",
+			Before:     `{"VolunteerID":1,"VolunteerName":"Tim","User":"Seth","Email":"","Phone":"","PreferredContact":"","Notes":"","Active":1,"DeactivatedAt":null}`,
+			After:      `{"VolunteerID":1,"VolunteerName":"Timothy","User":"Seth","Email":"","Phone":"","PreferredContact":"","Notes":"","Active":1,"DeactivatedAt":null}`,
+		}}, nil, err)
+	})
+	t.Run("Log nothing for a change that was rolled back", func(t *testing.T) {
+		before, err := env.sample.RequestAuditLog("Seth", auditFilter{})
+		if err != nil {
+			t.Errorf("RequestAuditLog failed: %v", err)
+		}
+		err = env.sample.CreateWFS("Seth", []weekdayForSchedule{{Weekday: "Monday", Schedule: test0.ScheduleID}, {Weekday: "Sundae", Schedule: test0.ScheduleID}})
+		if err == nil {
+			t.Errorf("CreateWFS accepted a misspelled weekday")
+		}
+		after, err := env.sample.RequestAuditLog("Seth", auditFilter{})
+		checkResults(t, len(after), len(before), 0, err)
+	})
+	t.Run("Never log passwords", func(t *testing.T) {
+		ans, err := env.sample.RequestAuditLog("Ann", auditFilter{TableName: "Users"})
+		checkResultsSlice(t, []string{ans[0].User, ans[0].After}, []string{"Ann", `{"UserName":"Ann"}`}, nil, err)
+	})
+	t.Run("Attribute changes of a shared schedule to the member", func(t *testing.T) {
+		err := env.sample.ShareSchedule("Seth", test1.ScheduleID, "Ann", ScheduleEditor)
+		if err != nil {
+			t.Errorf("ShareSchedule failed: %v", err)
+			t.FailNow()
+		}
+		data, err := env.sample.FetchAndSendData("Ann", "test1")
+		if err != nil {
+			t.Errorf("FetchAndSendData failed: %v", err)
+			t.FailNow()
+		}
+		data.ShiftsOff = 2
+		err = env.sample.RecieveAndStoreData(data)
+		if err != nil {
+			t.Errorf("RecieveAndStoreData failed: %v", err)
+		}
+		ans, err := env.sample.RequestAuditLog("Seth", auditFilter{User: "Ann", TableName: "Schedules"})
+		checkResults(t, len(ans), 1, 0, err)
+		checkResultsSlice(t, []string{ans[0].Operation, ans[0].Owner}, []string{"update", "Seth"}, nil, nil)
+	})
+	t.Run("Hide changes of schedules the user can't view", func(t *testing.T) {
+		ans, err := env.sample.RequestAuditLog("Ann", auditFilter{Schedule: test0.ScheduleID})
+		checkResults(t, len(ans), 0, 0, err)
+		ans, err = env.sample.RequestAuditLog("Ann", auditFilter{Schedule: test1.ScheduleID})
+		checkResults(t, len(ans) > 0, true, false, err)
+	})
+}
@@ -0,0 +1,481 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+)
+
+const backupVersion = 2 // version 2 added the profile fields of volunteers
+
+// Everything one Users row owns. IDs only link the entries of the document to each other and are replaced on import, and all dates are ISO 8601 strings (YYYY-MM-DD), so the document does not depend on the database it was written from.
+type backupDocument struct {
+	Version                     int
+	User                        string
+	CreatedAt                   time.Time
+	Volunteers                  []backupVolunteer
+	Schedules                   []backupSchedule
+	WeekdaysForSchedule         []backupWFS
+	VolunteersForSchedule       []backupVFS
+	UnavailabilitiesForSchedule []backupUFS
+	CompletedSchedules          []backupCompletedSchedule
+}
+
+type backupVolunteer struct {
+	VolunteerID      int
+	VolunteerName    string
+	Email            string `json:",omitempty"`
+	Phone            string `json:",omitempty"`
+	PreferredContact string `json:",omitempty"`
+	Notes            string `json:",omitempty"`
+	Inactive         bool   `json:",omitempty"`
+}
+
+type backupSchedule struct {
+	ScheduleID         int
+	ScheduleName       string
+	ShiftsOff          int
+	VolunteersPerShift int
+	StartDate          string
+	EndDate            string
+}
+
+type backupWFS struct {
+	Schedule int
+	Weekday  string
+}
+
+type backupVFS struct {
+	VFSID     int
+	Schedule  int
+	Volunteer int
+}
+
+type backupUFS struct {
+	VolunteerForSchedule int
+	Date                 string
+}
+
+type backupCompletedSchedule struct {
+	Schedule int
+	Shifts   []backupShift
+}
+
+type backupShift struct {
+	Date       string
+	Volunteers []int
+}
+
+type backupImportReport struct {
+	VolunteersCreated         int
+	VolunteersReused          int // volunteers that already existed with the same VolunteerName
+	SchedulesCreated          int
+	WFSCreated                int
+	VFSCreated                int
+	UFSCreated                int
+	CompletedSchedulesCreated int
+}
+
+// Looks up dates by DateID with a single `in` query, which unlike RequestDates doesn't grow the query's expression depth with the number of dates.
+func (sm SampleModel) requestDatesByID(dateIDs []int) (map[int]date, error) {
+	result := map[int]date{}
+	if len(dateIDs) == 0 {
+		return result, nil
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(dateIDs)), ", ")
+	args := []any{}
+	for _, val := range dateIDs {
+		args = append(args, val)
+	}
+	dateQuery := fmt.Sprintf(`select DateID, Month, Day, Year, Weekday from Dates where DateID in (%s)`, placeholders)
+	rows, err := sm.DB.Query(dateQuery, args...)
+	if err != nil {
+		return map[int]date{}, fmt.Errorf("error in requestDatesByID: sql.DB.Query error: %w. Value of dateQuery is `%s`", err, dateQuery)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var dateStruct date
+		err = rows.Scan(&dateStruct.DateID, &dateStruct.Month, &dateStruct.Day, &dateStruct.Year, &dateStruct.Weekday)
+		if err != nil {
+			return map[int]date{}, fmt.Errorf("error in requestDatesByID: sql.Rows.Scan error: %w. Value of dateStruct is `%+v`", err, dateStruct)
+		}
+		result[dateStruct.DateID] = dateStruct
+	}
+	err = rows.Err()
+	if err != nil {
+		return map[int]date{}, fmt.Errorf("error in requestDatesByID: sql.Rows.Err error: %w", err)
+	}
+	for _, val := range dateIDs {
+		if _, ok := result[val]; !ok {
+			return map[int]date{}, fmt.Errorf("error in requestDatesByID: method failed to locate DateID %d", val)
+		}
+	}
+	return result, nil
+}
+
+// Collects every row owned by currentUser into a backupDocument.
+func (sm SampleModel) RequestBackup(currentUser string) (backupDocument, error) {
+	doc := backupDocument{
+		Version:                     backupVersion,
+		User:                        currentUser,
+		CreatedAt:                   time.Now().UTC().Truncate(time.Second),
+		Volunteers:                  []backupVolunteer{},
+		Schedules:                   []backupSchedule{},
+		WeekdaysForSchedule:         []backupWFS{},
+		VolunteersForSchedule:       []backupVFS{},
+		UnavailabilitiesForSchedule: []backupUFS{},
+		CompletedSchedules:          []backupCompletedSchedule{},
+	}
+	volunteers, err := sm.RequestVolunteers(currentUser, []volunteer{})
+	if err != nil {
+		return backupDocument{}, fmt.Errorf("error in RequestBackup: %w", err)
+	}
+	schedules, err := sm.RequestSchedulesExtended(currentUser, []schedule{{ShiftsOff: -1, User: currentUser}}, true) // schedules shared with currentUser belong to their owners
+	if err != nil {
+		return backupDocument{}, fmt.Errorf("error in RequestBackup: %w", err)
+	}
+	weekdaysForSchedule, err := sm.RequestWFS(currentUser, []weekdayForSchedule{{User: currentUser}})
+	if err != nil {
+		return backupDocument{}, fmt.Errorf("error in RequestBackup: %w", err)
+	}
+	volunteersForSchedule, err := sm.RequestVFS(currentUser, []volunteerForSchedule{{User: currentUser}})
+	if err != nil {
+		return backupDocument{}, fmt.Errorf("error in RequestBackup: %w", err)
+	}
+	unavailabilitiesForSchedule, err := sm.RequestUFS(currentUser, []unavailabilityForSchedule{{User: currentUser}})
+	if err != nil {
+		return backupDocument{}, fmt.Errorf("error in RequestBackup: %w", err)
+	}
+	completedSchedules, err := sm.RequestCompletedSchedules(currentUser, []completedSchedule{{User: currentUser}})
+	if err != nil {
+		return backupDocument{}, fmt.Errorf("error in RequestBackup: %w", err)
+	}
+	var dateIDs []int
+	addDateID := func(dateID int) {
+		if !slices.Contains(dateIDs, dateID) {
+			dateIDs = append(dateIDs, dateID)
+		}
+	}
+	for _, val := range schedules {
+		addDateID(val.StartDate)
+		addDateID(val.EndDate)
+	}
+	for _, val := range unavailabilitiesForSchedule {
+		addDateID(val.Date)
+	}
+	rosters := map[int][]rosterShift{}
+	for _, val := range completedSchedules {
+		shifts, err := parseScheduleData(val.ScheduleData)
+		if err != nil {
+			return backupDocument{}, fmt.Errorf("error in RequestBackup: %w. Value of CScheduleID is %d", err, val.CScheduleID)
+		}
+		for _, shift := range shifts {
+			addDateID(shift.Date)
+		}
+		rosters[val.CScheduleID] = shifts
+	}
+	dates, err := sm.requestDatesByID(dateIDs)
+	if err != nil {
+		return backupDocument{}, fmt.Errorf("error in RequestBackup: %w", err)
+	}
+	for _, val := range volunteers {
+		doc.Volunteers = append(doc.Volunteers, backupVolunteer{VolunteerID: val.VolunteerID, VolunteerName: val.VolunteerName, Email: val.Email, Phone: val.Phone, PreferredContact: val.PreferredContact, Notes: val.Notes, Inactive: val.Inactive})
+	}
+	for _, val := range schedules {
+		doc.Schedules = append(doc.Schedules, backupSchedule{
+			ScheduleID:         val.ScheduleID,
+			ScheduleName:       val.ScheduleName,
+			ShiftsOff:          val.ShiftsOff,
+			VolunteersPerShift: val.VolunteersPerShift,
+			StartDate:          isoDate(dates[val.StartDate]),
+			EndDate:            isoDate(dates[val.EndDate]),
+		})
+	}
+	for _, val := range weekdaysForSchedule {
+		doc.WeekdaysForSchedule = append(doc.WeekdaysForSchedule, backupWFS{Schedule: val.Schedule, Weekday: val.Weekday})
+	}
+	for _, val := range volunteersForSchedule {
+		doc.VolunteersForSchedule = append(doc.VolunteersForSchedule, backupVFS{VFSID: val.VFSID, Schedule: val.Schedule, Volunteer: val.Volunteer})
+	}
+	for _, val := range unavailabilitiesForSchedule {
+		doc.UnavailabilitiesForSchedule = append(doc.UnavailabilitiesForSchedule, backupUFS{VolunteerForSchedule: val.VolunteerForSchedule, Date: isoDate(dates[val.Date])})
+	}
+	for _, val := range completedSchedules {
+		backupStruct := backupCompletedSchedule{Schedule: val.Schedule, Shifts: []backupShift{}}
+		for _, shift := range rosters[val.CScheduleID] {
+			backupStruct.Shifts = append(backupStruct.Shifts, backupShift{Date: isoDate(dates[shift.Date]), Volunteers: shift.Volunteers})
+		}
+		doc.CompletedSchedules = append(doc.CompletedSchedules, backupStruct)
+	}
+	return doc, nil
+}
+
+// Writes RequestBackup's document as indented JSON.
+func (sm SampleModel) ExportBackup(currentUser string, w io.Writer) error {
+	doc, err := sm.RequestBackup(currentUser)
+	if err != nil {
+		return fmt.Errorf("error in ExportBackup: %w", err)
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "\t")
+	err = encoder.Encode(doc)
+	if err != nil {
+		return fmt.Errorf("error in ExportBackup: json.Encoder.Encode error: %w", err)
+	}
+	return nil
+}
+
+// Checks that every reference in doc points to an entry of doc, so nothing has to be undone halfway through ImportBackup.
+func (doc backupDocument) validate() error {
+	if doc.Version < 1 || doc.Version > backupVersion {
+		return fmt.Errorf("backup version %d is not supported (latest is %d)", doc.Version, backupVersion)
+	}
+	volunteerIDs := map[int]bool{}
+	for _, val := range doc.Volunteers {
+		if val.VolunteerID < 1 || len(val.VolunteerName) == 0 || volunteerIDs[val.VolunteerID] {
+			return fmt.Errorf("volunteer %+v is missing a value or is a duplicate", val)
+		}
+		err := validateVolunteerProfile(volunteer{VolunteerName: val.VolunteerName, Email: val.Email, Phone: val.Phone, PreferredContact: val.PreferredContact, Notes: val.Notes})
+		if err != nil {
+			return fmt.Errorf("volunteer %+v has an invalid profile: %w", val, err)
+		}
+		volunteerIDs[val.VolunteerID] = true
+	}
+	scheduleIDs := map[int]bool{}
+	var scheduleNames []string
+	for _, val := range doc.Schedules {
+		if val.ScheduleID < 1 || len(val.ScheduleName) == 0 || scheduleIDs[val.ScheduleID] || slices.Contains(scheduleNames, val.ScheduleName) {
+			return fmt.Errorf("schedule %+v is missing a value or is a duplicate", val)
+		}
+		if val.ShiftsOff < 0 || val.VolunteersPerShift < 1 || val.StartDate > val.EndDate {
+			return fmt.Errorf("schedule %+v has an invalid ShiftsOff, VolunteersPerShift, or date range", val)
+		}
+		scheduleIDs[val.ScheduleID] = true
+		scheduleNames = append(scheduleNames, val.ScheduleName)
+	}
+	for _, val := range doc.WeekdaysForSchedule {
+		if !scheduleIDs[val.Schedule] {
+			return fmt.Errorf("weekday %+v refers to a schedule that is not in the backup", val)
+		}
+	}
+	vfsIDs := map[int]bool{}
+	for _, val := range doc.VolunteersForSchedule {
+		if val.VFSID < 1 || vfsIDs[val.VFSID] || !scheduleIDs[val.Schedule] || !volunteerIDs[val.Volunteer] {
+			return fmt.Errorf("volunteer for schedule %+v is a duplicate or refers to a schedule or volunteer that is not in the backup", val)
+		}
+		vfsIDs[val.VFSID] = true
+	}
+	for _, val := range doc.UnavailabilitiesForSchedule {
+		if !vfsIDs[val.VolunteerForSchedule] {
+			return fmt.Errorf("unavailability %+v refers to a volunteer for schedule that is not in the backup", val)
+		}
+	}
+	for _, val := range doc.CompletedSchedules {
+		if !scheduleIDs[val.Schedule] {
+			return fmt.Errorf("completed schedule of schedule %d refers to a schedule that is not in the backup", val.Schedule)
+		}
+		for _, shift := range val.Shifts {
+			for _, volunteerID := range shift.Volunteers {
+				if !volunteerIDs[volunteerID] {
+					return fmt.Errorf("completed schedule of schedule %d refers to volunteer %d that is not in the backup", val.Schedule, volunteerID)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// Resolves ISO date strings to DateIDs with one prepared statement.
+func requestDateIDs(tx *sql.Tx, values []string) (map[string]int, error) {
+	result := map[string]int{}
+	dateIDString := `select DateID from Dates where Year = ? and Month = ? and Day = ?`
+	dateIDStmt, err := tx.Prepare(dateIDString)
+	if err != nil {
+		return map[string]int{}, fmt.Errorf("error in requestDateIDs: sql.Tx.Prepare error: %w. Value of dateIDString is `%s`", err, dateIDString)
+	}
+	defer dateIDStmt.Close()
+	for _, value := range values {
+		if _, ok := result[value]; ok {
+			continue
+		}
+		parsed, err := parseISODate(value)
+		if err != nil {
+			return map[string]int{}, fmt.Errorf("error in requestDateIDs: %w", err)
+		}
+		var dateID int
+		err = dateIDStmt.QueryRow(parsed.Year, parsed.Month, parsed.Day).Scan(&dateID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return map[string]int{}, fmt.Errorf("error in requestDateIDs: method failed to locate date %s", value)
+		}
+		if err != nil {
+			return map[string]int{}, fmt.Errorf("error in requestDateIDs: sql.Stmt.QueryRow error: %w. Value of value is `%s`", err, value)
+		}
+		result[value] = dateID
+	}
+	return result, nil
+}
+
+// Recreates a document written by ExportBackup for currentUser in a single transaction, whichever user it was exported from. Every ID is remapped to the IDs of the new rows.
+// Volunteers are matched to existing volunteers of currentUser by VolunteerName. Schedules are always created, so the import fails if one of their ScheduleNames already exists.
+func (sm SampleModel) ImportBackup(currentUser string, r io.Reader) (backupImportReport, error) {
+	var doc backupDocument
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(&doc)
+	if err != nil {
+		return backupImportReport{}, fmt.Errorf("error in ImportBackup: json.Decoder.Decode error: %w", err)
+	}
+	err = doc.validate()
+	if err != nil {
+		return backupImportReport{}, fmt.Errorf("error in ImportBackup: method failed because the backup is invalid: %w", err)
+	}
+	var report backupImportReport
+	tx, err := sm.beginAudited(currentUser)
+	if err != nil {
+		return backupImportReport{}, fmt.Errorf("error in ImportBackup: %w", err)
+	}
+	defer tx.Rollback()
+	var userCount int
+	err = tx.QueryRow(`select count(*) from Users where UserName = ?`, currentUser).Scan(&userCount)
+	if err != nil {
+		return backupImportReport{}, fmt.Errorf("error in ImportBackup: sql.Tx.QueryRow error: %w", err)
+	}
+	if userCount != 1 {
+		return backupImportReport{}, fmt.Errorf("error in ImportBackup: method failed to locate user `%s`", currentUser)
+	}
+	var dateValues []string
+	for _, val := range doc.Schedules {
+		dateValues = append(dateValues, val.StartDate, val.EndDate)
+	}
+	for _, val := range doc.UnavailabilitiesForSchedule {
+		dateValues = append(dateValues, val.Date)
+	}
+	for _, val := range doc.CompletedSchedules {
+		for _, shift := range val.Shifts {
+			dateValues = append(dateValues, shift.Date)
+		}
+	}
+	dateIDs, err := requestDateIDs(tx.Tx, dateValues)
+	if err != nil {
+		return backupImportReport{}, fmt.Errorf("error in ImportBackup: %w", err)
+	}
+	insert := func(query string, args ...any) (int, error) {
+		res, err := tx.Exec(query, args...)
+		if err != nil {
+			return 0, fmt.Errorf("sql.Tx.Exec error: %w. Value of query is `%s` and value of args is `%v`", err, query, args)
+		}
+		lastID, err := res.LastInsertId()
+		if err != nil {
+			return 0, fmt.Errorf("sql.Result.LastInsertId error: %w", err)
+		}
+		return int(lastID), nil
+	}
+	volunteerIDs := map[int]int{} // VolunteerID in doc to VolunteerID in the database
+	var inactiveIDs []int         // volunteers to deactivate once the VFS rows are restored
+	for _, val := range doc.Volunteers {
+		var existingID int
+		var existingActive bool
+		err := tx.QueryRow(`select VolunteerID, Active from Volunteers where User = ? and VolunteerName = ? order by VolunteerID limit 1`, currentUser, val.VolunteerName).Scan(&existingID, &existingActive)
+		if err == nil {
+			volunteerIDs[val.VolunteerID] = existingID
+			if !existingActive {
+				inactiveIDs = append(inactiveIDs, existingID)
+			}
+			report.VolunteersReused++
+			continue
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return backupImportReport{}, fmt.Errorf("error in ImportBackup: sql.Tx.QueryRow error: %w. Value of val is `%+v`", err, val)
+		}
+		volunteerIDs[val.VolunteerID], err = insert(`insert into Volunteers (VolunteerName, User, Email, Phone, PreferredContact, Notes) values (?, ?, ?, ?, ?, ?)`,
+			val.VolunteerName, currentUser, val.Email, val.Phone, val.PreferredContact, val.Notes)
+		if err != nil {
+			return backupImportReport{}, fmt.Errorf("error in ImportBackup: %w", err)
+		}
+		if val.Inactive {
+			inactiveIDs = append(inactiveIDs, volunteerIDs[val.VolunteerID])
+		}
+		report.VolunteersCreated++
+	}
+	scheduleIDs := map[int]int{}
+	for _, val := range doc.Schedules {
+		var existingCount int
+		err := tx.QueryRow(`select count(*) from Schedules where User = ? and ScheduleName = ?`, currentUser, val.ScheduleName).Scan(&existingCount)
+		if err != nil {
+			return backupImportReport{}, fmt.Errorf("error in ImportBackup: sql.Tx.QueryRow error: %w. Value of val is `%+v`", err, val)
+		}
+		if existingCount > 0 {
+			return backupImportReport{}, fmt.Errorf("error in ImportBackup: method failed because schedule `%s` already exists", val.ScheduleName)
+		}
+		scheduleIDs[val.ScheduleID], err = insert(`insert into Schedules (ScheduleName, ShiftsOff, VolunteersPerShift, User, StartDate, EndDate) values (?, ?, ?, ?, ?, ?)`,
+			val.ScheduleName, val.ShiftsOff, val.VolunteersPerShift, currentUser, dateIDs[val.StartDate], dateIDs[val.EndDate])
+		if err != nil {
+			return backupImportReport{}, fmt.Errorf("error in ImportBackup: %w", err)
+		}
+		report.SchedulesCreated++
+	}
+	for _, val := range doc.WeekdaysForSchedule {
+		_, err := insert(`insert into WeekdaysForSchedule (User, Weekday, Schedule) values (?, ?, ?)`, currentUser, val.Weekday, scheduleIDs[val.Schedule])
+		if err != nil {
+			return backupImportReport{}, fmt.Errorf("error in ImportBackup: %w", err)
+		}
+		report.WFSCreated++
+	}
+	// Inactive volunteers can't be added to schedules, so they are active until their VFS rows are restored. Those that were inactive already keep their DeactivatedAt.
+	for _, val := range inactiveIDs {
+		_, err := tx.Exec(`update Volunteers set Active = 1 where VolunteerID = ?`, val)
+		if err != nil {
+			return backupImportReport{}, fmt.Errorf("error in ImportBackup: sql.Tx.Exec error: %w", err)
+		}
+	}
+	vfsIDs := map[int]int{}
+	for _, val := range doc.VolunteersForSchedule {
+		vfsIDs[val.VFSID], err = insert(`insert into VolunteersForSchedule (User, Schedule, Volunteer) values (?, ?, ?)`, currentUser, scheduleIDs[val.Schedule], volunteerIDs[val.Volunteer])
+		if err != nil {
+			return backupImportReport{}, fmt.Errorf("error in ImportBackup: %w", err)
+		}
+		report.VFSCreated++
+	}
+	for _, val := range inactiveIDs {
+		_, err := tx.Exec(`update Volunteers set Active = 0, DeactivatedAt = coalesce(DeactivatedAt, unixepoch()) where VolunteerID = ?`, val)
+		if err != nil {
+			return backupImportReport{}, fmt.Errorf("error in ImportBackup: sql.Tx.Exec error: %w", err)
+		}
+	}
+	for _, val := range doc.UnavailabilitiesForSchedule {
+		_, err := insert(`insert into UnavailabilitiesForSchedule (User, VolunteerForSchedule, Date) values (?, ?, ?)`, currentUser, vfsIDs[val.VolunteerForSchedule], dateIDs[val.Date])
+		if err != nil {
+			return backupImportReport{}, fmt.Errorf("error in ImportBackup: %w", err)
+		}
+		report.UFSCreated++
+	}
+	for _, val := range doc.CompletedSchedules {
+		shifts := []rosterShift{}
+		for _, shift := range val.Shifts {
+			remapped := rosterShift{Date: dateIDs[shift.Date], Volunteers: []int{}}
+			for _, volunteerID := range shift.Volunteers {
+				remapped.Volunteers = append(remapped.Volunteers, volunteerIDs[volunteerID])
+			}
+			shifts = append(shifts, remapped)
+		}
+		scheduleData, err := json.Marshal(shifts)
+		if err != nil {
+			return backupImportReport{}, fmt.Errorf("error in ImportBackup: json.Marshal error: %w", err)
+		}
+		_, err = insert(`insert into CompletedSchedules (ScheduleData, User, Schedule) values (?, ?, ?)`, string(scheduleData), currentUser, scheduleIDs[val.Schedule])
+		if err != nil {
+			return backupImportReport{}, fmt.Errorf("error in ImportBackup: %w", err)
+		}
+		report.CompletedSchedulesCreated++
+	}
+	err = tx.Commit()
+	if err != nil {
+		return backupImportReport{}, fmt.Errorf("error in ImportBackup: sql.Tx.Commit error: %w", err)
+	}
+	return report, nil
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRequestBackup(t *testing.T) {
+	env, tearDownEnvironment := setUpEnvironment(t)
+	defer tearDownEnvironment(t)
+	setUpSampleData(t, env)
+	err := env.sample.CreateCompletedSchedules(env.loggedInUser, []completedSchedule{{ScheduleData: sampleScheduleData, Schedule: 2}})
+	if err != nil {
+		t.Errorf("Error setting up test (CreateCompletedSchedules failed): %v", err)
+		t.FailNow()
+	}
+	ans, err := env.sample.RequestBackup(env.loggedInUser)
+	if err != nil {
+		t.Errorf("got error: `%v`", err)
+	}
+	if ans.Version != backupVersion || ans.User != env.loggedInUser || ans.CreatedAt.IsZero() {
+		t.Errorf("got Version %d, User `%s`, and CreatedAt %v", ans.Version, ans.User, ans.CreatedAt)
+	}
+	if len(ans.Volunteers) != 7 || len(ans.Schedules) != 4 || len(ans.WeekdaysForSchedule) != 4 || len(ans.VolunteersForSchedule) != 11 || len(ans.UnavailabilitiesForSchedule) != 6 || len(ans.CompletedSchedules) != 1 {
+		t.Errorf("got %d volunteers, %d schedules, %d WFS, %d VFS, %d UFS, and %d completed schedules, want 7, 4, 4, 11, 6, and 1", len(ans.Volunteers), len(ans.Schedules), len(ans.WeekdaysForSchedule), len(ans.VolunteersForSchedule), len(ans.UnavailabilitiesForSchedule), len(ans.CompletedSchedules))
+		t.FailNow()
+	}
+	wantSchedule := backupSchedule{ScheduleID: 2, ScheduleName: "test1", ShiftsOff: 3, VolunteersPerShift: 3, StartDate: "2024-01-01", EndDate: "2024-03-01"}
+	checkResults(t, ans.Schedules[1], wantSchedule, backupSchedule{}, nil)
+	checkResults(t, ans.UnavailabilitiesForSchedule[0], backupUFS{VolunteerForSchedule: 1, Date: "2024-01-14"}, backupUFS{}, nil)
+	wantCompleted := backupCompletedSchedule{Schedule: 2, Shifts: []backupShift{
+		{Date: "2024-01-14", Volunteers: []int{2, 3, 4}},
+		{Date: "2024-01-07", Volunteers: []int{1, 2, 3}},
+		{Date: "2024-01-21", Volunteers: []int{1, 3}},
+	}}
+	if !reflect.DeepEqual(ans.CompletedSchedules[0], wantCompleted) {
+		t.Errorf("got %+v, want %+v", ans.CompletedSchedules[0], wantCompleted)
+	}
+}
+
+func TestImportBackup(t *testing.T) {
+	source, tearDownSource := setUpEnvironment(t)
+	defer tearDownSource(t)
+	setUpSampleData(t, source)
+	err := source.sample.CreateCompletedSchedules(source.loggedInUser, []completedSchedule{{ScheduleData: sampleScheduleData, Schedule: 2}})
+	if err != nil {
+		t.Errorf("Error setting up test (CreateCompletedSchedules failed): %v", err)
+		t.FailNow()
+	}
+	// George is inactive but still on schedules, which the import has to restore
+	err = source.sample.DeleteVolunteers(source.loggedInUser, []volunteer{{VolunteerName: "George"}})
+	if err != nil {
+		t.Errorf("Error setting up test (DeleteVolunteers failed): %v", err)
+		t.FailNow()
+	}
+	var backup bytes.Buffer
+	err = source.sample.ExportBackup(source.loggedInUser, &backup)
+	if err != nil {
+		t.Errorf("Error setting up test (ExportBackup failed): %v", err)
+		t.FailNow()
+	}
+	// the target database already has volunteers, so every VolunteerID has to be remapped and Bill is reused even though Bill is inactive there
+	target, tearDownTarget := setUpEnvironment(t)
+	defer tearDownTarget(t)
+	err = target.sample.CreateVolunteers(target.loggedInUser, []volunteer{{VolunteerName: "Zed"}, {VolunteerName: "Bill", Inactive: true}})
+	if err != nil {
+		t.Errorf("Error setting up test (CreateVolunteers failed): %v", err)
+		t.FailNow()
+	}
+	t.Run("Import a backup", func(t *testing.T) {
+		ans, err := target.sample.ImportBackup(target.loggedInUser, bytes.NewReader(backup.Bytes()))
+		want := backupImportReport{VolunteersCreated: 6, VolunteersReused: 1, SchedulesCreated: 4, WFSCreated: 4, VFSCreated: 11, UFSCreated: 6, CompletedSchedulesCreated: 1}
+		checkResults(t, ans, want, backupImportReport{}, err)
+		for _, scheduleName := range []string{"test0", "test1", "test2", "test3"} {
+			sourceData, sourceErr := source.sample.FetchAndSendData(source.loggedInUser, scheduleName)
+			targetData, targetErr := target.sample.FetchAndSendData(target.loggedInUser, scheduleName)
+			if sourceErr != nil || targetErr != nil {
+				t.Errorf("got errors: `%v` and `%v`", sourceErr, targetErr)
+			}
+			sourceData.CompletedSchedules, targetData.CompletedSchedules = nil, nil // they hold the remapped IDs
+			if !reflect.DeepEqual(sourceData, targetData) {
+				t.Errorf("got %+v, want %+v", targetData, sourceData)
+			}
+		}
+		inactive, err := target.sample.RequestVolunteers(target.loggedInUser, []volunteer{{Inactive: true}})
+		var inactiveNames []string
+		for _, val := range inactive {
+			inactiveNames = append(inactiveNames, val.VolunteerName)
+		}
+		checkResultsSlice(t, inactiveNames, []string{"Bill", "George"}, nil, err)
+		var sourceRoster, targetRoster strings.Builder
+		sourceErr := source.sample.ExportRosterCSV(source.loggedInUser, 1, rosterLong, &sourceRoster)
+		targetErr := target.sample.ExportRosterCSV(target.loggedInUser, 1, rosterLong, &targetRoster)
+		if sourceErr != nil || targetErr != nil || sourceRoster.String() != targetRoster.String() {
+			t.Errorf("got roster %q (errors: `%v` and `%v`), want %q", targetRoster.String(), sourceErr, targetErr, sourceRoster.String())
+		}
+	})
+	t.Run("Fail by importing a backup whose schedules already exist", func(t *testing.T) {
+		_, err := target.sample.ImportBackup(target.loggedInUser, bytes.NewReader(backup.Bytes()))
+		if err == nil || !strings.Contains(err.Error(), "already exists") {
+			t.Errorf("got error: `%v`, want an error about an existing schedule", err)
+		}
+		volunteers, err := target.sample.RequestVolunteers(target.loggedInUser, []volunteer{})
+		if err != nil || len(volunteers) != 8 {
+			t.Errorf("got %d volunteers (error: %v), want 8", len(volunteers), err)
+		}
+	})
+	invalid := []struct {
+		name string
+		edit func(doc map[string]any)
+	}{
+		{name: "Fail by importing a newer version", edit: func(doc map[string]any) { doc["Version"] = backupVersion + 1 }},
+		{name: "Fail by importing a VFS with an unknown volunteer", edit: func(doc map[string]any) {
+			doc["VolunteersForSchedule"].([]any)[0].(map[string]any)["Volunteer"] = 100
+		}},
+		{name: "Fail by importing a date that does not exist", edit: func(doc map[string]any) {
+			doc["UnavailabilitiesForSchedule"].([]any)[0].(map[string]any)["Date"] = "2024-02-30"
+		}},
+		{name: "Fail by importing an unknown field", edit: func(doc map[string]any) { doc["Extra"] = true }},
+	}
+	for _, tt := range invalid {
+		t.Run(tt.name, func(t *testing.T) {
+			fresh, tearDownFresh := setUpEnvironment(t)
+			defer tearDownFresh(t)
+			var doc map[string]any
+			err := json.Unmarshal(backup.Bytes(), &doc)
+			if err != nil {
+				t.Errorf("Error setting up test (json.Unmarshal failed): %v", err)
+				t.FailNow()
+			}
+			tt.edit(doc)
+			edited, _ := json.Marshal(doc)
+			_, err = fresh.sample.ImportBackup(fresh.loggedInUser, bytes.NewReader(edited))
+			if err == nil {
+				t.Errorf("got no error, want an error")
+			}
+			volunteers, err := fresh.sample.RequestVolunteers(fresh.loggedInUser, []volunteer{})
+			if err != nil || len(volunteers) != 0 {
+				t.Errorf("got %d volunteers (error: %v), want 0", len(volunteers), err)
+			}
+		})
+	}
+}
@@ -0,0 +1,612 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// ChangePlan describes every change RecieveAndStoreData would make to store a SendReceiveDataStruct. PlanReceivedData builds it without writing anything, and ApplyChangePlan executes it exactly as it is, so a plan can be shown to a coordinator before it is applied.
+type ChangePlan struct {
+	User                     string // the owner of the schedule, whose rows the plan changes. For a schedule shared with the sender this is not SendReceiveDataStruct.User
+	RequestedBy              string // the sender of the SendReceiveDataStruct, whom AuditLog attributes the changes to
+	ScheduleName             string
+	Revision                 int            // the Schedules Revision the plan was made against. ApplyChangePlan fails with a *RevisionConflictError if it has changed
+	CreateSchedule           bool           // true if the user has no schedule named ScheduleName yet
+	Schedule                 schedule       // the Schedules row as it will be stored. ScheduleID is 0 if CreateSchedule is true
+	ScheduleChanges          []columnChange // the Schedules columns that will change. Empty if CreateSchedule is true
+	VolunteersToCreate       []string       // VolunteerNames that will be added to Volunteers
+	VolunteersRejected       []string       // incoming VolunteerNames that are left out because every volunteer of that name in the pool is inactive, see DeleteVolunteers
+	VolunteersToAdd          []plannedVolunteer
+	VolunteersToRemove       []plannedVolunteer
+	WeekdaysToAdd            []weekdayForSchedule
+	WeekdaysToRemove         []weekdayForSchedule
+	UnavailabilitiesToAdd    []plannedUnavailability
+	UnavailabilitiesToRemove []plannedUnavailability
+}
+
+// RevisionConflictError is returned when data or a plan was based on a Revision of a schedule that is no longer the stored one, because someone else changed the schedule in the meantime.
+type RevisionConflictError struct {
+	ScheduleName    string
+	Revision        int      // the Revision the data or plan was based on
+	CurrentRevision int      // the stored Revision, or -1 if the schedule no longer exists
+	Diverged        []string // the parts of the data that differ from what is stored, e.g. "ShiftsOff" or "VolunteerAvailabilityData[Tim]"
+}
+
+func (e *RevisionConflictError) Error() string {
+	if e.CurrentRevision < 0 {
+		return fmt.Sprintf("schedule %s was changed by someone else: it was at revision %d but no longer exists", e.ScheduleName, e.Revision)
+	}
+	return fmt.Sprintf("schedule %s was changed by someone else: it was at revision %d but is now at revision %d. Diverged: %s", e.ScheduleName, e.Revision, e.CurrentRevision, strings.Join(e.Diverged, ", "))
+}
+
+type columnChange struct {
+	Column string
+	From   string
+	To     string
+}
+
+// VFSID is only set for volunteers that will be removed from the schedule.
+type plannedVolunteer struct {
+	VFSID         int
+	VolunteerName string
+}
+
+// UFSID is only set for unavailabilities that will be removed.
+type plannedUnavailability struct {
+	UFSID         int
+	VolunteerName string
+	Date          date
+}
+
+// The rows stored for one schedule, as loaded by requestScheduleState.
+type scheduleState struct {
+	schedule       schedule
+	wfs            []weekdayForSchedule
+	vfs            []volunteerForSchedule
+	volunteerNames map[int]string                      // VolunteerID to VolunteerName
+	ufs            map[int][]unavailabilityForSchedule // VFSID to its UFS rows, sorted by Date
+	dates          map[int]date                        // DateID to date for StartDate, EndDate, and every UFS Date
+}
+
+func (cp ChangePlan) IsEmpty() bool {
+	return !cp.CreateSchedule && len(cp.ScheduleChanges) == 0 && len(cp.VolunteersToCreate) == 0 && len(cp.VolunteersToAdd) == 0 && len(cp.VolunteersToRemove) == 0 && len(cp.WeekdaysToAdd) == 0 && len(cp.WeekdaysToRemove) == 0 && len(cp.UnavailabilitiesToAdd) == 0 && len(cp.UnavailabilitiesToRemove) == 0
+}
+
+// Returns one human readable line per change, in the order ApplyChangePlan executes them, followed by one line per rejected volunteer.
+func (cp ChangePlan) Summary() []string {
+	var result []string
+	if cp.CreateSchedule {
+		result = append(result, fmt.Sprintf("create schedule %q (ShiftsOff %d, VolunteersPerShift %d)", cp.ScheduleName, cp.Schedule.ShiftsOff, cp.Schedule.VolunteersPerShift))
+	}
+	for _, val := range cp.ScheduleChanges {
+		result = append(result, fmt.Sprintf("change %s of schedule %q from %s to %s", val.Column, cp.ScheduleName, val.From, val.To))
+	}
+	for _, val := range cp.UnavailabilitiesToRemove {
+		result = append(result, fmt.Sprintf("remove unavailability of %s on %s", val.VolunteerName, isoDate(val.Date)))
+	}
+	for _, val := range cp.VolunteersToRemove {
+		result = append(result, fmt.Sprintf("remove volunteer %s from the schedule", val.VolunteerName))
+	}
+	for _, val := range cp.WeekdaysToRemove {
+		result = append(result, fmt.Sprintf("remove weekday %s", val.Weekday))
+	}
+	for _, val := range cp.WeekdaysToAdd {
+		result = append(result, fmt.Sprintf("add weekday %s", val.Weekday))
+	}
+	for _, val := range cp.VolunteersToCreate {
+		result = append(result, fmt.Sprintf("create volunteer %s", val))
+	}
+	for _, val := range cp.VolunteersToAdd {
+		result = append(result, fmt.Sprintf("add volunteer %s to the schedule", val.VolunteerName))
+	}
+	for _, val := range cp.UnavailabilitiesToAdd {
+		result = append(result, fmt.Sprintf("add unavailability of %s on %s", val.VolunteerName, isoDate(val.Date)))
+	}
+	for _, val := range cp.VolunteersRejected { // not a change, but the sender should know it won't be stored
+		result = append(result, fmt.Sprintf("leave out volunteer %s, who is inactive", val))
+	}
+	return result
+}
+
+// Resolves ISO 8601 date strings to rows of Dates with a single RequestDates call. The result maps each string in values to its date.
+func (sm SampleModel) resolveISODates(values []string) (map[string]date, error) {
+	result := map[string]date{}
+	if len(values) == 0 {
+		return result, nil
+	}
+	var toRequest []date
+	for _, val := range values {
+		parsed, err := parseISODate(val)
+		if err != nil {
+			return map[string]date{}, fmt.Errorf("error in resolveISODates: %w", err)
+		}
+		toRequest = append(toRequest, parsed)
+	}
+	found, err := sm.RequestDates(toRequest)
+	if err != nil {
+		return map[string]date{}, fmt.Errorf("error in resolveISODates: %w", err)
+	}
+	byISO := map[string]date{}
+	for _, val := range found {
+		byISO[isoDate(val)] = val
+	}
+	for i, val := range values {
+		dateStruct, ok := byISO[isoDate(toRequest[i])]
+		if !ok {
+			return map[string]date{}, fmt.Errorf("error in resolveISODates: method failed because `%s` is not in the Dates table", val)
+		}
+		result[val] = dateStruct
+	}
+	return result, nil
+}
+
+// Lists the parts of data that differ from what is stored for data.ScheduleName. Used to fill RevisionConflictError.Diverged.
+func (sm SampleModel) divergedParts(data SendReceiveDataStruct) ([]string, error) {
+	current, err := sm.FetchAndSendData(data.User, data.ScheduleName)
+	if err != nil {
+		return []string{}, fmt.Errorf("error in divergedParts: %w", err)
+	}
+	var result []string
+	if current.StartDate != data.StartDate {
+		result = append(result, "StartDate")
+	}
+	if current.EndDate != data.EndDate {
+		result = append(result, "EndDate")
+	}
+	if current.ShiftsOff != data.ShiftsOff {
+		result = append(result, "ShiftsOff")
+	}
+	if current.VolunteersPerShift != data.VolunteersPerShift {
+		result = append(result, "VolunteersPerShift")
+	}
+	currentWeekdays, dataWeekdays := slices.Clone(current.WeekdaysForSchedule), slices.Clone(data.WeekdaysForSchedule)
+	slices.Sort(currentWeekdays)
+	slices.Sort(dataWeekdays)
+	if !slices.Equal(currentWeekdays, dataWeekdays) {
+		result = append(result, "WeekdaysForSchedule")
+	}
+	flatten := func(availability []map[string][]string) map[string][]string {
+		flat := map[string][]string{}
+		for _, volunteerDatesPair := range availability {
+			for key, value := range volunteerDatesPair {
+				dates := slices.Clone(value)
+				slices.Sort(dates)
+				flat[key] = dates
+			}
+		}
+		return flat
+	}
+	currentAvailability, dataAvailability := flatten(current.VolunteerAvailabilityData), flatten(data.VolunteerAvailabilityData)
+	var names []string
+	for key := range currentAvailability {
+		names = append(names, key)
+	}
+	for key := range dataAvailability {
+		if _, ok := currentAvailability[key]; !ok {
+			names = append(names, key)
+		}
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		currentDates, inCurrent := currentAvailability[name]
+		dataDates, inData := dataAvailability[name]
+		if inCurrent != inData || !slices.Equal(currentDates, dataDates) {
+			result = append(result, fmt.Sprintf("VolunteerAvailabilityData[%s]", name))
+		}
+	}
+	return result, nil
+}
+
+func (sm SampleModel) requestScheduleState(currentUser string, scheduleStruct schedule) (scheduleState, error) {
+	state := scheduleState{schedule: scheduleStruct, volunteerNames: map[int]string{}, ufs: map[int][]unavailabilityForSchedule{}, dates: map[int]date{}}
+	var err error
+	state.wfs, err = sm.RequestWFS(currentUser, []weekdayForSchedule{{Schedule: scheduleStruct.ScheduleID}})
+	if err != nil {
+		return scheduleState{}, fmt.Errorf("error in requestScheduleState: %w", err)
+	}
+	state.vfs, err = sm.RequestVFS(currentUser, []volunteerForSchedule{{Schedule: scheduleStruct.ScheduleID}})
+	if err != nil {
+		return scheduleState{}, fmt.Errorf("error in requestScheduleState: %w", err)
+	}
+	volunteers, err := sm.RequestPoolVolunteers(scheduleStruct.User, []volunteer{}) // VFS rows of a shared schedule point to volunteers its owner may draw from
+	if err != nil {
+		return scheduleState{}, fmt.Errorf("error in requestScheduleState: %w", err)
+	}
+	for _, val := range volunteers {
+		state.volunteerNames[val.VolunteerID] = val.VolunteerName
+	}
+	datesToRequest := []date{{DateID: scheduleStruct.StartDate}, {DateID: scheduleStruct.EndDate}}
+	if len(state.vfs) > 0 {
+		var ufsToRequest []unavailabilityForSchedule
+		for _, val := range state.vfs {
+			ufsToRequest = append(ufsToRequest, unavailabilityForSchedule{VolunteerForSchedule: val.VFSID})
+		}
+		unavailabilities, err := sm.RequestUFS(currentUser, ufsToRequest)
+		if err != nil {
+			return scheduleState{}, fmt.Errorf("error in requestScheduleState: %w", err)
+		}
+		for _, val := range unavailabilities {
+			state.ufs[val.VolunteerForSchedule] = append(state.ufs[val.VolunteerForSchedule], val)
+			datesToRequest = append(datesToRequest, date{DateID: val.Date})
+		}
+		for _, val := range state.ufs {
+			sort.Slice(val, func(i, j int) bool { return val[i].Date < val[j].Date })
+		}
+	}
+	dates, err := sm.RequestDates(datesToRequest)
+	if err != nil {
+		return scheduleState{}, fmt.Errorf("error in requestScheduleState: %w", err)
+	}
+	for _, val := range dates {
+		state.dates[val.DateID] = val
+	}
+	return state, nil
+}
+
+// Compares data with what is stored for data.User and returns the changes needed to make the database match it. Nothing is written.
+// A schedule shared with data.User can be changed with the editor role, and the plan then changes the rows of its owner, volunteers included.
+// data is treated as the complete state of the schedule: volunteers, weekdays, and unavailable dates missing from data will be removed. CompletedSchedules are output only and are ignored.
+// data.Revision must be the stored Revision of the schedule (0 for a schedule that does not exist yet), otherwise a *RevisionConflictError is returned.
+func (sm SampleModel) PlanReceivedData(data SendReceiveDataStruct) (ChangePlan, error) {
+	if len(data.User) == 0 {
+		return ChangePlan{}, errors.New("error in PlanReceivedData: method failed because data did not have a value for User")
+	}
+	if len(data.ScheduleName) == 0 {
+		return ChangePlan{}, fmt.Errorf("error in PlanReceivedData: method failed because data did not have a value for ScheduleName: %+v", data)
+	}
+	if data.ShiftsOff < 0 {
+		return ChangePlan{}, fmt.Errorf("error in PlanReceivedData: method failed because data did not have a valid value for ShiftsOff: %d", data.ShiftsOff)
+	}
+	if data.VolunteersPerShift < 1 {
+		return ChangePlan{}, fmt.Errorf("error in PlanReceivedData: method failed because data did not have a valid value for VolunteersPerShift: %d", data.VolunteersPerShift)
+	}
+	// Collect the incoming volunteers in order, so the plan is deterministic even though each entry is a map.
+	var incomingNames []string
+	incomingAvailability := map[string][]string{}
+	allDates := []string{data.StartDate, data.EndDate}
+	for _, volunteerDatesPair := range data.VolunteerAvailabilityData {
+		var names []string
+		for key := range volunteerDatesPair {
+			names = append(names, key)
+		}
+		slices.Sort(names)
+		for _, name := range names {
+			if len(name) == 0 {
+				return ChangePlan{}, errors.New("error in PlanReceivedData: method failed because one of the entries in VolunteerAvailabilityData did not have a VolunteerName")
+			}
+			if _, ok := incomingAvailability[name]; ok {
+				return ChangePlan{}, fmt.Errorf("error in PlanReceivedData: method failed because volunteer %s appears more than once in VolunteerAvailabilityData", name)
+			}
+			incomingNames = append(incomingNames, name)
+			incomingAvailability[name] = volunteerDatesPair[name]
+			allDates = append(allDates, volunteerDatesPair[name]...)
+		}
+	}
+	resolvedDates, err := sm.resolveISODates(allDates)
+	if err != nil {
+		return ChangePlan{}, fmt.Errorf("error in PlanReceivedData: %w", err)
+	}
+	startDate, endDate := resolvedDates[data.StartDate], resolvedDates[data.EndDate]
+	if endDate.DateID < startDate.DateID {
+		return ChangePlan{}, fmt.Errorf("error in PlanReceivedData: method failed because EndDate %s is before StartDate %s", data.EndDate, data.StartDate)
+	}
+	var incomingWeekdays []string
+	for _, val := range data.WeekdaysForSchedule {
+		weekdayStruct, err := sm.RequestWeekday(weekday{WeekdayName: val})
+		if err != nil {
+			return ChangePlan{}, fmt.Errorf("error in PlanReceivedData: %w", err)
+		}
+		if slices.Contains(incomingWeekdays, weekdayStruct.WeekdayName) {
+			return ChangePlan{}, fmt.Errorf("error in PlanReceivedData: method failed because weekday %s appears more than once in WeekdaysForSchedule", val)
+		}
+		incomingWeekdays = append(incomingWeekdays, weekdayStruct.WeekdayName)
+	}
+	plan := ChangePlan{
+		User:         data.User,
+		RequestedBy:  data.User,
+		ScheduleName: data.ScheduleName,
+		Revision:     data.Revision,
+		Schedule: schedule{
+			ScheduleName:       data.ScheduleName,
+			ShiftsOff:          data.ShiftsOff,
+			VolunteersPerShift: data.VolunteersPerShift,
+			User:               data.User,
+			StartDate:          startDate.DateID,
+			EndDate:            endDate.DateID,
+		},
+	}
+	existing, err := sm.RequestSchedules(data.User, []schedule{{ScheduleName: data.ScheduleName}})
+	if err != nil {
+		return ChangePlan{}, fmt.Errorf("error in PlanReceivedData: %w", err)
+	}
+	existing = preferOwnedSchedules(data.User, existing)
+	var state scheduleState
+	switch len(existing) {
+	case 0:
+		if data.Revision != 0 {
+			return ChangePlan{}, fmt.Errorf("error in PlanReceivedData: %w", &RevisionConflictError{ScheduleName: data.ScheduleName, Revision: data.Revision, CurrentRevision: -1})
+		}
+		plan.CreateSchedule = true
+		state = scheduleState{volunteerNames: map[int]string{}, ufs: map[int][]unavailabilityForSchedule{}, dates: map[int]date{}}
+	case 1:
+		revision, err := sm.RequestScheduleRevision(data.User, existing[0].ScheduleID)
+		if err != nil {
+			return ChangePlan{}, fmt.Errorf("error in PlanReceivedData: %w", err)
+		}
+		if revision != data.Revision {
+			diverged, err := sm.divergedParts(data)
+			if err != nil {
+				return ChangePlan{}, fmt.Errorf("error in PlanReceivedData: %w", err)
+			}
+			return ChangePlan{}, fmt.Errorf("error in PlanReceivedData: %w", &RevisionConflictError{ScheduleName: data.ScheduleName, Revision: data.Revision, CurrentRevision: revision, Diverged: diverged})
+		}
+		if _, err := sm.authorizeSchedule(data.User, existing[0].ScheduleID, ScheduleEditor); err != nil {
+			return ChangePlan{}, fmt.Errorf("error in PlanReceivedData: %w", err)
+		}
+		plan.User, plan.Schedule.User = existing[0].User, existing[0].User
+		state, err = sm.requestScheduleState(data.User, existing[0])
+		if err != nil {
+			return ChangePlan{}, fmt.Errorf("error in PlanReceivedData: %w", err)
+		}
+		current := existing[0]
+		plan.Schedule.ScheduleID = current.ScheduleID
+		if current.ShiftsOff != data.ShiftsOff {
+			plan.ScheduleChanges = append(plan.ScheduleChanges, columnChange{Column: "ShiftsOff", From: fmt.Sprint(current.ShiftsOff), To: fmt.Sprint(data.ShiftsOff)})
+		}
+		if current.VolunteersPerShift != data.VolunteersPerShift {
+			plan.ScheduleChanges = append(plan.ScheduleChanges, columnChange{Column: "VolunteersPerShift", From: fmt.Sprint(current.VolunteersPerShift), To: fmt.Sprint(data.VolunteersPerShift)})
+		}
+		if current.StartDate != startDate.DateID {
+			plan.ScheduleChanges = append(plan.ScheduleChanges, columnChange{Column: "StartDate", From: isoDate(state.dates[current.StartDate]), To: isoDate(startDate)})
+		}
+		if current.EndDate != endDate.DateID {
+			plan.ScheduleChanges = append(plan.ScheduleChanges, columnChange{Column: "EndDate", From: isoDate(state.dates[current.EndDate]), To: isoDate(endDate)})
+		}
+	default:
+		return ChangePlan{}, fmt.Errorf("error in PlanReceivedData: method failed because %d schedules are named %s", len(existing), data.ScheduleName)
+	}
+	for _, val := range state.wfs {
+		if !slices.Contains(incomingWeekdays, val.Weekday) {
+			plan.WeekdaysToRemove = append(plan.WeekdaysToRemove, val)
+		}
+	}
+	for _, val := range incomingWeekdays {
+		if !slices.ContainsFunc(state.wfs, func(wfs weekdayForSchedule) bool { return wfs.Weekday == val }) {
+			plan.WeekdaysToAdd = append(plan.WeekdaysToAdd, weekdayForSchedule{User: plan.User, Weekday: val, Schedule: plan.Schedule.ScheduleID})
+		}
+	}
+	volunteers, err := sm.RequestPoolVolunteers(plan.User, []volunteer{})
+	if err != nil {
+		return ChangePlan{}, fmt.Errorf("error in PlanReceivedData: %w", err)
+	}
+	inPool, active := map[string]bool{}, map[string]bool{}
+	for _, val := range volunteers {
+		inPool[val.VolunteerName] = true
+		active[val.VolunteerName] = active[val.VolunteerName] || !val.Inactive
+	}
+	enrolled := map[string]volunteerForSchedule{}
+	for _, val := range state.vfs {
+		name := state.volunteerNames[val.Volunteer]
+		enrolled[name] = val
+		if _, ok := incomingAvailability[name]; !ok {
+			plan.VolunteersToRemove = append(plan.VolunteersToRemove, plannedVolunteer{VFSID: val.VFSID, VolunteerName: name})
+			for _, ufs := range state.ufs[val.VFSID] {
+				plan.UnavailabilitiesToRemove = append(plan.UnavailabilitiesToRemove, plannedUnavailability{UFSID: ufs.UFSID, VolunteerName: name, Date: state.dates[ufs.Date]})
+			}
+		}
+	}
+	for _, name := range incomingNames {
+		vfs, isEnrolled := enrolled[name]
+		if !isEnrolled && inPool[name] && !active[name] { // inactive volunteers already on the schedule stay, but they can't be added
+			plan.VolunteersRejected = append(plan.VolunteersRejected, name)
+			continue
+		}
+		if !inPool[name] {
+			plan.VolunteersToCreate = append(plan.VolunteersToCreate, name)
+		}
+		if !isEnrolled {
+			plan.VolunteersToAdd = append(plan.VolunteersToAdd, plannedVolunteer{VolunteerName: name})
+		}
+		var incomingDates []date
+		for _, val := range incomingAvailability[name] {
+			dateStruct := resolvedDates[val]
+			if dateStruct.DateID < startDate.DateID || dateStruct.DateID > endDate.DateID {
+				return ChangePlan{}, fmt.Errorf("error in PlanReceivedData: method failed because unavailable date %s of volunteer %s is outside of the schedule (%s to %s)", val, name, data.StartDate, data.EndDate)
+			}
+			if slices.Contains(incomingDates, dateStruct) {
+				return ChangePlan{}, fmt.Errorf("error in PlanReceivedData: method failed because unavailable date %s of volunteer %s appears more than once", val, name)
+			}
+			incomingDates = append(incomingDates, dateStruct)
+		}
+		sort.Slice(incomingDates, func(i, j int) bool { return incomingDates[i].DateID < incomingDates[j].DateID })
+		var currentUFS []unavailabilityForSchedule
+		if isEnrolled {
+			currentUFS = state.ufs[vfs.VFSID]
+		}
+		for _, ufs := range currentUFS {
+			if !slices.ContainsFunc(incomingDates, func(d date) bool { return d.DateID == ufs.Date }) {
+				plan.UnavailabilitiesToRemove = append(plan.UnavailabilitiesToRemove, plannedUnavailability{UFSID: ufs.UFSID, VolunteerName: name, Date: state.dates[ufs.Date]})
+			}
+		}
+		for _, dateStruct := range incomingDates {
+			if !slices.ContainsFunc(currentUFS, func(ufs unavailabilityForSchedule) bool { return ufs.Date == dateStruct.DateID }) {
+				plan.UnavailabilitiesToAdd = append(plan.UnavailabilitiesToAdd, plannedUnavailability{VolunteerName: name, Date: dateStruct})
+			}
+		}
+	}
+	return plan, nil
+}
+
+// Executes plan in a single transaction. If the schedule's Revision is no longer plan.Revision, a *RevisionConflictError is returned (without Diverged, because the plan does not hold the data it was made from) and nothing is written.
+func (sm SampleModel) ApplyChangePlan(plan ChangePlan) error {
+	if len(plan.User) == 0 {
+		return fmt.Errorf("error in ApplyChangePlan: method failed because plan did not have a value for User: %+v", plan)
+	}
+	if !plan.CreateSchedule && plan.Schedule.ScheduleID < 1 {
+		return fmt.Errorf("error in ApplyChangePlan: method failed because plan updates a schedule but did not have a ScheduleID: %+v", plan.Schedule)
+	}
+	tx, err := sm.beginAudited(plan.RequestedBy)
+	if err != nil {
+		return fmt.Errorf("error in ApplyChangePlan: %w", err)
+	}
+	defer tx.Rollback()
+	scheduleID := plan.Schedule.ScheduleID
+	err = checkRevision(tx.Tx, plan)
+	if err != nil {
+		return fmt.Errorf("error in ApplyChangePlan: %w", err)
+	}
+	if plan.IsEmpty() {
+		return nil
+	}
+	if plan.CreateSchedule {
+		res, err := tx.Exec(`insert into Schedules (ScheduleName, ShiftsOff, VolunteersPerShift, User, StartDate, EndDate) values (?, ?, ?, ?, ?, ?)`, plan.ScheduleName, plan.Schedule.ShiftsOff, plan.Schedule.VolunteersPerShift, plan.User, plan.Schedule.StartDate, plan.Schedule.EndDate)
+		if err != nil {
+			return fmt.Errorf("error in ApplyChangePlan: sql.Tx.Exec error: %w. Value of plan.Schedule is `%+v`", err, plan.Schedule)
+		}
+		lastID, err := res.LastInsertId()
+		if err != nil {
+			return fmt.Errorf("error in ApplyChangePlan: sql.Result.LastInsertId error: %w", err)
+		}
+		scheduleID = int(lastID)
+	} else if len(plan.ScheduleChanges) > 0 {
+		err = execOneRow(tx.Tx, `update Schedules set ShiftsOff=?, VolunteersPerShift=?, StartDate=?, EndDate=? where User=? and ScheduleID=?`, plan.Schedule.ShiftsOff, plan.Schedule.VolunteersPerShift, plan.Schedule.StartDate, plan.Schedule.EndDate, plan.User, scheduleID)
+		if err != nil {
+			return fmt.Errorf("error in ApplyChangePlan: %w", err)
+		}
+	}
+	for _, val := range plan.UnavailabilitiesToRemove {
+		err = execOneRow(tx.Tx, `delete from UnavailabilitiesForSchedule where User=? and UFSID=?`, plan.User, val.UFSID)
+		if err != nil {
+			return fmt.Errorf("error in ApplyChangePlan: %w", err)
+		}
+	}
+	for _, val := range plan.VolunteersToRemove {
+		err = execOneRow(tx.Tx, `delete from VolunteersForSchedule where User=? and VFSID=?`, plan.User, val.VFSID)
+		if err != nil {
+			return fmt.Errorf("error in ApplyChangePlan: %w", err)
+		}
+	}
+	for _, val := range plan.WeekdaysToRemove {
+		err = execOneRow(tx.Tx, `delete from WeekdaysForSchedule where User=? and WFSID=?`, plan.User, val.WFSID)
+		if err != nil {
+			return fmt.Errorf("error in ApplyChangePlan: %w", err)
+		}
+	}
+	for _, val := range plan.WeekdaysToAdd {
+		_, err = tx.Exec(`insert into WeekdaysForSchedule (User, Weekday, Schedule) values (?, ?, ?)`, plan.User, val.Weekday, scheduleID)
+		if err != nil {
+			return fmt.Errorf("error in ApplyChangePlan: sql.Tx.Exec error: %w. Value of val is `%+v`", err, val)
+		}
+	}
+	volunteerIDs := map[string]int{}
+	for _, val := range plan.VolunteersToCreate {
+		res, err := tx.Exec(`insert into Volunteers (VolunteerName, User) values (?, ?)`, val, plan.User)
+		if err != nil {
+			return fmt.Errorf("error in ApplyChangePlan: sql.Tx.Exec error: %w. Value of val is `%s`", err, val)
+		}
+		lastID, err := res.LastInsertId()
+		if err != nil {
+			return fmt.Errorf("error in ApplyChangePlan: sql.Result.LastInsertId error: %w", err)
+		}
+		volunteerIDs[val] = int(lastID)
+	}
+	volunteerID := func(name string) (int, error) {
+		if id, ok := volunteerIDs[name]; ok {
+			return id, nil
+		}
+		var id int
+		// an active volunteer wins over an inactive one, and the owner's own volunteer over one of the same name in an organization's pool
+		err := tx.QueryRow(fmt.Sprintf(`select VolunteerID from Volunteers where %s and VolunteerName=? order by Active desc, User != ? limit 1`, volunteerPool(plan.User)), name, plan.User).Scan(&id)
+		if err != nil {
+			return 0, fmt.Errorf("sql.Tx.QueryRow error: %w. Value of name is `%s`", err, name)
+		}
+		volunteerIDs[name] = id
+		return id, nil
+	}
+	vfsIDs := map[string]int{}
+	for _, val := range plan.VolunteersToAdd {
+		id, err := volunteerID(val.VolunteerName)
+		if err != nil {
+			return fmt.Errorf("error in ApplyChangePlan: %w", err)
+		}
+		res, err := tx.Exec(`insert into VolunteersForSchedule (User, Schedule, Volunteer) values (?, ?, ?)`, plan.User, scheduleID, id)
+		if err != nil {
+			return fmt.Errorf("error in ApplyChangePlan: sql.Tx.Exec error: %w. Value of val is `%+v`", err, val)
+		}
+		lastID, err := res.LastInsertId()
+		if err != nil {
+			return fmt.Errorf("error in ApplyChangePlan: sql.Result.LastInsertId error: %w", err)
+		}
+		vfsIDs[val.VolunteerName] = int(lastID)
+	}
+	for _, val := range plan.UnavailabilitiesToAdd {
+		vfsID, ok := vfsIDs[val.VolunteerName]
+		if !ok {
+			id, err := volunteerID(val.VolunteerName)
+			if err != nil {
+				return fmt.Errorf("error in ApplyChangePlan: %w", err)
+			}
+			err = tx.QueryRow(`select VFSID from VolunteersForSchedule where User=? and Schedule=? and Volunteer=?`, plan.User, scheduleID, id).Scan(&vfsID)
+			if err != nil {
+				return fmt.Errorf("error in ApplyChangePlan: sql.Tx.QueryRow error: %w. Value of val is `%+v`", err, val)
+			}
+			vfsIDs[val.VolunteerName] = vfsID
+		}
+		_, err = tx.Exec(`insert into UnavailabilitiesForSchedule (User, VolunteerForSchedule, Date) values (?, ?, ?)`, plan.User, vfsID, val.Date.DateID)
+		if err != nil {
+			return fmt.Errorf("error in ApplyChangePlan: sql.Tx.Exec error: %w. Value of val is `%+v`", err, val)
+		}
+	}
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("error in ApplyChangePlan: sql.Tx.Commit error: %w", err)
+	}
+	return nil
+}
+
+// Verifies inside tx that plan's schedule is still at plan.Revision, or still does not exist if plan creates it.
+func checkRevision(tx *sql.Tx, plan ChangePlan) error {
+	if plan.CreateSchedule {
+		var count int
+		err := tx.QueryRow(`select count(*) from Schedules where User=? and ScheduleName=?`, plan.User, plan.ScheduleName).Scan(&count)
+		if err != nil {
+			return fmt.Errorf("sql.Tx.QueryRow error: %w", err)
+		}
+		if count > 0 {
+			return &RevisionConflictError{ScheduleName: plan.ScheduleName, Revision: plan.Revision, CurrentRevision: 0}
+		}
+		return nil
+	}
+	var revision int
+	err := tx.QueryRow(`select Revision from Schedules where User=? and ScheduleID=?`, plan.User, plan.Schedule.ScheduleID).Scan(&revision)
+	if errors.Is(err, sql.ErrNoRows) {
+		return &RevisionConflictError{ScheduleName: plan.ScheduleName, Revision: plan.Revision, CurrentRevision: -1}
+	}
+	if err != nil {
+		return fmt.Errorf("sql.Tx.QueryRow error: %w", err)
+	}
+	if revision != plan.Revision {
+		return &RevisionConflictError{ScheduleName: plan.ScheduleName, Revision: plan.Revision, CurrentRevision: revision}
+	}
+	return nil
+}
+
+// Executes query in tx and fails unless it changed exactly one row.
+func execOneRow(tx *sql.Tx, query string, args ...any) error {
+	res, err := tx.Exec(query, args...)
+	if err != nil {
+		return fmt.Errorf("sql.Tx.Exec error: %w. Value of query is `%s`", err, query)
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("sql.Result.RowsAffected error: %w", err)
+	}
+	if affected != 1 {
+		return fmt.Errorf("expected `%s` with %v to change exactly one row but it changed %d, so the plan is out of date", query, args, affected)
+	}
+	return nil
+}
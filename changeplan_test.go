@@ -0,0 +1,252 @@
+package main
+
+import (
+	"errors"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+// Returns the test1 payload from setUpSampleData after edit has been applied to it.
+func editedPayload(t *testing.T, env *Env, edit func(data *SendReceiveDataStruct)) SendReceiveDataStruct {
+	data, err := env.sample.FetchAndSendData(env.loggedInUser, "test1")
+	if err != nil {
+		t.Errorf("Error setting up test (FetchAndSendData failed): %v", err)
+		t.FailNow()
+	}
+	edit(&data)
+	return data
+}
+
+func TestPlanReceivedData(t *testing.T) {
+	env, tearDownEnvironment := setUpEnvironment(t)
+	defer tearDownEnvironment(t)
+	setUpSampleData(t, env)
+	tests := []struct {
+		name    string
+		input   SendReceiveDataStruct
+		want    []string
+		wantErr bool
+	}{
+		{name: "Unchanged payload plans nothing", input: editedPayload(t, env, func(data *SendReceiveDataStruct) {}), want: nil},
+		{name: "Add a new volunteer with an unavailable date", input: editedPayload(t, env, func(data *SendReceiveDataStruct) {
+			data.VolunteerAvailabilityData = append(data.VolunteerAvailabilityData, map[string][]string{"Zed": {"2024-02-04"}})
+		}), want: []string{"create volunteer Zed", "add volunteer Zed to the schedule", "add unavailability of Zed on 2024-02-04"}},
+		{name: "Add an existing volunteer", input: editedPayload(t, env, func(data *SendReceiveDataStruct) {
+			data.VolunteerAvailabilityData = append(data.VolunteerAvailabilityData, map[string][]string{"Larry": {}})
+		}), want: []string{"add volunteer Larry to the schedule"}},
+		{name: "Remove a volunteer and their unavailability", input: editedPayload(t, env, func(data *SendReceiveDataStruct) {
+			data.VolunteerAvailabilityData = data.VolunteerAvailabilityData[1:]
+		}), want: []string{"remove unavailability of Tim on 2024-01-14", "remove volunteer Tim from the schedule"}},
+		{name: "Swap an unavailable date", input: editedPayload(t, env, func(data *SendReceiveDataStruct) {
+			data.VolunteerAvailabilityData[1] = map[string][]string{"Bill": {"2024-01-28"}}
+		}), want: []string{"remove unavailability of Bill on 2024-01-21", "add unavailability of Bill on 2024-01-28"}},
+		{name: "Change weekdays and schedule columns", input: editedPayload(t, env, func(data *SendReceiveDataStruct) {
+			data.WeekdaysForSchedule = []string{"Saturday"}
+			data.ShiftsOff = 2
+			data.EndDate = "2024-02-01"
+		}), want: []string{`change ShiftsOff of schedule "test1" from 3 to 2`, `change EndDate of schedule "test1" from 2024-03-01 to 2024-02-01`, "remove weekday Sunday", "add weekday Saturday"}},
+		{name: "Create a new schedule", input: SendReceiveDataStruct{
+			User:                      env.loggedInUser,
+			ScheduleName:              "test9",
+			VolunteerAvailabilityData: []map[string][]string{{"Tim": {"2024-10-06"}}},
+			StartDate:                 "2024-10-01",
+			EndDate:                   "2024-10-31",
+			WeekdaysForSchedule:       []string{"Sunday"},
+			ShiftsOff:                 1,
+			VolunteersPerShift:        2,
+		}, want: []string{`create schedule "test9" (ShiftsOff 1, VolunteersPerShift 2)`, "add weekday Sunday", "add volunteer Tim to the schedule", "add unavailability of Tim on 2024-10-06"}},
+		{name: "Fail because of a malformed date", input: editedPayload(t, env, func(data *SendReceiveDataStruct) {
+			data.VolunteerAvailabilityData[0] = map[string][]string{"Tim": {"1/14/2024"}}
+		}), wantErr: true},
+		{name: "Fail because of a date outside of the schedule", input: editedPayload(t, env, func(data *SendReceiveDataStruct) {
+			data.VolunteerAvailabilityData[0] = map[string][]string{"Tim": {"2024-05-05"}}
+		}), wantErr: true},
+		{name: "Fail because of a misspelled weekday", input: editedPayload(t, env, func(data *SendReceiveDataStruct) {
+			data.WeekdaysForSchedule = []string{"Sundae"}
+		}), wantErr: true},
+		{name: "Fail because a volunteer appears twice", input: editedPayload(t, env, func(data *SendReceiveDataStruct) {
+			data.VolunteerAvailabilityData = append(data.VolunteerAvailabilityData, map[string][]string{"Tim": {}})
+		}), wantErr: true},
+		{name: "Fail because EndDate is before StartDate", input: editedPayload(t, env, func(data *SendReceiveDataStruct) {
+			data.EndDate = "2023-12-01"
+		}), wantErr: true},
+		{name: "Fail because User is missing", input: editedPayload(t, env, func(data *SendReceiveDataStruct) {
+			data.User = ""
+		}), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ans, err := env.sample.PlanReceivedData(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("got error: `%v`, want error: %t", err, tt.wantErr)
+			} else if err != nil {
+				t.Logf("logged error: `%v` for input: `%+v`", err, tt.input)
+			}
+			if !slices.Equal(ans.Summary(), tt.want) {
+				t.Errorf("got %q, want %q", ans.Summary(), tt.want)
+			}
+		})
+	}
+	if check, err := env.sample.FetchAndSendData(env.loggedInUser, "test1"); err != nil || !reflect.DeepEqual(check, editedPayload(t, env, func(data *SendReceiveDataStruct) {})) {
+		t.Errorf("PlanReceivedData must not write anything, but test1 is now %+v (error: %v)", check, err)
+	}
+}
+
+func TestApplyChangePlan(t *testing.T) {
+	env, tearDownEnvironment := setUpEnvironment(t)
+	defer tearDownEnvironment(t)
+	setUpSampleData(t, env)
+	tests := []struct {
+		name  string
+		input SendReceiveDataStruct
+	}{
+		{name: "Apply a mix of additions and removals", input: editedPayload(t, env, func(data *SendReceiveDataStruct) {
+			data.VolunteerAvailabilityData = []map[string][]string{
+				{"Bill": {"2024-01-28", "2024-02-04"}},
+				{"Jack": {}},
+				{"George": {"2024-01-07"}},
+				{"Zed": {"2024-01-14"}},
+			}
+			data.WeekdaysForSchedule = []string{"Saturday", "Sunday"}
+			data.VolunteersPerShift = 2
+		})},
+		{name: "Apply a payload for a new schedule", input: SendReceiveDataStruct{
+			User:                      env.loggedInUser,
+			ScheduleName:              "test9",
+			VolunteerAvailabilityData: []map[string][]string{{"Tim": {"2024-10-06"}}, {"Yves": {}}},
+			StartDate:                 "2024-10-01",
+			EndDate:                   "2024-10-31",
+			WeekdaysForSchedule:       []string{"Sunday"},
+			ShiftsOff:                 1,
+			VolunteersPerShift:        2,
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan, err := env.sample.PlanReceivedData(tt.input)
+			if err != nil {
+				t.Errorf("got error from PlanReceivedData: `%v`", err)
+				t.FailNow()
+			}
+			err = env.sample.ApplyChangePlan(plan)
+			if err != nil {
+				t.Errorf("got error: `%v` for plan: `%+v`", err, plan)
+			}
+			check, err := env.sample.FetchAndSendData(env.loggedInUser, tt.input.ScheduleName)
+			if err != nil {
+				t.Errorf("got error from FetchAndSendData: `%v`", err)
+			}
+			if check.Revision <= tt.input.Revision {
+				t.Errorf("got Revision %d after applying, want more than %d", check.Revision, tt.input.Revision)
+			}
+			tt.input.Revision = check.Revision
+			replan, err := env.sample.PlanReceivedData(tt.input)
+			if err != nil || !replan.IsEmpty() {
+				t.Errorf("applying the plan did not store the payload, stored %+v, remaining changes %q (error: %v)", check, replan.Summary(), err)
+			}
+		})
+	}
+	t.Run("Fail to apply an outdated plan", func(t *testing.T) {
+		plan, err := env.sample.PlanReceivedData(editedPayload(t, env, func(data *SendReceiveDataStruct) {
+			data.VolunteerAvailabilityData = data.VolunteerAvailabilityData[1:]
+		}))
+		if err != nil {
+			t.Errorf("got error from PlanReceivedData: `%v`", err)
+			t.FailNow()
+		}
+		err = env.sample.DeleteUFS(env.loggedInUser, []unavailabilityForSchedule{{UFSID: plan.UnavailabilitiesToRemove[0].UFSID}})
+		if err != nil {
+			t.Errorf("got error from DeleteUFS: `%v`", err)
+		}
+		before, _ := env.sample.FetchAndSendData(env.loggedInUser, "test1")
+		err = env.sample.ApplyChangePlan(plan)
+		if err == nil {
+			t.Errorf("got no error, want an error because the plan removes a UFS row that no longer exists")
+		}
+		after, _ := env.sample.FetchAndSendData(env.loggedInUser, "test1")
+		if !reflect.DeepEqual(before, after) {
+			t.Errorf("a failed plan must not write anything, got %+v, want %+v", after, before)
+		}
+	})
+}
+
+func TestRecieveAndStoreData(t *testing.T) {
+	env, tearDownEnvironment := setUpEnvironment(t)
+	defer tearDownEnvironment(t)
+	setUpSampleData(t, env)
+	input := editedPayload(t, env, func(data *SendReceiveDataStruct) {
+		data.VolunteerAvailabilityData = append(data.VolunteerAvailabilityData[2:], map[string][]string{"Larry": {"2024-02-11"}})
+	})
+	err := env.sample.RecieveAndStoreData(input)
+	if err != nil {
+		t.Errorf("got error: `%v` for input: `%+v`", err, input)
+	}
+	check, err := env.sample.FetchAndSendData(env.loggedInUser, "test1")
+	if err != nil {
+		t.Errorf("got error from FetchAndSendData: `%v`", err)
+	}
+	input.Revision = check.Revision
+	if !reflect.DeepEqual(check, input) {
+		t.Errorf("got %+v, want %+v", check, input)
+	}
+}
+
+func TestRevisionConflicts(t *testing.T) {
+	env, tearDownEnvironment := setUpEnvironment(t)
+	defer tearDownEnvironment(t)
+	setUpSampleData(t, env)
+	stale := editedPayload(t, env, func(data *SendReceiveDataStruct) {
+		data.VolunteerAvailabilityData[0] = map[string][]string{"Tim": {"2024-01-14", "2024-01-28"}}
+	})
+	// Another device changes ShiftsOff and Bill's unavailability after stale was fetched.
+	err := env.sample.RecieveAndStoreData(editedPayload(t, env, func(data *SendReceiveDataStruct) {
+		data.ShiftsOff = 2
+		data.VolunteerAvailabilityData[1] = map[string][]string{"Bill": {}}
+	}))
+	if err != nil {
+		t.Errorf("Error setting up test (RecieveAndStoreData failed): %v", err)
+		t.FailNow()
+	}
+	current := editedPayload(t, env, func(data *SendReceiveDataStruct) {})
+	tests := []struct {
+		name         string
+		input        SendReceiveDataStruct
+		wantDiverged []string
+	}{
+		{name: "Reject a stale payload", input: stale, wantDiverged: []string{"ShiftsOff", "VolunteerAvailabilityData[Bill]", "VolunteerAvailabilityData[Tim]"}},
+		{name: "Reject a payload for a schedule that no longer exists", input: SendReceiveDataStruct{User: env.loggedInUser, ScheduleName: "test9", StartDate: "2024-10-01", EndDate: "2024-10-31", ShiftsOff: 1, VolunteersPerShift: 1, Revision: 3}, wantDiverged: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := env.sample.RecieveAndStoreData(tt.input)
+			var conflict *RevisionConflictError
+			if !errors.As(err, &conflict) {
+				t.Errorf("got error: `%v`, want a *RevisionConflictError", err)
+				t.FailNow()
+			}
+			t.Logf("logged error: `%v`", err)
+			if !slices.Equal(conflict.Diverged, tt.wantDiverged) {
+				t.Errorf("got Diverged %q, want %q", conflict.Diverged, tt.wantDiverged)
+			}
+		})
+	}
+	t.Run("Reject a plan that became stale before it was applied", func(t *testing.T) {
+		plan, err := env.sample.PlanReceivedData(editedPayload(t, env, func(data *SendReceiveDataStruct) { data.ShiftsOff = 4 }))
+		if err != nil {
+			t.Errorf("got error from PlanReceivedData: `%v`", err)
+			t.FailNow()
+		}
+		err = env.sample.CreateWFS(env.loggedInUser, []weekdayForSchedule{{Weekday: "Monday", Schedule: plan.Schedule.ScheduleID}})
+		if err != nil {
+			t.Errorf("got error from CreateWFS: `%v`", err)
+		}
+		var conflict *RevisionConflictError
+		if err := env.sample.ApplyChangePlan(plan); !errors.As(err, &conflict) {
+			t.Errorf("got error: `%v`, want a *RevisionConflictError", err)
+		}
+	})
+	if check := editedPayload(t, env, func(data *SendReceiveDataStruct) {}); check.ShiftsOff != current.ShiftsOff {
+		t.Errorf("a rejected payload or plan must not be stored, got ShiftsOff %d, want %d", check.ShiftsOff, current.ShiftsOff)
+	}
+}
@@ -0,0 +1,1388 @@
+package main
+
+import (
+	"bufio"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+const cliUsage = `usage: sampledatabase [--db PATH] [--user NAME] [--format table|json|csv] COMMAND [ARGS]
+
+commands:
+  users add NAME
+  users passwd NAME
+  users delete NAME [--dry-run] [--archive PATH]
+  users unlock NAME | users unlock --source ADDR
+  users attempts NAME [--limit N]
+  keys create NAME --scope read-only|roster-write|admin [--expires-in DURATION]
+  keys list
+  keys revoke KEY_ID
+  volunteers add NAME...
+  volunteers list [--all]
+  volunteers rename NAME NEW_NAME
+  volunteers show NAME
+  volunteers set NAME [--email ADDR] [--phone NUMBER] [--contact email|phone|sms|none] [--notes TEXT] [--active=true|false]
+  volunteers remove NAME...
+  volunteers reactivate NAME...
+  volunteers duplicates
+  volunteers merge SURVIVOR DUPLICATE
+  schedules create NAME --start YYYY-MM-DD --end YYYY-MM-DD --shifts-off N --per-shift N
+  schedules list
+  schedules update NAME [--name NEW_NAME] [--start YYYY-MM-DD] [--end YYYY-MM-DD] [--shifts-off N] [--per-shift N]
+  schedules delete NAME...
+  schedule weekdays set SCHEDULE WEEKDAY...
+  schedule volunteers add SCHEDULE NAME...
+  schedule members list SCHEDULE
+  schedule members set SCHEDULE USER viewer|editor|owner
+  schedule members remove SCHEDULE USER
+  orgs create NAME
+  orgs list
+  orgs delete NAME
+  org members list ORG
+  org members add ORG USER
+  org members remove ORG USER
+  org volunteers list ORG
+  org volunteers add ORG NAME...
+  org volunteers remove ORG NAME...
+  org schedules ORG
+  groups create NAME
+  groups list
+  groups rename NAME NEW_NAME
+  groups delete NAME
+  group volunteers list GROUP
+  group volunteers add GROUP NAME...
+  group volunteers remove GROUP NAME...
+  group enroll GROUP SCHEDULE
+  unavailable add SCHEDULE VOLUNTEER YYYY-MM-DD...
+  roster generate SCHEDULE
+  roster show SCHEDULE [--layout wide|long]
+  audit [--schedule SCHEDULE] [--volunteer NAME] [--user NAME] [--table TABLE] [--limit N]
+  shell
+  serve [ADDR]
+  demo
+
+users add reads the password from the first line of stdin, and users passwd reads the old and the new password from the first two lines.
+users delete deletes the user with everything it owns. --dry-run only counts the rows, and --archive exports them as a backup first.
+users unlock lifts the login backoff or lockout of a user or of a source address, and users attempts lists the user's latest login attempts.
+volunteers set only changes the fields given. An empty value clears a field.
+volunteers remove deactivates volunteers rather than deleting them, so the schedules they were on keep their names. Inactive volunteers can't be added to schedules, and volunteers list only shows them with --all.
+volunteers duplicates lists volunteers that are likely the same person, and volunteers merge moves everything of DUPLICATE to SURVIVOR and deletes DUPLICATE.
+group enroll adds every active volunteer of GROUP to SCHEDULE, skipping those on it already and, on a shared schedule, those outside the owner's pool.
+keys create prints a new API key for scripts to authenticate with as a Bearer token. It is only shown once. --expires-in takes a duration like 720h, and keys without it never expire.
+audit lists who changed what, oldest first. --limit keeps only the newest N changes.
+`
+
+// A mistake in how a command was invoked rather than a failure while running it. run prints cliUsage and exits with 2 for these.
+type usageError struct {
+	msg string
+}
+
+func (e usageError) Error() string {
+	return e.msg
+}
+
+type cli struct {
+	env    *Env
+	format string // table, json, or csv
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+}
+
+type cliCommand struct {
+	path []string // the words naming the command, for example {"schedule", "weekdays", "set"}
+	run  func(c cli, args []string) error
+}
+
+var cliCommands []cliCommand
+
+// cliCommands is assigned here rather than in its declaration because the shell command looks commands up in it, which would be an initialization cycle.
+func init() {
+	cliCommands = []cliCommand{
+		{path: []string{"users", "add"}, run: cli.usersAdd},
+		{path: []string{"users", "passwd"}, run: cli.usersPasswd},
+		{path: []string{"users", "delete"}, run: cli.usersDelete},
+		{path: []string{"users", "unlock"}, run: cli.usersUnlock},
+		{path: []string{"users", "attempts"}, run: cli.usersAttempts},
+		{path: []string{"keys", "create"}, run: cli.keysCreate},
+		{path: []string{"keys", "list"}, run: cli.keysList},
+		{path: []string{"keys", "revoke"}, run: cli.keysRevoke},
+		{path: []string{"volunteers", "add"}, run: cli.volunteersAdd},
+		{path: []string{"volunteers", "list"}, run: cli.volunteersList},
+		{path: []string{"volunteers", "rename"}, run: cli.volunteersRename},
+		{path: []string{"volunteers", "show"}, run: cli.volunteersShow},
+		{path: []string{"volunteers", "set"}, run: cli.volunteersSet},
+		{path: []string{"volunteers", "remove"}, run: cli.volunteersRemove},
+		{path: []string{"volunteers", "reactivate"}, run: cli.volunteersReactivate},
+		{path: []string{"volunteers", "duplicates"}, run: cli.volunteersDuplicates},
+		{path: []string{"volunteers", "merge"}, run: cli.volunteersMerge},
+		{path: []string{"schedules", "create"}, run: cli.schedulesCreate},
+		{path: []string{"schedules", "list"}, run: cli.schedulesList},
+		{path: []string{"schedules", "update"}, run: cli.schedulesUpdate},
+		{path: []string{"schedules", "delete"}, run: cli.schedulesDelete},
+		{path: []string{"schedule", "weekdays", "set"}, run: cli.scheduleWeekdaysSet},
+		{path: []string{"schedule", "volunteers", "add"}, run: cli.scheduleVolunteersAdd},
+		{path: []string{"schedule", "members", "list"}, run: cli.scheduleMembersList},
+		{path: []string{"schedule", "members", "set"}, run: cli.scheduleMembersSet},
+		{path: []string{"schedule", "members", "remove"}, run: cli.scheduleMembersRemove},
+		{path: []string{"orgs", "create"}, run: cli.orgsCreate},
+		{path: []string{"orgs", "list"}, run: cli.orgsList},
+		{path: []string{"orgs", "delete"}, run: cli.orgsDelete},
+		{path: []string{"org", "members", "list"}, run: cli.orgMembersList},
+		{path: []string{"org", "members", "add"}, run: cli.orgMembersAdd},
+		{path: []string{"org", "members", "remove"}, run: cli.orgMembersRemove},
+		{path: []string{"org", "volunteers", "list"}, run: cli.orgVolunteersList},
+		{path: []string{"org", "volunteers", "add"}, run: cli.orgVolunteersAdd},
+		{path: []string{"org", "volunteers", "remove"}, run: cli.orgVolunteersRemove},
+		{path: []string{"org", "schedules"}, run: cli.orgSchedules},
+		{path: []string{"groups", "create"}, run: cli.groupsCreate},
+		{path: []string{"groups", "list"}, run: cli.groupsList},
+		{path: []string{"groups", "rename"}, run: cli.groupsRename},
+		{path: []string{"groups", "delete"}, run: cli.groupsDelete},
+		{path: []string{"group", "volunteers", "list"}, run: cli.groupVolunteersList},
+		{path: []string{"group", "volunteers", "add"}, run: cli.groupVolunteersAdd},
+		{path: []string{"group", "volunteers", "remove"}, run: cli.groupVolunteersRemove},
+		{path: []string{"group", "enroll"}, run: cli.groupEnroll},
+		{path: []string{"audit"}, run: cli.audit},
+		{path: []string{"unavailable", "add"}, run: cli.unavailableAdd},
+		{path: []string{"roster", "generate"}, run: cli.rosterGenerate},
+		{path: []string{"roster", "show"}, run: cli.rosterShow},
+		{path: []string{"shell"}, run: cli.shell},
+		{path: []string{"serve"}, run: cli.serve},
+		{path: []string{"demo"}, run: cli.demo},
+	}
+}
+
+// Runs the command named by args and returns the process exit code: 0 on success, 1 if the command failed, and 2 if it was invoked incorrectly.
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	globals := flag.NewFlagSet("sampledatabase", flag.ContinueOnError)
+	globals.SetOutput(io.Discard)
+	dbPath := globals.String("db", "./sample.db", "path of the SQLite database, created if it does not exist")
+	currentUser := globals.String("user", "Seth", "UserName to act as")
+	format := globals.String("format", "table", "output format: table, json, or csv")
+	err := globals.Parse(args)
+	if errors.Is(err, flag.ErrHelp) {
+		fmt.Fprint(stdout, cliUsage)
+		return 0
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "%v\n%s", err, cliUsage)
+		return 2
+	}
+	if !slices.Contains([]string{"table", "json", "csv"}, *format) {
+		fmt.Fprintf(stderr, "unknown format `%s`\n%s", *format, cliUsage)
+		return 2
+	}
+	args = globals.Args()
+	if len(args) == 0 || args[0] == "help" {
+		fmt.Fprint(stdout, cliUsage)
+		return 0
+	}
+	var command *cliCommand
+	for i, val := range cliCommands {
+		if len(args) >= len(val.path) && slices.Equal(args[:len(val.path)], val.path) {
+			command = &cliCommands[i]
+			break
+		}
+	}
+	if command == nil {
+		fmt.Fprintf(stderr, "unknown command `%s`\n%s", strings.Join(args, " "), cliUsage)
+		return 2
+	}
+	sampleModel, err := openDatabase(*dbPath)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	defer sampleModel.DB.Close()
+	c := cli{env: &Env{sample: sampleModel, loggedInUser: *currentUser}, format: *format, stdin: stdin, stdout: stdout, stderr: stderr}
+	err = command.run(c, args[len(command.path):])
+	var usage usageError
+	if errors.As(err, &usage) {
+		fmt.Fprintf(stderr, "%s: %v\n%s", strings.Join(command.path, " "), err, cliUsage)
+		return 2
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	return 0
+}
+
+// Opens the SQLite database at path with foreign keys on, creates the tables if the file is new, and applies any pending migrations.
+func openDatabase(path string) (SampleModel, error) {
+	db, err := sql.Open("sqlite3", fmt.Sprintf("%s?_foreign_keys=on", path))
+	if err != nil {
+		return SampleModel{}, fmt.Errorf("error in openDatabase: sql.Open error: %w", err)
+	}
+	sampleModel := SampleModel{DB: db}
+	var tables int
+	err = db.QueryRow(`select count(*) from sqlite_master where type = "table" and name = "Weekdays"`).Scan(&tables)
+	if err != nil {
+		db.Close()
+		return SampleModel{}, fmt.Errorf("error in openDatabase: sql.DB.QueryRow error: %w", err)
+	}
+	if tables == 0 {
+		err = sampleModel.CreateDatabase()
+		if err != nil {
+			db.Close()
+			return SampleModel{}, fmt.Errorf("error in openDatabase: %w", err)
+		}
+	}
+	err = sampleModel.MigrateDatabase()
+	if err != nil {
+		db.Close()
+		return SampleModel{}, fmt.Errorf("error in openDatabase: %w", err)
+	}
+	return sampleModel, nil
+}
+
+// Parses args with flags, allowing flags before, between, and after the positional arguments, and checks the number of positional arguments. maxArgs < 0 means there is no maximum.
+func parseCommandArgs(flags *flag.FlagSet, args []string, minArgs, maxArgs int) ([]string, error) {
+	flags.SetOutput(io.Discard)
+	var positional []string
+	for {
+		err := flags.Parse(args)
+		if err != nil {
+			return nil, usageError{msg: err.Error()}
+		}
+		if flags.NArg() == 0 {
+			break
+		}
+		positional = append(positional, flags.Arg(0))
+		args = flags.Args()[1:]
+	}
+	if len(positional) < minArgs || maxArgs >= 0 && len(positional) > maxArgs {
+		return nil, usageError{msg: fmt.Sprintf("got %d arguments", len(positional))}
+	}
+	return positional, nil
+}
+
+// Parses value as YYYY-MM-DD and looks it up in Dates.
+func (c cli) resolveDate(value string) (date, error) {
+	parsed, err := parseISODate(value)
+	if err != nil {
+		return date{}, err
+	}
+	dateStruct, err := c.env.sample.RequestDate(parsed)
+	if err != nil {
+		return date{}, fmt.Errorf("error in resolveDate: %w", err)
+	}
+	return dateStruct, nil
+}
+
+// Writes rows under header as an aligned table or as CSV, or writes value as indented JSON, depending on c.format.
+func (c cli) write(header []string, rows [][]string, value any) error {
+	switch c.format {
+	case "json":
+		encoder := json.NewEncoder(c.stdout)
+		encoder.SetIndent("", "  ")
+		err := encoder.Encode(value)
+		if err != nil {
+			return fmt.Errorf("error in write: json.Encoder.Encode error: %w", err)
+		}
+	case "csv":
+		writer := csv.NewWriter(c.stdout)
+		err := writer.WriteAll(append([][]string{header}, rows...))
+		if err != nil {
+			return fmt.Errorf("error in write: csv.Writer.WriteAll error: %w", err)
+		}
+	default:
+		writer := tabwriter.NewWriter(c.stdout, 0, 0, 2, ' ', 0)
+		for _, val := range append([][]string{header}, rows...) {
+			fmt.Fprintln(writer, strings.Join(val, "\t"))
+		}
+		err := writer.Flush()
+		if err != nil {
+			return fmt.Errorf("error in write: tabwriter.Writer.Flush error: %w", err)
+		}
+	}
+	return nil
+}
+
+// Reads count lines from stdin, so passwords don't end up in the shell history or the process list.
+func (c cli) readLines(count int) ([]string, error) {
+	scanner := bufio.NewScanner(c.stdin)
+	var lines []string
+	for len(lines) < count && scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error in readLines: bufio.Scanner.Scan error: %w", err)
+	}
+	if len(lines) < count {
+		return nil, usageError{msg: fmt.Sprintf("expected %d lines on stdin, got %d", count, len(lines))}
+	}
+	return lines, nil
+}
+
+func (c cli) usersAdd(args []string) error {
+	names, err := parseCommandArgs(flag.NewFlagSet("users add", flag.ContinueOnError), args, 1, 1)
+	if err != nil {
+		return err
+	}
+	lines, err := c.readLines(1)
+	if err != nil {
+		return err
+	}
+	err = validatePassword(names[0], lines[0])
+	if err != nil {
+		return err
+	}
+	err = c.env.sample.CreateUser(user{UserName: names[0]})
+	if err != nil {
+		return err
+	}
+	return c.env.sample.SetPassword(names[0], lines[0])
+}
+
+func (c cli) usersPasswd(args []string) error {
+	names, err := parseCommandArgs(flag.NewFlagSet("users passwd", flag.ContinueOnError), args, 1, 1)
+	if err != nil {
+		return err
+	}
+	lines, err := c.readLines(2)
+	if err != nil {
+		return err
+	}
+	return c.env.sample.ChangePassword(names[0], lines[0], lines[1], "")
+}
+
+func (c cli) usersUnlock(args []string) error {
+	flags := flag.NewFlagSet("users unlock", flag.ContinueOnError)
+	source := flags.String("source", "", "unlock this source address instead of a user")
+	names, err := parseCommandArgs(flags, args, 0, 1)
+	if err != nil {
+		return err
+	}
+	if (len(names) == 1) == (len(*source) > 0) {
+		return usageError{msg: "give either NAME or --source ADDR"}
+	}
+	if len(names) == 0 {
+		return c.env.sample.UnlockLogin("", *source)
+	}
+	return c.env.sample.UnlockLogin(names[0], "")
+}
+
+func (c cli) usersAttempts(args []string) error {
+	flags := flag.NewFlagSet("users attempts", flag.ContinueOnError)
+	limit := flags.Int("limit", 20, "only the newest N attempts, or every attempt if 0")
+	names, err := parseCommandArgs(flags, args, 1, 1)
+	if err != nil {
+		return err
+	}
+	attempts, err := c.env.sample.RequestLoginAttempts(names[0], *limit)
+	if err != nil {
+		return err
+	}
+	rows := [][]string{}
+	for _, val := range attempts {
+		rows = append(rows, []string{val.AttemptedAt.Format(time.RFC3339), val.Source, val.Outcome})
+	}
+	return c.write([]string{"AttemptedAt", "Source", "Outcome"}, rows, attempts)
+}
+
+func (c cli) keysCreate(args []string) error {
+	flags := flag.NewFlagSet("keys create", flag.ContinueOnError)
+	scopeName := flags.String("scope", "", "read-only, roster-write, or admin")
+	expiresIn := flags.Duration("expires-in", 0, "how long the key is valid, or forever if 0")
+	names, err := parseCommandArgs(flags, args, 1, 1)
+	if err != nil {
+		return err
+	}
+	scope, err := parseAPIKeyScope(*scopeName)
+	if err != nil {
+		return usageError{msg: fmt.Sprintf("unknown scope `%s`", *scopeName)}
+	}
+	if *expiresIn < 0 {
+		return usageError{msg: "--expires-in must not be negative"}
+	}
+	var expiresAt time.Time
+	if *expiresIn > 0 {
+		expiresAt = time.Now().Add(*expiresIn)
+	}
+	token, created, err := c.env.sample.CreateAPIKey(c.env.loggedInUser, names[0], scope, expiresAt)
+	if err != nil {
+		return err
+	}
+	return c.write([]string{"KeyID", "Scope", "Token"}, [][]string{{fmt.Sprint(created.KeyID), created.Scope, token}}, apiKeyResponse{Token: token, Key: created})
+}
+
+// Formats optional times such as those of an apiKey, leaving the zero time empty.
+func formatOptionalTime(value time.Time) string {
+	if value.IsZero() {
+		return ""
+	}
+	return value.Format(time.RFC3339)
+}
+
+func (c cli) keysList(args []string) error {
+	_, err := parseCommandArgs(flag.NewFlagSet("keys list", flag.ContinueOnError), args, 0, 0)
+	if err != nil {
+		return err
+	}
+	keys, err := c.env.sample.RequestAPIKeys(c.env.loggedInUser)
+	if err != nil {
+		return err
+	}
+	rows := [][]string{}
+	for _, val := range keys {
+		rows = append(rows, []string{fmt.Sprint(val.KeyID), val.Name, val.Prefix, val.Scope, val.CreatedAt.Format(time.RFC3339), formatOptionalTime(val.ExpiresAt), formatOptionalTime(val.LastUsedAt), formatOptionalTime(val.RevokedAt)})
+	}
+	return c.write([]string{"KeyID", "Name", "Prefix", "Scope", "CreatedAt", "ExpiresAt", "LastUsedAt", "RevokedAt"}, rows, keys)
+}
+
+func (c cli) keysRevoke(args []string) error {
+	names, err := parseCommandArgs(flag.NewFlagSet("keys revoke", flag.ContinueOnError), args, 1, 1)
+	if err != nil {
+		return err
+	}
+	keyID, err := strconv.Atoi(names[0])
+	if err != nil {
+		return usageError{msg: fmt.Sprintf("`%s` is not a KEY_ID", names[0])}
+	}
+	return c.env.sample.RevokeAPIKey(c.env.loggedInUser, keyID)
+}
+
+func (c cli) usersDelete(args []string) error {
+	flags := flag.NewFlagSet("users delete", flag.ContinueOnError)
+	dryRun := flags.Bool("dry-run", false, "only count the rows that would be deleted")
+	archivePath := flags.String("archive", "", "export the user's rows to this file before deleting them")
+	names, err := parseCommandArgs(flags, args, 1, 1)
+	if err != nil {
+		return err
+	}
+	var counts []userRowCount
+	if *dryRun {
+		counts, err = c.env.sample.PreviewDeleteUserCascade(names[0])
+	} else if len(*archivePath) > 0 {
+		counts, err = c.deleteUserWithArchive(names[0], *archivePath)
+	} else {
+		counts, err = c.env.sample.DeleteUserCascade(names[0], nil)
+	}
+	if err != nil {
+		return err
+	}
+	var rows [][]string
+	for _, val := range counts {
+		rows = append(rows, []string{val.Table, fmt.Sprint(val.Rows)})
+	}
+	return c.write([]string{"Table", "Rows"}, rows, counts)
+}
+
+// The archive is created before anything is deleted and kept if the deletion fails.
+func (c cli) deleteUserWithArchive(userName string, archivePath string) ([]userRowCount, error) {
+	archive, err := os.Create(archivePath)
+	if err != nil {
+		return nil, fmt.Errorf("error in deleteUserWithArchive: os.Create error: %w", err)
+	}
+	counts, err := c.env.sample.DeleteUserCascade(userName, archive)
+	closeErr := archive.Close()
+	if err != nil {
+		return nil, fmt.Errorf("error in deleteUserWithArchive: %w", err)
+	}
+	if closeErr != nil {
+		return counts, fmt.Errorf("error in deleteUserWithArchive: os.File.Close error: %w", closeErr)
+	}
+	return counts, nil
+}
+
+func (c cli) volunteersAdd(args []string) error {
+	names, err := parseCommandArgs(flag.NewFlagSet("volunteers add", flag.ContinueOnError), args, 1, -1)
+	if err != nil {
+		return err
+	}
+	var toCreate []volunteer
+	for _, val := range names {
+		toCreate = append(toCreate, volunteer{VolunteerName: val})
+	}
+	return c.env.sample.CreateVolunteers(c.env.loggedInUser, toCreate)
+}
+
+func (c cli) volunteersList(args []string) error {
+	flags := flag.NewFlagSet("volunteers list", flag.ContinueOnError)
+	all := flags.Bool("all", false, "include inactive volunteers")
+	_, err := parseCommandArgs(flags, args, 0, 0)
+	if err != nil {
+		return err
+	}
+	request := c.env.sample.RequestActiveVolunteers
+	if *all {
+		request = c.env.sample.RequestVolunteers
+	}
+	volunteers, err := request(c.env.loggedInUser, []volunteer{})
+	if err != nil {
+		return err
+	}
+	type cliVolunteer struct {
+		VolunteerID   int
+		VolunteerName string
+	}
+	rows := [][]string{}
+	value := []cliVolunteer{}
+	for _, val := range volunteers {
+		rows = append(rows, []string{fmt.Sprint(val.VolunteerID), val.VolunteerName})
+		value = append(value, cliVolunteer{VolunteerID: val.VolunteerID, VolunteerName: val.VolunteerName})
+	}
+	return c.write([]string{"VolunteerID", "VolunteerName"}, rows, value)
+}
+
+func (c cli) volunteersRename(args []string) error {
+	names, err := parseCommandArgs(flag.NewFlagSet("volunteers rename", flag.ContinueOnError), args, 2, 2)
+	if err != nil {
+		return err
+	}
+	volunteerStruct, err := c.env.sample.RequestVolunteer(c.env.loggedInUser, volunteer{VolunteerName: names[0]})
+	if err != nil {
+		return err
+	}
+	return c.env.sample.UpdateVolunteers(c.env.loggedInUser, []volunteer{{VolunteerID: volunteerStruct.VolunteerID, VolunteerName: names[1]}})
+}
+
+func (c cli) volunteersShow(args []string) error {
+	names, err := parseCommandArgs(flag.NewFlagSet("volunteers show", flag.ContinueOnError), args, 1, 1)
+	if err != nil {
+		return err
+	}
+	volunteerStruct, err := c.env.sample.RequestVolunteer(c.env.loggedInUser, volunteer{VolunteerName: names[0]})
+	if err != nil {
+		return err
+	}
+	rows := [][]string{
+		{"VolunteerID", fmt.Sprint(volunteerStruct.VolunteerID)},
+		{"VolunteerName", volunteerStruct.VolunteerName},
+		{"Email", volunteerStruct.Email},
+		{"Phone", volunteerStruct.Phone},
+		{"PreferredContact", volunteerStruct.PreferredContact},
+		{"Notes", volunteerStruct.Notes},
+		{"Active", fmt.Sprint(!volunteerStruct.Inactive)},
+	}
+	if volunteerStruct.DeactivatedAt != nil {
+		rows = append(rows, []string{"DeactivatedAt", formatOptionalTime(*volunteerStruct.DeactivatedAt)})
+	}
+	return c.write([]string{"Field", "Value"}, rows, volunteerStruct)
+}
+
+func (c cli) volunteersSet(args []string) error {
+	flags := flag.NewFlagSet("volunteers set", flag.ContinueOnError)
+	email := flags.String("email", "", "email address")
+	phone := flags.String("phone", "", "phone number")
+	contact := flags.String("contact", "", "preferred contact method: email, phone, sms, or none")
+	notes := flags.String("notes", "", "free-form notes")
+	active := flags.Bool("active", true, "whether the volunteer is active")
+	names, err := parseCommandArgs(flags, args, 1, 1)
+	if err != nil {
+		return err
+	}
+	volunteerStruct, err := c.env.sample.RequestVolunteer(c.env.loggedInUser, volunteer{VolunteerName: names[0]})
+	if err != nil {
+		return err
+	}
+	var profileChanged, activeChanged bool
+	flags.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "email":
+			volunteerStruct.Email = *email
+		case "phone":
+			volunteerStruct.Phone = *phone
+		case "contact":
+			volunteerStruct.PreferredContact = *contact
+			if *contact == "none" {
+				volunteerStruct.PreferredContact = ""
+			}
+		case "notes":
+			volunteerStruct.Notes = *notes
+		}
+		if f.Name == "active" {
+			activeChanged = true
+		} else {
+			profileChanged = true
+		}
+	})
+	if !profileChanged && !activeChanged {
+		return usageError{msg: "give at least one field to set"}
+	}
+	if profileChanged {
+		err = c.env.sample.replaceVolunteerProfile(c.env.loggedInUser, volunteerStruct) // flags set to "" clear their field
+		if err != nil {
+			return err
+		}
+	}
+	if !activeChanged {
+		return nil
+	}
+	if *active {
+		return c.env.sample.ReactivateVolunteers(c.env.loggedInUser, []volunteer{{VolunteerID: volunteerStruct.VolunteerID}})
+	}
+	return c.env.sample.DeleteVolunteers(c.env.loggedInUser, []volunteer{{VolunteerID: volunteerStruct.VolunteerID}})
+}
+
+func (c cli) volunteersRemove(args []string) error {
+	names, err := parseCommandArgs(flag.NewFlagSet("volunteers remove", flag.ContinueOnError), args, 1, -1)
+	if err != nil {
+		return err
+	}
+	var toDelete []volunteer
+	for _, val := range names {
+		volunteerStruct, err := c.env.sample.RequestVolunteer(c.env.loggedInUser, volunteer{VolunteerName: val})
+		if err != nil {
+			return err
+		}
+		toDelete = append(toDelete, volunteer{VolunteerID: volunteerStruct.VolunteerID})
+	}
+	return c.env.sample.DeleteVolunteers(c.env.loggedInUser, toDelete)
+}
+
+func (c cli) volunteersReactivate(args []string) error {
+	names, err := parseCommandArgs(flag.NewFlagSet("volunteers reactivate", flag.ContinueOnError), args, 1, -1)
+	if err != nil {
+		return err
+	}
+	var toReactivate []volunteer
+	for _, val := range names {
+		volunteerStruct, err := c.env.sample.RequestVolunteer(c.env.loggedInUser, volunteer{VolunteerName: val})
+		if err != nil {
+			return err
+		}
+		toReactivate = append(toReactivate, volunteer{VolunteerID: volunteerStruct.VolunteerID})
+	}
+	return c.env.sample.ReactivateVolunteers(c.env.loggedInUser, toReactivate)
+}
+
+func (c cli) volunteersDuplicates(args []string) error {
+	_, err := parseCommandArgs(flag.NewFlagSet("volunteers duplicates", flag.ContinueOnError), args, 0, 0)
+	if err != nil {
+		return err
+	}
+	duplicates, err := c.env.sample.FindDuplicateVolunteers(c.env.loggedInUser)
+	if err != nil {
+		return err
+	}
+	rows := [][]string{}
+	for _, val := range duplicates {
+		rows = append(rows, []string{val.Volunteer.VolunteerName, val.Duplicate.VolunteerName, val.Reason})
+	}
+	return c.write([]string{"Volunteer", "Duplicate", "Reason"}, rows, duplicates)
+}
+
+func (c cli) volunteersMerge(args []string) error {
+	names, err := parseCommandArgs(flag.NewFlagSet("volunteers merge", flag.ContinueOnError), args, 2, 2)
+	if err != nil {
+		return err
+	}
+	survivor, err := c.env.sample.RequestVolunteer(c.env.loggedInUser, volunteer{VolunteerName: names[0]})
+	if err != nil {
+		return err
+	}
+	duplicate, err := c.env.sample.RequestVolunteer(c.env.loggedInUser, volunteer{VolunteerName: names[1]})
+	if err != nil {
+		return err
+	}
+	report, err := c.env.sample.MergeVolunteers(c.env.loggedInUser, survivor.VolunteerID, duplicate.VolunteerID)
+	if err != nil {
+		return err
+	}
+	rows := [][]string{
+		{"VFSMoved", fmt.Sprint(report.VFSMoved)},
+		{"VFSMerged", fmt.Sprint(report.VFSMerged)},
+		{"UFSMoved", fmt.Sprint(report.UFSMoved)},
+		{"UFSDropped", fmt.Sprint(report.UFSDropped)},
+		{"CompletedSchedulesUpdated", fmt.Sprint(report.CompletedSchedulesUpdated)},
+	}
+	return c.write([]string{"Field", "Value"}, rows, report)
+}
+
+// Registers the flags shared by schedules create and schedules update. ShiftsOff and VolunteersPerShift default to -1 so unset flags can be told apart from 0.
+func scheduleFlags(name string) (*flag.FlagSet, *string, *string, *int, *int) {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	start := flags.String("start", "", "first date of the schedule (YYYY-MM-DD)")
+	end := flags.String("end", "", "last date of the schedule (YYYY-MM-DD)")
+	shiftsOff := flags.Int("shifts-off", -1, "shifts a volunteer has off after serving")
+	perShift := flags.Int("per-shift", -1, "volunteers per shift")
+	return flags, start, end, shiftsOff, perShift
+}
+
+func (c cli) schedulesCreate(args []string) error {
+	flags, start, end, shiftsOff, perShift := scheduleFlags("schedules create")
+	names, err := parseCommandArgs(flags, args, 1, 1)
+	if err != nil {
+		return err
+	}
+	if len(*start) == 0 || len(*end) == 0 || *shiftsOff < 0 || *perShift < 1 {
+		return usageError{msg: "--start, --end, --shifts-off, and --per-shift are required"}
+	}
+	startDate, err := c.resolveDate(*start)
+	if err != nil {
+		return err
+	}
+	endDate, err := c.resolveDate(*end)
+	if err != nil {
+		return err
+	}
+	existing, err := c.env.sample.RequestSchedules(c.env.loggedInUser, []schedule{{ScheduleName: names[0]}})
+	if err != nil {
+		return err
+	}
+	if len(existing) > 0 {
+		return fmt.Errorf("error in schedulesCreate: method failed because schedule `%s` already exists", names[0])
+	}
+	return c.env.sample.CreateSchedulesExtended(c.env.loggedInUser, []schedule{{
+		ScheduleName:       names[0],
+		ShiftsOff:          *shiftsOff,
+		VolunteersPerShift: *perShift,
+		StartDate:          startDate.DateID,
+		EndDate:            endDate.DateID,
+	}}, true)
+}
+
+func (c cli) schedulesList(args []string) error {
+	_, err := parseCommandArgs(flag.NewFlagSet("schedules list", flag.ContinueOnError), args, 0, 0)
+	if err != nil {
+		return err
+	}
+	schedules, err := c.env.sample.RequestSchedulesExtended(c.env.loggedInUser, []schedule{}, true)
+	if err != nil {
+		return err
+	}
+	var dateIDs []int
+	for _, val := range schedules {
+		dateIDs = append(dateIDs, val.StartDate, val.EndDate)
+	}
+	dates, err := c.env.sample.requestDatesByID(dateIDs)
+	if err != nil {
+		return err
+	}
+	type cliSchedule struct {
+		ScheduleName       string
+		StartDate          string
+		EndDate            string
+		ShiftsOff          int
+		VolunteersPerShift int
+	}
+	rows := [][]string{}
+	value := []cliSchedule{}
+	for _, val := range schedules {
+		current := cliSchedule{
+			ScheduleName:       val.ScheduleName,
+			StartDate:          isoDate(dates[val.StartDate]),
+			EndDate:            isoDate(dates[val.EndDate]),
+			ShiftsOff:          val.ShiftsOff,
+			VolunteersPerShift: val.VolunteersPerShift,
+		}
+		rows = append(rows, []string{current.ScheduleName, current.StartDate, current.EndDate, fmt.Sprint(current.ShiftsOff), fmt.Sprint(current.VolunteersPerShift)})
+		value = append(value, current)
+	}
+	return c.write([]string{"ScheduleName", "StartDate", "EndDate", "ShiftsOff", "VolunteersPerShift"}, rows, value)
+}
+
+func (c cli) schedulesUpdate(args []string) error {
+	flags, start, end, shiftsOff, perShift := scheduleFlags("schedules update")
+	newName := flags.String("name", "", "new ScheduleName")
+	names, err := parseCommandArgs(flags, args, 1, 1)
+	if err != nil {
+		return err
+	}
+	scheduleStruct, err := c.env.sample.RequestSchedule(c.env.loggedInUser, schedule{ScheduleName: names[0]})
+	if err != nil {
+		return err
+	}
+	toUpdate := schedule{ScheduleID: scheduleStruct.ScheduleID, ScheduleName: *newName, ShiftsOff: *shiftsOff, VolunteersPerShift: max(*perShift, 0)}
+	if len(*start) > 0 {
+		startDate, err := c.resolveDate(*start)
+		if err != nil {
+			return err
+		}
+		toUpdate.StartDate = startDate.DateID
+	}
+	if len(*end) > 0 {
+		endDate, err := c.resolveDate(*end)
+		if err != nil {
+			return err
+		}
+		toUpdate.EndDate = endDate.DateID
+	}
+	if toUpdate == (schedule{ScheduleID: scheduleStruct.ScheduleID, ShiftsOff: -1}) {
+		return usageError{msg: "at least one of --name, --start, --end, --shifts-off, and --per-shift is required"}
+	}
+	return c.env.sample.UpdateSchedulesExtended(c.env.loggedInUser, []schedule{toUpdate}, true)
+}
+
+func (c cli) schedulesDelete(args []string) error {
+	names, err := parseCommandArgs(flag.NewFlagSet("schedules delete", flag.ContinueOnError), args, 1, -1)
+	if err != nil {
+		return err
+	}
+	var toDelete []schedule
+	for _, val := range names {
+		scheduleStruct, err := c.env.sample.RequestSchedule(c.env.loggedInUser, schedule{ScheduleName: val})
+		if err != nil {
+			return err
+		}
+		toDelete = append(toDelete, schedule{ScheduleID: scheduleStruct.ScheduleID})
+	}
+	return c.env.sample.DeleteSchedules(c.env.loggedInUser, toDelete)
+}
+
+// Fetches the schedule named scheduleName with FetchAndSendData, lets edit change it, and stores the result with RecieveAndStoreData, so the same validation applies as for data sent by a client.
+func (c cli) editScheduleData(scheduleName string, edit func(data *SendReceiveDataStruct) error) error {
+	data, err := c.env.sample.FetchAndSendData(c.env.loggedInUser, scheduleName)
+	if err != nil {
+		return err
+	}
+	err = edit(&data)
+	if err != nil {
+		return err
+	}
+	plan, err := c.env.sample.PlanReceivedData(data)
+	if err != nil {
+		return err
+	}
+	if len(plan.VolunteersRejected) > 0 { // the plan would leave them out, but a command naming them should fail
+		return fmt.Errorf("error in editScheduleData: method failed because inactive volunteers can't be added to a schedule: %s", strings.Join(plan.VolunteersRejected, ", "))
+	}
+	return c.env.sample.RecieveAndStoreData(data)
+}
+
+func (c cli) scheduleWeekdaysSet(args []string) error {
+	names, err := parseCommandArgs(flag.NewFlagSet("schedule weekdays set", flag.ContinueOnError), args, 2, -1)
+	if err != nil {
+		return err
+	}
+	return c.editScheduleData(names[0], func(data *SendReceiveDataStruct) error {
+		data.WeekdaysForSchedule = []string{}
+		for _, val := range names[1:] {
+			weekdayStruct, err := c.env.sample.RequestWeekday(weekday{WeekdayName: val})
+			if err != nil {
+				return err
+			}
+			if !slices.Contains(data.WeekdaysForSchedule, weekdayStruct.WeekdayName) {
+				data.WeekdaysForSchedule = append(data.WeekdaysForSchedule, weekdayStruct.WeekdayName)
+			}
+		}
+		return nil
+	})
+}
+
+// Returns the index of the VolunteerAvailabilityData entry for volunteerName, or -1.
+func availabilityIndex(data *SendReceiveDataStruct, volunteerName string) int {
+	return slices.IndexFunc(data.VolunteerAvailabilityData, func(entry map[string][]string) bool {
+		_, ok := entry[volunteerName]
+		return ok
+	})
+}
+
+func (c cli) scheduleVolunteersAdd(args []string) error {
+	names, err := parseCommandArgs(flag.NewFlagSet("schedule volunteers add", flag.ContinueOnError), args, 2, -1)
+	if err != nil {
+		return err
+	}
+	return c.editScheduleData(names[0], func(data *SendReceiveDataStruct) error {
+		for _, val := range names[1:] {
+			if availabilityIndex(data, val) < 0 {
+				data.VolunteerAvailabilityData = append(data.VolunteerAvailabilityData, map[string][]string{val: {}})
+			}
+		}
+		return nil
+	})
+}
+
+func (c cli) scheduleMembersList(args []string) error {
+	names, err := parseCommandArgs(flag.NewFlagSet("schedule members list", flag.ContinueOnError), args, 1, 1)
+	if err != nil {
+		return err
+	}
+	scheduleStruct, err := c.env.sample.RequestSchedule(c.env.loggedInUser, schedule{ScheduleName: names[0]})
+	if err != nil {
+		return err
+	}
+	members, err := c.env.sample.RequestScheduleMembers(c.env.loggedInUser, scheduleStruct.ScheduleID)
+	if err != nil {
+		return err
+	}
+	rows := [][]string{{scheduleStruct.User, ScheduleOwner.String()}}
+	for _, val := range members {
+		rows = append(rows, []string{val.User, val.Role})
+	}
+	return c.write([]string{"User", "Role"}, rows, append([]scheduleMember{{Schedule: scheduleStruct.ScheduleID, User: scheduleStruct.User, Role: ScheduleOwner.String()}}, members...))
+}
+
+func (c cli) scheduleMembersSet(args []string) error {
+	names, err := parseCommandArgs(flag.NewFlagSet("schedule members set", flag.ContinueOnError), args, 3, 3)
+	if err != nil {
+		return err
+	}
+	role, err := parseScheduleRole(names[2])
+	if err != nil {
+		return usageError{msg: fmt.Sprintf("unknown role `%s`", names[2])}
+	}
+	scheduleStruct, err := c.env.sample.RequestSchedule(c.env.loggedInUser, schedule{ScheduleName: names[0]})
+	if err != nil {
+		return err
+	}
+	return c.env.sample.ShareSchedule(c.env.loggedInUser, scheduleStruct.ScheduleID, names[1], role)
+}
+
+func (c cli) scheduleMembersRemove(args []string) error {
+	names, err := parseCommandArgs(flag.NewFlagSet("schedule members remove", flag.ContinueOnError), args, 2, 2)
+	if err != nil {
+		return err
+	}
+	scheduleStruct, err := c.env.sample.RequestSchedule(c.env.loggedInUser, schedule{ScheduleName: names[0]})
+	if err != nil {
+		return err
+	}
+	return c.env.sample.UnshareSchedule(c.env.loggedInUser, scheduleStruct.ScheduleID, names[1])
+}
+
+func (c cli) orgsCreate(args []string) error {
+	names, err := parseCommandArgs(flag.NewFlagSet("orgs create", flag.ContinueOnError), args, 1, 1)
+	if err != nil {
+		return err
+	}
+	_, err = c.env.sample.CreateOrganization(c.env.loggedInUser, names[0])
+	return err
+}
+
+func (c cli) orgsList(args []string) error {
+	_, err := parseCommandArgs(flag.NewFlagSet("orgs list", flag.ContinueOnError), args, 0, 0)
+	if err != nil {
+		return err
+	}
+	organizations, err := c.env.sample.RequestOrganizations(c.env.loggedInUser)
+	if err != nil {
+		return err
+	}
+	rows := [][]string{}
+	for _, val := range organizations {
+		rows = append(rows, []string{fmt.Sprint(val.OrganizationID), val.OrganizationName})
+	}
+	return c.write([]string{"OrganizationID", "OrganizationName"}, rows, organizations)
+}
+
+func (c cli) orgsDelete(args []string) error {
+	names, err := parseCommandArgs(flag.NewFlagSet("orgs delete", flag.ContinueOnError), args, 1, 1)
+	if err != nil {
+		return err
+	}
+	organizationStruct, err := c.env.sample.RequestOrganization(c.env.loggedInUser, organization{OrganizationName: names[0]})
+	if err != nil {
+		return err
+	}
+	return c.env.sample.DeleteOrganization(c.env.loggedInUser, organizationStruct.OrganizationID)
+}
+
+// Parses the ORG argument of an org subcommand followed by minArgs to maxArgs further arguments (any number if maxArgs is -1), and looks the organization up by name.
+func (c cli) orgArgs(name string, args []string, minArgs int, maxArgs int) (organization, []string, error) {
+	if maxArgs >= 0 {
+		maxArgs++
+	}
+	names, err := parseCommandArgs(flag.NewFlagSet(name, flag.ContinueOnError), args, minArgs+1, maxArgs)
+	if err != nil {
+		return organization{}, nil, err
+	}
+	organizationStruct, err := c.env.sample.RequestOrganization(c.env.loggedInUser, organization{OrganizationName: names[0]})
+	if err != nil {
+		return organization{}, nil, err
+	}
+	return organizationStruct, names[1:], nil
+}
+
+func (c cli) orgMembersList(args []string) error {
+	organizationStruct, _, err := c.orgArgs("org members list", args, 0, 0)
+	if err != nil {
+		return err
+	}
+	members, err := c.env.sample.RequestOrganizationMembers(c.env.loggedInUser, organizationStruct.OrganizationID)
+	if err != nil {
+		return err
+	}
+	rows := [][]string{}
+	for _, val := range members {
+		rows = append(rows, []string{val})
+	}
+	return c.write([]string{"User"}, rows, members)
+}
+
+func (c cli) orgMembersAdd(args []string) error {
+	organizationStruct, names, err := c.orgArgs("org members add", args, 1, 1)
+	if err != nil {
+		return err
+	}
+	return c.env.sample.AddOrganizationMember(c.env.loggedInUser, organizationStruct.OrganizationID, names[0])
+}
+
+func (c cli) orgMembersRemove(args []string) error {
+	organizationStruct, names, err := c.orgArgs("org members remove", args, 1, 1)
+	if err != nil {
+		return err
+	}
+	return c.env.sample.RemoveOrganizationMember(c.env.loggedInUser, organizationStruct.OrganizationID, names[0])
+}
+
+func (c cli) orgVolunteersList(args []string) error {
+	organizationStruct, _, err := c.orgArgs("org volunteers list", args, 0, 0)
+	if err != nil {
+		return err
+	}
+	volunteers, err := c.env.sample.RequestOrganizationVolunteers(c.env.loggedInUser, organizationStruct.OrganizationID, []volunteer{})
+	if err != nil {
+		return err
+	}
+	rows := [][]string{}
+	for _, val := range volunteers {
+		rows = append(rows, []string{fmt.Sprint(val.VolunteerID), val.VolunteerName, val.User})
+	}
+	if volunteers == nil {
+		volunteers = []volunteer{}
+	}
+	return c.write([]string{"VolunteerID", "VolunteerName", "User"}, rows, volunteers)
+}
+
+func (c cli) orgVolunteersAdd(args []string) error {
+	organizationStruct, names, err := c.orgArgs("org volunteers add", args, 1, -1)
+	if err != nil {
+		return err
+	}
+	var toAdd []volunteer
+	for _, val := range names {
+		toAdd = append(toAdd, volunteer{VolunteerName: val})
+	}
+	return c.env.sample.AddOrganizationVolunteers(c.env.loggedInUser, organizationStruct.OrganizationID, toAdd)
+}
+
+func (c cli) orgVolunteersRemove(args []string) error {
+	organizationStruct, names, err := c.orgArgs("org volunteers remove", args, 1, -1)
+	if err != nil {
+		return err
+	}
+	var toRemove []volunteer
+	for _, val := range names {
+		toRemove = append(toRemove, volunteer{VolunteerName: val})
+	}
+	return c.env.sample.RemoveOrganizationVolunteers(c.env.loggedInUser, organizationStruct.OrganizationID, toRemove)
+}
+
+func (c cli) orgSchedules(args []string) error {
+	organizationStruct, _, err := c.orgArgs("org schedules", args, 0, 0)
+	if err != nil {
+		return err
+	}
+	schedules, err := c.env.sample.RequestOrganizationSchedules(c.env.loggedInUser, organizationStruct.OrganizationID)
+	if err != nil {
+		return err
+	}
+	type cliOrganizationSchedule struct {
+		ScheduleName string
+		User         string
+	}
+	rows := [][]string{}
+	value := []cliOrganizationSchedule{}
+	for _, val := range schedules {
+		rows = append(rows, []string{val.ScheduleName, val.User})
+		value = append(value, cliOrganizationSchedule{ScheduleName: val.ScheduleName, User: val.User})
+	}
+	return c.write([]string{"ScheduleName", "User"}, rows, value)
+}
+
+func (c cli) groupsCreate(args []string) error {
+	names, err := parseCommandArgs(flag.NewFlagSet("groups create", flag.ContinueOnError), args, 1, 1)
+	if err != nil {
+		return err
+	}
+	_, err = c.env.sample.CreateVolunteerGroup(c.env.loggedInUser, names[0])
+	return err
+}
+
+func (c cli) groupsList(args []string) error {
+	_, err := parseCommandArgs(flag.NewFlagSet("groups list", flag.ContinueOnError), args, 0, 0)
+	if err != nil {
+		return err
+	}
+	groups, err := c.env.sample.RequestVolunteerGroups(c.env.loggedInUser)
+	if err != nil {
+		return err
+	}
+	rows := [][]string{}
+	for _, val := range groups {
+		rows = append(rows, []string{fmt.Sprint(val.GroupID), val.GroupName})
+	}
+	return c.write([]string{"GroupID", "GroupName"}, rows, groups)
+}
+
+func (c cli) groupsRename(args []string) error {
+	group, names, err := c.groupArgs("groups rename", args, 1, 1)
+	if err != nil {
+		return err
+	}
+	return c.env.sample.RenameVolunteerGroup(c.env.loggedInUser, group.GroupID, names[0])
+}
+
+func (c cli) groupsDelete(args []string) error {
+	group, _, err := c.groupArgs("groups delete", args, 0, 0)
+	if err != nil {
+		return err
+	}
+	return c.env.sample.DeleteVolunteerGroup(c.env.loggedInUser, group.GroupID)
+}
+
+// Like orgArgs, for the GROUP argument of a group subcommand.
+func (c cli) groupArgs(name string, args []string, minArgs int, maxArgs int) (volunteerGroup, []string, error) {
+	if maxArgs >= 0 {
+		maxArgs++
+	}
+	names, err := parseCommandArgs(flag.NewFlagSet(name, flag.ContinueOnError), args, minArgs+1, maxArgs)
+	if err != nil {
+		return volunteerGroup{}, nil, err
+	}
+	group, err := c.env.sample.RequestVolunteerGroup(c.env.loggedInUser, volunteerGroup{GroupName: names[0]})
+	if err != nil {
+		return volunteerGroup{}, nil, err
+	}
+	return group, names[1:], nil
+}
+
+func (c cli) groupVolunteersList(args []string) error {
+	group, _, err := c.groupArgs("group volunteers list", args, 0, 0)
+	if err != nil {
+		return err
+	}
+	volunteers, err := c.env.sample.RequestGroupVolunteers(c.env.loggedInUser, group.GroupID, []volunteer{})
+	if err != nil {
+		return err
+	}
+	rows := [][]string{}
+	for _, val := range volunteers {
+		rows = append(rows, []string{fmt.Sprint(val.VolunteerID), val.VolunteerName, fmt.Sprint(!val.Inactive)})
+	}
+	if volunteers == nil {
+		volunteers = []volunteer{}
+	}
+	return c.write([]string{"VolunteerID", "VolunteerName", "Active"}, rows, volunteers)
+}
+
+func (c cli) groupVolunteersAdd(args []string) error {
+	group, names, err := c.groupArgs("group volunteers add", args, 1, -1)
+	if err != nil {
+		return err
+	}
+	var toAdd []volunteer
+	for _, val := range names {
+		toAdd = append(toAdd, volunteer{VolunteerName: val})
+	}
+	return c.env.sample.AddGroupVolunteers(c.env.loggedInUser, group.GroupID, toAdd)
+}
+
+func (c cli) groupVolunteersRemove(args []string) error {
+	group, names, err := c.groupArgs("group volunteers remove", args, 1, -1)
+	if err != nil {
+		return err
+	}
+	var toRemove []volunteer
+	for _, val := range names {
+		toRemove = append(toRemove, volunteer{VolunteerName: val})
+	}
+	return c.env.sample.RemoveGroupVolunteers(c.env.loggedInUser, group.GroupID, toRemove)
+}
+
+func (c cli) groupEnroll(args []string) error {
+	group, names, err := c.groupArgs("group enroll", args, 1, 1)
+	if err != nil {
+		return err
+	}
+	scheduleStruct, err := c.env.sample.RequestSchedule(c.env.loggedInUser, schedule{ScheduleName: names[0]})
+	if err != nil {
+		return err
+	}
+	report, err := c.env.sample.EnrollVolunteerGroup(c.env.loggedInUser, group.GroupID, scheduleStruct.ScheduleID)
+	if err != nil {
+		return err
+	}
+	rows := [][]string{}
+	for _, outcome := range []struct {
+		name  string
+		names []string
+	}{{"enrolled", report.Enrolled}, {"already enrolled", report.AlreadyEnrolled}, {"inactive", report.Inactive}, {"not in pool", report.NotInPool}} {
+		for _, val := range outcome.names {
+			rows = append(rows, []string{val, outcome.name})
+		}
+	}
+	return c.write([]string{"VolunteerName", "Outcome"}, rows, report)
+}
+
+func (c cli) audit(args []string) error {
+	flags := flag.NewFlagSet("audit", flag.ContinueOnError)
+	scheduleName := flags.String("schedule", "", "only changes of this schedule and its rows")
+	volunteerName := flags.String("volunteer", "", "only changes of this volunteer and its rows")
+	userName := flags.String("user", "", "only changes made by this user")
+	tableName := flags.String("table", "", "only changes of this table")
+	limit := flags.Int("limit", 0, "only the newest N changes")
+	_, err := parseCommandArgs(flags, args, 0, 0)
+	if err != nil {
+		return err
+	}
+	filter := auditFilter{User: *userName, TableName: *tableName, Limit: *limit}
+	if len(*scheduleName) > 0 {
+		scheduleStruct, err := c.env.sample.RequestSchedule(c.env.loggedInUser, schedule{ScheduleName: *scheduleName})
+		if err != nil {
+			return err
+		}
+		filter.Schedule = scheduleStruct.ScheduleID
+	}
+	if len(*volunteerName) > 0 {
+		volunteers, err := c.env.sample.RequestPoolVolunteers(c.env.loggedInUser, []volunteer{{VolunteerName: *volunteerName}})
+		if err != nil {
+			return err
+		}
+		if len(volunteers) != 1 {
+			return fmt.Errorf("found %d volunteers named `%s`", len(volunteers), *volunteerName)
+		}
+		filter.Volunteer = volunteers[0].VolunteerID
+	}
+	entries, err := c.env.sample.RequestAuditLog(c.env.loggedInUser, filter)
+	if err != nil {
+		return err
+	}
+	rows := [][]string{}
+	for _, val := range entries {
+		rows = append(rows, []string{val.CreatedAt.Format(time.RFC3339), val.User, val.Operation, val.TableName, val.PrimaryKey, val.Before, val.After})
+	}
+	return c.write([]string{"CreatedAt", "User", "Operation", "TableName", "PrimaryKey", "Before", "After"}, rows, entries)
+}
+
+func (c cli) unavailableAdd(args []string) error {
+	names, err := parseCommandArgs(flag.NewFlagSet("unavailable add", flag.ContinueOnError), args, 3, -1)
+	if err != nil {
+		return err
+	}
+	return c.editScheduleData(names[0], func(data *SendReceiveDataStruct) error {
+		i := availabilityIndex(data, names[1])
+		if i < 0 {
+			return fmt.Errorf("error in unavailableAdd: method failed to locate volunteer `%s` in schedule `%s`", names[1], names[0])
+		}
+		for _, val := range names[2:] {
+			dateStruct, err := c.resolveDate(val)
+			if err != nil {
+				return err
+			}
+			if !slices.Contains(data.VolunteerAvailabilityData[i][names[1]], isoDate(dateStruct)) {
+				data.VolunteerAvailabilityData[i][names[1]] = append(data.VolunteerAvailabilityData[i][names[1]], isoDate(dateStruct))
+			}
+		}
+		return nil
+	})
+}
+
+// Returns the CScheduleID of the most recently stored completed schedule of the schedule named scheduleName.
+func (c cli) latestCScheduleID(scheduleName string) (int, error) {
+	scheduleStruct, err := c.env.sample.RequestSchedule(c.env.loggedInUser, schedule{ScheduleName: scheduleName})
+	if err != nil {
+		return 0, err
+	}
+	completedSchedules, err := c.env.sample.RequestCompletedSchedules(c.env.loggedInUser, []completedSchedule{{Schedule: scheduleStruct.ScheduleID}})
+	if err != nil {
+		return 0, err
+	}
+	if len(completedSchedules) == 0 {
+		return 0, fmt.Errorf("error in latestCScheduleID: method failed to locate a completed schedule for schedule `%s`", scheduleName)
+	}
+	latest := 0
+	for _, val := range completedSchedules {
+		latest = max(latest, val.CScheduleID)
+	}
+	return latest, nil
+}
+
+func (c cli) rosterGenerate(args []string) error {
+	names, err := parseCommandArgs(flag.NewFlagSet("roster generate", flag.ContinueOnError), args, 1, 1)
+	if err != nil {
+		return err
+	}
+	scheduleStruct, err := c.env.sample.RequestSchedule(c.env.loggedInUser, schedule{ScheduleName: names[0]})
+	if err != nil {
+		return err
+	}
+	shifts, err := c.env.sample.GenerateRoster(c.env.loggedInUser, scheduleStruct.ScheduleID)
+	if err != nil {
+		return err
+	}
+	scheduleData, err := json.Marshal(shifts)
+	if err != nil {
+		return fmt.Errorf("error in rosterGenerate: json.Marshal error: %w", err)
+	}
+	err = c.env.sample.CreateCompletedSchedules(c.env.loggedInUser, []completedSchedule{{ScheduleData: string(scheduleData), Schedule: scheduleStruct.ScheduleID}})
+	if err != nil {
+		return err
+	}
+	return c.rosterShow([]string{names[0]})
+}
+
+func (c cli) rosterShow(args []string) error {
+	flags := flag.NewFlagSet("roster show", flag.ContinueOnError)
+	layoutName := flags.String("layout", "wide", "wide (one row per date) or long (one row per volunteer and date)")
+	names, err := parseCommandArgs(flags, args, 1, 1)
+	if err != nil {
+		return err
+	}
+	layouts := map[string]rosterLayout{"wide": rosterWide, "long": rosterLong}
+	layout, ok := layouts[*layoutName]
+	if !ok {
+		return usageError{msg: fmt.Sprintf("unknown layout `%s`", *layoutName)}
+	}
+	cScheduleID, err := c.latestCScheduleID(names[0])
+	if err != nil {
+		return err
+	}
+	rosterStruct, err := c.env.sample.RequestRoster(c.env.loggedInUser, cScheduleID)
+	if err != nil {
+		return err
+	}
+	records, err := rosterRecords(rosterStruct, layout)
+	if err != nil {
+		return err
+	}
+	type cliShift struct {
+		Date       string
+		Weekday    string
+		Volunteers []string
+	}
+	value := []cliShift{}
+	for _, shift := range rosterStruct.Shifts {
+		current := cliShift{Date: isoDate(shift.Date), Weekday: shift.Date.Weekday, Volunteers: []string{}}
+		for _, val := range shift.Volunteers {
+			current.Volunteers = append(current.Volunteers, val.VolunteerName)
+		}
+		value = append(value, current)
+	}
+	return c.write(records[0], records[1:], value)
+}
+
+func (c cli) serve(args []string) error {
+	addrs, err := parseCommandArgs(flag.NewFlagSet("serve", flag.ContinueOnError), args, 0, 1)
+	if err != nil {
+		return err
+	}
+	addr := ":8080"
+	if len(addrs) > 0 {
+		addr = addrs[0]
+	}
+	return c.env.serve(addr)
+}
+
+func (c cli) demo(args []string) error {
+	_, err := parseCommandArgs(flag.NewFlagSet("demo", flag.ContinueOnError), args, 0, 0)
+	if err != nil {
+		return err
+	}
+	return loadDemoData(c.env)
+}
@@ -0,0 +1,292 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Runs the CLI against dbPath and returns the exit code and output. It fails the test if the exit code is not wantCode.
+func runCLI(t *testing.T, dbPath string, wantCode int, args ...string) (string, string) {
+	t.Helper()
+	var stdout, stderr strings.Builder
+	ans := run(append([]string{"--db", dbPath}, args...), strings.NewReader(""), &stdout, &stderr)
+	if ans != wantCode {
+		t.Errorf("%q: got exit code %d, want %d. stderr: %s", args, ans, wantCode, stderr.String())
+	}
+	return stdout.String(), stderr.String()
+}
+
+func TestRun(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "cli.db")
+	t.Run("Build a schedule and generate its roster", func(t *testing.T) {
+		runCLI(t, dbPath, 0, "schedules", "create", "spring", "--start", "2024-03-03", "--end", "2024-03-24", "--shifts-off", "1", "--per-shift", "2")
+		runCLI(t, dbPath, 0, "schedule", "weekdays", "set", "spring", "Sunday")
+		runCLI(t, dbPath, 0, "schedule", "volunteers", "add", "spring", "Ann", "Ben", "Cat", "Dan")
+		runCLI(t, dbPath, 0, "unavailable", "add", "spring", "Ann", "2024-03-10")
+		ans, _ := runCLI(t, dbPath, 0, "--format", "csv", "roster", "generate", "spring")
+		want := "Date,Weekday,Volunteer 1,Volunteer 2\n" +
+			"2024-03-03,Sunday,Ann,Ben\n" +
+			"2024-03-10,Sunday,Cat,Dan\n" +
+			"2024-03-17,Sunday,Ann,Ben\n" +
+			"2024-03-24,Sunday,Cat,Dan\n"
+		if ans != want {
+			t.Errorf("got %q, want %q", ans, want)
+		}
+		ans, _ = runCLI(t, dbPath, 0, "--format", "csv", "roster", "show", "spring", "--layout", "long")
+		if !strings.HasPrefix(ans, "Date,Weekday,Volunteer\n2024-03-03,Sunday,Ann\n2024-03-03,Sunday,Ben\n") {
+			t.Errorf("got %q", ans)
+		}
+	})
+	t.Run("List schedules as JSON", func(t *testing.T) {
+		runCLI(t, dbPath, 0, "schedules", "update", "spring", "--name", "spring24", "--shifts-off", "0")
+		ans, _ := runCLI(t, dbPath, 0, "--format", "json", "schedules", "list")
+		var got []map[string]any
+		err := json.Unmarshal([]byte(ans), &got)
+		want := []map[string]any{{"ScheduleName": "spring24", "StartDate": "2024-03-03", "EndDate": "2024-03-24", "ShiftsOff": 0.0, "VolunteersPerShift": 2.0}}
+		if err != nil || !reflect.DeepEqual(got, want) {
+			t.Errorf("got %v (error: %v), want %v", got, err, want)
+		}
+	})
+	t.Run("Rename and remove volunteers", func(t *testing.T) {
+		runCLI(t, dbPath, 0, "volunteers", "add", "Eve")
+		runCLI(t, dbPath, 0, "volunteers", "rename", "Eve", "Eva")
+		runCLI(t, dbPath, 0, "volunteers", "remove", "Eva")
+		ans, _ := runCLI(t, dbPath, 0, "volunteers", "list")
+		want := "VolunteerID  VolunteerName\n1            Ann\n2            Ben\n3            Cat\n4            Dan\n"
+		if ans != want {
+			t.Errorf("got %q, want %q", ans, want)
+		}
+	})
+	failures := []struct {
+		name     string
+		args     []string
+		wantCode int
+		wantErr  string
+	}{
+		{name: "Fail with an unknown command", args: []string{"volunteers", "fire"}, wantCode: 2, wantErr: "unknown command"},
+		{name: "Fail with a missing argument", args: []string{"volunteers", "rename", "Ann"}, wantCode: 2, wantErr: "got 1 arguments"},
+		{name: "Fail with an unknown flag", args: []string{"roster", "show", "spring24", "--colour"}, wantCode: 2, wantErr: "flag provided but not defined"},
+		{name: "Fail with a malformed date", args: []string{"unavailable", "add", "spring24", "Ann", "2024-13-01"}, wantCode: 1, wantErr: "is not a YYYY-MM-DD date"},
+		{name: "Fail with an unknown schedule", args: []string{"roster", "show", "winter"}, wantCode: 1, wantErr: "failed to locate"},
+		{name: "Fail by creating a schedule that exists", args: []string{"schedules", "create", "spring24", "--start", "2024-03-03", "--end", "2024-03-24", "--shifts-off", "1", "--per-shift", "2"}, wantCode: 1, wantErr: "already exists"},
+	}
+	for _, tt := range failures {
+		t.Run(tt.name, func(t *testing.T) {
+			_, stderr := runCLI(t, dbPath, tt.wantCode, tt.args...)
+			if !strings.Contains(stderr, tt.wantErr) {
+				t.Errorf("got stderr %q, want it to contain %q", stderr, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGenerateRoster(t *testing.T) {
+	env, tearDownEnvironment := setUpEnvironment(t)
+	defer tearDownEnvironment(t)
+	setUpSampleData(t, env)
+	ans, err := env.sample.GenerateRoster(env.loggedInUser, 2)
+	if err != nil {
+		t.Errorf("got error: `%v`", err)
+	}
+	if len(ans) != 8 { // the Sundays from 2024-01-07 to 2024-02-25
+		t.Errorf("got %d shifts, want 8", len(ans))
+		t.FailNow()
+	}
+	// with four volunteers, three per shift, and three shifts off, only volunteers who have not served yet count as rested, so the rest are picked by fewest and least recent shifts while skipping Tim on 2024-01-14 and Bill on 2024-01-21
+	want := []rosterShift{
+		{Date: 372, Volunteers: []int{1, 2, 3}},
+		{Date: 379, Volunteers: []int{4, 2, 3}},
+		{Date: 386, Volunteers: []int{1, 4, 3}},
+	}
+	if !reflect.DeepEqual(ans[:3], want) {
+		t.Errorf("got %+v, want %+v", ans[:3], want)
+	}
+	_, err = env.sample.GenerateRoster(env.loggedInUser, 1)
+	if err == nil {
+		t.Errorf("got no error for a schedule without weekdays, want an error")
+	}
+}
+
+func TestUsersCommands(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "users.db")
+	var stdout, stderr strings.Builder
+	ans := run([]string{"--db", dbPath, "users", "add", "Ann"}, strings.NewReader("correct horse\n"), &stdout, &stderr)
+	if ans != 0 {
+		t.Errorf("got exit code %d with `%s`, want 0", ans, stderr.String())
+	}
+	ans = run([]string{"--db", dbPath, "users", "passwd", "Ann"}, strings.NewReader("wrong horse\nbattery staple\n"), &stdout, &stderr)
+	if ans != 1 {
+		t.Errorf("got exit code %d, want 1 for a wrong old password", ans)
+	}
+	ans = run([]string{"--db", dbPath, "users", "passwd", "Ann"}, strings.NewReader("correct horse\n"), &stdout, &stderr)
+	if ans != 2 {
+		t.Errorf("got exit code %d, want 2 for a missing new password", ans)
+	}
+	ans = run([]string{"--db", dbPath, "users", "passwd", "Ann"}, strings.NewReader("correct horse\nbattery staple\n"), &stdout, &stderr)
+	if ans != 0 {
+		t.Errorf("got exit code %d with `%s`, want 0", ans, stderr.String())
+	}
+	ans = run([]string{"--db", dbPath, "users", "passwd", "Ann"}, strings.NewReader("wrong horse\nbattery staple\n"), &stdout, &stderr)
+	if ans != 1 {
+		t.Errorf("got exit code %d, want 1 for a wrong old password", ans)
+	}
+	attempts, _ := runCLI(t, dbPath, 0, "--format", "csv", "users", "attempts", "Ann", "--limit", "1")
+	if !strings.HasSuffix(attempts, ",,failure\n") {
+		t.Errorf("got %q, want the failed attempt", attempts)
+	}
+	runCLI(t, dbPath, 0, "users", "unlock", "Ann")
+	runCLI(t, dbPath, 1, "users", "unlock", "Ann")
+	runCLI(t, dbPath, 2, "users", "unlock")
+	runCLI(t, dbPath, 0, "--user", "Ann", "volunteers", "add", "Eve", "Fay")
+	preview, _ := runCLI(t, dbPath, 0, "--format", "csv", "users", "delete", "Ann", "--dry-run")
+	if !strings.Contains(preview, "Volunteers,2\n") || !strings.HasSuffix(preview, "Users,1\n") {
+		t.Errorf("got preview %q, want 2 Volunteers and 1 Users row", preview)
+	}
+	archivePath := filepath.Join(t.TempDir(), "ann.json")
+	deleted, _ := runCLI(t, dbPath, 0, "--format", "csv", "users", "delete", "Ann", "--archive", archivePath)
+	if deleted != preview {
+		t.Errorf("got %q, want the preview %q", deleted, preview)
+	}
+	archive, err := os.ReadFile(archivePath)
+	if err != nil || !strings.Contains(string(archive), `"VolunteerName": "Fay"`) {
+		t.Errorf("got archive %q (error: %v), want it to contain Fay", archive, err)
+	}
+	runCLI(t, dbPath, 1, "users", "delete", "Ann")
+}
+
+func TestOrgCommands(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "orgs.db")
+	var stdout, stderr strings.Builder
+	ans := run([]string{"--db", dbPath, "users", "add", "Ann"}, strings.NewReader("correct horse\n"), &stdout, &stderr)
+	if ans != 0 {
+		t.Errorf("got exit code %d with `%s`, want 0", ans, stderr.String())
+	}
+	runCLI(t, dbPath, 0, "volunteers", "add", "Eve", "Fay")
+	runCLI(t, dbPath, 0, "orgs", "create", "Crew")
+	runCLI(t, dbPath, 0, "org", "members", "add", "Crew", "Ann")
+	runCLI(t, dbPath, 0, "org", "volunteers", "add", "Crew", "Eve")
+	runCLI(t, dbPath, 1, "org", "volunteers", "add", "Crew", "Nobody")
+	runCLI(t, dbPath, 2, "org", "members", "add", "Crew")
+	volunteers, _ := runCLI(t, dbPath, 0, "--user", "Ann", "--format", "csv", "org", "volunteers", "list", "Crew")
+	if !strings.Contains(volunteers, ",Eve,") || strings.Contains(volunteers, "Fay") {
+		t.Errorf("got %q, want Eve but not Fay", volunteers)
+	}
+	members, _ := runCLI(t, dbPath, 0, "--user", "Ann", "--format", "csv", "org", "members", "list", "Crew")
+	if !strings.HasSuffix(members, "Ann\n") {
+		t.Errorf("got %q, want Ann to be the last member", members)
+	}
+	runCLI(t, dbPath, 0, "--user", "Ann", "orgs", "delete", "Crew")
+	runCLI(t, dbPath, 1, "org", "members", "list", "Crew")
+}
+
+func TestKeysCommands(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "keys.db")
+	created, _ := runCLI(t, dbPath, 0, "--format", "csv", "keys", "create", "backup", "--scope", "read-only", "--expires-in", "720h")
+	if !strings.HasPrefix(created, "KeyID,Scope,Token\n1,read-only,"+apiKeyTokenPrefix) {
+		t.Errorf("got %q, want the new key with its token", created)
+	}
+	runCLI(t, dbPath, 2, "keys", "create", "backup", "--scope", "root")
+	runCLI(t, dbPath, 2, "keys", "revoke", "first")
+	runCLI(t, dbPath, 0, "keys", "revoke", "1")
+	runCLI(t, dbPath, 1, "keys", "revoke", "1")
+	keys, _ := runCLI(t, dbPath, 0, "--format", "csv", "keys", "list")
+	lines := strings.Split(strings.TrimSpace(keys), "\n")
+	if len(lines) != 2 || !strings.HasPrefix(lines[1], "1,backup,"+apiKeyTokenPrefix) || strings.HasSuffix(lines[1], ",") {
+		t.Errorf("got %q, want one revoked key", keys)
+	}
+}
+
+func TestVolunteersProfileCommands(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "profiles.db")
+	runCLI(t, dbPath, 0, "volunteers", "add", "Eve")
+	runCLI(t, dbPath, 0, "volunteers", "set", "Eve", "--email", "eve@example.com", "--contact", "email", "--notes", "keys to the hall")
+	runCLI(t, dbPath, 0, "volunteers", "set", "Eve", "--active=false")
+	runCLI(t, dbPath, 0, "volunteers", "rename", "Eve", "Eva")
+	runCLI(t, dbPath, 1, "volunteers", "set", "Eva", "--contact", "phone")
+	runCLI(t, dbPath, 2, "volunteers", "set", "Eva")
+	ans, _ := runCLI(t, dbPath, 0, "--format", "csv", "volunteers", "show", "Eva")
+	want := "Field,Value\nVolunteerID,1\nVolunteerName,Eva\nEmail,eve@example.com\nPhone,\nPreferredContact,email\nNotes,keys to the hall\nActive,false\nDeactivatedAt,"
+	if !strings.HasPrefix(ans, want) {
+		t.Errorf("got %q, want it to start with %q", ans, want)
+	}
+}
+
+func TestVolunteersReactivateCommand(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "reactivate.db")
+	runCLI(t, dbPath, 0, "volunteers", "add", "Ann", "Ben")
+	runCLI(t, dbPath, 0, "schedules", "create", "spring24", "--start", "2024-03-03", "--end", "2024-03-24", "--shifts-off", "1", "--per-shift", "1")
+	runCLI(t, dbPath, 0, "volunteers", "remove", "Ben")
+	_, stderr := runCLI(t, dbPath, 1, "schedule", "volunteers", "add", "spring24", "Ben")
+	if !strings.Contains(stderr, "inactive volunteers can't be added") {
+		t.Errorf("got stderr %q, want it to explain that Ben is inactive", stderr)
+	}
+	ans, _ := runCLI(t, dbPath, 0, "volunteers", "list")
+	want := "VolunteerID  VolunteerName\n1            Ann\n"
+	if ans != want {
+		t.Errorf("got %q, want %q", ans, want)
+	}
+	ans, _ = runCLI(t, dbPath, 0, "volunteers", "list", "--all")
+	want = "VolunteerID  VolunteerName\n1            Ann\n2            Ben\n"
+	if ans != want {
+		t.Errorf("got %q, want %q", ans, want)
+	}
+	runCLI(t, dbPath, 0, "volunteers", "reactivate", "Ben")
+	runCLI(t, dbPath, 0, "schedule", "volunteers", "add", "spring24", "Ben")
+}
+
+func TestVolunteersMergeCommands(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "merge.db")
+	runCLI(t, dbPath, 0, "volunteers", "add", "Tim", "Ann", "Timmy")
+	runCLI(t, dbPath, 0, "schedules", "create", "spring24", "--start", "2024-03-03", "--end", "2024-03-24", "--shifts-off", "1", "--per-shift", "1")
+	runCLI(t, dbPath, 0, "schedule", "volunteers", "add", "spring24", "Timmy")
+	ans, _ := runCLI(t, dbPath, 0, "--format", "csv", "volunteers", "duplicates")
+	want := "Volunteer,Duplicate,Reason\nTim,Timmy,similar name\n"
+	if ans != want {
+		t.Errorf("got %q, want %q", ans, want)
+	}
+	ans, _ = runCLI(t, dbPath, 0, "--format", "csv", "volunteers", "merge", "Tim", "Timmy")
+	want = "Field,Value\nVFSMoved,1\nVFSMerged,0\nUFSMoved,0\nUFSDropped,0\nCompletedSchedulesUpdated,0\n"
+	if ans != want {
+		t.Errorf("got %q, want %q", ans, want)
+	}
+	runCLI(t, dbPath, 1, "volunteers", "merge", "Tim", "Timmy")
+	ans, _ = runCLI(t, dbPath, 0, "volunteers", "list")
+	want = "VolunteerID  VolunteerName\n1            Tim\n2            Ann\n"
+	if ans != want {
+		t.Errorf("got %q, want %q", ans, want)
+	}
+}
+
+func TestGroupCommands(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "groups.db")
+	runCLI(t, dbPath, 0, "volunteers", "add", "Ann", "Ben", "Cat")
+	runCLI(t, dbPath, 0, "schedules", "create", "spring24", "--start", "2024-03-03", "--end", "2024-03-24", "--shifts-off", "1", "--per-shift", "1")
+	runCLI(t, dbPath, 0, "schedule", "volunteers", "add", "spring24", "Ann")
+	runCLI(t, dbPath, 0, "groups", "create", "Sunday")
+	runCLI(t, dbPath, 1, "groups", "create", "Sunday")
+	runCLI(t, dbPath, 0, "groups", "rename", "Sunday", "Sunday team")
+	runCLI(t, dbPath, 0, "group", "volunteers", "add", "Sunday team", "Ann", "Ben", "Cat")
+	runCLI(t, dbPath, 1, "group", "volunteers", "add", "Sunday team", "Nobody")
+	runCLI(t, dbPath, 0, "volunteers", "remove", "Cat")
+	ans, _ := runCLI(t, dbPath, 0, "--format", "csv", "group", "volunteers", "list", "Sunday team")
+	want := "VolunteerID,VolunteerName,Active\n1,Ann,true\n2,Ben,true\n3,Cat,false\n"
+	if ans != want {
+		t.Errorf("got %q, want %q", ans, want)
+	}
+	ans, _ = runCLI(t, dbPath, 0, "--format", "csv", "group", "enroll", "Sunday team", "spring24")
+	want = "VolunteerName,Outcome\nBen,enrolled\nAnn,already enrolled\nCat,inactive\n"
+	if ans != want {
+		t.Errorf("got %q, want %q", ans, want)
+	}
+	runCLI(t, dbPath, 0, "group", "volunteers", "remove", "Sunday team", "Cat")
+	runCLI(t, dbPath, 1, "group", "volunteers", "remove", "Sunday team", "Cat")
+	runCLI(t, dbPath, 0, "groups", "delete", "Sunday team")
+	ans, _ = runCLI(t, dbPath, 0, "--format", "csv", "groups", "list")
+	if ans != "GroupID,GroupName\n" {
+		t.Errorf("got %q, want no groups", ans)
+	}
+}
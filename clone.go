@@ -0,0 +1,158 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+)
+
+type cloneReport struct {
+	Schedule                  schedule // the new Schedules row
+	WFSCopied                 int
+	VFSCopied                 int
+	VFSSkipped                int // VFS rows left behind because their volunteer is inactive
+	RecurringUnavailabilities int // UFS rows created from weekdays a volunteer was repeatedly unavailable on
+	OneOffCopied              int // UFS dates copied because they are within the new range
+	OneOffSkipped             int // UFS dates left behind because they are outside the new range
+}
+
+// Copies the schedule with sourceID under target's ScheduleName, StartDate, and EndDate, with its ShiftsOff, VolunteersPerShift, WFS, and VFS rows, all in one transaction. VFS rows of inactive volunteers are not copied.
+// With carryUnavailability, a weekday a volunteer was unavailable on at least twice counts as recurring and the volunteer is marked unavailable on every date of that weekday in the new range. Other UFS dates are copied only if they are within the new range.
+func (sm SampleModel) CloneSchedule(currentUser string, sourceID int, target schedule, carryUnavailability bool) (cloneReport, error) {
+	if len(target.ScheduleName) == 0 || target.StartDate < 1 || target.EndDate < target.StartDate {
+		return cloneReport{}, fmt.Errorf("error in CloneSchedule: method failed because target needs a ScheduleName and a StartDate no later than its EndDate: %+v", target)
+	}
+	source, err := sm.RequestSchedule(currentUser, schedule{ScheduleID: sourceID})
+	if err != nil {
+		return cloneReport{}, fmt.Errorf("error in CloneSchedule: %w", err)
+	}
+	if source.User != currentUser { // the copy would belong to currentUser but enroll volunteers of the source's owner
+		return cloneReport{}, fmt.Errorf("error in CloneSchedule: %w", &UserError{Kind: UserForbidden, UserName: currentUser, Detail: fmt.Sprintf("schedule `%s` is shared with the user, and only its owner can clone it", source.ScheduleName)})
+	}
+	existing, err := sm.RequestSchedules(currentUser, []schedule{{ScheduleName: target.ScheduleName}})
+	if err != nil {
+		return cloneReport{}, fmt.Errorf("error in CloneSchedule: %w", err)
+	}
+	if len(existing) > 0 {
+		return cloneReport{}, fmt.Errorf("error in CloneSchedule: method failed because schedule `%s` already exists", target.ScheduleName)
+	}
+	newDates, err := sm.RequestDatesBetween(target.StartDate, target.EndDate)
+	if err != nil {
+		return cloneReport{}, fmt.Errorf("error in CloneSchedule: %w", err)
+	}
+	weekdaysForSchedule, err := sm.RequestWFS(currentUser, []weekdayForSchedule{{Schedule: sourceID}})
+	if err != nil {
+		return cloneReport{}, fmt.Errorf("error in CloneSchedule: %w", err)
+	}
+	volunteersForSchedule, err := sm.RequestVFS(currentUser, []volunteerForSchedule{{Schedule: sourceID}})
+	if err != nil {
+		return cloneReport{}, fmt.Errorf("error in CloneSchedule: %w", err)
+	}
+	inactiveVolunteers, err := sm.RequestPoolVolunteers(currentUser, []volunteer{{Inactive: true}})
+	if err != nil {
+		return cloneReport{}, fmt.Errorf("error in CloneSchedule: %w", err)
+	}
+	inactive := map[int]bool{}
+	for _, val := range inactiveVolunteers {
+		inactive[val.VolunteerID] = true
+	}
+	unavailable := map[int][]date{} // source VFSID to unavailable dates
+	if carryUnavailability && len(volunteersForSchedule) > 0 {
+		var toRequest []unavailabilityForSchedule
+		for _, val := range volunteersForSchedule {
+			toRequest = append(toRequest, unavailabilityForSchedule{VolunteerForSchedule: val.VFSID})
+		}
+		unavailabilitiesForSchedule, err := sm.RequestUFS(currentUser, toRequest)
+		if err != nil {
+			return cloneReport{}, fmt.Errorf("error in CloneSchedule: %w", err)
+		}
+		var dateIDs []int
+		for _, val := range unavailabilitiesForSchedule {
+			dateIDs = append(dateIDs, val.Date)
+		}
+		dates, err := sm.requestDatesByID(dateIDs)
+		if err != nil {
+			return cloneReport{}, fmt.Errorf("error in CloneSchedule: %w", err)
+		}
+		for _, val := range unavailabilitiesForSchedule {
+			unavailable[val.VolunteerForSchedule] = append(unavailable[val.VolunteerForSchedule], dates[val.Date])
+		}
+	}
+	report := cloneReport{Schedule: schedule{
+		ScheduleName:       target.ScheduleName,
+		ShiftsOff:          source.ShiftsOff,
+		VolunteersPerShift: source.VolunteersPerShift,
+		User:               currentUser,
+		StartDate:          target.StartDate,
+		EndDate:            target.EndDate,
+	}}
+	tx, err := sm.beginAudited(currentUser)
+	if err != nil {
+		return cloneReport{}, fmt.Errorf("error in CloneSchedule: %w", err)
+	}
+	defer tx.Rollback()
+	res, err := tx.Exec(`insert into Schedules (ScheduleName, ShiftsOff, VolunteersPerShift, User, StartDate, EndDate) values (?, ?, ?, ?, ?, ?)`,
+		report.Schedule.ScheduleName, report.Schedule.ShiftsOff, report.Schedule.VolunteersPerShift, currentUser, report.Schedule.StartDate, report.Schedule.EndDate)
+	if err != nil {
+		return cloneReport{}, fmt.Errorf("error in CloneSchedule: sql.Tx.Exec error: %w. Value of report.Schedule is `%+v`", err, report.Schedule)
+	}
+	lastID, err := res.LastInsertId()
+	if err != nil {
+		return cloneReport{}, fmt.Errorf("error in CloneSchedule: sql.Result.LastInsertId error: %w", err)
+	}
+	report.Schedule.ScheduleID = int(lastID)
+	for _, val := range weekdaysForSchedule {
+		_, err := tx.Exec(`insert into WeekdaysForSchedule (User, Weekday, Schedule) values (?, ?, ?)`, currentUser, val.Weekday, report.Schedule.ScheduleID)
+		if err != nil {
+			return cloneReport{}, fmt.Errorf("error in CloneSchedule: sql.Tx.Exec error: %w. Value of val is `%+v`", err, val)
+		}
+		report.WFSCopied++
+	}
+	for _, val := range volunteersForSchedule {
+		if inactive[val.Volunteer] {
+			report.VFSSkipped++
+			continue
+		}
+		res, err := tx.Exec(`insert into VolunteersForSchedule (User, Schedule, Volunteer) values (?, ?, ?)`, currentUser, report.Schedule.ScheduleID, val.Volunteer)
+		if err != nil {
+			return cloneReport{}, fmt.Errorf("error in CloneSchedule: sql.Tx.Exec error: %w. Value of val is `%+v`", err, val)
+		}
+		report.VFSCopied++
+		lastID, err := res.LastInsertId()
+		if err != nil {
+			return cloneReport{}, fmt.Errorf("error in CloneSchedule: sql.Result.LastInsertId error: %w", err)
+		}
+		weekdayCounts := map[string]int{}
+		for _, dateStruct := range unavailable[val.VFSID] {
+			weekdayCounts[dateStruct.Weekday]++
+		}
+		var dateIDs []int
+		for _, dateStruct := range newDates {
+			if weekdayCounts[dateStruct.Weekday] > 1 {
+				dateIDs = append(dateIDs, dateStruct.DateID)
+			}
+		}
+		report.RecurringUnavailabilities += len(dateIDs)
+		for _, dateStruct := range unavailable[val.VFSID] {
+			switch {
+			case weekdayCounts[dateStruct.Weekday] > 1:
+			case dateStruct.DateID < target.StartDate || dateStruct.DateID > target.EndDate:
+				report.OneOffSkipped++
+			default:
+				dateIDs = append(dateIDs, dateStruct.DateID)
+				report.OneOffCopied++
+			}
+		}
+		slices.Sort(dateIDs)
+		for _, dateID := range dateIDs {
+			_, err := tx.Exec(`insert into UnavailabilitiesForSchedule (User, VolunteerForSchedule, Date) values (?, ?, ?)`, currentUser, lastID, dateID)
+			if err != nil {
+				return cloneReport{}, fmt.Errorf("error in CloneSchedule: sql.Tx.Exec error: %w. Value of dateID is %d", err, dateID)
+			}
+		}
+	}
+	err = tx.Commit()
+	if err != nil {
+		return cloneReport{}, fmt.Errorf("error in CloneSchedule: sql.Tx.Commit error: %w", err)
+	}
+	return report, nil
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCloneSchedule(t *testing.T) {
+	env, tearDownEnvironment := setUpEnvironment(t)
+	defer tearDownEnvironment(t)
+	setUpSampleData(t, env)
+	// Jack is unavailable on two Sundays of test1, which makes Sunday recurring, and on one Wednesday
+	err := env.sample.CreateUFS(env.loggedInUser, []unavailabilityForSchedule{{VolunteerForSchedule: 3, Date: 372}, {VolunteerForSchedule: 3, Date: 393}, {VolunteerForSchedule: 3, Date: 424}})
+	if err != nil {
+		t.Errorf("Error setting up test (CreateUFS failed): %v", err)
+		t.FailNow()
+	}
+	test1 := Must(env.sample.RequestSchedule(env.loggedInUser, schedule{ScheduleName: "test1"}))
+	tests := []struct {
+		name                string
+		target              schedule
+		carryUnavailability bool
+		want                cloneReport
+		wantAvailability    []map[string][]string
+	}{
+		{name: "Clone with unavailability", target: schedule{ScheduleName: "test1b", StartDate: 411, EndDate: 486}, carryUnavailability: true, want: cloneReport{
+			Schedule:                  schedule{ScheduleID: 5, ScheduleName: "test1b", ShiftsOff: 3, VolunteersPerShift: 3, User: env.loggedInUser, StartDate: 411, EndDate: 486},
+			WFSCopied:                 1,
+			VFSCopied:                 4,
+			RecurringUnavailabilities: 11,
+			OneOffCopied:              1,
+			OneOffSkipped:             2,
+		}, wantAvailability: []map[string][]string{
+			{"Tim": {}},
+			{"Bill": {}},
+			{"Jack": {"2024-02-18", "2024-02-25", "2024-02-28", "2024-03-03", "2024-03-10", "2024-03-17", "2024-03-24", "2024-03-31", "2024-04-07", "2024-04-14", "2024-04-21", "2024-04-28"}},
+			{"George": {}},
+		}},
+		{name: "Clone without unavailability", target: schedule{ScheduleName: "test1c", StartDate: 411, EndDate: 486}, want: cloneReport{
+			Schedule:  schedule{ScheduleID: 6, ScheduleName: "test1c", ShiftsOff: 3, VolunteersPerShift: 3, User: env.loggedInUser, StartDate: 411, EndDate: 486},
+			WFSCopied: 1,
+			VFSCopied: 4,
+		}, wantAvailability: []map[string][]string{{"Tim": {}}, {"Bill": {}}, {"Jack": {}}, {"George": {}}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ans, err := env.sample.CloneSchedule(env.loggedInUser, test1.ScheduleID, tt.target, tt.carryUnavailability)
+			checkResults(t, ans, tt.want, cloneReport{}, err)
+			check, err := env.sample.FetchAndSendData(env.loggedInUser, tt.target.ScheduleName)
+			if err != nil || !reflect.DeepEqual(check.VolunteerAvailabilityData, tt.wantAvailability) || !reflect.DeepEqual(check.WeekdaysForSchedule, []string{"Sunday"}) {
+				t.Errorf("got %+v (error: %v), want availability %v", check, err, tt.wantAvailability)
+			}
+		})
+	}
+	failing := []struct {
+		name     string
+		sourceID int
+		target   schedule
+	}{
+		{name: "Fail by cloning into an existing ScheduleName", sourceID: test1.ScheduleID, target: schedule{ScheduleName: "test2", StartDate: 411, EndDate: 486}},
+		{name: "Fail by providing an EndDate before the StartDate", sourceID: test1.ScheduleID, target: schedule{ScheduleName: "test1d", StartDate: 486, EndDate: 411}},
+		{name: "Fail by providing a nonexistent EndDate", sourceID: test1.ScheduleID, target: schedule{ScheduleName: "test1d", StartDate: 411, EndDate: 100000}},
+		{name: "Fail by providing a nonexistent source", sourceID: 100, target: schedule{ScheduleName: "test1d", StartDate: 411, EndDate: 486}},
+	}
+	for _, tt := range failing {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := env.sample.CloneSchedule(env.loggedInUser, tt.sourceID, tt.target, true)
+			if err == nil {
+				t.Errorf("got no error, want an error for target %+v", tt.target)
+			}
+			schedules, err := env.sample.RequestSchedulesExtended(env.loggedInUser, []schedule{}, true)
+			if err != nil || len(schedules) != 6 {
+				t.Errorf("got %d schedules (error: %v), want 6", len(schedules), err)
+			}
+		})
+	}
+	t.Run("Leave inactive volunteers behind", func(t *testing.T) {
+		err := env.sample.DeleteVolunteers(env.loggedInUser, []volunteer{{VolunteerName: "George"}})
+		if err != nil {
+			t.Errorf("Error setting up test (DeleteVolunteers failed): %v", err)
+		}
+		ans, err := env.sample.CloneSchedule(env.loggedInUser, test1.ScheduleID, schedule{ScheduleName: "test1d", StartDate: 411, EndDate: 486}, false)
+		checkResultsSlice(t, []int{ans.VFSCopied, ans.VFSSkipped}, []int{3, 1}, nil, err)
+	})
+}
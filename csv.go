@@ -0,0 +1,290 @@
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+)
+
+// A problem with one line of an imported CSV file. Lines with problems are skipped and the rest of the file is still imported.
+type csvLineError struct {
+	Line int
+	Err  string
+}
+
+type csvImportReport struct {
+	VolunteersCreated       []string // VolunteerNames added to Volunteers
+	VolunteersEnrolled      []string // VolunteerNames added to the schedule (VolunteersForSchedule)
+	UnavailabilitiesCreated int      // number of UnavailabilitiesForSchedule rows added
+	UnavailabilitiesSkipped int      // number of dates that were already stored
+	Errors                  []csvLineError
+}
+
+// Parses a date written either as YYYY-MM-DD or as M/D/YYYY into a date struct with Month, Day, and Year set.
+func parseCSVDate(value string) (date, error) {
+	value = strings.TrimSpace(value)
+	for _, layout := range []string{time.DateOnly, "1/2/2006"} {
+		if parsed, err := time.Parse(layout, value); err == nil {
+			return date{Month: int(parsed.Month()), Day: parsed.Day(), Year: parsed.Year()}, nil
+		}
+	}
+	return date{}, fmt.Errorf("`%s` is not a YYYY-MM-DD or M/D/YYYY date", value)
+}
+
+// Reads volunteers from CSV and adds them to the schedule with scheduleID, all in one transaction.
+// Each line holds a VolunteerName followed by any number of dates the volunteer is unavailable, either one per field or separated by ";" within a field. A first line starting with "Name", "Volunteer", or "VolunteerName" is treated as a header.
+// Volunteers, VFS rows, and UFS rows that already exist are reused, and names are looked up in the pool of the schedule's owner, see volunteerPool. A volunteer listed on several lines gets the dates of all of them. Lines naming an inactive volunteer are reported as errors.
+func (sm SampleModel) ImportVolunteersCSV(currentUser string, scheduleID int, r io.Reader) (csvImportReport, error) {
+	report := csvImportReport{VolunteersCreated: []string{}, VolunteersEnrolled: []string{}, Errors: []csvLineError{}}
+	scheduleStruct, err := sm.authorizeSchedule(currentUser, scheduleID, ScheduleEditor)
+	if err != nil {
+		return csvImportReport{}, fmt.Errorf("error in ImportVolunteersCSV: %w", err)
+	}
+	owner := scheduleStruct.User // volunteers and rows are stored under the owner of a shared schedule
+	type csvLine struct {
+		line  int
+		name  string
+		dates []date
+	}
+	var lines []csvLine
+	var toRequest []date
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	for first := true; ; first = false {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			report.Errors = append(report.Errors, csvLineError{Line: parseErr.Line, Err: parseErr.Err.Error()})
+			continue
+		}
+		if err != nil {
+			return csvImportReport{}, fmt.Errorf("error in ImportVolunteersCSV: csv.Reader.Read error: %w", err)
+		}
+		lineNumber, _ := reader.FieldPos(0)
+		name := strings.TrimSpace(record[0])
+		if first && slices.Contains([]string{"name", "volunteer", "volunteername"}, strings.ToLower(name)) {
+			continue
+		}
+		if len(name) == 0 {
+			if slices.ContainsFunc(record, func(field string) bool { return len(strings.TrimSpace(field)) > 0 }) {
+				report.Errors = append(report.Errors, csvLineError{Line: lineNumber, Err: "missing VolunteerName"})
+			}
+			continue
+		}
+		current := csvLine{line: lineNumber, name: name}
+		var lineErr error
+		for _, field := range record[1:] {
+			for _, value := range strings.Split(field, ";") {
+				if len(strings.TrimSpace(value)) == 0 {
+					continue
+				}
+				parsed, err := parseCSVDate(value)
+				if err != nil {
+					lineErr = err
+					break
+				}
+				current.dates = append(current.dates, parsed)
+			}
+		}
+		if lineErr != nil {
+			report.Errors = append(report.Errors, csvLineError{Line: lineNumber, Err: lineErr.Error()})
+			continue
+		}
+		lines = append(lines, current)
+		toRequest = append(toRequest, current.dates...)
+	}
+	// Resolve every date of the file with one RequestDates call, then check each line against the result.
+	found := map[date]date{}
+	if len(toRequest) > 0 {
+		dates, err := sm.RequestDates(toRequest)
+		if err != nil {
+			return csvImportReport{}, fmt.Errorf("error in ImportVolunteersCSV: %w", err)
+		}
+		for _, val := range dates {
+			found[date{Month: val.Month, Day: val.Day, Year: val.Year}] = val
+		}
+	}
+	// Names are resolved through the owner's pool like the other VFS rows of the schedule, preferring the owner's own volunteer when a pool has one of the same name.
+	volunteers, err := sm.RequestPoolVolunteers(owner, []volunteer{})
+	if err != nil {
+		return csvImportReport{}, fmt.Errorf("error in ImportVolunteersCSV: %w", err)
+	}
+	pool := map[string]volunteer{}
+	for _, val := range volunteers {
+		if existing, ok := pool[val.VolunteerName]; !ok || existing.User != owner && val.User == owner {
+			pool[val.VolunteerName] = val
+		}
+	}
+	var names []string
+	unavailable := map[string][]int{} // VolunteerName to DateIDs
+	for _, current := range lines {
+		var dateIDs []int
+		var lineErr string
+		for _, val := range current.dates {
+			dateStruct, ok := found[val]
+			if !ok {
+				lineErr = fmt.Sprintf("%s is not in the Dates table", isoDate(val))
+				break
+			}
+			if dateStruct.DateID < scheduleStruct.StartDate || dateStruct.DateID > scheduleStruct.EndDate {
+				lineErr = fmt.Sprintf("%s is outside of schedule %s", isoDate(val), scheduleStruct.ScheduleName)
+				break
+			}
+			dateIDs = append(dateIDs, dateStruct.DateID)
+		}
+		if len(lineErr) == 0 && pool[current.name].Inactive {
+			lineErr = fmt.Sprintf("volunteer %s is inactive and can't be added to a schedule", current.name)
+		}
+		if len(lineErr) > 0 {
+			report.Errors = append(report.Errors, csvLineError{Line: current.line, Err: lineErr})
+			continue
+		}
+		if _, ok := unavailable[current.name]; !ok {
+			names = append(names, current.name)
+			unavailable[current.name] = []int{}
+		}
+		for _, val := range dateIDs {
+			if !slices.Contains(unavailable[current.name], val) {
+				unavailable[current.name] = append(unavailable[current.name], val)
+			}
+		}
+	}
+	enrolled, err := sm.RequestVFS(currentUser, []volunteerForSchedule{{Schedule: scheduleID}})
+	if err != nil {
+		return csvImportReport{}, fmt.Errorf("error in ImportVolunteersCSV: %w", err)
+	}
+	vfsIDs := map[int]int{} // VolunteerID to VFSID
+	var ufsToRequest []unavailabilityForSchedule
+	for _, val := range enrolled {
+		vfsIDs[val.Volunteer] = val.VFSID
+		ufsToRequest = append(ufsToRequest, unavailabilityForSchedule{VolunteerForSchedule: val.VFSID})
+	}
+	existingUFS := []unavailabilityForSchedule{}
+	if len(ufsToRequest) > 0 {
+		existingUFS, err = sm.RequestUFS(currentUser, ufsToRequest)
+		if err != nil {
+			return csvImportReport{}, fmt.Errorf("error in ImportVolunteersCSV: %w", err)
+		}
+	}
+	tx, err := sm.beginAudited(currentUser)
+	if err != nil {
+		return csvImportReport{}, fmt.Errorf("error in ImportVolunteersCSV: %w", err)
+	}
+	defer tx.Rollback()
+	for _, name := range names {
+		volunteerStruct, ok := pool[name]
+		volunteerID := volunteerStruct.VolunteerID
+		if !ok {
+			res, err := tx.Exec(`insert into Volunteers (VolunteerName, User) values (?, ?)`, name, owner)
+			if err != nil {
+				return csvImportReport{}, fmt.Errorf("error in ImportVolunteersCSV: sql.Tx.Exec error: %w. Value of name is `%s`", err, name)
+			}
+			lastID, err := res.LastInsertId()
+			if err != nil {
+				return csvImportReport{}, fmt.Errorf("error in ImportVolunteersCSV: sql.Result.LastInsertId error: %w", err)
+			}
+			volunteerID = int(lastID)
+			report.VolunteersCreated = append(report.VolunteersCreated, name)
+		}
+		vfsID, ok := vfsIDs[volunteerID]
+		if !ok {
+			res, err := tx.Exec(`insert into VolunteersForSchedule (User, Schedule, Volunteer) values (?, ?, ?)`, owner, scheduleID, volunteerID)
+			if err != nil {
+				return csvImportReport{}, fmt.Errorf("error in ImportVolunteersCSV: sql.Tx.Exec error: %w. Value of name is `%s`", err, name)
+			}
+			lastID, err := res.LastInsertId()
+			if err != nil {
+				return csvImportReport{}, fmt.Errorf("error in ImportVolunteersCSV: sql.Result.LastInsertId error: %w", err)
+			}
+			vfsID = int(lastID)
+			report.VolunteersEnrolled = append(report.VolunteersEnrolled, name)
+		}
+		for _, dateID := range unavailable[name] {
+			if slices.ContainsFunc(existingUFS, func(ufs unavailabilityForSchedule) bool {
+				return ufs.VolunteerForSchedule == vfsID && ufs.Date == dateID
+			}) {
+				report.UnavailabilitiesSkipped++
+				continue
+			}
+			_, err := tx.Exec(`insert into UnavailabilitiesForSchedule (User, VolunteerForSchedule, Date) values (?, ?, ?)`, owner, vfsID, dateID)
+			if err != nil {
+				return csvImportReport{}, fmt.Errorf("error in ImportVolunteersCSV: sql.Tx.Exec error: %w. Value of name is `%s` and value of dateID is %d", err, name, dateID)
+			}
+			report.UnavailabilitiesCreated++
+		}
+	}
+	err = tx.Commit()
+	if err != nil {
+		return csvImportReport{}, fmt.Errorf("error in ImportVolunteersCSV: sql.Tx.Commit error: %w", err)
+	}
+	slices.SortStableFunc(report.Errors, func(a, b csvLineError) int { return a.Line - b.Line })
+	return report, nil
+}
+
+type rosterLayout int
+
+const (
+	rosterWide rosterLayout = iota // one row per service date: Date, Weekday, Volunteer 1..n
+	rosterLong                     // one row per assignment: Date, Weekday, Volunteer
+)
+
+// Writes the completed schedule with cScheduleID as CSV. Dates are written as YYYY-MM-DD.
+func (sm SampleModel) ExportRosterCSV(currentUser string, cScheduleID int, layout rosterLayout, w io.Writer) error {
+	rosterStruct, err := sm.RequestRoster(currentUser, cScheduleID)
+	if err != nil {
+		return fmt.Errorf("error in ExportRosterCSV: %w", err)
+	}
+	records, err := rosterRecords(rosterStruct, layout)
+	if err != nil {
+		return fmt.Errorf("error in ExportRosterCSV: %w", err)
+	}
+	writer := csv.NewWriter(w)
+	err = writer.WriteAll(records)
+	if err != nil {
+		return fmt.Errorf("error in ExportRosterCSV: csv.Writer.WriteAll error: %w", err)
+	}
+	return nil
+}
+
+// Lays rosterStruct out as rows with a header row first. Dates are written as YYYY-MM-DD.
+func rosterRecords(rosterStruct roster, layout rosterLayout) ([][]string, error) {
+	var records [][]string
+	switch layout {
+	case rosterWide:
+		columns := 0
+		for _, shift := range rosterStruct.Shifts {
+			columns = max(columns, len(shift.Volunteers))
+		}
+		header := []string{"Date", "Weekday"}
+		for i := 1; i <= columns; i++ {
+			header = append(header, fmt.Sprintf("Volunteer %d", i))
+		}
+		records = append(records, header)
+		for _, shift := range rosterStruct.Shifts {
+			record := make([]string, len(header))
+			record[0], record[1] = isoDate(shift.Date), shift.Date.Weekday
+			for i, val := range shift.Volunteers {
+				record[i+2] = val.VolunteerName
+			}
+			records = append(records, record)
+		}
+	case rosterLong:
+		records = append(records, []string{"Date", "Weekday", "Volunteer"})
+		for _, shift := range rosterStruct.Shifts {
+			for _, val := range shift.Volunteers {
+				records = append(records, []string{isoDate(shift.Date), shift.Date.Weekday, val.VolunteerName})
+			}
+		}
+	default:
+		return nil, fmt.Errorf("error in rosterRecords: method failed because layout %d is not a rosterLayout", layout)
+	}
+	return records, nil
+}
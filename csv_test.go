@@ -0,0 +1,183 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseCSVDate(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  date
+	}{
+		{name: "Parse an ISO date", input: "2024-01-14", want: date{Month: 1, Day: 14, Year: 2024}},
+		{name: "Parse an M/D/YYYY date", input: "1/14/2024", want: date{Month: 1, Day: 14, Year: 2024}},
+		{name: "Parse an MM/DD/YYYY date with spaces", input: " 01/07/2024 ", want: date{Month: 1, Day: 7, Year: 2024}},
+		{name: "Fail to parse a D.M.YYYY date", input: "14.1.2024", want: date{}},
+		{name: "Fail to parse an impossible date", input: "2/30/2024", want: date{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ans, err := parseCSVDate(tt.input)
+			checkResults(t, ans, tt.want, date{}, err)
+		})
+	}
+}
+
+func TestImportVolunteersCSV(t *testing.T) {
+	env, tearDownEnvironment := setUpEnvironment(t)
+	defer tearDownEnvironment(t)
+	setUpSampleData(t, env)
+	test1 := Must(env.sample.RequestSchedule(env.loggedInUser, schedule{ScheduleName: "test1"})).ScheduleID
+	// Bob is inactive, and Ola is in the pool of an organization of Seth but was created by Ann
+	err := env.sample.DeleteVolunteers(env.loggedInUser, []volunteer{{VolunteerName: "Bob"}})
+	if err == nil {
+		err = env.sample.CreateUser(user{UserName: "Ann"})
+	}
+	if err == nil {
+		err = env.sample.CreateVolunteers("Ann", []volunteer{{VolunteerName: "Ola"}})
+	}
+	var crew organization
+	if err == nil {
+		crew, err = env.sample.CreateOrganization(env.loggedInUser, "Crew")
+	}
+	if err == nil {
+		err = env.sample.AddOrganizationMember(env.loggedInUser, crew.OrganizationID, "Ann")
+	}
+	if err == nil {
+		err = env.sample.AddOrganizationVolunteers("Ann", crew.OrganizationID, []volunteer{{VolunteerName: "Ola"}})
+	}
+	if err != nil {
+		t.Errorf("Error setting up test: %v", err)
+		t.FailNow()
+	}
+	tests := []struct {
+		name  string
+		input string
+		want  csvImportReport
+		// the test1 payload after the import, as returned by FetchAndSendData
+		wantAvailability []map[string][]string
+	}{
+		{name: "Import new and existing volunteers with a header", input: "Name,Unavailable\nTim,1/28/2024\nZed,2024-02-04;2/11/2024\nLarry,\n", want: csvImportReport{
+			VolunteersCreated:       []string{"Zed"},
+			VolunteersEnrolled:      []string{"Zed", "Larry"},
+			UnavailabilitiesCreated: 3,
+			Errors:                  []csvLineError{},
+		}, wantAvailability: []map[string][]string{
+			{"Tim": {"2024-01-14", "2024-01-28"}},
+			{"Bill": {"2024-01-21"}},
+			{"Jack": {}},
+			{"George": {}},
+			{"Zed": {"2024-02-04", "2024-02-11"}},
+			{"Larry": {}},
+		}},
+		{name: "Skip dates that are already stored and report bad lines", input: "Tim,2024-01-14,2024-01-28\nBill,2024-13-01\n,2024-02-04\nJack,2024-05-05\n\"Yves,2024-02-04\nGeorge,2024-02-18\n", want: csvImportReport{
+			VolunteersCreated:       []string{},
+			VolunteersEnrolled:      []string{},
+			UnavailabilitiesCreated: 0,
+			UnavailabilitiesSkipped: 2,
+			Errors: []csvLineError{
+				{Line: 2, Err: "`2024-13-01` is not a YYYY-MM-DD or M/D/YYYY date"},
+				{Line: 3, Err: "missing VolunteerName"},
+				{Line: 4, Err: "2024-05-05 is outside of schedule test1"},
+				{Line: 6, Err: "extraneous or missing \" in quoted-field"},
+			},
+		}, wantAvailability: []map[string][]string{
+			{"Tim": {"2024-01-14", "2024-01-28"}},
+			{"Bill": {"2024-01-21"}},
+			{"Jack": {}},
+			{"George": {}},
+			{"Zed": {"2024-02-04", "2024-02-11"}},
+			{"Larry": {}},
+		}},
+		{name: "Merge a volunteer listed twice", input: "Jack,2024-01-07\nJack,2024-01-14;2024-01-07\n", want: csvImportReport{
+			VolunteersCreated:       []string{},
+			VolunteersEnrolled:      []string{},
+			UnavailabilitiesCreated: 2,
+			Errors:                  []csvLineError{},
+		}, wantAvailability: []map[string][]string{
+			{"Tim": {"2024-01-14", "2024-01-28"}},
+			{"Bill": {"2024-01-21"}},
+			{"Jack": {"2024-01-07", "2024-01-14"}},
+			{"George": {}},
+			{"Zed": {"2024-02-04", "2024-02-11"}},
+			{"Larry": {}},
+		}},
+		{name: "Use the pool and report inactive volunteers", input: "Bob,2024-01-28\nOla,2024-01-28\n", want: csvImportReport{
+			VolunteersCreated:       []string{},
+			VolunteersEnrolled:      []string{"Ola"},
+			UnavailabilitiesCreated: 1,
+			Errors:                  []csvLineError{{Line: 1, Err: "volunteer Bob is inactive and can't be added to a schedule"}},
+		}, wantAvailability: []map[string][]string{
+			{"Tim": {"2024-01-14", "2024-01-28"}},
+			{"Bill": {"2024-01-21"}},
+			{"Jack": {"2024-01-07", "2024-01-14"}},
+			{"George": {}},
+			{"Zed": {"2024-02-04", "2024-02-11"}},
+			{"Larry": {}},
+			{"Ola": {"2024-01-28"}},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ans, err := env.sample.ImportVolunteersCSV(env.loggedInUser, test1, strings.NewReader(tt.input))
+			if err != nil {
+				t.Errorf("got error: `%v` for input: `%s`", err, tt.input)
+			}
+			if !reflect.DeepEqual(ans, tt.want) {
+				t.Errorf("got %+v, want %+v", ans, tt.want)
+			}
+			check, err := env.sample.FetchAndSendData(env.loggedInUser, "test1")
+			if err != nil || !reflect.DeepEqual(check.VolunteerAvailabilityData, tt.wantAvailability) {
+				t.Errorf("got %v (error: %v), want %v", check.VolunteerAvailabilityData, err, tt.wantAvailability)
+			}
+		})
+	}
+	t.Run("Fail to import into a nonexistent schedule", func(t *testing.T) {
+		_, err := env.sample.ImportVolunteersCSV(env.loggedInUser, 100, strings.NewReader("Tim\n"))
+		if err == nil {
+			t.Errorf("got no error, want an error for ScheduleID 100")
+		}
+	})
+}
+
+func TestExportRosterCSV(t *testing.T) {
+	env, tearDownEnvironment := setUpEnvironment(t)
+	defer tearDownEnvironment(t)
+	setUpSampleData(t, env)
+	err := env.sample.CreateCompletedSchedules(env.loggedInUser, []completedSchedule{{ScheduleData: sampleScheduleData, Schedule: 2}})
+	if err != nil {
+		t.Errorf("Error setting up test (CreateCompletedSchedules failed): %v", err)
+		t.FailNow()
+	}
+	tests := []struct {
+		name    string
+		layout  rosterLayout
+		want    string
+		wantErr bool
+	}{
+		{name: "Export one row per date", layout: rosterWide, want: "Date,Weekday,Volunteer 1,Volunteer 2,Volunteer 3\n" +
+			"2024-01-07,Sunday,Tim,Bill,Jack\n" +
+			"2024-01-14,Sunday,Bill,Jack,George\n" +
+			"2024-01-21,Sunday,Tim,Jack,\n"},
+		{name: "Export one row per assignment", layout: rosterLong, want: "Date,Weekday,Volunteer\n" +
+			"2024-01-07,Sunday,Tim\n2024-01-07,Sunday,Bill\n2024-01-07,Sunday,Jack\n" +
+			"2024-01-14,Sunday,Bill\n2024-01-14,Sunday,Jack\n2024-01-14,Sunday,George\n" +
+			"2024-01-21,Sunday,Tim\n2024-01-21,Sunday,Jack\n"},
+		{name: "Fail by providing an unknown layout", layout: rosterLayout(5), want: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ans strings.Builder
+			err := env.sample.ExportRosterCSV(env.loggedInUser, 1, tt.layout, &ans)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("got error: `%v`, want error: %v", err, tt.wantErr)
+			}
+			if ans.String() != tt.want {
+				t.Errorf("got %q, want %q", ans.String(), tt.want)
+			}
+		})
+	}
+}
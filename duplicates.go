@@ -0,0 +1,287 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// Two volunteers of the same user that are likely the same person, see FindDuplicateVolunteers.
+type duplicateVolunteers struct {
+	Volunteer volunteer
+	Duplicate volunteer // the one created later, which MergeVolunteers would fold into Volunteer
+	Reason    string    // same name, same email, same phone, or similar name
+}
+
+// What MergeVolunteers changed.
+type mergeReport struct {
+	Survivor                  volunteer
+	VFSMoved                  int // VFS rows of the duplicate that now point to the survivor
+	VFSMerged                 int // VFS rows of the duplicate on a schedule the survivor was on already, whose UFS rows were moved to the survivor's row
+	UFSMoved                  int
+	UFSDropped                int // unavailabilities the survivor already had on the same schedule and date
+	CompletedSchedulesUpdated int
+}
+
+// Lowercases name and drops everything but letters and digits, so "Mary-Ann " and "maryann" compare equal.
+func normalizeVolunteerName(name string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(name) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+func normalizePhone(phone string) string {
+	var b strings.Builder
+	for _, r := range phone {
+		if unicode.IsDigit(r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// The Levenshtein distance between a and b, counted in runes.
+func editDistance(a string, b string) int {
+	ar, br := []rune(a), []rune(b)
+	previous := make([]int, len(br)+1)
+	current := make([]int, len(br)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(ar); i++ {
+		current[0] = i
+		for j := 1; j <= len(br); j++ {
+			substitution := previous[j-1]
+			if ar[i-1] != br[j-1] {
+				substitution++
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, substitution)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(br)]
+}
+
+// Returns why a and b are likely the same person, or an empty string if they are not. Names are similar if one starts with the other ("Tim" and "Timmy") or if they are a typo apart, which short names are too short to judge.
+func duplicateReason(a volunteer, b volunteer) string {
+	nameA, nameB := normalizeVolunteerName(a.VolunteerName), normalizeVolunteerName(b.VolunteerName)
+	shorter := min(len([]rune(nameA)), len([]rune(nameB)))
+	switch {
+	case nameA == nameB:
+		return "same name"
+	case len(a.Email) > 0 && strings.EqualFold(a.Email, b.Email):
+		return "same email"
+	case len(normalizePhone(a.Phone)) > 0 && normalizePhone(a.Phone) == normalizePhone(b.Phone):
+		return "same phone"
+	case shorter >= 3 && (strings.HasPrefix(nameA, nameB) || strings.HasPrefix(nameB, nameA)):
+		return "similar name"
+	case shorter >= 4 && editDistance(nameA, nameB) <= 1 || shorter >= 8 && editDistance(nameA, nameB) <= 2:
+		return "similar name"
+	}
+	return ""
+}
+
+// Returns the pairs of volunteers of currentUser, active or not, that are likely the same person, ordered by the VolunteerIDs of Volunteer and then Duplicate. Nothing is changed; see MergeVolunteers.
+func (sm SampleModel) FindDuplicateVolunteers(currentUser string) ([]duplicateVolunteers, error) {
+	volunteers, err := sm.RequestVolunteers(currentUser, []volunteer{})
+	if err != nil {
+		return []duplicateVolunteers{}, fmt.Errorf("error in FindDuplicateVolunteers: %w", err)
+	}
+	slices.SortFunc(volunteers, func(a, b volunteer) int { return a.VolunteerID - b.VolunteerID })
+	result := []duplicateVolunteers{}
+	for i, val := range volunteers {
+		for _, other := range volunteers[i+1:] {
+			if reason := duplicateReason(val, other); len(reason) > 0 {
+				result = append(result, duplicateVolunteers{Volunteer: val, Duplicate: other, Reason: reason})
+			}
+		}
+	}
+	return result, nil
+}
+
+// Folds the volunteer with duplicateID into the one with survivorID, both created by currentUser, in one transaction. The duplicate's VFS rows are repointed to the survivor, or, on schedules the survivor is on already, their UFS rows are moved to the survivor's VFS row. CompletedSchedules that name the duplicate name the survivor instead, the survivor takes the duplicate's place in an organization's pool if it is in none and in the duplicate's groups, and profile fields the survivor lacks are taken from the duplicate. Then the duplicate is deleted. Like any VFS row, those of the duplicate can't be moved to an inactive survivor.
+func (sm SampleModel) MergeVolunteers(currentUser string, survivorID int, duplicateID int) (mergeReport, error) {
+	if survivorID == duplicateID {
+		return mergeReport{}, fmt.Errorf("error in MergeVolunteers: method failed because survivorID and duplicateID are both %d", survivorID)
+	}
+	survivor, err := sm.RequestVolunteer(currentUser, volunteer{VolunteerID: survivorID})
+	if err != nil {
+		return mergeReport{}, fmt.Errorf("error in MergeVolunteers: %w", err)
+	}
+	duplicate, err := sm.RequestVolunteer(currentUser, volunteer{VolunteerID: duplicateID})
+	if err != nil {
+		return mergeReport{}, fmt.Errorf("error in MergeVolunteers: %w", err)
+	}
+	for _, field := range []struct{ survivor, duplicate *string }{{&survivor.Email, &duplicate.Email}, {&survivor.Phone, &duplicate.Phone}, {&survivor.PreferredContact, &duplicate.PreferredContact}, {&survivor.Notes, &duplicate.Notes}} {
+		if len(*field.survivor) == 0 {
+			*field.survivor = *field.duplicate
+		}
+	}
+	report := mergeReport{Survivor: survivor}
+	tx, err := sm.beginAudited(currentUser)
+	if err != nil {
+		return mergeReport{}, fmt.Errorf("error in MergeVolunteers: %w", err)
+	}
+	defer tx.Rollback()
+	err = mergeVFS(tx.Tx, survivorID, duplicateID, &report)
+	if err != nil {
+		return mergeReport{}, fmt.Errorf("error in MergeVolunteers: %w", err)
+	}
+	report.CompletedSchedulesUpdated, err = replaceRosterVolunteer(tx.Tx, survivorID, duplicateID)
+	if err != nil {
+		return mergeReport{}, fmt.Errorf("error in MergeVolunteers: %w", err)
+	}
+	for _, table := range []string{"OrganizationVolunteers", "VolunteerGroupMembers"} {
+		_, err = tx.Exec(fmt.Sprintf(`update or ignore %s set Volunteer = ? where Volunteer = ?`, table), survivorID, duplicateID)
+		if err != nil {
+			return mergeReport{}, fmt.Errorf("error in MergeVolunteers: sql.Tx.Exec error: %w", err)
+		}
+	}
+	_, err = tx.Exec(`update Volunteers set Email = ?, Phone = ?, PreferredContact = ?, Notes = ? where VolunteerID = ?`, survivor.Email, survivor.Phone, survivor.PreferredContact, survivor.Notes, survivorID)
+	if err != nil {
+		return mergeReport{}, fmt.Errorf("error in MergeVolunteers: sql.Tx.Exec error: %w", err)
+	}
+	_, err = tx.Exec(`delete from Volunteers where VolunteerID = ?`, duplicateID) // OrganizationVolunteers and VolunteerGroupMembers rows the survivor could not take over are deleted with it
+	if err != nil {
+		return mergeReport{}, fmt.Errorf("error in MergeVolunteers: sql.Tx.Exec error: %w", err)
+	}
+	err = tx.Commit()
+	if err != nil {
+		return mergeReport{}, fmt.Errorf("error in MergeVolunteers: sql.Tx.Commit error: %w", err)
+	}
+	return report, nil
+}
+
+// Repoints the VFS rows of duplicateID to survivorID, merging those on schedules survivorID is on already, for MergeVolunteers.
+func mergeVFS(tx *sql.Tx, survivorID int, duplicateID int, report *mergeReport) error {
+	rows, err := tx.Query(`select VFSID, Schedule from VolunteersForSchedule where Volunteer = ? order by VFSID`, duplicateID)
+	if err != nil {
+		return fmt.Errorf("sql.Tx.Query error: %w", err)
+	}
+	duplicateVFS := map[int]int{} // VFSID to Schedule
+	var vfsIDs []int
+	for rows.Next() {
+		var vfsID, scheduleID int
+		err = rows.Scan(&vfsID, &scheduleID)
+		if err != nil {
+			rows.Close()
+			return fmt.Errorf("sql.Rows.Scan error: %w", err)
+		}
+		duplicateVFS[vfsID] = scheduleID
+		vfsIDs = append(vfsIDs, vfsID)
+	}
+	rows.Close()
+	err = rows.Err()
+	if err != nil {
+		return fmt.Errorf("sql.Rows.Err error: %w", err)
+	}
+	for _, vfsID := range vfsIDs {
+		var survivorVFSID int
+		err := tx.QueryRow(`select VFSID from VolunteersForSchedule where Volunteer = ? and Schedule = ? order by VFSID limit 1`, survivorID, duplicateVFS[vfsID]).Scan(&survivorVFSID)
+		if errors.Is(err, sql.ErrNoRows) {
+			_, err = tx.Exec(`update VolunteersForSchedule set Volunteer = ? where VFSID = ?`, survivorID, vfsID)
+			if err != nil {
+				return fmt.Errorf("sql.Tx.Exec error: %w. Value of vfsID is `%d`", err, vfsID)
+			}
+			report.VFSMoved++
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("sql.Tx.QueryRow error: %w", err)
+		}
+		res, err := tx.Exec(`update UnavailabilitiesForSchedule set VolunteerForSchedule = ? where VolunteerForSchedule = ? and Date not in (select Date from UnavailabilitiesForSchedule where VolunteerForSchedule = ?)`, survivorVFSID, vfsID, survivorVFSID)
+		if err != nil {
+			return fmt.Errorf("sql.Tx.Exec error: %w. Value of vfsID is `%d`", err, vfsID)
+		}
+		moved, err := res.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("sql.Result.RowsAffected error: %w", err)
+		}
+		res, err = tx.Exec(`delete from UnavailabilitiesForSchedule where VolunteerForSchedule = ?`, vfsID)
+		if err != nil {
+			return fmt.Errorf("sql.Tx.Exec error: %w. Value of vfsID is `%d`", err, vfsID)
+		}
+		dropped, err := res.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("sql.Result.RowsAffected error: %w", err)
+		}
+		_, err = tx.Exec(`delete from VolunteersForSchedule where VFSID = ?`, vfsID)
+		if err != nil {
+			return fmt.Errorf("sql.Tx.Exec error: %w. Value of vfsID is `%d`", err, vfsID)
+		}
+		report.VFSMerged++
+		report.UFSMoved += int(moved)
+		report.UFSDropped += int(dropped)
+	}
+	return nil
+}
+
+// Replaces duplicateID with survivorID in the ScheduleData of every completed schedule, without listing a volunteer twice in one shift, and returns how many completed schedules changed.
+func replaceRosterVolunteer(tx *sql.Tx, survivorID int, duplicateID int) (int, error) {
+	rows, err := tx.Query(`select CScheduleID, ScheduleData from CompletedSchedules order by CScheduleID`)
+	if err != nil {
+		return 0, fmt.Errorf("sql.Tx.Query error: %w", err)
+	}
+	updated := map[int]string{} // CScheduleID to the new ScheduleData
+	var cScheduleIDs []int
+	for rows.Next() {
+		var cScheduleID int
+		var scheduleData string
+		err = rows.Scan(&cScheduleID, &scheduleData)
+		if err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("sql.Rows.Scan error: %w", err)
+		}
+		shifts, err := parseScheduleData(scheduleData)
+		if err != nil {
+			continue // RequestRoster reports malformed ScheduleData, which the merge leaves alone
+		}
+		var changed bool
+		for i, shift := range shifts {
+			if !slices.Contains(shift.Volunteers, duplicateID) {
+				continue
+			}
+			changed = true
+			volunteers := []int{}
+			for _, val := range shift.Volunteers {
+				if val == duplicateID {
+					val = survivorID
+				}
+				if !slices.Contains(volunteers, val) {
+					volunteers = append(volunteers, val)
+				}
+			}
+			shifts[i].Volunteers = volunteers
+		}
+		if !changed {
+			continue
+		}
+		encoded, err := json.Marshal(shifts)
+		if err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("json.Marshal error: %w", err)
+		}
+		updated[cScheduleID] = string(encoded)
+		cScheduleIDs = append(cScheduleIDs, cScheduleID)
+	}
+	rows.Close()
+	err = rows.Err()
+	if err != nil {
+		return 0, fmt.Errorf("sql.Rows.Err error: %w", err)
+	}
+	for _, cScheduleID := range cScheduleIDs {
+		_, err := tx.Exec(`update CompletedSchedules set ScheduleData = ? where CScheduleID = ?`, updated[cScheduleID], cScheduleID)
+		if err != nil {
+			return 0, fmt.Errorf("sql.Tx.Exec error: %w. Value of cScheduleID is `%d`", err, cScheduleID)
+		}
+	}
+	return len(cScheduleIDs), nil
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestDuplicateReason(t *testing.T) {
+	tests := []struct {
+		name string
+		a    volunteer
+		b    volunteer
+		want string
+	}{
+		{name: "Match names that only differ in case and punctuation", a: volunteer{VolunteerName: "Mary-Ann"}, b: volunteer{VolunteerName: "mary ann"}, want: "same name"},
+		{name: "Match a name that starts with the other", a: volunteer{VolunteerName: "Tim"}, b: volunteer{VolunteerName: "Timmy"}, want: "similar name"},
+		{name: "Match a typo", a: volunteer{VolunteerName: "George"}, b: volunteer{VolunteerName: "Gorge"}, want: "similar name"},
+		{name: "Match two typos in a long name", a: volunteer{VolunteerName: "Bartholomew"}, b: volunteer{VolunteerName: "Bartolomeu"}, want: "similar name"},
+		{name: "Match the same email", a: volunteer{VolunteerName: "Robert", Email: "bob@example.com"}, b: volunteer{VolunteerName: "Bob", Email: "Bob@Example.com"}, want: "same email"},
+		{name: "Match the same phone", a: volunteer{VolunteerName: "Robert", Phone: "555-0100"}, b: volunteer{VolunteerName: "Bob", Phone: "(555) 0100"}, want: "same phone"},
+		{name: "Ignore short names a typo apart", a: volunteer{VolunteerName: "Bob"}, b: volunteer{VolunteerName: "Rob"}},
+		{name: "Ignore different names", a: volunteer{VolunteerName: "Lance"}, b: volunteer{VolunteerName: "Larry"}},
+		{name: "Ignore empty emails", a: volunteer{VolunteerName: "Jack"}, b: volunteer{VolunteerName: "Bill"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checkResults(t, duplicateReason(tt.a, tt.b), tt.want, "", nil)
+		})
+	}
+}
+
+func TestFindDuplicateVolunteers(t *testing.T) {
+	env, tearDownEnvironment := setUpEnvironment(t)
+	defer tearDownEnvironment(t)
+	err := env.sample.CreateVolunteers(env.loggedInUser, append(slices.Clone(sampleVolunteers), volunteer{VolunteerName: "Timmy"}))
+	if err != nil {
+		t.Errorf("Error setting up test (CreateVolunteers failed): %v", err)
+		t.FailNow()
+	}
+	ans, err := env.sample.FindDuplicateVolunteers(env.loggedInUser)
+	checkResults(t, len(ans), 1, 0, err)
+	if len(ans) == 1 {
+		checkResultsSlice(t, []string{ans[0].Volunteer.VolunteerName, ans[0].Duplicate.VolunteerName, ans[0].Reason}, []string{"Tim", "Timmy", "similar name"}, nil, nil)
+	}
+}
+
+func TestMergeVolunteers(t *testing.T) {
+	env, tearDownEnvironment := setUpEnvironment(t)
+	defer tearDownEnvironment(t)
+	setUpSampleData(t, env)
+	// Timmy is on test1 like Tim, unavailable on a date Tim is unavailable on too, and alone on test3
+	err := env.sample.CreateVolunteers(env.loggedInUser, []volunteer{{VolunteerName: "Timmy", Email: "tim@example.com", PreferredContact: "email"}})
+	if err != nil {
+		t.Errorf("Error setting up test (CreateVolunteers failed): %v", err)
+		t.FailNow()
+	}
+	err = env.sample.CreateVFS(env.loggedInUser, []volunteerForSchedule{{Schedule: 2, Volunteer: 8}, {Schedule: 4, Volunteer: 8}})
+	if err != nil {
+		t.Errorf("Error setting up test (CreateVFS failed): %v", err)
+		t.FailNow()
+	}
+	err = env.sample.CreateUFS(env.loggedInUser, []unavailabilityForSchedule{{VolunteerForSchedule: 12, Date: 379}, {VolunteerForSchedule: 12, Date: 386}})
+	if err != nil {
+		t.Errorf("Error setting up test (CreateUFS failed): %v", err)
+		t.FailNow()
+	}
+	err = env.sample.CreateCompletedSchedules(env.loggedInUser, []completedSchedule{{ScheduleData: `[{"Date":372,"Volunteers":[1,8]},{"Date":379,"Volunteers":[8,2]}]`, Schedule: 2}})
+	if err != nil {
+		t.Errorf("Error setting up test (CreateCompletedSchedules failed): %v", err)
+		t.FailNow()
+	}
+	group, err := env.sample.CreateVolunteerGroup(env.loggedInUser, "Youth")
+	if err == nil {
+		err = env.sample.AddGroupVolunteers(env.loggedInUser, group.GroupID, []volunteer{{VolunteerName: "Timmy"}})
+	}
+	if err != nil {
+		t.Errorf("Error setting up test (AddGroupVolunteers failed): %v", err)
+		t.FailNow()
+	}
+	t.Run("Fail by merging a volunteer into itself", func(t *testing.T) {
+		_, err := env.sample.MergeVolunteers(env.loggedInUser, 8, 8)
+		if err == nil {
+			t.Errorf("MergeVolunteers merged a volunteer into itself")
+		}
+	})
+	t.Run("Fail by merging a volunteer of another user", func(t *testing.T) {
+		_, err := env.sample.MergeVolunteers("Nobody", 1, 8)
+		if err == nil {
+			t.Errorf("MergeVolunteers merged the volunteers of another user")
+		}
+	})
+	t.Run("Merge a duplicate", func(t *testing.T) {
+		ans, err := env.sample.MergeVolunteers(env.loggedInUser, 1, 8)
+		want := mergeReport{
+			Survivor:                  volunteer{VolunteerID: 1, VolunteerName: "Tim", User: env.loggedInUser, Email: "tim@example.com", PreferredContact: "email"},
+			VFSMoved:                  1,
+			VFSMerged:                 1,
+			UFSMoved:                  1,
+			UFSDropped:                1,
+			CompletedSchedulesUpdated: 1,
+		}
+		checkResults(t, ans, want, mergeReport{}, err)
+		tim, err := env.sample.RequestVolunteer(env.loggedInUser, volunteer{VolunteerID: 1})
+		checkResults(t, tim, want.Survivor, volunteer{}, err)
+		timmy, err := env.sample.RequestVolunteers(env.loggedInUser, []volunteer{{VolunteerName: "Timmy"}})
+		checkResults(t, len(timmy), 0, 0, err)
+	})
+	t.Run("Move the rows of the duplicate", func(t *testing.T) {
+		test1VFS, err := env.sample.RequestVFS(env.loggedInUser, []volunteerForSchedule{{Schedule: 2, Volunteer: 1}})
+		checkResults(t, len(test1VFS), 1, 0, err)
+		test3VFS, err := env.sample.RequestVFS(env.loggedInUser, []volunteerForSchedule{{Schedule: 4, Volunteer: 1}})
+		checkResultsSlice(t, test3VFS, []volunteerForSchedule{{VFSID: 13, User: env.loggedInUser, Schedule: 4, Volunteer: 1}}, nil, err)
+		ufs, err := env.sample.RequestUFS(env.loggedInUser, []unavailabilityForSchedule{{VolunteerForSchedule: 1}})
+		var dates []int
+		for _, val := range ufs {
+			dates = append(dates, val.Date)
+		}
+		checkResultsSlice(t, dates, []int{379, 386}, nil, err)
+	})
+	t.Run("Move the group memberships of the duplicate", func(t *testing.T) {
+		members, err := env.sample.RequestGroupVolunteers(env.loggedInUser, group.GroupID, []volunteer{})
+		checkResults(t, len(members), 1, 0, err)
+		if len(members) == 1 {
+			checkResults(t, members[0].VolunteerID, 1, 0, nil)
+		}
+	})
+	t.Run("Replace the duplicate in completed schedules", func(t *testing.T) {
+		ans, err := env.sample.RequestCompletedSchedules(env.loggedInUser, []completedSchedule{{CScheduleID: 1}})
+		checkResults(t, len(ans), 1, 0, err)
+		if len(ans) == 1 {
+			checkResults(t, ans[0].ScheduleData, `[{"Date":372,"Volunteers":[1]},{"Date":379,"Volunteers":[1,2]}]`, "", nil)
+		}
+	})
+}
@@ -3,3 +3,8 @@ module SampleDatabase
 go 1.23.1
 
 require github.com/mattn/go-sqlite3 v1.14.23
+
+require (
+	golang.org/x/crypto v0.36.0
+	golang.org/x/sys v0.31.0 // indirect
+)
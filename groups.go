@@ -0,0 +1,245 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// A named group of the volunteers of one user, for example "Sunday team", see EnrollVolunteerGroup.
+type volunteerGroup struct {
+	GroupID   int
+	GroupName string
+	User      string
+}
+
+// What EnrollVolunteerGroup did with each member of the group, by VolunteerName.
+type enrollReport struct {
+	Enrolled        []string
+	AlreadyEnrolled []string
+	Inactive        []string // members that were skipped because inactive volunteers can't be added to schedules
+	NotInPool       []string // members that were skipped because the owner of a shared schedule can't draw from them, see volunteerPool
+}
+
+// Creates a group named groupName for currentUser. Group names are unique per user.
+func (sm SampleModel) CreateVolunteerGroup(currentUser string, groupName string) (volunteerGroup, error) {
+	if len(strings.TrimSpace(groupName)) == 0 {
+		return volunteerGroup{}, errors.New("error in CreateVolunteerGroup: method failed because groupName is empty")
+	}
+	existing, err := sm.RequestVolunteerGroups(currentUser)
+	if err != nil {
+		return volunteerGroup{}, fmt.Errorf("error in CreateVolunteerGroup: %w", err)
+	}
+	for _, val := range existing {
+		if val.GroupName == groupName {
+			return volunteerGroup{}, fmt.Errorf("error in CreateVolunteerGroup: method failed because group `%s` already exists in the database", groupName)
+		}
+	}
+	res, err := sm.execAudited(currentUser, `insert into VolunteerGroups (GroupName, User) values (?, ?)`, groupName, currentUser)
+	if err != nil {
+		return volunteerGroup{}, fmt.Errorf("error in CreateVolunteerGroup: %w", err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return volunteerGroup{}, fmt.Errorf("error in CreateVolunteerGroup: sql.Result.LastInsertId error: %w", err)
+	}
+	return volunteerGroup{GroupID: int(id), GroupName: groupName, User: currentUser}, nil
+}
+
+// Lists the groups of currentUser, ordered by GroupID.
+func (sm SampleModel) RequestVolunteerGroups(currentUser string) ([]volunteerGroup, error) {
+	rows, err := sm.DB.Query(`select GroupID, GroupName, User from VolunteerGroups where User = ? order by GroupID`, currentUser)
+	if err != nil {
+		return []volunteerGroup{}, fmt.Errorf("error in RequestVolunteerGroups: sql.DB.Query error: %w", err)
+	}
+	defer rows.Close()
+	result := []volunteerGroup{}
+	for rows.Next() {
+		var group volunteerGroup
+		err = rows.Scan(&group.GroupID, &group.GroupName, &group.User)
+		if err != nil {
+			return []volunteerGroup{}, fmt.Errorf("error in RequestVolunteerGroups: sql.Rows.Scan error: %w", err)
+		}
+		result = append(result, group)
+	}
+	err = rows.Err()
+	if err != nil {
+		return []volunteerGroup{}, fmt.Errorf("error in RequestVolunteerGroups: sql.Rows.Err error: %w", err)
+	}
+	return result, nil
+}
+
+// Returns the group of currentUser that matches the GroupID or, if that is 0, the GroupName of group.
+func (sm SampleModel) RequestVolunteerGroup(currentUser string, group volunteerGroup) (volunteerGroup, error) {
+	groups, err := sm.RequestVolunteerGroups(currentUser)
+	if err != nil {
+		return volunteerGroup{}, fmt.Errorf("error in RequestVolunteerGroup: %w", err)
+	}
+	for _, val := range groups {
+		if val.GroupID == group.GroupID || (group.GroupID == 0 && val.GroupName == group.GroupName) {
+			return val, nil
+		}
+	}
+	return volunteerGroup{}, fmt.Errorf("error in RequestVolunteerGroup: method failed to locate group %+v among the groups of user `%s`", group, currentUser)
+}
+
+// Renames the group with groupID to groupName.
+func (sm SampleModel) RenameVolunteerGroup(currentUser string, groupID int, groupName string) error {
+	if len(strings.TrimSpace(groupName)) == 0 {
+		return errors.New("error in RenameVolunteerGroup: method failed because groupName is empty")
+	}
+	_, err := sm.RequestVolunteerGroup(currentUser, volunteerGroup{GroupID: groupID})
+	if err != nil {
+		return fmt.Errorf("error in RenameVolunteerGroup: %w", err)
+	}
+	existing, err := sm.RequestVolunteerGroup(currentUser, volunteerGroup{GroupName: groupName})
+	if err == nil && existing.GroupID != groupID {
+		return fmt.Errorf("error in RenameVolunteerGroup: method failed because group `%s` already exists in the database", groupName)
+	}
+	_, err = sm.execAudited(currentUser, `update VolunteerGroups set GroupName = ? where GroupID = ? and User = ?`, groupName, groupID, currentUser)
+	if err != nil {
+		return fmt.Errorf("error in RenameVolunteerGroup: %w", err)
+	}
+	return nil
+}
+
+// Deletes the group with groupID. Its volunteers and the schedules they were enrolled in are left alone.
+func (sm SampleModel) DeleteVolunteerGroup(currentUser string, groupID int) error {
+	_, err := sm.RequestVolunteerGroup(currentUser, volunteerGroup{GroupID: groupID})
+	if err != nil {
+		return fmt.Errorf("error in DeleteVolunteerGroup: %w", err)
+	}
+	_, err = sm.execAudited(currentUser, `delete from VolunteerGroups where GroupID = ? and User = ?`, groupID, currentUser)
+	if err != nil {
+		return fmt.Errorf("error in DeleteVolunteerGroup: %w", err)
+	}
+	return nil
+}
+
+// Returns the volunteers in the group with groupID, including inactive ones, that also match any of volunteers, or all of them if volunteers is empty.
+func (sm SampleModel) RequestGroupVolunteers(currentUser string, groupID int, volunteers []volunteer) ([]volunteer, error) {
+	_, err := sm.RequestVolunteerGroup(currentUser, volunteerGroup{GroupID: groupID})
+	if err != nil {
+		return []volunteer{}, fmt.Errorf("error in RequestGroupVolunteers: %w", err)
+	}
+	return sm.requestVolunteersWhere("RequestGroupVolunteers", fmt.Sprintf(`VolunteerID in (select Volunteer from VolunteerGroupMembers where VolunteerGroup = %d)`, groupID), volunteers)
+}
+
+// Adds volunteers created by currentUser to the group with groupID. Volunteers already in the group are skipped.
+func (sm SampleModel) AddGroupVolunteers(currentUser string, groupID int, volunteers []volunteer) error {
+	_, err := sm.RequestVolunteerGroup(currentUser, volunteerGroup{GroupID: groupID})
+	if err != nil {
+		return fmt.Errorf("error in AddGroupVolunteers: %w", err)
+	}
+	var toAdd []volunteer
+	for _, val := range volunteers {
+		volunteerStruct, err := sm.RequestVolunteer(currentUser, val)
+		if err != nil {
+			return fmt.Errorf("error in AddGroupVolunteers: %w", err)
+		}
+		toAdd = append(toAdd, volunteerStruct)
+	}
+	tx, err := sm.beginAudited(currentUser)
+	if err != nil {
+		return fmt.Errorf("error in AddGroupVolunteers: %w", err)
+	}
+	defer tx.Rollback()
+	for _, val := range toAdd {
+		_, err = tx.Exec(`insert into VolunteerGroupMembers (VolunteerGroup, Volunteer) values (?, ?) on conflict (VolunteerGroup, Volunteer) do nothing`, groupID, val.VolunteerID)
+		if err != nil {
+			return fmt.Errorf("error in AddGroupVolunteers: sql.Tx.Exec error: %w", err)
+		}
+	}
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("error in AddGroupVolunteers: sql.Tx.Commit error: %w", err)
+	}
+	return nil
+}
+
+// Takes volunteers out of the group with groupID. It fails if one of them is not in the group.
+func (sm SampleModel) RemoveGroupVolunteers(currentUser string, groupID int, volunteers []volunteer) error {
+	var toRemove []volunteer
+	for _, val := range volunteers {
+		members, err := sm.RequestGroupVolunteers(currentUser, groupID, []volunteer{val})
+		if err != nil {
+			return fmt.Errorf("error in RemoveGroupVolunteers: %w", err)
+		}
+		if len(members) != 1 {
+			return fmt.Errorf("error in RemoveGroupVolunteers: method failed to locate volunteer %+v in group %d", val, groupID)
+		}
+		toRemove = append(toRemove, members[0])
+	}
+	tx, err := sm.beginAudited(currentUser)
+	if err != nil {
+		return fmt.Errorf("error in RemoveGroupVolunteers: %w", err)
+	}
+	defer tx.Rollback()
+	for _, val := range toRemove {
+		_, err = tx.Exec(`delete from VolunteerGroupMembers where VolunteerGroup = ? and Volunteer = ?`, groupID, val.VolunteerID)
+		if err != nil {
+			return fmt.Errorf("error in RemoveGroupVolunteers: sql.Tx.Exec error: %w", err)
+		}
+	}
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("error in RemoveGroupVolunteers: sql.Tx.Commit error: %w", err)
+	}
+	return nil
+}
+
+// Creates a VFS row on the schedule with scheduleID for every active member of the group with groupID, in one transaction. Members already on the schedule, inactive members, and members outside the pool of the schedule's owner are skipped and listed in the report. currentUser needs the editor role on the schedule, like for CreateVFS.
+func (sm SampleModel) EnrollVolunteerGroup(currentUser string, groupID int, scheduleID int) (enrollReport, error) {
+	scheduleStruct, err := sm.authorizeSchedule(currentUser, scheduleID, ScheduleEditor)
+	if err != nil {
+		return enrollReport{}, fmt.Errorf("error in EnrollVolunteerGroup: %w", err)
+	}
+	members, err := sm.RequestGroupVolunteers(currentUser, groupID, []volunteer{})
+	if err != nil {
+		return enrollReport{}, fmt.Errorf("error in EnrollVolunteerGroup: %w", err)
+	}
+	enrolled, err := sm.RequestVFS(currentUser, []volunteerForSchedule{{Schedule: scheduleID}})
+	if err != nil {
+		return enrollReport{}, fmt.Errorf("error in EnrollVolunteerGroup: %w", err)
+	}
+	onSchedule := map[int]bool{}
+	for _, val := range enrolled {
+		onSchedule[val.Volunteer] = true
+	}
+	// The group holds volunteers of currentUser, but a shared schedule resolves its VFS rows through its owner's pool, see requestScheduleState
+	pool, err := sm.RequestPoolVolunteers(scheduleStruct.User, []volunteer{})
+	if err != nil {
+		return enrollReport{}, fmt.Errorf("error in EnrollVolunteerGroup: %w", err)
+	}
+	inPool := map[int]bool{}
+	for _, val := range pool {
+		inPool[val.VolunteerID] = true
+	}
+	report := enrollReport{Enrolled: []string{}, AlreadyEnrolled: []string{}, Inactive: []string{}, NotInPool: []string{}}
+	tx, err := sm.beginAudited(currentUser)
+	if err != nil {
+		return enrollReport{}, fmt.Errorf("error in EnrollVolunteerGroup: %w", err)
+	}
+	defer tx.Rollback()
+	for _, val := range members {
+		switch {
+		case onSchedule[val.VolunteerID]:
+			report.AlreadyEnrolled = append(report.AlreadyEnrolled, val.VolunteerName)
+		case !inPool[val.VolunteerID]:
+			report.NotInPool = append(report.NotInPool, val.VolunteerName)
+		case val.Inactive:
+			report.Inactive = append(report.Inactive, val.VolunteerName)
+		default:
+			_, err = tx.Exec(`insert into VolunteersForSchedule (User, Schedule, Volunteer) values (?, ?, ?)`, scheduleStruct.User, scheduleID, val.VolunteerID)
+			if err != nil {
+				return enrollReport{}, fmt.Errorf("error in EnrollVolunteerGroup: sql.Tx.Exec error: %w. Value of val is `%+v`", err, val)
+			}
+			report.Enrolled = append(report.Enrolled, val.VolunteerName)
+		}
+	}
+	err = tx.Commit()
+	if err != nil {
+		return enrollReport{}, fmt.Errorf("error in EnrollVolunteerGroup: sql.Tx.Commit error: %w", err)
+	}
+	return report, nil
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"testing"
+)
+
+func checkEnrollReport(t *testing.T, ans enrollReport, want enrollReport, err error) {
+	t.Helper()
+	checkResultsSlice(t, ans.Enrolled, want.Enrolled, nil, err)
+	checkResultsSlice(t, ans.AlreadyEnrolled, want.AlreadyEnrolled, nil, err)
+	checkResultsSlice(t, ans.Inactive, want.Inactive, nil, err)
+	checkResultsSlice(t, ans.NotInPool, want.NotInPool, nil, err)
+}
+
+func TestVolunteerGroups(t *testing.T) {
+	env, tearDownEnvironment := setUpEnvironment(t)
+	defer tearDownEnvironment(t)
+	setUpSampleData(t, env)
+	var group volunteerGroup
+	t.Run("Create a group", func(t *testing.T) {
+		var err error
+		group, err = env.sample.CreateVolunteerGroup(env.loggedInUser, "Sunday team")
+		checkResults(t, group, volunteerGroup{GroupID: 1, GroupName: "Sunday team", User: env.loggedInUser}, volunteerGroup{}, err)
+	})
+	t.Run("Fail by creating a group twice", func(t *testing.T) {
+		_, err := env.sample.CreateVolunteerGroup(env.loggedInUser, "Sunday team")
+		if err == nil {
+			t.Errorf("CreateVolunteerGroup created a group that already exists")
+		}
+	})
+	t.Run("Fail by renaming a group to an empty name", func(t *testing.T) {
+		err := env.sample.RenameVolunteerGroup(env.loggedInUser, group.GroupID, " ")
+		if err == nil {
+			t.Errorf("RenameVolunteerGroup accepted an empty name")
+		}
+	})
+	t.Run("Rename a group", func(t *testing.T) {
+		err := env.sample.RenameVolunteerGroup(env.loggedInUser, group.GroupID, "Youth")
+		ans, err2 := env.sample.RequestVolunteerGroups(env.loggedInUser)
+		if err == nil {
+			err = err2
+		}
+		checkResultsSlice(t, ans, []volunteerGroup{{GroupID: 1, GroupName: "Youth", User: env.loggedInUser}}, nil, err)
+	})
+	t.Run("Fail by finding the group of another user", func(t *testing.T) {
+		_, err := env.sample.RequestVolunteerGroup("Nobody", volunteerGroup{GroupID: group.GroupID})
+		if err == nil {
+			t.Errorf("RequestVolunteerGroup found the group of another user")
+		}
+	})
+	t.Run("Add volunteers, skipping members", func(t *testing.T) {
+		err := env.sample.AddGroupVolunteers(env.loggedInUser, group.GroupID, []volunteer{{VolunteerName: "Tim"}, {VolunteerName: "Bob"}})
+		if err == nil {
+			err = env.sample.AddGroupVolunteers(env.loggedInUser, group.GroupID, []volunteer{{VolunteerName: "Bob"}, {VolunteerName: "Lance"}})
+		}
+		ans, err2 := env.sample.RequestGroupVolunteers(env.loggedInUser, group.GroupID, []volunteer{})
+		if err == nil {
+			err = err2
+		}
+		var names []string
+		for _, val := range ans {
+			names = append(names, val.VolunteerName)
+		}
+		checkResultsSlice(t, names, []string{"Tim", "Bob", "Lance"}, nil, err)
+	})
+	t.Run("Fail by removing a volunteer who isn't a member", func(t *testing.T) {
+		err := env.sample.RemoveGroupVolunteers(env.loggedInUser, group.GroupID, []volunteer{{VolunteerName: "Jack"}})
+		if err == nil {
+			t.Errorf("RemoveGroupVolunteers removed a volunteer who isn't a member")
+		}
+	})
+	t.Run("Enroll a group, skipping enrolled and inactive members", func(t *testing.T) {
+		err := env.sample.DeleteVolunteers(env.loggedInUser, []volunteer{{VolunteerName: "Lance"}})
+		if err != nil {
+			t.Errorf("Error setting up test (DeleteVolunteers failed): %v", err)
+			t.FailNow()
+		}
+		// Tim is on test1 already, Bob isn't
+		ans, err := env.sample.EnrollVolunteerGroup(env.loggedInUser, group.GroupID, 2)
+		checkEnrollReport(t, ans, enrollReport{Enrolled: []string{"Bob"}, AlreadyEnrolled: []string{"Tim"}, Inactive: []string{"Lance"}}, err)
+		vfs, err := env.sample.RequestVFS(env.loggedInUser, []volunteerForSchedule{{Schedule: 2, Volunteer: 5}})
+		checkResults(t, len(vfs), 1, 0, err)
+		ans, err = env.sample.EnrollVolunteerGroup(env.loggedInUser, group.GroupID, 2)
+		checkEnrollReport(t, ans, enrollReport{Enrolled: []string{}, AlreadyEnrolled: []string{"Tim", "Bob"}, Inactive: []string{"Lance"}}, err)
+	})
+	t.Run("Fail by enrolling into a schedule of another user", func(t *testing.T) {
+		_, err := env.sample.EnrollVolunteerGroup("Nobody", group.GroupID, 2)
+		if err == nil {
+			t.Errorf("EnrollVolunteerGroup enrolled into a schedule of another user")
+		}
+	})
+	t.Run("Delete a group, keeping its volunteers", func(t *testing.T) {
+		err := env.sample.DeleteVolunteerGroup(env.loggedInUser, group.GroupID)
+		groups, err2 := env.sample.RequestVolunteerGroups(env.loggedInUser)
+		if err == nil {
+			err = err2
+		}
+		checkResults(t, len(groups), 0, 0, err)
+		volunteers, err := env.sample.RequestVolunteers(env.loggedInUser, []volunteer{{VolunteerName: "Tim"}})
+		checkResults(t, len(volunteers), 1, 0, err)
+	})
+}
+
+func TestEnrollVolunteerGroupOnSharedSchedule(t *testing.T) {
+	env, tearDownEnvironment := setUpEnvironment(t)
+	defer tearDownEnvironment(t)
+	setUpSampleData(t, env)
+	// Ann may edit test1 of Seth, and her group holds Ola, whom she created
+	err := env.sample.CreateUser(user{UserName: "Ann"})
+	if err == nil {
+		err = env.sample.ShareSchedule(env.loggedInUser, 2, "Ann", ScheduleEditor)
+	}
+	if err == nil {
+		err = env.sample.CreateVolunteers("Ann", []volunteer{{VolunteerName: "Ola"}})
+	}
+	var group volunteerGroup
+	if err == nil {
+		group, err = env.sample.CreateVolunteerGroup("Ann", "Youth")
+	}
+	if err == nil {
+		err = env.sample.AddGroupVolunteers("Ann", group.GroupID, []volunteer{{VolunteerName: "Ola"}})
+	}
+	if err != nil {
+		t.Errorf("Error setting up test: %v", err)
+		t.FailNow()
+	}
+	t.Run("Skip members outside the pool of the owner", func(t *testing.T) {
+		ans, err := env.sample.EnrollVolunteerGroup("Ann", group.GroupID, 2)
+		checkEnrollReport(t, ans, enrollReport{NotInPool: []string{"Ola"}}, err)
+		vfs, err := env.sample.RequestVFS(env.loggedInUser, []volunteerForSchedule{{Schedule: 2}})
+		checkResults(t, len(vfs), 4, 0, err)
+	})
+	t.Run("Enroll members in the pool of an organization of the owner", func(t *testing.T) {
+		crew, err := env.sample.CreateOrganization(env.loggedInUser, "Crew")
+		if err == nil {
+			err = env.sample.AddOrganizationMember(env.loggedInUser, crew.OrganizationID, "Ann")
+		}
+		if err == nil {
+			err = env.sample.AddOrganizationVolunteers("Ann", crew.OrganizationID, []volunteer{{VolunteerName: "Ola"}})
+		}
+		if err != nil {
+			t.Errorf("Error setting up test: %v", err)
+			t.FailNow()
+		}
+		ans, err := env.sample.EnrollVolunteerGroup("Ann", group.GroupID, 2)
+		checkEnrollReport(t, ans, enrollReport{Enrolled: []string{"Ola"}}, err)
+		data, err := env.sample.FetchAndSendData(env.loggedInUser, "test1")
+		checkResults(t, len(data.VolunteerAvailabilityData), 5, 0, err)
+	})
+}
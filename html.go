@@ -0,0 +1,175 @@
+package main
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"time"
+)
+
+type htmlRosterPage struct {
+	Title     string
+	Range     string
+	Highlight string // VolunteerName whose shifts are highlighted, if any
+	Shifts    int    // number of shifts of Highlight
+	Weekdays  []string
+	Months    []htmlRosterMonth
+}
+
+type htmlRosterMonth struct {
+	Name  string
+	Year  int
+	Weeks [][]htmlRosterDay
+}
+
+type htmlRosterDay struct {
+	Day         int // 0 for the cells before the first and after the last day of the month
+	InSchedule  bool
+	Volunteers  []htmlRosterVolunteer
+	Highlighted bool
+}
+
+type htmlRosterVolunteer struct {
+	Name        string
+	Highlighted bool
+}
+
+var htmlRosterTemplate = template.Must(template.New("roster").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}}</title>
+<style>
+body { font-family: sans-serif; margin: 1.5em; color: #222; }
+h1 { margin-bottom: 0.2em; }
+p.range { margin-top: 0; color: #555; }
+section.month { margin-bottom: 2em; }
+table { border-collapse: collapse; width: 100%; table-layout: fixed; }
+th, td { border: 1px solid #999; padding: 0.3em; vertical-align: top; }
+th { background: #eee; }
+td { height: 5em; }
+td.outside { background: #f6f6f6; color: #aaa; }
+td.blank { border: none; }
+span.day { display: block; font-weight: bold; }
+ul { list-style: none; margin: 0; padding: 0; }
+td.highlighted { background: #fff3b0; }
+li.highlighted { font-weight: bold; text-decoration: underline; }
+@media print {
+	body { margin: 0; font-size: 10pt; }
+	section.month { break-inside: avoid; page-break-inside: avoid; }
+	td.highlighted { background: none; outline: 2pt solid #000; outline-offset: -2pt; }
+	td.outside { background: none; }
+}
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<p class="range">{{.Range}}{{if .Highlight}} &middot; {{.Highlight}}: {{.Shifts}} {{if eq .Shifts 1}}shift{{else}}shifts{{end}}{{end}}</p>
+{{- range .Months}}
+<section class="month">
+<h2>{{.Name}} {{.Year}}</h2>
+<table>
+<thead><tr>{{range $.Weekdays}}<th>{{.}}</th>{{end}}</tr></thead>
+<tbody>
+{{- range .Weeks}}
+<tr>{{range .}}{{if eq .Day 0}}<td class="blank"></td>{{else}}<td class="{{if not .InSchedule}}outside {{end}}{{if .Highlighted}}highlighted{{end}}"><span class="day">{{.Day}}</span>{{if .Volunteers}}<ul>{{range .Volunteers}}<li{{if .Highlighted}} class="highlighted"{{end}}>{{.Name}}</li>{{end}}</ul>{{end}}</td>{{end}}{{end}}</tr>
+{{- end}}
+</tbody>
+</table>
+</section>
+{{- end}}
+</body>
+</html>
+`))
+
+// Lists the MonthNames ordered by MonthID and the WeekdayNames ordered by WeekdayID (Sunday first).
+func (sm SampleModel) requestCalendarNames() ([]string, []string, error) {
+	var names [2][]string
+	for i, query := range []string{`select MonthName from Months order by MonthID`, `select WeekdayName from Weekdays order by WeekdayID`} {
+		rows, err := sm.DB.Query(query)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error in requestCalendarNames: sql.DB.Query error: %w. Value of query is `%s`", err, query)
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var name string
+			err = rows.Scan(&name)
+			if err != nil {
+				return nil, nil, fmt.Errorf("error in requestCalendarNames: sql.Rows.Scan error: %w", err)
+			}
+			names[i] = append(names[i], name)
+		}
+		err = rows.Err()
+		if err != nil {
+			return nil, nil, fmt.Errorf("error in requestCalendarNames: sql.Rows.Err error: %w", err)
+		}
+	}
+	if len(names[0]) != 12 || len(names[1]) != 7 {
+		return nil, nil, fmt.Errorf("error in requestCalendarNames: method failed because Months has %d rows and Weekdays has %d rows", len(names[0]), len(names[1]))
+	}
+	return names[0], names[1], nil
+}
+
+// Writes the completed schedule with cScheduleID as a single HTML page with one calendar grid per month of the schedule and a print stylesheet. All styles are inline, so the file can be emailed or printed as is.
+// If highlight is a VolunteerName, that volunteer's shifts are highlighted.
+func (sm SampleModel) ExportRosterHTML(currentUser string, cScheduleID int, highlight string, w io.Writer) error {
+	rosterStruct, err := sm.RequestRoster(currentUser, cScheduleID)
+	if err != nil {
+		return fmt.Errorf("error in ExportRosterHTML: %w", err)
+	}
+	monthNames, weekdayNames, err := sm.requestCalendarNames()
+	if err != nil {
+		return fmt.Errorf("error in ExportRosterHTML: %w", err)
+	}
+	bounds, err := sm.RequestDates([]date{{DateID: rosterStruct.Schedule.StartDate}, {DateID: rosterStruct.Schedule.EndDate}})
+	if err != nil {
+		return fmt.Errorf("error in ExportRosterHTML: %w", err)
+	}
+	toTime := func(dateStruct date) time.Time {
+		return time.Date(dateStruct.Year, time.Month(dateStruct.Month), dateStruct.Day, 0, 0, 0, 0, time.UTC)
+	}
+	first, last := toTime(bounds[0]), toTime(bounds[len(bounds)-1])
+	if first.After(last) {
+		first, last = last, first
+	}
+	shifts := map[time.Time]resolvedShift{}
+	for _, shift := range rosterStruct.Shifts {
+		shifts[toTime(shift.Date)] = shift
+	}
+	page := htmlRosterPage{
+		Title:     rosterStruct.Schedule.ScheduleName,
+		Range:     fmt.Sprintf("%s to %s", first.Format(time.DateOnly), last.Format(time.DateOnly)),
+		Highlight: highlight,
+		Weekdays:  weekdayNames,
+	}
+	for month := time.Date(first.Year(), first.Month(), 1, 0, 0, 0, 0, time.UTC); !month.After(last); month = month.AddDate(0, 1, 0) {
+		current := htmlRosterMonth{Name: monthNames[month.Month()-1], Year: month.Year()}
+		week := make([]htmlRosterDay, int(month.Weekday()))
+		for day := month; day.Month() == month.Month(); day = day.AddDate(0, 0, 1) {
+			cell := htmlRosterDay{Day: day.Day(), InSchedule: !day.Before(first) && !day.After(last)}
+			for _, val := range shifts[day].Volunteers {
+				isHighlighted := len(highlight) > 0 && val.VolunteerName == highlight
+				cell.Volunteers = append(cell.Volunteers, htmlRosterVolunteer{Name: val.VolunteerName, Highlighted: isHighlighted})
+				if isHighlighted {
+					cell.Highlighted = true
+					page.Shifts++
+				}
+			}
+			week = append(week, cell)
+			if len(week) == 7 {
+				current.Weeks = append(current.Weeks, week)
+				week = []htmlRosterDay{}
+			}
+		}
+		if len(week) > 0 {
+			current.Weeks = append(current.Weeks, append(week, make([]htmlRosterDay, 7-len(week))...))
+		}
+		page.Months = append(page.Months, current)
+	}
+	err = htmlRosterTemplate.Execute(w, page)
+	if err != nil {
+		return fmt.Errorf("error in ExportRosterHTML: template.Template.Execute error: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExportRosterHTML(t *testing.T) {
+	env, tearDownEnvironment := setUpEnvironment(t)
+	defer tearDownEnvironment(t)
+	setUpSampleData(t, env)
+	err := env.sample.CreateCompletedSchedules(env.loggedInUser, []completedSchedule{{ScheduleData: sampleScheduleData, Schedule: 2}})
+	if err != nil {
+		t.Errorf("Error setting up test (CreateCompletedSchedules failed): %v", err)
+		t.FailNow()
+	}
+	err = env.sample.UpdateVolunteers(env.loggedInUser, []volunteer{{VolunteerID: 3, VolunteerName: "Jack <Jr>"}})
+	if err != nil {
+		t.Errorf("Error setting up test (UpdateVolunteers failed): %v", err)
+		t.FailNow()
+	}
+	tests := []struct {
+		name      string
+		highlight string
+		want      []string // substrings of the page
+		wantCount map[string]int
+		notWant   []string
+	}{
+		{name: "Export the roster", want: []string{
+			"<title>test1</title>",
+			"<p class=\"range\">2024-01-01 to 2024-03-01</p>",
+			"<th>Sunday</th><th>Monday</th><th>Tuesday</th><th>Wednesday</th><th>Thursday</th><th>Friday</th><th>Saturday</th>",
+			"<h2>January 2024</h2>", "<h2>February 2024</h2>", "<h2>March 2024</h2>",
+			// January 1st 2024 is a Monday
+			"<tr><td class=\"blank\"></td><td class=\"\"><span class=\"day\">1</span></td>",
+			"<td class=\"\"><span class=\"day\">7</span><ul><li>Tim</li><li>Bill</li><li>Jack &lt;Jr&gt;</li></ul></td>",
+			"<td class=\"outside \"><span class=\"day\">2</span></td>",
+			"@media print",
+		}, wantCount: map[string]int{"<section class=\"month\">": 3, "class=\"highlighted\"": 0}, notWant: []string{"<link", "<script", "src=", "Jack <Jr>"}},
+		{name: "Highlight the shifts of Tim", highlight: "Tim", want: []string{
+			"2024-01-01 to 2024-03-01 &middot; Tim: 2 shifts",
+			"<td class=\"highlighted\"><span class=\"day\">21</span><ul><li class=\"highlighted\">Tim</li><li>Jack &lt;Jr&gt;</li></ul></td>",
+		}, wantCount: map[string]int{"<li class=\"highlighted\">": 2, "<td class=\"highlighted\">": 2}},
+		{name: "Highlight a volunteer without shifts", highlight: "Larry", want: []string{"Larry: 0 shifts"}, wantCount: map[string]int{"class=\"highlighted\"": 0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ans strings.Builder
+			err := env.sample.ExportRosterHTML(env.loggedInUser, 1, tt.highlight, &ans)
+			if err != nil {
+				t.Errorf("got error: `%v`", err)
+			}
+			for _, val := range tt.want {
+				if !strings.Contains(ans.String(), val) {
+					t.Errorf("got a page without `%s`:\n%s", val, ans.String())
+				}
+			}
+			for val, count := range tt.wantCount {
+				if strings.Count(ans.String(), val) != count {
+					t.Errorf("got %d times `%s`, want %d", strings.Count(ans.String(), val), val, count)
+				}
+			}
+			for _, val := range tt.notWant {
+				if strings.Contains(ans.String(), val) {
+					t.Errorf("got a page with `%s`", val)
+				}
+			}
+		})
+	}
+	t.Run("Fail by providing a nonexistent CScheduleID", func(t *testing.T) {
+		err := env.sample.ExportRosterHTML(env.loggedInUser, 2, "", &strings.Builder{})
+		if err == nil {
+			t.Errorf("got no error, want an error for CScheduleID 2")
+		}
+	})
+}
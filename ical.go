@@ -0,0 +1,632 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Settings for the iCalendar (RFC 5545) feeds. With a zero ShiftLength every shift is an all-day event.
+type icalOptions struct {
+	ShiftStart  time.Duration  // start of a shift as an offset from midnight, e.g. 9*time.Hour + 30*time.Minute
+	ShiftLength time.Duration  // length of a shift
+	Location    *time.Location // time zone the shift times are in. Times are written in UTC, so no VTIMEZONE is needed. Defaults to time.UTC
+	UIDDomain   string         // right-hand side of every UID. Defaults to "sampledatabase.invalid"
+	Stamp       time.Time      // DTSTAMP of every event. Defaults to time.Now()
+}
+
+// UIDs only depend on ScheduleID, DateID, and VolunteerID, so regenerating a roster and re-importing the feed updates events instead of duplicating them.
+func icalUID(opts icalOptions, scheduleID int, dateID int, volunteerID int) string {
+	domain := opts.UIDDomain
+	if len(domain) == 0 {
+		domain = "sampledatabase.invalid"
+	}
+	if volunteerID > 0 {
+		return fmt.Sprintf("schedule%d-date%d-volunteer%d@%s", scheduleID, dateID, volunteerID, domain)
+	}
+	return fmt.Sprintf("schedule%d-date%d@%s", scheduleID, dateID, domain)
+}
+
+// Escapes a TEXT value (RFC 5545 section 3.3.11).
+func icalText(value string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`).Replace(value)
+}
+
+// Folds a content line into lines of at most 75 octets without splitting UTF-8 sequences (RFC 5545 section 3.1).
+func icalFold(line string) string {
+	var builder strings.Builder
+	width := 0
+	for _, r := range line {
+		size := len(string(r))
+		if width+size > 75 {
+			builder.WriteString("\r\n ")
+			width = 1
+		}
+		builder.WriteRune(r)
+		width += size
+	}
+	builder.WriteString("\r\n")
+	return builder.String()
+}
+
+type icalEvent struct {
+	UID         string
+	Date        date
+	Summary     string
+	Description string
+}
+
+func writeICal(w io.Writer, name string, events []icalEvent, opts icalOptions) error {
+	location := opts.Location
+	if location == nil {
+		location = time.UTC
+	}
+	stamp := opts.Stamp
+	if stamp.IsZero() {
+		stamp = time.Now()
+	}
+	const utcLayout = "20060102T150405Z"
+	lines := []string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:-//SampleDatabase//Roster//EN",
+		"CALSCALE:GREGORIAN",
+		"METHOD:PUBLISH",
+		"X-WR-CALNAME:" + icalText(name),
+		"X-WR-TIMEZONE:" + location.String(),
+	}
+	for _, event := range events {
+		lines = append(lines, "BEGIN:VEVENT", "UID:"+event.UID, "DTSTAMP:"+stamp.UTC().Format(utcLayout))
+		day := time.Date(event.Date.Year, time.Month(event.Date.Month), event.Date.Day, 0, 0, 0, 0, location)
+		if opts.ShiftLength > 0 {
+			start := day.Add(opts.ShiftStart)
+			lines = append(lines, "DTSTART:"+start.UTC().Format(utcLayout), "DTEND:"+start.Add(opts.ShiftLength).UTC().Format(utcLayout))
+		} else {
+			lines = append(lines, "DTSTART;VALUE=DATE:"+day.Format("20060102"), "DTEND;VALUE=DATE:"+day.AddDate(0, 0, 1).Format("20060102"))
+		}
+		lines = append(lines, "SUMMARY:"+icalText(event.Summary))
+		if len(event.Description) > 0 {
+			lines = append(lines, "DESCRIPTION:"+icalText(event.Description))
+		}
+		lines = append(lines, "TRANSP:OPAQUE", "END:VEVENT")
+	}
+	lines = append(lines, "END:VCALENDAR")
+	for _, line := range lines {
+		_, err := io.WriteString(w, icalFold(line))
+		if err != nil {
+			return fmt.Errorf("error in writeICal: io.WriteString error: %w", err)
+		}
+	}
+	return nil
+}
+
+func volunteerNames(volunteers []volunteer) []string {
+	result := []string{}
+	for _, val := range volunteers {
+		result = append(result, val.VolunteerName)
+	}
+	return result
+}
+
+// Writes one event per shift of the completed schedule with cScheduleID, listing every volunteer on the shift.
+func (sm SampleModel) ExportScheduleICal(currentUser string, cScheduleID int, opts icalOptions, w io.Writer) error {
+	rosterStruct, err := sm.RequestRoster(currentUser, cScheduleID)
+	if err != nil {
+		return fmt.Errorf("error in ExportScheduleICal: %w", err)
+	}
+	events := []icalEvent{}
+	for _, shift := range rosterStruct.Shifts {
+		events = append(events, icalEvent{
+			UID:         icalUID(opts, rosterStruct.Schedule.ScheduleID, shift.Date.DateID, 0),
+			Date:        shift.Date,
+			Summary:     rosterStruct.Schedule.ScheduleName,
+			Description: "Volunteers: " + strings.Join(volunteerNames(shift.Volunteers), ", "),
+		})
+	}
+	err = writeICal(w, rosterStruct.Schedule.ScheduleName, events, opts)
+	if err != nil {
+		return fmt.Errorf("error in ExportScheduleICal: %w", err)
+	}
+	return nil
+}
+
+// Writes one event per shift the volunteer with volunteerID is assigned to, across the latest completed schedule (highest CScheduleID) of every schedule.
+func (sm SampleModel) ExportVolunteerICal(currentUser string, volunteerID int, opts icalOptions, w io.Writer) error {
+	volunteerStruct, err := sm.RequestVolunteer(currentUser, volunteer{VolunteerID: volunteerID})
+	if err != nil {
+		return fmt.Errorf("error in ExportVolunteerICal: %w", err)
+	}
+	completedSchedules, err := sm.RequestCompletedSchedules(currentUser, []completedSchedule{})
+	if err != nil {
+		return fmt.Errorf("error in ExportVolunteerICal: %w", err)
+	}
+	latest := map[int]int{} // ScheduleID to CScheduleID
+	for _, val := range completedSchedules {
+		latest[val.Schedule] = max(latest[val.Schedule], val.CScheduleID)
+	}
+	var cScheduleIDs []int
+	for _, val := range latest {
+		cScheduleIDs = append(cScheduleIDs, val)
+	}
+	slices.Sort(cScheduleIDs)
+	events := []icalEvent{}
+	for _, cScheduleID := range cScheduleIDs {
+		rosterStruct, err := sm.RequestRoster(currentUser, cScheduleID)
+		if err != nil {
+			return fmt.Errorf("error in ExportVolunteerICal: %w", err)
+		}
+		for _, shift := range rosterStruct.Shifts {
+			if !slices.ContainsFunc(shift.Volunteers, func(v volunteer) bool { return v.VolunteerID == volunteerID }) {
+				continue
+			}
+			others := slices.DeleteFunc(volunteerNames(shift.Volunteers), func(name string) bool { return name == volunteerStruct.VolunteerName })
+			event := icalEvent{
+				UID:     icalUID(opts, rosterStruct.Schedule.ScheduleID, shift.Date.DateID, volunteerID),
+				Date:    shift.Date,
+				Summary: rosterStruct.Schedule.ScheduleName,
+			}
+			if len(others) > 0 {
+				event.Description = "Serving with " + strings.Join(others, ", ")
+			}
+			events = append(events, event)
+		}
+	}
+	err = writeICal(w, volunteerStruct.VolunteerName, events, opts)
+	if err != nil {
+		return fmt.Errorf("error in ExportVolunteerICal: %w", err)
+	}
+	return nil
+}
+
+type icalImportReport struct {
+	EventsRead              int
+	UnavailabilitiesCreated []string // ISO dates added to UnavailabilitiesForSchedule
+	UnavailabilitiesSkipped []string // ISO dates that were already stored
+	Warnings                []string // events or rules that were skipped or only partly understood
+}
+
+// One property of a component, e.g. `DTSTART;TZID=America/Chicago:20240107T090000`.
+type icalProperty struct {
+	Name   string
+	Params map[string]string
+	Value  string
+}
+
+type icalRecurrence struct {
+	Freq     string // DAILY, WEEKLY, MONTHLY, or YEARLY
+	Interval int
+	Count    int       // 0 if the rule has no COUNT
+	Until    time.Time // zero if the rule has no UNTIL
+	ByDay    []time.Weekday
+}
+
+// A VEVENT as far as it matters for unavailability. Start and End are in the time zone of the event.
+type icalParsedEvent struct {
+	AllDay     bool
+	Start      time.Time
+	End        time.Time
+	Recurrence *icalRecurrence
+	ExDates    []time.Time
+}
+
+// Unfolds the content lines of r (RFC 5545 section 3.1) and splits them into properties. Lines without a ":" are skipped.
+func readICalProperties(r io.Reader) ([]icalProperty, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return []icalProperty{}, fmt.Errorf("error in readICalProperties: io.ReadAll error: %w", err)
+	}
+	var lines []string
+	for _, line := range strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n") {
+		if len(lines) > 0 && (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	properties := []icalProperty{}
+	for _, line := range lines {
+		// the name and parameters end at the first ":" outside of a quoted parameter value
+		quoted, split := false, -1
+		for i, r := range line {
+			if r == '"' {
+				quoted = !quoted
+			} else if r == ':' && !quoted {
+				split = i
+				break
+			}
+		}
+		if split < 0 {
+			continue
+		}
+		parts := strings.Split(line[:split], ";")
+		property := icalProperty{Name: strings.ToUpper(parts[0]), Params: map[string]string{}, Value: line[split+1:]}
+		for _, param := range parts[1:] {
+			if key, value, ok := strings.Cut(param, "="); ok {
+				property.Params[strings.ToUpper(key)] = strings.Trim(value, `"`)
+			}
+		}
+		properties = append(properties, property)
+	}
+	return properties, nil
+}
+
+// Parses a DATE or DATE-TIME value. Times with a TZID are placed in that zone, UTC times ("Z") in UTC, and floating times in location.
+func parseICalTime(property icalProperty, value string, location *time.Location) (time.Time, bool, error) {
+	if len(value) == 8 {
+		parsed, err := time.ParseInLocation("20060102", value, location)
+		return parsed, true, err
+	}
+	if strings.HasSuffix(value, "Z") {
+		parsed, err := time.Parse("20060102T150405Z", value)
+		return parsed, false, err
+	}
+	if tzid, ok := property.Params["TZID"]; ok {
+		zone, err := time.LoadLocation(tzid)
+		if err != nil {
+			return time.Time{}, false, fmt.Errorf("unknown TZID `%s`", tzid)
+		}
+		location = zone
+	}
+	parsed, err := time.ParseInLocation("20060102T150405", value, location)
+	return parsed, false, err
+}
+
+// Parses a DURATION value such as P1D, PT1H30M, or P1W.
+func parseICalDuration(value string) (time.Duration, error) {
+	rest, negative := strings.CutPrefix(strings.TrimPrefix(value, "+"), "-")
+	rest, ok := strings.CutPrefix(rest, "P")
+	if !ok || len(rest) == 0 {
+		return 0, fmt.Errorf("`%s` is not a DURATION", value)
+	}
+	units := map[rune]time.Duration{'W': 7 * 24 * time.Hour, 'D': 24 * time.Hour, 'H': time.Hour, 'M': time.Minute, 'S': time.Second}
+	var result time.Duration
+	number, inTime := 0, false
+	for _, r := range rest {
+		switch {
+		case r >= '0' && r <= '9':
+			number = number*10 + int(r-'0')
+		case r == 'T':
+			inTime = true
+		case r == 'M' && !inTime: // months are not allowed in a DURATION
+			return 0, fmt.Errorf("`%s` is not a DURATION", value)
+		case units[r] > 0:
+			result += time.Duration(number) * units[r]
+			number = 0
+		default:
+			return 0, fmt.Errorf("`%s` is not a DURATION", value)
+		}
+	}
+	if negative {
+		result = -result
+	}
+	return result, nil
+}
+
+var icalWeekdays = map[string]time.Weekday{"SU": time.Sunday, "MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday, "TH": time.Thursday, "FR": time.Friday, "SA": time.Saturday}
+
+// Parses the subset of RRULE that rosters need: FREQ, INTERVAL, COUNT, UNTIL, and plain BYDAY values with FREQ=WEEKLY.
+func parseICalRecurrence(property icalProperty, location *time.Location) (*icalRecurrence, error) {
+	rule := icalRecurrence{Interval: 1}
+	for _, part := range strings.Split(property.Value, ";") {
+		key, value, _ := strings.Cut(part, "=")
+		var err error
+		switch strings.ToUpper(key) {
+		case "FREQ":
+			rule.Freq = strings.ToUpper(value)
+			if !slices.Contains([]string{"DAILY", "WEEKLY", "MONTHLY", "YEARLY"}, rule.Freq) {
+				return nil, fmt.Errorf("FREQ=%s is not supported", value)
+			}
+		case "INTERVAL":
+			_, err = fmt.Sscanf(value, "%d", &rule.Interval)
+			if err == nil && rule.Interval < 1 {
+				err = fmt.Errorf("INTERVAL=%s is not positive", value)
+			}
+		case "COUNT":
+			_, err = fmt.Sscanf(value, "%d", &rule.Count)
+		case "UNTIL":
+			rule.Until, _, err = parseICalTime(property, value, location)
+			if err == nil && len(value) == 8 { // an UNTIL date includes the whole day
+				rule.Until = rule.Until.AddDate(0, 0, 1).Add(-time.Nanosecond)
+			}
+		case "BYDAY":
+			for _, day := range strings.Split(strings.ToUpper(value), ",") {
+				weekdayValue, ok := icalWeekdays[day]
+				if !ok {
+					return nil, fmt.Errorf("BYDAY=%s is not supported", value)
+				}
+				rule.ByDay = append(rule.ByDay, weekdayValue)
+			}
+		case "WKST":
+		default:
+			return nil, fmt.Errorf("%s is not supported", part)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("`%s` could not be parsed: %w", part, err)
+		}
+	}
+	if len(rule.Freq) == 0 {
+		return nil, errors.New("RRULE has no FREQ")
+	}
+	if len(rule.ByDay) > 0 && rule.Freq != "WEEKLY" {
+		return nil, fmt.Errorf("BYDAY is only supported with FREQ=WEEKLY")
+	}
+	return &rule, nil
+}
+
+// Lists the start times of every occurrence of event that starts no later than limit. EXDATEs are removed.
+func (event icalParsedEvent) occurrences(limit time.Time) []time.Time {
+	result := []time.Time{}
+	rule := event.Recurrence
+	if rule == nil {
+		rule = &icalRecurrence{Freq: "DAILY", Interval: 1, Count: 1}
+	}
+	emitted := 0
+	add := func(start time.Time) bool { // reports whether expansion should continue
+		if start.After(limit) || (!rule.Until.IsZero() && start.After(rule.Until)) || (rule.Count > 0 && emitted >= rule.Count) {
+			return false
+		}
+		emitted++
+		if !slices.ContainsFunc(event.ExDates, start.Equal) {
+			result = append(result, start)
+		}
+		return true
+	}
+	switch {
+	case rule.Freq == "WEEKLY" && len(rule.ByDay) > 0:
+		// weeks start on Monday (the default WKST)
+		weekStart := event.Start.AddDate(0, 0, -((int(event.Start.Weekday()) + 6) % 7))
+		for week := 0; ; week++ {
+			current := weekStart.AddDate(0, 0, 7*week*rule.Interval)
+			if current.After(limit) {
+				return result
+			}
+			for offset := 0; offset < 7; offset++ {
+				start := current.AddDate(0, 0, offset)
+				if start.Before(event.Start) || !slices.Contains(rule.ByDay, start.Weekday()) {
+					continue
+				}
+				if !add(start) {
+					return result
+				}
+			}
+		}
+	case rule.Freq == "MONTHLY" || rule.Freq == "YEARLY":
+		for i := 0; ; i++ {
+			var start time.Time
+			if rule.Freq == "MONTHLY" {
+				start = event.Start.AddDate(0, i*rule.Interval, 0)
+			} else {
+				start = event.Start.AddDate(i*rule.Interval, 0, 0)
+			}
+			if start.Day() != event.Start.Day() { // e.g. the 31st in a month with 30 days is skipped, not moved
+				if start.After(limit) {
+					return result
+				}
+				continue
+			}
+			if !add(start) {
+				return result
+			}
+		}
+	default:
+		days := 1
+		if rule.Freq == "WEEKLY" {
+			days = 7
+		}
+		for i := 0; ; i++ {
+			if !add(event.Start.AddDate(0, 0, i*days*rule.Interval)) {
+				return result
+			}
+		}
+	}
+}
+
+// Lists the calendar days covered by every occurrence of event, as dates in location.
+func (event icalParsedEvent) coveredDays(limit time.Time, location *time.Location) []time.Time {
+	result := []time.Time{}
+	for _, start := range event.occurrences(limit) {
+		if event.AllDay {
+			days := max(1, int(event.End.Sub(event.Start).Round(24*time.Hour)/(24*time.Hour)))
+			for i := 0; i < days; i++ {
+				day := start.AddDate(0, 0, i)
+				result = append(result, time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.UTC))
+			}
+			continue
+		}
+		first := start.In(location)
+		last := first
+		if duration := event.End.Sub(event.Start); duration > 0 {
+			last = start.Add(duration - time.Nanosecond).In(location)
+		}
+		day := time.Date(first.Year(), first.Month(), first.Day(), 0, 0, 0, 0, time.UTC)
+		lastDay := time.Date(last.Year(), last.Month(), last.Day(), 0, 0, 0, 0, time.UTC)
+		for ; !day.After(lastDay); day = day.AddDate(0, 0, 1) {
+			result = append(result, day)
+		}
+	}
+	return result
+}
+
+// Parses the VEVENTs of an iCalendar file. Events that are cancelled, marked free (TRANSP:TRANSPARENT), or can't be understood are skipped with a warning.
+func parseICalEvents(r io.Reader, location *time.Location) ([]icalParsedEvent, int, []string, error) {
+	properties, err := readICalProperties(r)
+	if err != nil {
+		return nil, 0, nil, fmt.Errorf("error in parseICalEvents: %w", err)
+	}
+	if len(properties) == 0 || properties[0].Name != "BEGIN" || strings.ToUpper(properties[0].Value) != "VCALENDAR" {
+		return nil, 0, nil, errors.New("error in parseICalEvents: method failed because the data does not start with BEGIN:VCALENDAR")
+	}
+	events := []icalParsedEvent{}
+	warnings := []string{}
+	eventsRead := 0
+	var current []icalProperty
+	depth := 0 // nesting inside the current VEVENT, e.g. a VALARM
+	for _, property := range properties {
+		switch {
+		case property.Name == "BEGIN" && strings.ToUpper(property.Value) == "VEVENT" && current == nil:
+			current = []icalProperty{}
+		case current == nil:
+		case property.Name == "BEGIN":
+			depth++
+		case property.Name == "END" && depth > 0:
+			depth--
+		case property.Name == "END" && strings.ToUpper(property.Value) == "VEVENT":
+			eventsRead++
+			event, err := parseICalEvent(current, location)
+			if err != nil {
+				warnings = append(warnings, fmt.Sprintf("skipped event %d: %v", eventsRead, err))
+			} else if event != nil {
+				events = append(events, *event)
+			}
+			current = nil
+		case depth == 0:
+			current = append(current, property)
+		}
+	}
+	return events, eventsRead, warnings, nil
+}
+
+// Returns nil without an error for events that don't make the attendee unavailable.
+func parseICalEvent(properties []icalProperty, location *time.Location) (*icalParsedEvent, error) {
+	var event icalParsedEvent
+	var duration *time.Duration
+	hasStart, hasEnd := false, false
+	summary := ""
+	for _, property := range properties {
+		var err error
+		switch property.Name {
+		case "SUMMARY":
+			summary = property.Value
+		case "STATUS":
+			if strings.ToUpper(property.Value) == "CANCELLED" {
+				return nil, nil
+			}
+		case "TRANSP":
+			if strings.ToUpper(property.Value) == "TRANSPARENT" {
+				return nil, nil
+			}
+		case "DTSTART":
+			event.Start, event.AllDay, err = parseICalTime(property, property.Value, location)
+			hasStart = true
+		case "DTEND":
+			event.End, _, err = parseICalTime(property, property.Value, location)
+			hasEnd = true
+		case "DURATION":
+			var parsed time.Duration
+			parsed, err = parseICalDuration(property.Value)
+			duration = &parsed
+		case "RRULE":
+			event.Recurrence, err = parseICalRecurrence(property, location)
+		case "EXDATE":
+			for _, value := range strings.Split(property.Value, ",") {
+				var exDate time.Time
+				exDate, _, err = parseICalTime(property, value, location)
+				if err != nil {
+					break
+				}
+				event.ExDates = append(event.ExDates, exDate)
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s `%s`: %w", property.Name, summary, err)
+		}
+	}
+	if !hasStart {
+		return nil, fmt.Errorf("`%s` has no DTSTART", summary)
+	}
+	switch {
+	case hasEnd:
+	case duration != nil:
+		event.End = event.Start.Add(*duration)
+	case event.AllDay:
+		event.End = event.Start.AddDate(0, 0, 1)
+	default:
+		event.End = event.Start
+	}
+	if event.End.Before(event.Start) {
+		return nil, fmt.Errorf("`%s` ends before it starts", summary)
+	}
+	return &event, nil
+}
+
+// Reads an iCalendar file and marks the volunteer of the VFS row with vfsID unavailable on every day within the schedule's StartDate..EndDate that an event covers, in one transaction.
+// Recurring events are expanded (see parseICalRecurrence for the supported rules). Timed events count for every day they touch in location, which defaults to time.UTC. Dates that are already stored are skipped.
+func (sm SampleModel) ImportUnavailabilityICal(currentUser string, vfsID int, r io.Reader, location *time.Location) (icalImportReport, error) {
+	if location == nil {
+		location = time.UTC
+	}
+	vfsStruct, err := sm.RequestVFSSingle(currentUser, volunteerForSchedule{VFSID: vfsID})
+	if err != nil {
+		return icalImportReport{}, fmt.Errorf("error in ImportUnavailabilityICal: %w", err)
+	}
+	scheduleStruct, err := sm.authorizeSchedule(currentUser, vfsStruct.Schedule, ScheduleEditor)
+	if err != nil {
+		return icalImportReport{}, fmt.Errorf("error in ImportUnavailabilityICal: %w", err)
+	}
+	bounds, err := sm.RequestDates([]date{{DateID: scheduleStruct.StartDate}, {DateID: scheduleStruct.EndDate}})
+	if err != nil {
+		return icalImportReport{}, fmt.Errorf("error in ImportUnavailabilityICal: %w", err)
+	}
+	if len(bounds) == 0 || (len(bounds) == 1 && scheduleStruct.StartDate != scheduleStruct.EndDate) {
+		return icalImportReport{}, fmt.Errorf("error in ImportUnavailabilityICal: method failed to locate the StartDate and EndDate of schedule %+v", scheduleStruct)
+	}
+	slices.SortFunc(bounds, func(a, b date) int { return a.DateID - b.DateID })
+	first := time.Date(bounds[0].Year, time.Month(bounds[0].Month), bounds[0].Day, 0, 0, 0, 0, time.UTC)
+	last := time.Date(bounds[len(bounds)-1].Year, time.Month(bounds[len(bounds)-1].Month), bounds[len(bounds)-1].Day, 0, 0, 0, 0, time.UTC)
+	// occurrences starting up to a day after the schedule ends can't touch it, whatever their time zone
+	limit := time.Date(last.Year(), last.Month(), last.Day()+2, 0, 0, 0, 0, location)
+	events, eventsRead, warnings, err := parseICalEvents(r, location)
+	if err != nil {
+		return icalImportReport{}, fmt.Errorf("error in ImportUnavailabilityICal: %w", err)
+	}
+	report := icalImportReport{EventsRead: eventsRead, UnavailabilitiesCreated: []string{}, UnavailabilitiesSkipped: []string{}, Warnings: warnings}
+	var days []time.Time
+	for _, event := range events {
+		for _, day := range event.coveredDays(limit, location) {
+			if !day.Before(first) && !day.After(last) && !slices.ContainsFunc(days, day.Equal) {
+				days = append(days, day)
+			}
+		}
+	}
+	if len(days) == 0 {
+		return report, nil
+	}
+	slices.SortFunc(days, func(a, b time.Time) int { return a.Compare(b) })
+	var toRequest []date
+	for _, day := range days {
+		toRequest = append(toRequest, date{Month: int(day.Month()), Day: day.Day(), Year: day.Year()})
+	}
+	dates, err := sm.RequestDates(toRequest)
+	if err != nil {
+		return icalImportReport{}, fmt.Errorf("error in ImportUnavailabilityICal: %w", err)
+	}
+	slices.SortFunc(dates, func(a, b date) int { return a.DateID - b.DateID })
+	existingUFS, err := sm.RequestUFS(currentUser, []unavailabilityForSchedule{{VolunteerForSchedule: vfsID}})
+	if err != nil {
+		return icalImportReport{}, fmt.Errorf("error in ImportUnavailabilityICal: %w", err)
+	}
+	tx, err := sm.beginAudited(currentUser)
+	if err != nil {
+		return icalImportReport{}, fmt.Errorf("error in ImportUnavailabilityICal: %w", err)
+	}
+	defer tx.Rollback()
+	for _, dateStruct := range dates {
+		if slices.ContainsFunc(existingUFS, func(ufs unavailabilityForSchedule) bool { return ufs.Date == dateStruct.DateID }) {
+			report.UnavailabilitiesSkipped = append(report.UnavailabilitiesSkipped, isoDate(dateStruct))
+			continue
+		}
+		_, err := tx.Exec(`insert into UnavailabilitiesForSchedule (User, VolunteerForSchedule, Date) values (?, ?, ?)`, scheduleStruct.User, vfsID, dateStruct.DateID)
+		if err != nil {
+			return icalImportReport{}, fmt.Errorf("error in ImportUnavailabilityICal: sql.Tx.Exec error: %w. Value of dateStruct is `%+v`", err, dateStruct)
+		}
+		report.UnavailabilitiesCreated = append(report.UnavailabilitiesCreated, isoDate(dateStruct))
+	}
+	err = tx.Commit()
+	if err != nil {
+		return icalImportReport{}, fmt.Errorf("error in ImportUnavailabilityICal: sql.Tx.Commit error: %w", err)
+	}
+	return report, nil
+}
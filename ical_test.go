@@ -0,0 +1,241 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestICalFold(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{name: "Keep a short line", input: "SUMMARY:test1", want: "SUMMARY:test1\r\n"},
+		{name: "Fold a long line", input: "DESCRIPTION:" + strings.Repeat("a", 70), want: "DESCRIPTION:" + strings.Repeat("a", 63) + "\r\n " + strings.Repeat("a", 7) + "\r\n"},
+		{name: "Fold without splitting a multi-byte character", input: strings.Repeat("a", 74) + "é", want: strings.Repeat("a", 74) + "\r\n é\r\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ans := icalFold(tt.input)
+			checkResults(t, ans, tt.want, tt.input, nil)
+		})
+	}
+}
+
+func TestICalText(t *testing.T) {
+	ans := icalText("Tim, Bill; Jack\\George\nLarry")
+	checkResults(t, ans, `Tim\, Bill\; Jack\\George\nLarry`, "", nil)
+}
+
+func TestExportScheduleICal(t *testing.T) {
+	env, tearDownEnvironment := setUpEnvironment(t)
+	defer tearDownEnvironment(t)
+	setUpSampleData(t, env)
+	err := env.sample.CreateCompletedSchedules(env.loggedInUser, []completedSchedule{{ScheduleData: sampleScheduleData, Schedule: 2}})
+	if err != nil {
+		t.Errorf("Error setting up test (CreateCompletedSchedules failed): %v", err)
+		t.FailNow()
+	}
+	opts := icalOptions{UIDDomain: "example.org", Stamp: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)}
+	want := strings.ReplaceAll(`BEGIN:VCALENDAR
+VERSION:2.0
+PRODID:-//SampleDatabase//Roster//EN
+CALSCALE:GREGORIAN
+METHOD:PUBLISH
+X-WR-CALNAME:test1
+X-WR-TIMEZONE:UTC
+BEGIN:VEVENT
+UID:schedule2-date372@example.org
+DTSTAMP:20240101T120000Z
+DTSTART;VALUE=DATE:20240107
+DTEND;VALUE=DATE:20240108
+SUMMARY:test1
+DESCRIPTION:Volunteers: Tim\, Bill\, Jack
+TRANSP:OPAQUE
+END:VEVENT
+BEGIN:VEVENT
+UID:schedule2-date379@example.org
+DTSTAMP:20240101T120000Z
+DTSTART;VALUE=DATE:20240114
+DTEND;VALUE=DATE:20240115
+SUMMARY:test1
+DESCRIPTION:Volunteers: Bill\, Jack\, George
+TRANSP:OPAQUE
+END:VEVENT
+BEGIN:VEVENT
+UID:schedule2-date386@example.org
+DTSTAMP:20240101T120000Z
+DTSTART;VALUE=DATE:20240121
+DTEND;VALUE=DATE:20240122
+SUMMARY:test1
+DESCRIPTION:Volunteers: Tim\, Jack
+TRANSP:OPAQUE
+END:VEVENT
+END:VCALENDAR
+`, "\n", "\r\n")
+	var ans strings.Builder
+	err = env.sample.ExportScheduleICal(env.loggedInUser, 1, opts, &ans)
+	checkResults(t, ans.String(), want, "", err)
+	err = env.sample.ExportScheduleICal(env.loggedInUser, 2, opts, &strings.Builder{})
+	if err == nil {
+		t.Errorf("got no error, want an error for a nonexistent CScheduleID")
+	}
+}
+
+func TestExportVolunteerICal(t *testing.T) {
+	env, tearDownEnvironment := setUpEnvironment(t)
+	defer tearDownEnvironment(t)
+	setUpSampleData(t, env)
+	// the second roster of test1 replaces the first one, and test2 has a roster of its own
+	err := env.sample.CreateCompletedSchedules(env.loggedInUser, []completedSchedule{
+		{ScheduleData: sampleScheduleData, Schedule: 2},
+		{ScheduleData: `[{"Date":386,"Volunteers":[2,3]},{"Date":372,"Volunteers":[1,4]}]`, Schedule: 2},
+		{ScheduleData: `[{"Date":432,"Volunteers":[1,5,6]}]`, Schedule: 3},
+	})
+	if err != nil {
+		t.Errorf("Error setting up test (CreateCompletedSchedules failed): %v", err)
+		t.FailNow()
+	}
+	opts := icalOptions{
+		ShiftStart:  9*time.Hour + 30*time.Minute,
+		ShiftLength: 2 * time.Hour,
+		Location:    time.FixedZone("CST", -6*60*60),
+		Stamp:       time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC),
+	}
+	want := strings.ReplaceAll(`BEGIN:VCALENDAR
+VERSION:2.0
+PRODID:-//SampleDatabase//Roster//EN
+CALSCALE:GREGORIAN
+METHOD:PUBLISH
+X-WR-CALNAME:Tim
+X-WR-TIMEZONE:CST
+BEGIN:VEVENT
+UID:schedule2-date372-volunteer1@sampledatabase.invalid
+DTSTAMP:20240101T120000Z
+DTSTART:20240107T153000Z
+DTEND:20240107T173000Z
+SUMMARY:test1
+DESCRIPTION:Serving with George
+TRANSP:OPAQUE
+END:VEVENT
+BEGIN:VEVENT
+UID:schedule3-date432-volunteer1@sampledatabase.invalid
+DTSTAMP:20240101T120000Z
+DTSTART:20240307T153000Z
+DTEND:20240307T173000Z
+SUMMARY:test2
+DESCRIPTION:Serving with Bob\, Lance
+TRANSP:OPAQUE
+END:VEVENT
+END:VCALENDAR
+`, "\n", "\r\n")
+	var ans strings.Builder
+	err = env.sample.ExportVolunteerICal(env.loggedInUser, 1, opts, &ans)
+	checkResults(t, ans.String(), want, "", err)
+	// a deactivated volunteer still gets the shifts they were rostered for
+	err = env.sample.DeleteVolunteers(env.loggedInUser, []volunteer{{VolunteerID: 1}})
+	if err != nil {
+		t.Errorf("Error setting up test (DeleteVolunteers failed): %v", err)
+		t.FailNow()
+	}
+	ans.Reset()
+	err = env.sample.ExportVolunteerICal(env.loggedInUser, 1, opts, &ans)
+	checkResults(t, ans.String(), want, "", err)
+	err = env.sample.ExportVolunteerICal(env.loggedInUser, 100, opts, &strings.Builder{})
+	if err == nil {
+		t.Errorf("got no error, want an error for a nonexistent VolunteerID")
+	}
+}
+
+func TestParseICalDuration(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  time.Duration
+	}{
+		{name: "Parse days", input: "P2D", want: 48 * time.Hour},
+		{name: "Parse weeks", input: "P1W", want: 7 * 24 * time.Hour},
+		{name: "Parse hours and minutes", input: "PT1H30M", want: 90 * time.Minute},
+		{name: "Parse days and seconds", input: "P1DT15S", want: 24*time.Hour + 15*time.Second},
+		{name: "Parse a negative duration", input: "-PT15M", want: -15 * time.Minute},
+		{name: "Fail to parse months", input: "P1M", want: 0},
+		{name: "Fail to parse a missing P", input: "1D", want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ans, err := parseICalDuration(tt.input)
+			checkResults(t, ans, tt.want, 0, err)
+		})
+	}
+}
+
+const sampleUnavailabilityICal = "BEGIN:VCALENDAR\r\nVERSION:2.0\r\nPRODID:-//Test//EN\r\n" +
+	// two days, the first of which is already stored for Tim
+	"BEGIN:VEVENT\r\nUID:1\r\nSUMMARY:Trip\r\nDTSTART;VALUE=DATE:20240114\r\nDTEND;VALUE=DATE:20240116\r\nEND:VEVENT\r\n" +
+	// a timed event over midnight
+	"BEGIN:VEVENT\r\nUID:2\r\nSUMMARY:Late shift\r\nDTSTART:20240120T230000Z\r\nDURATION:PT2H\r\nBEGIN:VALARM\r\nTRIGGER:-PT15M\r\nDTSTART:20240101T000000Z\r\nEND:VALARM\r\nEND:VEVENT\r\n" +
+	// a weekly rule with an exception
+	"BEGIN:VEVENT\r\nUID:3\r\nSUMMARY:Class\r\nDTSTART;VALUE=DATE:20240207\r\nRRULE:FREQ=WEEKLY;BYDAY=WE,FR;COUNT=4\r\nEXDATE;VALUE=DATE:20240214\r\nEND:VEVENT\r\n" +
+	// a monthly rule on the 31st skips February
+	"BEGIN:VEVENT\r\nUID:4\r\nSUMMARY:Month end\r\nDTSTART;VALUE=DATE:20231231\r\nRRULE:FREQ=MONTHLY\r\nEND:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\nUID:5\r\nSUMMARY:Cancelled\r\nSTATUS:CANCELLED\r\nDTSTART;VALUE=DATE:20240201\r\nEND:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\nUID:6\r\nSUMMARY:Hourly\r\nDTSTART:20240202T100000Z\r\nRRULE:FREQ=HOURLY\r\nEND:VEVENT\r\n" +
+	// a floating daily rule with an UNTIL date, folded
+	"BEGIN:VEVENT\r\nUID:7\r\nSUMMARY:New year\r\nDTSTART:20231230T100000\r\nDTEND:20231230T120000\r\nRRULE:FREQ=DAILY;\r\n UNTIL=20240103\r\nEND:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\nUID:8\r\nSUMMARY:Available anyway\r\nTRANSP:TRANSPARENT\r\nDTSTART;VALUE=DATE:20240203\r\nEND:VEVENT\r\n" +
+	"END:VCALENDAR\r\n"
+
+func TestImportUnavailabilityICal(t *testing.T) {
+	env, tearDownEnvironment := setUpEnvironment(t)
+	defer tearDownEnvironment(t)
+	setUpSampleData(t, env)
+	tests := []struct {
+		name     string
+		vfsID    int
+		input    string
+		location *time.Location
+		want     icalImportReport
+	}{
+		{name: "Import events for Tim", vfsID: 1, input: sampleUnavailabilityICal, want: icalImportReport{
+			EventsRead:              8,
+			UnavailabilitiesCreated: []string{"2024-01-01", "2024-01-02", "2024-01-03", "2024-01-15", "2024-01-20", "2024-01-21", "2024-01-31", "2024-02-07", "2024-02-09", "2024-02-16"},
+			UnavailabilitiesSkipped: []string{"2024-01-14"},
+			Warnings:                []string{"skipped event 6: RRULE `Hourly`: FREQ=HOURLY is not supported"},
+		}},
+		{name: "Skip events that were already imported", vfsID: 1, input: sampleUnavailabilityICal, want: icalImportReport{
+			EventsRead:              8,
+			UnavailabilitiesCreated: []string{},
+			UnavailabilitiesSkipped: []string{"2024-01-01", "2024-01-02", "2024-01-03", "2024-01-14", "2024-01-15", "2024-01-20", "2024-01-21", "2024-01-31", "2024-02-07", "2024-02-09", "2024-02-16"},
+			Warnings:                []string{"skipped event 6: RRULE `Hourly`: FREQ=HOURLY is not supported"},
+		}},
+		{name: "Place timed events in the given time zone", vfsID: 2, location: time.FixedZone("CST", -6*60*60), input: "BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nDTSTART:20240120T230000Z\r\nDTEND:20240121T010000Z\r\nEND:VEVENT\r\nEND:VCALENDAR\r\n", want: icalImportReport{
+			EventsRead:              1,
+			UnavailabilitiesCreated: []string{"2024-01-20"},
+			UnavailabilitiesSkipped: []string{},
+			Warnings:                []string{},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ans, err := env.sample.ImportUnavailabilityICal(env.loggedInUser, tt.vfsID, strings.NewReader(tt.input), tt.location)
+			if err != nil || !reflect.DeepEqual(ans, tt.want) {
+				t.Errorf("got %+v (error: %v), want %+v", ans, err, tt.want)
+			}
+		})
+	}
+	t.Run("Fail by providing something other than iCalendar", func(t *testing.T) {
+		_, err := env.sample.ImportUnavailabilityICal(env.loggedInUser, 1, strings.NewReader("Tim,2024-01-14\n"), nil)
+		if err == nil {
+			t.Errorf("got no error, want an error for CSV input")
+		}
+	})
+	t.Run("Fail by providing a nonexistent VFSID", func(t *testing.T) {
+		_, err := env.sample.ImportUnavailabilityICal(env.loggedInUser, 100, strings.NewReader(sampleUnavailabilityICal), nil)
+		if err == nil {
+			t.Errorf("got no error, want an error for VFSID 100")
+		}
+	})
+}
@@ -0,0 +1,301 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// How failed logins of one user or from one source slow down further attempts. After FreeFailures failures each attempt has to wait BaseDelay, doubled for every further failure up to MaxDelay, and after LockoutFailures failures the subject is locked out for LockoutDuration. Failures are forgotten after ResetAfter without another one, and a successful login forgets those of its user.
+type loginPolicy struct {
+	FreeFailures    int
+	BaseDelay       time.Duration
+	MaxDelay        time.Duration
+	LockoutFailures int
+	LockoutDuration time.Duration
+	ResetAfter      time.Duration
+}
+
+var (
+	userLoginPolicy   = loginPolicy{FreeFailures: 3, BaseDelay: time.Second, MaxDelay: time.Minute, LockoutFailures: 10, LockoutDuration: 15 * time.Minute, ResetAfter: 24 * time.Hour}
+	sourceLoginPolicy = loginPolicy{FreeFailures: 10, BaseDelay: time.Second, MaxDelay: time.Minute, LockoutFailures: 100, LockoutDuration: time.Hour, ResetAfter: 24 * time.Hour} // a source may try many users, so it gets more failures
+)
+
+const loginAttemptRetention = 90 * 24 * time.Hour // how long cleanUpSessions keeps LoginAttempts rows
+
+// The kinds of subjects LoginThrottles counts failures for.
+const (
+	loginSubjectUser   = "user"
+	loginSubjectSource = "source"
+)
+
+// The outcomes recorded in LoginAttempts.
+const (
+	loginSucceeded = "success"
+	loginFailed    = "failure"   // the credentials were wrong
+	loginThrottled = "throttled" // the password was not checked because of the backoff or a lockout
+)
+
+// A row of LoginAttempts.
+type loginAttempt struct {
+	AttemptID   int
+	User        string // the UserName that was tried, which need not exist
+	Source      string // where the attempt came from, for example the remote address of a request. Empty if unknown
+	AttemptedAt time.Time
+	Outcome     string // success, failure, or throttled
+}
+
+// A row of LoginThrottles: the recent failures of one user or source.
+type loginThrottle struct {
+	Kind          string // user or source
+	Subject       string
+	Failures      int
+	LastFailureAt time.Time
+	LockedUntil   time.Time // the zero time if the subject is not locked out
+}
+
+func (p loginPolicy) delay(failures int) time.Duration {
+	if failures < p.FreeFailures {
+		return 0
+	}
+	shift := failures - p.FreeFailures
+	if shift > 30 || p.BaseDelay<<shift > p.MaxDelay {
+		return p.MaxDelay
+	}
+	return p.BaseDelay << shift
+}
+
+// Returns when the next login of the throttled subject is allowed, which is not after now if it is allowed already.
+func (p loginPolicy) allowedAt(throttle loginThrottle, now time.Time) time.Time {
+	if now.Sub(throttle.LastFailureAt) >= p.ResetAfter {
+		return throttle.LockedUntil
+	}
+	next := throttle.LastFailureAt.Add(p.delay(throttle.Failures))
+	if throttle.LockedUntil.After(next) {
+		return throttle.LockedUntil
+	}
+	return next
+}
+
+// The methods of *sql.DB, *sql.Tx, and *sql.Conn the login throttle needs, so beginLoginAttempt can run them on the connection that holds its transaction.
+type loginQueryer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+func requestLoginThrottle(q loginQueryer, kind string, subject string) (loginThrottle, error) {
+	result := loginThrottle{Kind: kind, Subject: subject}
+	var lastFailureAt, lockedUntil int64
+	err := q.QueryRowContext(context.Background(), `select Failures, LastFailureAt, LockedUntil from LoginThrottles where Kind = ? and Subject = ?`, kind, subject).Scan(&result.Failures, &lastFailureAt, &lockedUntil)
+	if errors.Is(err, sql.ErrNoRows) {
+		return result, nil
+	}
+	if err != nil {
+		return loginThrottle{}, fmt.Errorf("error in requestLoginThrottle: sql.Row.Scan error: %w", err)
+	}
+	result.LastFailureAt = time.Unix(lastFailureAt, 0)
+	if lockedUntil > 0 {
+		result.LockedUntil = time.Unix(lockedUntil, 0)
+	}
+	return result, nil
+}
+
+// Returns a UserLoginThrottled UserError if userName or source may not try to log in at now, and nil otherwise. An empty source is not throttled on its own.
+func checkLoginThrottle(q loginQueryer, userName string, source string, now time.Time) error {
+	subjects := []struct {
+		kind    string
+		subject string
+		policy  loginPolicy
+	}{{loginSubjectUser, userName, userLoginPolicy}, {loginSubjectSource, source, sourceLoginPolicy}}
+	var wait time.Duration
+	for _, val := range subjects {
+		if len(val.subject) == 0 {
+			continue
+		}
+		throttle, err := requestLoginThrottle(q, val.kind, val.subject)
+		if err != nil {
+			return fmt.Errorf("error in checkLoginThrottle: %w", err)
+		}
+		wait = max(wait, val.policy.allowedAt(throttle, now).Sub(now))
+	}
+	if wait <= 0 {
+		return nil
+	}
+	wait = wait.Round(time.Second) + time.Second // rounded up, so retrying after Detail is never too early
+	return &UserError{Kind: UserLoginThrottled, UserName: userName, Detail: fmt.Sprintf("try again in %s", wait), RetryAfter: wait}
+}
+
+// Checks the throttles of userName and source and records the attempt in LoginAttempts, in one transaction that holds the database's write lock from the start, so concurrent attempts are checked one after the other. An attempt that may go ahead is recorded and counted as a failure right away, before the slow password check, and Login takes that back with recordLoginSuccess if the password is right. Returns the AttemptID, and the UserLoginThrottled UserError of checkLoginThrottle after recording a throttled attempt.
+func (sm SampleModel) beginLoginAttempt(userName string, source string, now time.Time) (int64, error) {
+	ctx := context.Background()
+	conn, err := sm.DB.Conn(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("error in beginLoginAttempt: sql.DB.Conn error: %w", err)
+	}
+	defer conn.Close()
+	_, err = conn.ExecContext(ctx, `begin immediate`) // a deferred transaction would let two attempts read the throttles before either writes them
+	if err != nil {
+		return 0, fmt.Errorf("error in beginLoginAttempt: sql.Conn.ExecContext error: %w", err)
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			_, _ = conn.ExecContext(ctx, `rollback`)
+		}
+	}()
+	throttleErr := checkLoginThrottle(conn, userName, source, now)
+	var userErr *UserError
+	if throttleErr != nil && !errors.As(throttleErr, &userErr) {
+		return 0, fmt.Errorf("error in beginLoginAttempt: %w", throttleErr)
+	}
+	outcome := loginFailed
+	if throttleErr != nil {
+		outcome = loginThrottled
+	}
+	res, err := conn.ExecContext(ctx, `insert into LoginAttempts (User, Source, AttemptedAt, Outcome) values (?, ?, ?, ?)`, userName, source, now.Unix(), outcome)
+	if err != nil {
+		return 0, fmt.Errorf("error in beginLoginAttempt: sql.Conn.ExecContext error: %w", err)
+	}
+	attemptID, err := res.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("error in beginLoginAttempt: sql.Result.LastInsertId error: %w", err)
+	}
+	if outcome == loginFailed {
+		err = recordLoginFailure(conn, userName, source, now)
+		if err != nil {
+			return 0, fmt.Errorf("error in beginLoginAttempt: %w", err)
+		}
+	}
+	_, err = conn.ExecContext(ctx, `commit`)
+	if err != nil {
+		return 0, fmt.Errorf("error in beginLoginAttempt: sql.Conn.ExecContext error: %w", err)
+	}
+	committed = true
+	if throttleErr != nil {
+		return 0, fmt.Errorf("error in beginLoginAttempt: %w", throttleErr)
+	}
+	return attemptID, nil
+}
+
+// Counts a failure for userName and for source, if it is not empty, in LoginThrottles.
+func recordLoginFailure(q loginQueryer, userName string, source string, now time.Time) error {
+	for kind, policy := range map[string]loginPolicy{loginSubjectUser: userLoginPolicy, loginSubjectSource: sourceLoginPolicy} {
+		subject := userName
+		if kind == loginSubjectSource {
+			subject = source
+		}
+		if len(subject) == 0 {
+			continue
+		}
+		_, err := q.ExecContext(context.Background(), `insert into LoginThrottles (Kind, Subject, Failures, LastFailureAt, LockedUntil) values (?, ?, 1, ?, 0)
+			on conflict (Kind, Subject) do update set Failures = case when LastFailureAt <= ? then 1 else Failures + 1 end, LastFailureAt = excluded.LastFailureAt`,
+			kind, subject, now.Unix(), now.Add(-policy.ResetAfter).Unix())
+		if err != nil {
+			return fmt.Errorf("error in recordLoginFailure: sql.ExecContext error: %w", err)
+		}
+		_, err = q.ExecContext(context.Background(), `update LoginThrottles set LockedUntil = ? where Kind = ? and Subject = ? and Failures >= ?`, now.Add(policy.LockoutDuration).Unix(), kind, subject, policy.LockoutFailures)
+		if err != nil {
+			return fmt.Errorf("error in recordLoginFailure: sql.ExecContext error: %w", err)
+		}
+	}
+	return nil
+}
+
+// Marks the attempt with attemptID as successful, forgets the failures of userName, and takes back the failure beginLoginAttempt counted for source, in one transaction. The LastFailureAt of source keeps the time of the attempt.
+func (sm SampleModel) recordLoginSuccess(attemptID int64, userName string, source string) error {
+	tx, err := sm.DB.Begin()
+	if err != nil {
+		return fmt.Errorf("error in recordLoginSuccess: sql.DB.Begin error: %w", err)
+	}
+	defer tx.Rollback()
+	_, err = tx.Exec(`update LoginAttempts set Outcome = ? where AttemptID = ?`, loginSucceeded, attemptID)
+	if err != nil {
+		return fmt.Errorf("error in recordLoginSuccess: sql.Tx.Exec error: %w", err)
+	}
+	_, err = tx.Exec(`delete from LoginThrottles where Kind = ? and Subject = ?`, loginSubjectUser, userName)
+	if err != nil {
+		return fmt.Errorf("error in recordLoginSuccess: sql.Tx.Exec error: %w", err)
+	}
+	if len(source) > 0 {
+		_, err = tx.Exec(`delete from LoginThrottles where Kind = ? and Subject = ? and Failures = 1`, loginSubjectSource, source)
+		if err != nil {
+			return fmt.Errorf("error in recordLoginSuccess: sql.Tx.Exec error: %w", err)
+		}
+		_, err = tx.Exec(`update LoginThrottles set Failures = Failures - 1, LockedUntil = case when Failures - 1 < ? then 0 else LockedUntil end where Kind = ? and Subject = ?`, sourceLoginPolicy.LockoutFailures, loginSubjectSource, source)
+		if err != nil {
+			return fmt.Errorf("error in recordLoginSuccess: sql.Tx.Exec error: %w", err)
+		}
+	}
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("error in recordLoginSuccess: sql.Tx.Commit error: %w", err)
+	}
+	return nil
+}
+
+// Forgets the failures of userName, or of source if userName is empty, lifting any backoff or lockout. This is for whoever runs the database, not for users themselves, so it is only offered by the command line.
+func (sm SampleModel) UnlockLogin(userName string, source string) error {
+	kind, subject := loginSubjectUser, userName
+	if len(userName) == 0 {
+		kind, subject = loginSubjectSource, source
+	}
+	if len(subject) == 0 {
+		return errors.New("error in UnlockLogin: method failed because neither userName nor source was provided")
+	}
+	res, err := sm.DB.Exec(`delete from LoginThrottles where Kind = ? and Subject = ?`, kind, subject)
+	if err != nil {
+		return fmt.Errorf("error in UnlockLogin: sql.DB.Exec error: %w", err)
+	}
+	count, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error in UnlockLogin: sql.Result.RowsAffected error: %w", err)
+	}
+	if count == 0 {
+		return fmt.Errorf("error in UnlockLogin: method failed to locate failed logins of %s `%s`", kind, subject)
+	}
+	return nil
+}
+
+// Returns the login attempts for userName, newest first. If limit > 0 only the newest limit attempts are returned.
+func (sm SampleModel) RequestLoginAttempts(userName string, limit int) ([]loginAttempt, error) {
+	attemptsQuery := `select AttemptID, User, Source, AttemptedAt, Outcome from LoginAttempts where User = ? order by AttemptID desc`
+	if limit > 0 {
+		attemptsQuery = fmt.Sprintf(`%s limit %d`, attemptsQuery, limit)
+	}
+	rows, err := sm.DB.Query(attemptsQuery, userName)
+	if err != nil {
+		return []loginAttempt{}, fmt.Errorf("error in RequestLoginAttempts: sql.DB.Query error: %w", err)
+	}
+	defer rows.Close()
+	result := []loginAttempt{}
+	for rows.Next() {
+		var attempt loginAttempt
+		var attemptedAt int64
+		err = rows.Scan(&attempt.AttemptID, &attempt.User, &attempt.Source, &attemptedAt, &attempt.Outcome)
+		if err != nil {
+			return []loginAttempt{}, fmt.Errorf("error in RequestLoginAttempts: sql.Rows.Scan error: %w", err)
+		}
+		attempt.AttemptedAt = time.Unix(attemptedAt, 0)
+		result = append(result, attempt)
+	}
+	err = rows.Err()
+	if err != nil {
+		return []loginAttempt{}, fmt.Errorf("error in RequestLoginAttempts: sql.Rows.Err error: %w", err)
+	}
+	return result, nil
+}
+
+// Deletes the login attempts made before before and returns how many there were.
+func (sm SampleModel) DeleteLoginAttempts(before time.Time) (int, error) {
+	res, err := sm.DB.Exec(`delete from LoginAttempts where AttemptedAt < ?`, before.Unix())
+	if err != nil {
+		return 0, fmt.Errorf("error in DeleteLoginAttempts: sql.DB.Exec error: %w", err)
+	}
+	count, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("error in DeleteLoginAttempts: sql.Result.RowsAffected error: %w", err)
+	}
+	return int(count), nil
+}
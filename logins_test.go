@@ -0,0 +1,170 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLoginPolicyDelay(t *testing.T) {
+	tests := []struct {
+		name     string
+		failures int
+		want     time.Duration
+	}{
+		{name: "No delay before FreeFailures", failures: 2, want: 0},
+		{name: "BaseDelay at FreeFailures", failures: 3, want: time.Second},
+		{name: "Double for each further failure", failures: 5, want: 4 * time.Second},
+		{name: "Stop at MaxDelay", failures: 9, want: time.Minute},
+		{name: "Stop at MaxDelay without overflowing", failures: 1000, want: time.Minute},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ans := userLoginPolicy.delay(tt.failures)
+			checkResults(t, ans, tt.want, 0, nil)
+		})
+	}
+}
+
+func TestLoginThrottling(t *testing.T) {
+	env, tearDownEnvironment := setUpEnvironment(t)
+	defer tearDownEnvironment(t)
+	err := env.sample.CreateUser(user{UserName: "Ann"})
+	if err != nil {
+		t.Errorf("Error setting up test (CreateUser failed): %v", err)
+		t.FailNow()
+	}
+	err = env.sample.SetPassword("Ann", "correct horse")
+	if err != nil {
+		t.Errorf("Error setting up test (SetPassword failed): %v", err)
+		t.FailNow()
+	}
+	const source = "198.51.100.7"
+	wantKind := func(t *testing.T, err error, kind UserErrorKind) *UserError {
+		t.Helper()
+		var userErr *UserError
+		if !errors.As(err, &userErr) || userErr.Kind != kind {
+			t.Errorf("got error `%v`, want a UserError of kind %d", err, kind)
+			return &UserError{}
+		}
+		return userErr
+	}
+	setThrottle := func(t *testing.T, kind string, subject string, failures int, lastFailureAt time.Time) {
+		t.Helper()
+		_, err := env.sample.DB.Exec(`insert or replace into LoginThrottles (Kind, Subject, Failures, LastFailureAt, LockedUntil) values (?, ?, ?, ?, 0)`, kind, subject, failures, lastFailureAt.Unix())
+		if err != nil {
+			t.Errorf("Error setting up test (sql.DB.Exec failed): %v", err)
+		}
+	}
+	t.Run("Back off after FreeFailures failures", func(t *testing.T) {
+		for range userLoginPolicy.FreeFailures {
+			_, err := env.sample.Login("Ann", "wrong horse", source)
+			wantKind(t, err, UserCredentialsInvalid)
+		}
+		throttle, err := requestLoginThrottle(env.sample.DB, loginSubjectUser, "Ann")
+		checkResults(t, throttle.Failures, userLoginPolicy.FreeFailures, 0, err)
+		// Logins take long enough to hash that a BaseDelay backoff may be over already, so wait for a longer one.
+		setThrottle(t, loginSubjectUser, "Ann", userLoginPolicy.FreeFailures+3, time.Now())
+		_, err = env.sample.Login("Ann", "correct horse", source)
+		userErr := wantKind(t, err, UserLoginThrottled)
+		checkResults(t, userErr.RetryAfter >= 8*time.Second && userErr.RetryAfter <= 9*time.Second, true, false, nil)
+		attempts, err := env.sample.RequestLoginAttempts("Ann", 2)
+		checkResultsSlice(t, []string{attempts[0].Outcome, attempts[1].Outcome, attempts[0].Source}, []string{loginThrottled, loginFailed, source}, nil, err)
+	})
+	t.Run("Forget the failures of a user after a successful login", func(t *testing.T) {
+		setThrottle(t, loginSubjectUser, "Ann", userLoginPolicy.FreeFailures, time.Now().Add(-time.Minute))
+		ans, err := env.sample.Login("Ann", "correct horse", source)
+		checkResults(t, ans.UserName, "Ann", "", err)
+		throttle, err := requestLoginThrottle(env.sample.DB, loginSubjectUser, "Ann")
+		checkResults(t, throttle.Failures, 0, 0, err)
+		throttle, err = requestLoginThrottle(env.sample.DB, loginSubjectSource, source)
+		checkResults(t, throttle.Failures, userLoginPolicy.FreeFailures, 0, err) // only the failures of the first subtest
+	})
+	t.Run("Forget failures after ResetAfter", func(t *testing.T) {
+		setThrottle(t, loginSubjectUser, "Ann", userLoginPolicy.LockoutFailures-1, time.Now().Add(-userLoginPolicy.ResetAfter))
+		_, err := env.sample.Login("Ann", "wrong horse", "")
+		wantKind(t, err, UserCredentialsInvalid)
+		throttle, err := requestLoginThrottle(env.sample.DB, loginSubjectUser, "Ann")
+		checkResults(t, throttle.Failures, 1, 0, err)
+	})
+	t.Run("Lock out a user after LockoutFailures failures", func(t *testing.T) {
+		setThrottle(t, loginSubjectUser, "Ann", userLoginPolicy.LockoutFailures-1, time.Now().Add(-2*userLoginPolicy.MaxDelay))
+		_, err := env.sample.Login("Ann", "wrong horse", "")
+		wantKind(t, err, UserCredentialsInvalid)
+		_, err = env.sample.Login("Ann", "correct horse", "")
+		userErr := wantKind(t, err, UserLoginThrottled)
+		checkResults(t, userErr.RetryAfter > userLoginPolicy.LockoutDuration-time.Minute, true, false, nil)
+	})
+	t.Run("Unlock a user", func(t *testing.T) {
+		err := env.sample.UnlockLogin("Ann", "")
+		if err != nil {
+			t.Errorf("UnlockLogin failed: %v", err)
+		}
+		ans, err := env.sample.Login("Ann", "correct horse", "")
+		checkResults(t, ans.UserName, "Ann", "", err)
+		err = env.sample.UnlockLogin("Ann", "")
+		if err == nil {
+			t.Errorf("UnlockLogin succeeded for a user without failed logins")
+		}
+	})
+	t.Run("Throttle a source for every user", func(t *testing.T) {
+		setThrottle(t, loginSubjectSource, source, sourceLoginPolicy.FreeFailures, time.Now())
+		_, err := env.sample.Login("Nobody", "correct horse", source)
+		wantKind(t, err, UserLoginThrottled)
+		_, err = env.sample.Login("Ann", "correct horse", "203.0.113.9")
+		if err != nil {
+			t.Errorf("Login failed from another source: %v", err)
+		}
+		err = env.sample.UnlockLogin("", source)
+		if err != nil {
+			t.Errorf("UnlockLogin failed: %v", err)
+		}
+		_, err = env.sample.Login("Nobody", "correct horse", source)
+		wantKind(t, err, UserCredentialsInvalid)
+	})
+}
+
+func TestConcurrentLogins(t *testing.T) {
+	env, tearDownEnvironment := setUpEnvironment(t)
+	defer tearDownEnvironment(t)
+	err := env.sample.CreateUser(user{UserName: "Ann"})
+	if err == nil {
+		err = env.sample.SetPassword("Ann", "correct horse")
+	}
+	if err != nil {
+		t.Errorf("Error setting up test: %v", err)
+		t.FailNow()
+	}
+	// Ann may try once more now, and the failure after that backs off for MaxDelay, far longer than the guesses below take
+	_, err = env.sample.DB.Exec(`insert into LoginThrottles (Kind, Subject, Failures, LastFailureAt, LockedUntil) values (?, ?, ?, ?, 0)`, loginSubjectUser, "Ann", userLoginPolicy.FreeFailures+5, time.Now().Add(-userLoginPolicy.MaxDelay).Unix())
+	if err != nil {
+		t.Errorf("Error setting up test (sql.DB.Exec failed): %v", err)
+		t.FailNow()
+	}
+	const guesses = 8
+	kinds := make(chan UserErrorKind, guesses)
+	var wg sync.WaitGroup
+	for i := range guesses {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := env.sample.Login("Ann", fmt.Sprintf("wrong horse %d", i), "")
+			var userErr *UserError
+			if !errors.As(err, &userErr) {
+				t.Errorf("got error `%v`, want a UserError", err)
+				return
+			}
+			kinds <- userErr.Kind
+		}()
+	}
+	wg.Wait()
+	close(kinds)
+	counts := map[UserErrorKind]int{}
+	for kind := range kinds {
+		counts[kind]++
+	}
+	checkResults(t, counts[UserCredentialsInvalid], 1, 0, nil)
+	checkResults(t, counts[UserLoginThrottled], guesses-1, 0, nil)
+}
@@ -1,23 +1,23 @@
 package main
 
 import (
-	"bufio"
 	"database/sql"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"log"
+	"net/mail"
 	"os"
 	"slices"
 	"strconv"
 	"strings"
 	"time"
+	"unicode"
 
 	_ "github.com/mattn/go-sqlite3"
 )
 
-const dbName = "./sample.db?_foreign_keys=on"
-
 type Env struct { //define in main module
 	sample       SampleModel //would need to reference submodule with ".", i.e. models.SampleModel.
 	loggedInUser string
@@ -51,9 +51,15 @@ type user struct {
 }
 
 type volunteer struct {
-	VolunteerID   int
-	VolunteerName string
-	User          string
+	VolunteerID      int
+	VolunteerName    string
+	User             string
+	Email            string     `json:",omitempty"`
+	Phone            string     `json:",omitempty"`
+	PreferredContact string     `json:",omitempty"` // email, phone, or sms, or empty for no preference
+	Notes            string     `json:",omitempty"`
+	Inactive         bool       `json:",omitempty"` // the inverse of the Active column, so the zero value is the default for new volunteers. Filters only match inactive volunteers if it is true
+	DeactivatedAt    *time.Time `json:",omitempty"` // when the volunteer was made inactive, or nil while it is active. Ignored by filters and set by the methods themselves
 }
 
 type schedule struct {
@@ -88,22 +94,24 @@ type unavailabilityForSchedule struct {
 }
 
 type completedSchedule struct {
-	CScheduleID  string
+	CScheduleID  int
 	ScheduleData string
 	User         string
-	Schedule     string
+	Schedule     int
 }
 
+// Dates in SendReceiveDataStruct are ISO 8601 strings (YYYY-MM-DD), see isoDate and parseISODate.
 type SendReceiveDataStruct struct {
 	User                      string
 	ScheduleName              string
-	VolunteerAvailabilityData []map[string][]string // Need to create two functions: one to encode the db values into this format, one to decode this format into structs for insertion into the db
+	VolunteerAvailabilityData []map[string][]string // Each map has VolunteerNames as keys and the dates that volunteer is unavailable as values
 	StartDate                 string
 	EndDate                   string
 	WeekdaysForSchedule       []string
 	ShiftsOff                 int
 	VolunteersPerShift        int
 	CompletedSchedules        []string
+	Revision                  int // the Schedules Revision the data was fetched at. RecieveAndStoreData rejects data whose Revision is not the current one
 }
 
 func CsvSlice(stringSlice []string, trimQuotes bool) string {
@@ -136,6 +144,20 @@ func testEmpty[T comparable](sliceOfT []T, emptyT T) (bool, T) {
 	return false, emptyT
 }
 
+// Formats the Month, Day, and Year of dateStruct as an ISO 8601 date string (YYYY-MM-DD).
+func isoDate(dateStruct date) string {
+	return fmt.Sprintf("%04d-%02d-%02d", dateStruct.Year, dateStruct.Month, dateStruct.Day)
+}
+
+// Parses an ISO 8601 date string (YYYY-MM-DD) into a date struct with Month, Day, and Year set. DateID and Weekday are not set, so the result still needs to be passed to RequestDate(s).
+func parseISODate(value string) (date, error) {
+	parsed, err := time.Parse(time.DateOnly, strings.TrimSpace(value))
+	if err != nil {
+		return date{}, fmt.Errorf("error in parseISODate: `%s` is not a YYYY-MM-DD date: %w", value, err)
+	}
+	return date{Month: int(parsed.Month()), Day: parsed.Day(), Year: parsed.Year()}, nil
+}
+
 func Must[T any](value T, err error) T { // only to be used in main function testing code. Actual implementations need to handle the errors without crashing the program (unless the final step is to crash).
 	if err != nil {
 		log.Fatalf("Error from Must: %v", err)
@@ -265,6 +287,204 @@ func (sm SampleModel) CreateDatabase() error {
 	return nil
 }
 
+// Each entry upgrades the schema created by CreateDatabase by one version. PRAGMA user_version records how many entries have been applied, so databases created before an entry was added are upgraded the next time MigrateDatabase runs.
+var migrations = []string{
+	// Revision is bumped whenever a schedule or one of its WFS, VFS, or UFS rows changes, so stale SendReceiveDataStructs can be detected.
+	`
+	alter table Schedules add column Revision integer not null default 0 check (Revision > -1);
+	create trigger SchedulesRevisionOnUpdate after update of ScheduleName, ShiftsOff, VolunteersPerShift, User, StartDate, EndDate on Schedules begin
+		update Schedules set Revision = Revision + 1 where ScheduleID = new.ScheduleID;
+	end;
+	create trigger WFSRevisionOnInsert after insert on WeekdaysForSchedule begin
+		update Schedules set Revision = Revision + 1 where ScheduleID = new.Schedule;
+	end;
+	create trigger WFSRevisionOnUpdate after update on WeekdaysForSchedule begin
+		update Schedules set Revision = Revision + 1 where ScheduleID in (old.Schedule, new.Schedule);
+	end;
+	create trigger WFSRevisionOnDelete after delete on WeekdaysForSchedule begin
+		update Schedules set Revision = Revision + 1 where ScheduleID = old.Schedule;
+	end;
+	create trigger VFSRevisionOnInsert after insert on VolunteersForSchedule begin
+		update Schedules set Revision = Revision + 1 where ScheduleID = new.Schedule;
+	end;
+	create trigger VFSRevisionOnUpdate after update on VolunteersForSchedule begin
+		update Schedules set Revision = Revision + 1 where ScheduleID in (old.Schedule, new.Schedule);
+	end;
+	create trigger VFSRevisionOnDelete after delete on VolunteersForSchedule begin
+		update Schedules set Revision = Revision + 1 where ScheduleID = old.Schedule;
+	end;
+	create trigger UFSRevisionOnInsert after insert on UnavailabilitiesForSchedule begin
+		update Schedules set Revision = Revision + 1 where ScheduleID = (select Schedule from VolunteersForSchedule where VFSID = new.VolunteerForSchedule);
+	end;
+	create trigger UFSRevisionOnUpdate after update on UnavailabilitiesForSchedule begin
+		update Schedules set Revision = Revision + 1 where ScheduleID in (select Schedule from VolunteersForSchedule where VFSID in (old.VolunteerForSchedule, new.VolunteerForSchedule));
+	end;
+	create trigger UFSRevisionOnDelete after delete on UnavailabilitiesForSchedule begin
+		update Schedules set Revision = Revision + 1 where ScheduleID = (select Schedule from VolunteersForSchedule where VFSID = old.VolunteerForSchedule);
+	end;
+	`,
+	// Sessions of logged in users, see IssueSession. Only a SHA-256 hash of each token is stored, and times are Unix seconds.
+	`
+	create table Sessions (
+		SessionID integer primary key autoincrement,
+		TokenHash blob not null unique,
+		User text not null,
+		CreatedAt integer not null,
+		ExpiresAt integer not null,
+		LastSeenAt integer not null,
+		foreign key (User) references Users(UserName)
+	);
+	create index SessionsByUser on Sessions (User);
+	`,
+	// Users other than the owner in Schedules.User who may view or change a schedule, see ShareSchedule. Memberships go away with their schedule.
+	`
+	create table ScheduleMembers (
+		MemberID integer primary key autoincrement,
+		Schedule integer not null,
+		User text not null,
+		Role text not null check (Role in ('viewer', 'editor', 'owner')),
+		unique (Schedule, User),
+		foreign key (Schedule) references Schedules(ScheduleID) on delete cascade,
+		foreign key (User) references Users(UserName)
+	);
+	create index ScheduleMembersByUser on ScheduleMembers (User);
+	`,
+	// Organizations let several users share one pool of volunteers, see CreateOrganization. A volunteer keeps the User that created it and belongs to at most one organization.
+	`
+	create table Organizations (
+		OrganizationID integer primary key autoincrement,
+		OrganizationName text not null unique
+	);
+	create table OrganizationMembers (
+		MemberID integer primary key autoincrement,
+		Organization integer not null,
+		User text not null,
+		unique (Organization, User),
+		foreign key (Organization) references Organizations(OrganizationID) on delete cascade,
+		foreign key (User) references Users(UserName)
+	);
+	create index OrganizationMembersByUser on OrganizationMembers (User);
+	create table OrganizationVolunteers (
+		Volunteer integer primary key,
+		Organization integer not null,
+		foreign key (Volunteer) references Volunteers(VolunteerID) on delete cascade,
+		foreign key (Organization) references Organizations(OrganizationID) on delete cascade
+	);
+	create index OrganizationVolunteersByOrganization on OrganizationVolunteers (Organization);
+	`,
+	// An AuditLog row for every change of the tables in auditedTables, see RequestAuditLog.
+	auditMigration(),
+	// Every login attempt, and the recent failures of each user and source that Login throttles, see loginPolicy. Times are Unix seconds, and User is not a foreign key because unknown users are counted too.
+	`
+	create table LoginAttempts (
+		AttemptID integer primary key autoincrement,
+		User text not null,
+		Source text not null,
+		AttemptedAt integer not null,
+		Outcome text not null check (Outcome in ('success', 'failure', 'throttled'))
+	);
+	create index LoginAttemptsByUser on LoginAttempts (User);
+	create index LoginAttemptsByAttemptedAt on LoginAttempts (AttemptedAt);
+	create table LoginThrottles (
+		Kind text not null check (Kind in ('user', 'source')),
+		Subject text not null,
+		Failures integer not null check (Failures > 0),
+		LastFailureAt integer not null,
+		LockedUntil integer not null,
+		primary key (Kind, Subject)
+	) without rowid;
+	`,
+	// API keys that scripts authenticate with instead of a password, see CreateAPIKey. Like Sessions only a SHA-256 hash of each token is stored, and times are Unix seconds or null for never. Creating and revoking keys is audited, but TokenHash is never copied into the log and using a key is not a change of its own.
+	`
+	create table APIKeys (
+		KeyID integer primary key autoincrement,
+		TokenHash blob not null unique,
+		Prefix text not null,
+		Name text not null,
+		User text not null,
+		Scope text not null check (Scope in ('read-only', 'roster-write', 'admin')),
+		CreatedAt integer not null,
+		ExpiresAt integer,
+		LastUsedAt integer,
+		RevokedAt integer,
+		foreign key (User) references Users(UserName)
+	);
+	create index APIKeysByUser on APIKeys (User);
+	` + auditTriggers(auditedTable{name: "APIKeys", primaryKey: "KeyID", columns: []string{"KeyID", "Prefix", "Name", "User", "Scope", "CreatedAt", "ExpiresAt", "RevokedAt"},
+		updateOf: []string{"Prefix", "Name", "User", "Scope", "ExpiresAt", "RevokedAt"}, owner: "ROW.User", schedule: "null", volunteer: "null"}),
+	// Contact details and profile fields of volunteers, see validateVolunteerProfile. Existing volunteers get empty fields and stay active, and the audit triggers of Volunteers are replaced to log the new columns.
+	`
+	alter table Volunteers add column Email text not null default '';
+	alter table Volunteers add column Phone text not null default '';
+	alter table Volunteers add column PreferredContact text not null default '' check (PreferredContact in ('', 'email', 'phone', 'sms'));
+	alter table Volunteers add column Notes text not null default '';
+	alter table Volunteers add column Active integer not null default 1 check (Active in (0, 1));
+	` + replaceAuditTriggers(auditedTable{name: "Volunteers", primaryKey: "VolunteerID", columns: []string{"VolunteerID", "VolunteerName", "User", "Email", "Phone", "PreferredContact", "Notes", "Active"}, owner: "ROW.User", schedule: "null", volunteer: "ROW.VolunteerID"}),
+	// Volunteers are deactivated rather than deleted, see DeleteVolunteers, so past schedules keep their names. Inactive volunteers can't be added to schedules, but stay on the ones they are already on.
+	`
+	alter table Volunteers add column DeactivatedAt integer;
+	update Volunteers set DeactivatedAt = unixepoch() where Active = 0;
+	create trigger VFSActiveVolunteerOnInsert before insert on VolunteersForSchedule when (select Active from Volunteers where VolunteerID = new.Volunteer) = 0 begin
+		select raise(abort, 'inactive volunteers can''t be added to a schedule');
+	end;
+	create trigger VFSActiveVolunteerOnUpdate before update of Volunteer on VolunteersForSchedule when new.Volunteer != old.Volunteer and (select Active from Volunteers where VolunteerID = new.Volunteer) = 0 begin
+		select raise(abort, 'inactive volunteers can''t be added to a schedule');
+	end;
+	` + replaceAuditTriggers(auditedTable{name: "Volunteers", primaryKey: "VolunteerID", columns: []string{"VolunteerID", "VolunteerName", "User", "Email", "Phone", "PreferredContact", "Notes", "Active", "DeactivatedAt"}, owner: "ROW.User", schedule: "null", volunteer: "ROW.VolunteerID"}),
+	// Named groups of a user's volunteers that can be enrolled in a schedule at once, see EnrollVolunteerGroup. Memberships go away with their group or volunteer.
+	`
+	create table VolunteerGroups (
+		GroupID integer primary key autoincrement,
+		GroupName text not null,
+		User text not null,
+		unique (User, GroupName),
+		foreign key (User) references Users(UserName)
+	);
+	create table VolunteerGroupMembers (
+		MemberID integer primary key autoincrement,
+		VolunteerGroup integer not null,
+		Volunteer integer not null,
+		unique (VolunteerGroup, Volunteer),
+		foreign key (VolunteerGroup) references VolunteerGroups(GroupID) on delete cascade,
+		foreign key (Volunteer) references Volunteers(VolunteerID) on delete cascade
+	);
+	create index VolunteerGroupMembersByVolunteer on VolunteerGroupMembers (Volunteer);
+	` + auditTriggers(auditedTable{name: "VolunteerGroups", primaryKey: "GroupID", columns: []string{"GroupID", "GroupName", "User"}, owner: "ROW.User", schedule: "null", volunteer: "null"}) +
+		auditTriggers(auditedTable{name: "VolunteerGroupMembers", primaryKey: "MemberID", columns: []string{"MemberID", "VolunteerGroup", "Volunteer"}, owner: "coalesce((select User from VolunteerGroups where GroupID = ROW.VolunteerGroup), (select User from Volunteers where VolunteerID = ROW.Volunteer))", schedule: "null", volunteer: "ROW.Volunteer"}), // either may be gone already when a cascade deletes the membership
+}
+
+// Applies the entries of migrations that have not been applied to the database yet. It must be called after CreateDatabase and every time an existing database is opened.
+func (sm SampleModel) MigrateDatabase() error {
+	tx, err := sm.DB.Begin()
+	if err != nil {
+		return fmt.Errorf("error in MigrateDatabase: sql.DB.Begin error: %w", err)
+	}
+	defer tx.Rollback()
+	var version int
+	err = tx.QueryRow(`pragma user_version`).Scan(&version)
+	if err != nil {
+		return fmt.Errorf("error in MigrateDatabase: sql.Tx.QueryRow error: %w", err)
+	}
+	if version > len(migrations) {
+		return fmt.Errorf("error in MigrateDatabase: method failed because the database schema version (%d) is newer than the latest migration (%d)", version, len(migrations))
+	}
+	for i := version; i < len(migrations); i++ {
+		_, err = tx.Exec(migrations[i])
+		if err != nil {
+			return fmt.Errorf("error in MigrateDatabase: sql.Tx.Exec error: %w. Value of migrations[%d] is `%s`", err, i, migrations[i])
+		}
+	}
+	_, err = tx.Exec(fmt.Sprintf(`pragma user_version = %d`, len(migrations)))
+	if err != nil {
+		return fmt.Errorf("error in MigrateDatabase: sql.Tx.Exec error: %w", err)
+	}
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("error in MigrateDatabase: sql.Tx.Commit error: %w", err)
+	}
+	return nil
+}
+
 func (sm SampleModel) SendScheduleNames(currentUser string) []string {
 	var result []string
 	scheduleStructs, err := sm.RequestSchedules(currentUser, []schedule{})
@@ -277,17 +497,77 @@ func (sm SampleModel) SendScheduleNames(currentUser string) []string {
 	return result
 }
 
-func (sm SampleModel) FetchAndSendData(currentUser string, currentSchedule string) SendReceiveDataStruct {
-	var result SendReceiveDataStruct
-	scheduleQuery := fmt.Sprintf(`select StartDate, EndDate, ShiftsOff, VolunteersPerShift from Schedules where User = "%s" and ScheduleName = "%s"`, currentUser, currentSchedule)
-	fmt.Println(scheduleQuery)
-	result.User = currentUser
-	result.ScheduleName = currentSchedule
-	return result
+// Collects everything stored for currentSchedule into a SendReceiveDataStruct. This is the format RecieveAndStoreData accepts, so a client can edit the result and send it back.
+func (sm SampleModel) FetchAndSendData(currentUser string, currentSchedule string) (SendReceiveDataStruct, error) {
+	scheduleStruct, err := sm.RequestSchedule(currentUser, schedule{ScheduleName: currentSchedule})
+	if err != nil {
+		return SendReceiveDataStruct{}, fmt.Errorf("error in FetchAndSendData: %w", err)
+	}
+	state, err := sm.requestScheduleState(currentUser, scheduleStruct)
+	if err != nil {
+		return SendReceiveDataStruct{}, fmt.Errorf("error in FetchAndSendData: %w", err)
+	}
+	revision, err := sm.RequestScheduleRevision(currentUser, scheduleStruct.ScheduleID)
+	if err != nil {
+		return SendReceiveDataStruct{}, fmt.Errorf("error in FetchAndSendData: %w", err)
+	}
+	result := SendReceiveDataStruct{
+		User:                      currentUser,
+		ScheduleName:              scheduleStruct.ScheduleName,
+		VolunteerAvailabilityData: []map[string][]string{},
+		StartDate:                 isoDate(state.dates[scheduleStruct.StartDate]),
+		EndDate:                   isoDate(state.dates[scheduleStruct.EndDate]),
+		WeekdaysForSchedule:       []string{},
+		ShiftsOff:                 scheduleStruct.ShiftsOff,
+		VolunteersPerShift:        scheduleStruct.VolunteersPerShift,
+		CompletedSchedules:        []string{},
+		Revision:                  revision,
+	}
+	for _, wfs := range state.wfs {
+		result.WeekdaysForSchedule = append(result.WeekdaysForSchedule, wfs.Weekday)
+	}
+	for _, vfs := range state.vfs {
+		unavailableDates := []string{}
+		for _, ufs := range state.ufs[vfs.VFSID] {
+			unavailableDates = append(unavailableDates, isoDate(state.dates[ufs.Date]))
+		}
+		result.VolunteerAvailabilityData = append(result.VolunteerAvailabilityData, map[string][]string{state.volunteerNames[vfs.Volunteer]: unavailableDates})
+	}
+	completedSchedules, err := sm.RequestCompletedSchedules(currentUser, []completedSchedule{{Schedule: scheduleStruct.ScheduleID}})
+	if err != nil {
+		return SendReceiveDataStruct{}, fmt.Errorf("error in FetchAndSendData: %w", err)
+	}
+	for _, val := range completedSchedules {
+		result.CompletedSchedules = append(result.CompletedSchedules, val.ScheduleData)
+	}
+	return result, nil
+}
+
+// Plans the changes needed to store data (see PlanReceivedData) and applies them in a single transaction (see ApplyChangePlan). If data.Revision is stale, the returned error wraps a *RevisionConflictError.
+func (sm SampleModel) RecieveAndStoreData(data SendReceiveDataStruct) error {
+	plan, err := sm.PlanReceivedData(data)
+	if err != nil {
+		return fmt.Errorf("error in RecieveAndStoreData: %w", err)
+	}
+	err = sm.ApplyChangePlan(plan)
+	var conflict *RevisionConflictError
+	if errors.As(err, &conflict) { // the schedule changed between planning and applying, so ApplyChangePlan could not tell which parts diverged
+		conflict.Diverged, _ = sm.divergedParts(data)
+	}
+	if err != nil {
+		return fmt.Errorf("error in RecieveAndStoreData: %w", err)
+	}
+	return nil
 }
 
-func (sm SampleModel) RecieveAndStoreData(data SendReceiveDataStruct) { // should this return a completed/failed value?
-	// fill this in
+func (sm SampleModel) RequestScheduleRevision(currentUser string, scheduleID int) (int, error) {
+	var revision int
+	revisionQuery := fmt.Sprintf(`select Revision from Schedules where %s and ScheduleID = %d`, scheduleAccess("ScheduleID", currentUser, ScheduleViewer), scheduleID)
+	err := sm.DB.QueryRow(revisionQuery).Scan(&revision)
+	if err != nil {
+		return 0, fmt.Errorf("error in RequestScheduleRevision: sql.DB.QueryRow error: %w. Value of revisionQuery is `%s`", err, revisionQuery)
+	}
+	return revision, nil
 }
 
 // This function exists to validate WeekdayName spelling and provide WeekdayID if needed. There is no request Weekdays
@@ -409,8 +689,42 @@ func (sm SampleModel) RequestDates(dates []date) ([]date, error) {
 	return result, nil
 }
 
+// Lists every date from startDate to endDate (both DateIDs), in order.
+func (sm SampleModel) RequestDatesBetween(startDate int, endDate int) ([]date, error) {
+	if startDate < 1 || endDate < startDate {
+		return []date{}, fmt.Errorf("error in RequestDatesBetween: method failed because startDate (%d) must be positive and no later than endDate (%d)", startDate, endDate)
+	}
+	dateQuery := fmt.Sprintf(`select * from Dates where DateID between %d and %d order by DateID`, startDate, endDate)
+	var result []date
+	rows, err := sm.DB.Query(dateQuery)
+	if err != nil {
+		return []date{}, fmt.Errorf("error in RequestDatesBetween: sql.DB.Query error: %w. Value of dateQuery is `%s`", err, dateQuery)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var dateStruct date
+		err = rows.Scan(&dateStruct.DateID, &dateStruct.Month, &dateStruct.Day, &dateStruct.Year, &dateStruct.Weekday)
+		if err != nil {
+			return []date{}, fmt.Errorf("error in RequestDatesBetween: sql.Rows.Scan error: %w. Value of dateStruct is `%+v`", err, dateStruct)
+		}
+		result = append(result, dateStruct)
+	}
+	err = rows.Err()
+	if err != nil {
+		return []date{}, fmt.Errorf("error in RequestDatesBetween: sql.Rows.Err error: %w", err)
+	}
+	if len(result) != endDate-startDate+1 {
+		return []date{}, fmt.Errorf("error in RequestDatesBetween: method failed to locate every date from DateID %d to %d. Found %d dates", startDate, endDate, len(result))
+	}
+	return result, nil
+}
+
 func (sm SampleModel) CreateVolunteers(currentUser string, toCreate []volunteer) error {
-	check, err := sm.RequestVolunteers(currentUser, toCreate)
+	checkNames := []volunteer{}
+	for _, val := range toCreate {
+		checkNames = append(checkNames, volunteer{VolunteerName: val.VolunteerName})
+	}
+	check, err := sm.RequestVolunteers(currentUser, checkNames)
 	if err != nil {
 		return fmt.Errorf("error in CreateVolunteers: %w", err)
 	}
@@ -422,25 +736,29 @@ func (sm SampleModel) CreateVolunteers(currentUser string, toCreate []volunteer)
 		if val.VolunteerName == (volunteer{}.VolunteerName) {
 			return fmt.Errorf("error in CreateVolunteers: method failed because at least one of the volunteer structs in toCreate did not have a value for VolunteerName: %+v", val)
 		}
+		err = validateVolunteerProfile(val)
+		if err != nil {
+			return fmt.Errorf("error in CreateVolunteers: %w", err)
+		}
 		if !slices.Contains(checkDuplicates, volunteer{VolunteerName: val.VolunteerName}) {
 			checkDuplicates = append(checkDuplicates, volunteer{VolunteerName: val.VolunteerName})
 		} else {
 			return fmt.Errorf("error in CreateVolunteers: method failed because at least one of the volunteer structs in toCreate was a duplicate of another volunteer struct in toCreate: %+v", val)
 		}
 	}
-	tx, err := sm.DB.Begin()
+	tx, err := sm.beginAudited(currentUser)
 	if err != nil {
-		return fmt.Errorf("error in CreateVolunteers: sql.DB.Begin error: %w", err)
+		return fmt.Errorf("error in CreateVolunteers: %w", err)
 	}
 	defer tx.Rollback()
-	fillVolunteersTableString := `insert into Volunteers (VolunteerName, User) values (?, ?)`
+	fillVolunteersTableString := `insert into Volunteers (VolunteerName, User, Email, Phone, PreferredContact, Notes, Active, DeactivatedAt) values (?, ?, ?, ?, ?, ?, ?, case when ? then unixepoch() end)`
 	fillVolunteersTableStmt, err := tx.Prepare(fillVolunteersTableString)
 	if err != nil {
 		return fmt.Errorf("error in CreateVolunteers: sql.Tx.Prepare error: %w. Value of fillVolunteersTableString is `%s`", err, fillVolunteersTableString)
 	}
 	defer fillVolunteersTableStmt.Close()
 	for i := 0; i < len(toCreate); i++ {
-		_, err = fillVolunteersTableStmt.Exec(toCreate[i].VolunteerName, currentUser)
+		_, err = fillVolunteersTableStmt.Exec(toCreate[i].VolunteerName, currentUser, toCreate[i].Email, toCreate[i].Phone, toCreate[i].PreferredContact, toCreate[i].Notes, !toCreate[i].Inactive, toCreate[i].Inactive)
 		if err != nil {
 			return fmt.Errorf("error in CreateVolunteers: sql.Stmt.Exec error: %w. toCreate[i] is `%+v`", err, toCreate[i])
 		}
@@ -452,6 +770,34 @@ func (sm SampleModel) CreateVolunteers(currentUser string, toCreate []volunteer)
 	return nil
 }
 
+// The values PreferredContact may have, and which field must be set for each.
+var volunteerContactFields = map[string]func(volunteer) string{
+	"email": func(val volunteer) string { return val.Email },
+	"phone": func(val volunteer) string { return val.Phone },
+	"sms":   func(val volunteer) string { return val.Phone },
+}
+
+// Checks the profile fields of val: Email must be a bare address, and PreferredContact must name a way val can be contacted.
+func validateVolunteerProfile(val volunteer) error {
+	if len(val.Email) > 0 {
+		address, err := mail.ParseAddress(val.Email)
+		if err != nil || address.Address != val.Email {
+			return fmt.Errorf("error in validateVolunteerProfile: method failed because Email `%s` of volunteer `%s` is not an email address", val.Email, val.VolunteerName)
+		}
+	}
+	if len(val.PreferredContact) == 0 {
+		return nil
+	}
+	field, ok := volunteerContactFields[val.PreferredContact]
+	if !ok {
+		return fmt.Errorf("error in validateVolunteerProfile: method failed because PreferredContact `%s` of volunteer `%s` is not one of email, phone, or sms", val.PreferredContact, val.VolunteerName)
+	}
+	if len(field(val)) == 0 {
+		return fmt.Errorf("error in validateVolunteerProfile: method failed because PreferredContact of volunteer `%s` is %s, but the volunteer has no contact details for it", val.VolunteerName, val.PreferredContact)
+	}
+	return nil
+}
+
 func (sm SampleModel) RequestVolunteer(currentUser string, volunteerStruct volunteer) (volunteer, error) {
 	volunteers, err := sm.RequestVolunteers(currentUser, []volunteer{volunteerStruct})
 	if err != nil {
@@ -463,16 +809,35 @@ func (sm SampleModel) RequestVolunteer(currentUser string, volunteerStruct volun
 	return volunteers[0], nil
 }
 
+// Returns the volunteers of currentUser matching any of volunteers, or all of them if volunteers is empty, including inactive ones so names on past schedules can still be resolved. See RequestActiveVolunteers for lists of volunteers to choose from.
 func (sm SampleModel) RequestVolunteers(currentUser string, volunteers []volunteer) ([]volunteer, error) {
-	volunteersQuery := fmt.Sprintf(`select * from Volunteers where User = "%s"`, currentUser)
+	return sm.requestVolunteersWhere("RequestVolunteers", fmt.Sprintf(`User = "%s"`, currentUser), volunteers)
+}
+
+// Like RequestVolunteers, but leaves out the volunteers that were deactivated with DeleteVolunteers.
+func (sm SampleModel) RequestActiveVolunteers(currentUser string, volunteers []volunteer) ([]volunteer, error) {
+	return sm.requestVolunteersWhere("RequestActiveVolunteers", fmt.Sprintf(`User = "%s" and Active = 1`, currentUser), volunteers)
+}
+
+// Does the work of RequestVolunteers and its organization-scoped variants for the volunteers that also match the SQL condition scope. method names the caller in errors.
+func (sm SampleModel) requestVolunteersWhere(method string, scope string, volunteers []volunteer) ([]volunteer, error) {
+	volunteersQuery := fmt.Sprintf(`select VolunteerID, VolunteerName, User, Email, Phone, PreferredContact, Notes, Active, DeactivatedAt from Volunteers where %s`, scope)
 	if len(volunteers) > 0 {
-		if check, failed := testEmpty(volunteers, volunteer{}); check {
-			return []volunteer{}, fmt.Errorf("error in RequestVolunteers: method failed because one of the values in volunteers had an empty/default values volunteer struct: %+v", failed)
+		filters := slices.Clone(volunteers)
+		for i := range filters {
+			filters[i].DeactivatedAt = nil // ignored by filters, so a struct with only DeactivatedAt set filters nothing
+		}
+		if check, failed := testEmpty(filters, volunteer{}); check {
+			return []volunteer{}, fmt.Errorf("error in %s: method failed because one of the values in volunteers had an empty/default values volunteer struct: %+v", method, failed)
 		}
 		volunteersQuery = fmt.Sprintf(`%s and (`, volunteersQuery)
 	}
 	for i := 0; i < len(volunteers); i++ {
-		count := countGTZero([]int{volunteers[i].VolunteerID, len(volunteers[i].VolunteerName), len(volunteers[i].User)})
+		var inactive int
+		if volunteers[i].Inactive {
+			inactive = 1
+		}
+		count := countGTZero([]int{volunteers[i].VolunteerID, len(volunteers[i].VolunteerName), len(volunteers[i].User), len(volunteers[i].Email), len(volunteers[i].Phone), len(volunteers[i].PreferredContact), len(volunteers[i].Notes), inactive})
 		// count must be at least 1 because the testEmpty check passed
 		//fmt.Println(count)
 		volunteersQuery = fmt.Sprintf(`%s(`, volunteersQuery)
@@ -492,6 +857,42 @@ func (sm SampleModel) RequestVolunteers(currentUser string, volunteers []volunte
 		}
 		if len(volunteers[i].User) > 0 {
 			volunteersQuery = fmt.Sprintf(`%sUser = "%s"`, volunteersQuery, volunteers[i].User)
+			count--
+			if count > 0 {
+				volunteersQuery = fmt.Sprintf(`%s and `, volunteersQuery)
+			}
+		}
+		// The profile fields are free text, so their quotes are escaped
+		if len(volunteers[i].Email) > 0 {
+			volunteersQuery = fmt.Sprintf(`%sEmail = "%s"`, volunteersQuery, strings.ReplaceAll(volunteers[i].Email, `"`, `""`))
+			count--
+			if count > 0 {
+				volunteersQuery = fmt.Sprintf(`%s and `, volunteersQuery)
+			}
+		}
+		if len(volunteers[i].Phone) > 0 {
+			volunteersQuery = fmt.Sprintf(`%sPhone = "%s"`, volunteersQuery, strings.ReplaceAll(volunteers[i].Phone, `"`, `""`))
+			count--
+			if count > 0 {
+				volunteersQuery = fmt.Sprintf(`%s and `, volunteersQuery)
+			}
+		}
+		if len(volunteers[i].PreferredContact) > 0 {
+			volunteersQuery = fmt.Sprintf(`%sPreferredContact = "%s"`, volunteersQuery, strings.ReplaceAll(volunteers[i].PreferredContact, `"`, `""`))
+			count--
+			if count > 0 {
+				volunteersQuery = fmt.Sprintf(`%s and `, volunteersQuery)
+			}
+		}
+		if len(volunteers[i].Notes) > 0 {
+			volunteersQuery = fmt.Sprintf(`%sNotes = "%s"`, volunteersQuery, strings.ReplaceAll(volunteers[i].Notes, `"`, `""`))
+			count--
+			if count > 0 {
+				volunteersQuery = fmt.Sprintf(`%s and `, volunteersQuery)
+			}
+		}
+		if volunteers[i].Inactive {
+			volunteersQuery = fmt.Sprintf(`%sActive = 0`, volunteersQuery)
 		}
 		volunteersQuery = fmt.Sprintf(`%s)`, volunteersQuery)
 		if i+1 < len(volunteers) {
@@ -507,24 +908,32 @@ func (sm SampleModel) RequestVolunteers(currentUser string, volunteers []volunte
 	var result []volunteer
 	rows, err := sm.DB.Query(volunteersQuery)
 	if err != nil {
-		return []volunteer{}, fmt.Errorf("error in RequestVolunteers: sql.DB.Query error: %w. Value of volunteersQuery is `%s`", err, volunteersQuery)
+		return []volunteer{}, fmt.Errorf("error in %s: sql.DB.Query error: %w. Value of volunteersQuery is `%s`", method, err, volunteersQuery)
 	}
 	defer rows.Close()
 	for rows.Next() {
 		var volunteerStruct volunteer
-		err = rows.Scan(&volunteerStruct.VolunteerID, &volunteerStruct.VolunteerName, &volunteerStruct.User)
+		var active bool
+		var deactivatedAt sql.NullInt64
+		err = rows.Scan(&volunteerStruct.VolunteerID, &volunteerStruct.VolunteerName, &volunteerStruct.User, &volunteerStruct.Email, &volunteerStruct.Phone, &volunteerStruct.PreferredContact, &volunteerStruct.Notes, &active, &deactivatedAt)
 		if err != nil {
-			return []volunteer{}, fmt.Errorf("error in RequestVolunteers: sql.Rows.Scan error: %w. Value of volunteerStruct is `%+v`", err, volunteerStruct)
+			return []volunteer{}, fmt.Errorf("error in %s: sql.Rows.Scan error: %w. Value of volunteerStruct is `%+v`", method, err, volunteerStruct)
+		}
+		volunteerStruct.Inactive = !active
+		if deactivatedAt.Valid {
+			deactivated := time.Unix(deactivatedAt.Int64, 0)
+			volunteerStruct.DeactivatedAt = &deactivated
 		}
 		result = append(result, volunteerStruct)
 	}
 	err = rows.Err()
 	if err != nil {
-		return []volunteer{}, fmt.Errorf("error in RequestVolunteers: sql.Rows.Err error: %w", err)
+		return []volunteer{}, fmt.Errorf("error in %s: sql.Rows.Err error: %w", method, err)
 	}
 	return result, nil
 }
 
+// Updates the volunteers picked by the VolunteerID of each struct in toUpdate. Only fields with a value other than their zero value are changed, so a rename keeps the profile. Inactive and DeactivatedAt are ignored; use DeleteVolunteers and ReactivateVolunteers, or replaceVolunteerProfile to clear profile fields.
 func (sm SampleModel) UpdateVolunteers(currentUser string, toUpdate []volunteer) error {
 	if check, failed := testEmpty(toUpdate, volunteer{}); check {
 		return fmt.Errorf("error in UpdateVolunteers: method failed because one of the values in toUpdate had an empty/default values volunteer struct: %+v", failed)
@@ -533,35 +942,63 @@ func (sm SampleModel) UpdateVolunteers(currentUser string, toUpdate []volunteer)
 	for _, val := range toUpdate {
 		if val.VolunteerID == (volunteer{}.VolunteerID) { // User does not need to be provided in the volunteer struct
 			return fmt.Errorf("error in UpdateVolunteers: method failed because one of the volunteer structs in toUpdate had an empty/default value for VolunteerID: %+v", val)
-		} else if val.VolunteerName == (volunteer{}.VolunteerName) {
-			return fmt.Errorf("error in UpdateVolunteers: method failed because one of the volunteer structs in toUpdate had an empty/default value for VolunteerName: %+v", val)
-		} else if check, err := sm.RequestVolunteers(currentUser, []volunteer{{VolunteerName: val.VolunteerName, User: currentUser}}); len(check) > 0 {
-			if err != nil {
+		}
+		if countGTZero([]int{len(val.VolunteerName), len(val.Email), len(val.Phone), len(val.PreferredContact), len(val.Notes)}) == 0 {
+			return fmt.Errorf("error in UpdateVolunteers: method failed because only a VolunteerID was provided in a volunteer struct. At least two values (a VolunteerID and a value to update) must be provided: %+v", val)
+		}
+		current, err := sm.RequestVolunteer(currentUser, volunteer{VolunteerID: val.VolunteerID})
+		if err != nil {
+			return fmt.Errorf("error in UpdateVolunteers: %w", err)
+		}
+		if len(val.VolunteerName) > 0 {
+			if check, err := sm.RequestVolunteers(currentUser, []volunteer{{VolunteerName: val.VolunteerName, User: currentUser}}); err != nil {
 				return fmt.Errorf("error in UpdateVolunteers `%+v`: %w", val, err)
+			} else if len(check) > 0 && check[0].VolunteerID != val.VolunteerID {
+				return fmt.Errorf("error in UpdateVolunteers: method failed because one of the volunteer structs in toUpdate would create a duplicate volunteer (each volunteer name must be unique per user): %+v", val)
 			}
-			return fmt.Errorf("error in UpdateVolunteers: method failed because one of the volunteer structs in toUpdate would create a duplicate volunteer (each volunteer name must be unique per user): %+v", val)
+			if !slices.Contains(checkDuplicates, volunteer{VolunteerName: val.VolunteerName}) {
+				checkDuplicates = append(checkDuplicates, volunteer{VolunteerName: val.VolunteerName})
+			} else {
+				return fmt.Errorf("error in UpdateVolunteers: method failed because at least two of the volunteer structs in toUpdate would create duplicate volunteer structs in the database: %+v", volunteer{VolunteerName: val.VolunteerName})
+			}
+			current.VolunteerName = val.VolunteerName
 		}
-		if !slices.Contains(checkDuplicates, volunteer{VolunteerName: val.VolunteerName}) {
-			checkDuplicates = append(checkDuplicates, volunteer{VolunteerName: val.VolunteerName})
-		} else {
-			return fmt.Errorf("error in UpdateVolunteers: method failed because at least two of the volunteer structs in toUpdate would create duplicate volunteer structs in the database: %+v", volunteer{VolunteerName: val.VolunteerName})
+		// the profile is validated as it will be stored, so setting PreferredContact alone works if the contact details are there already
+		if len(val.Email) > 0 {
+			current.Email = val.Email
+		}
+		if len(val.Phone) > 0 {
+			current.Phone = val.Phone
+		}
+		if len(val.PreferredContact) > 0 {
+			current.PreferredContact = val.PreferredContact
+		}
+		if len(val.Notes) > 0 {
+			current.Notes = val.Notes
+		}
+		err = validateVolunteerProfile(current)
+		if err != nil {
+			return fmt.Errorf("error in UpdateVolunteers: %w", err)
 		}
 	}
-	tx, err := sm.DB.Begin()
+	tx, err := sm.beginAudited(currentUser)
 	if err != nil {
-		return fmt.Errorf("error in UpdateVolunteers: sql.DB.Begin error: %w", err)
+		return fmt.Errorf("error in UpdateVolunteers: %w", err)
 	}
 	defer tx.Rollback()
-	updateVolunteersString := fmt.Sprintf(`update Volunteers set VolunteerName=? where User="%s" and VolunteerID=?`, currentUser)
-	updateVolunteersStmt, err := tx.Prepare(updateVolunteersString)
-	if err != nil {
-		return fmt.Errorf("error in UpdateVolunteers: sql.Tx.Prepare error: %w. value of updateVolunteersString is `%s`", err, updateVolunteersString)
-	}
-	defer updateVolunteersStmt.Close()
 	for _, val := range toUpdate {
-		_, err = updateVolunteersStmt.Exec(val.VolunteerName, val.VolunteerID)
+		var assignments []string
+		var args []any
+		for _, field := range []struct{ column, value string }{{"VolunteerName", val.VolunteerName}, {"Email", val.Email}, {"Phone", val.Phone}, {"PreferredContact", val.PreferredContact}, {"Notes", val.Notes}} {
+			if len(field.value) > 0 {
+				assignments = append(assignments, fmt.Sprintf(`%s=?`, field.column))
+				args = append(args, field.value)
+			}
+		}
+		updateVolunteerString := fmt.Sprintf(`update Volunteers set %s where User=? and VolunteerID=?`, strings.Join(assignments, ", "))
+		_, err = tx.Exec(updateVolunteerString, append(args, currentUser, val.VolunteerID)...)
 		if err != nil {
-			return fmt.Errorf("error in UpdateVolunteers: sql.Stmt.Exec error: %w. Value of val is `%+v`", err, val)
+			return fmt.Errorf("error in UpdateVolunteers: sql.Tx.Exec error: %w. Value of updateVolunteerString is `%s`", err, updateVolunteerString)
 		}
 	}
 	err = tx.Commit()
@@ -571,47 +1008,90 @@ func (sm SampleModel) UpdateVolunteers(currentUser string, toUpdate []volunteer)
 	return nil
 }
 
-// Will delete Volunteers database entries that match the VolunteerID or that match the VolunteerName provided in each volunteer struct. If a VolunteerID > 0 is provided, the value for VolunteerName is ignored for that volunteer struct.
+// Stores the Email, Phone, PreferredContact, and Notes of toReplace as given, empty ones included, on the volunteer with its VolunteerID. It's for callers that request a volunteer, change it, and write it back, since UpdateVolunteers can't clear a field.
+func (sm SampleModel) replaceVolunteerProfile(currentUser string, toReplace volunteer) error {
+	err := validateVolunteerProfile(toReplace)
+	if err != nil {
+		return fmt.Errorf("error in replaceVolunteerProfile: %w", err)
+	}
+	res, err := sm.execAudited(currentUser, `update Volunteers set Email=?, Phone=?, PreferredContact=?, Notes=? where User=? and VolunteerID=?`,
+		toReplace.Email, toReplace.Phone, toReplace.PreferredContact, toReplace.Notes, currentUser, toReplace.VolunteerID)
+	if err != nil {
+		return fmt.Errorf("error in replaceVolunteerProfile: %w", err)
+	}
+	count, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error in replaceVolunteerProfile: sql.Result.RowsAffected error: %w", err)
+	}
+	if count == 0 {
+		return fmt.Errorf("error in replaceVolunteerProfile: method failed to locate VolunteerID %d among the volunteers of user `%s`", toReplace.VolunteerID, currentUser)
+	}
+	return nil
+}
+
+// Will deactivate the Volunteers database entries that match the VolunteerID or that match the VolunteerName provided in each volunteer struct. If a VolunteerID > 0 is provided, the value for VolunteerName is ignored for that volunteer struct. Rows are kept, so schedules the volunteers are on still resolve their names, but RequestActiveVolunteers leaves them out and they can't be added to schedules until ReactivateVolunteers is called.
 func (sm SampleModel) DeleteVolunteers(currentUser string, toDelete []volunteer) error {
-	if check, failed := testEmpty(toDelete, volunteer{}); check {
-		return fmt.Errorf("error in DeleteVolunteers: method failed because one of the values in toDelete had an empty/default values volunteer struct: %+v", failed)
+	err := sm.setVolunteersActive("DeleteVolunteers", currentUser, toDelete, false)
+	if err != nil {
+		return err
 	}
-	for _, val := range toDelete {
+	return nil
+}
+
+// Undoes DeleteVolunteers for the volunteers matching toReactivate, which are picked the same way.
+func (sm SampleModel) ReactivateVolunteers(currentUser string, toReactivate []volunteer) error {
+	err := sm.setVolunteersActive("ReactivateVolunteers", currentUser, toReactivate, true)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (sm SampleModel) setVolunteersActive(method string, currentUser string, toChange []volunteer, active bool) error {
+	if check, failed := testEmpty(toChange, volunteer{}); check {
+		return fmt.Errorf("error in %s: method failed because one of the values in toChange had an empty/default values volunteer struct: %+v", method, failed)
+	}
+	for _, val := range toChange {
 		if val.VolunteerID == (volunteer{}.VolunteerID) && val.VolunteerName == (volunteer{}.VolunteerName) { // User does not need to be provided in the volunteer struct. One of VolunteerID and VolunteerName must be provided
-			return fmt.Errorf("error in DeleteVolunteers: method failed because one of the volunteer structs in toDelete had empty/default values for VolunteerID and VolunteerName (at least one must be provided): %+v", val)
+			return fmt.Errorf("error in %s: method failed because one of the volunteer structs in toChange had empty/default values for VolunteerID and VolunteerName (at least one must be provided): %+v", method, val)
 		}
 	}
-	tx, err := sm.DB.Begin()
+	tx, err := sm.beginAudited(currentUser)
 	if err != nil {
-		return fmt.Errorf("error in DeleteVolunteers: sql.DB.Begin error: %w", err)
+		return fmt.Errorf("error in %s: %w", method, err)
 	}
 	defer tx.Rollback()
-	for _, val := range toDelete {
-		var deleteVolunteerString string
+	setActiveString := `update Volunteers set Active = 0, DeactivatedAt = unixepoch() where Active = 1`
+	if active {
+		setActiveString = `update Volunteers set Active = 1, DeactivatedAt = null where Active = 0`
+	}
+	for _, val := range toChange {
+		var setVolunteerString string
 		if val.VolunteerID > 0 {
-			deleteVolunteerString = fmt.Sprintf(`delete from Volunteers where User="%s" and VolunteerID=%d`, currentUser, val.VolunteerID)
+			setVolunteerString = fmt.Sprintf(`%s and User="%s" and VolunteerID=%d`, setActiveString, currentUser, val.VolunteerID)
 		} else {
-			deleteVolunteerString = fmt.Sprintf(`delete from Volunteers where User="%s" and VolunteerName="%s"`, currentUser, val.VolunteerName)
+			setVolunteerString = fmt.Sprintf(`%s and User="%s" and VolunteerName="%s"`, setActiveString, currentUser, val.VolunteerName)
 		}
-		_, err := tx.Exec(deleteVolunteerString)
+		_, err := tx.Exec(setVolunteerString)
 		if err != nil {
-			return fmt.Errorf("error in DeleteVolunteers: sql.Tx.Exec error %w. Value of deleteVolunteerString is `%s`", err, deleteVolunteerString)
+			return fmt.Errorf("error in %s: sql.Tx.Exec error %w. Value of setVolunteerString is `%s`", method, err, setVolunteerString)
 		}
 	}
 	err = tx.Commit()
 	if err != nil {
-		return fmt.Errorf("error in DeleteVolunteers: sql.Tx.Commit error: %w", err)
+		return fmt.Errorf("error in %s: sql.Tx.Commit error: %w", method, err)
 	}
 	return nil
 }
 
+// Deactivates the active volunteers of currentUser that are not on any schedule, like DeleteVolunteers.
 func (sm SampleModel) CleanOrphanedVolunteers(currentUser string) error {
-	tx, err := sm.DB.Begin()
+	tx, err := sm.beginAudited(currentUser)
 	if err != nil {
-		return fmt.Errorf("error in CleanOrphanedVolunteers: sql.DB.Begin error: %w", err)
+		return fmt.Errorf("error in CleanOrphanedVolunteers: %w", err)
 	}
 	defer tx.Rollback()
-	cleanOrphanedVolunteersString := fmt.Sprintf(`delete from Volunteers where User = "%s" and VolunteerID not in (select Volunteer from VolunteersForSchedule)`, currentUser)
+	cleanOrphanedVolunteersString := fmt.Sprintf(`update Volunteers set Active = 0, DeactivatedAt = unixepoch() where User = "%s" and Active = 1 and VolunteerID not in (select Volunteer from VolunteersForSchedule)`, currentUser)
 	_, err = tx.Exec(cleanOrphanedVolunteersString)
 	if err != nil {
 		return fmt.Errorf("error in CleanOrphanedVolunteers: sql.Tx.Exec error: %w. Value of cleanOrphanedVolunteersString is `%s`", err, cleanOrphanedVolunteersString)
@@ -663,9 +1143,9 @@ func (sm SampleModel) CreateSchedulesExtended(currentUser string, toCreate []sch
 			return fmt.Errorf("error in CreateSchedulesExtended: method failed because at least one of the schedule structs in toCreate was a duplicate of another schedule struct in toCreate: %+v", val)
 		}
 	}
-	tx, err := sm.DB.Begin()
+	tx, err := sm.beginAudited(currentUser)
 	if err != nil {
-		return fmt.Errorf("error in CreateSchedulesExtended: sql.DB.Begin error: %w", err)
+		return fmt.Errorf("error in CreateSchedulesExtended: %w", err)
 	}
 	defer tx.Rollback()
 	fillSchedulesTableString := `insert into Schedules (ScheduleName, ShiftsOff, VolunteersPerShift, User, StartDate, EndDate) values (?, ?, ?, ?, ?, ?)`
@@ -693,12 +1173,27 @@ func (sm SampleModel) RequestSchedule(currentUser string, scheduleStruct schedul
 	if err != nil {
 		return schedule{}, fmt.Errorf("error in RequestSchedule: %w", err)
 	}
+	schedules = preferOwnedSchedules(currentUser, schedules)
 	if len(schedules) != 1 {
 		return schedule{}, fmt.Errorf("error in RequestSchedule: method failed to locate exactly one schedule matching %+v. Found %d matches", scheduleStruct, len(schedules))
 	}
 	return schedules[0], nil
 }
 
+// A name only identifies a schedule among those its owner has; a shared schedule may carry the same name as one currentUser owns. When exactly one of the matches is owned by currentUser, it's the one meant and is returned alone.
+func preferOwnedSchedules(currentUser string, schedules []schedule) []schedule {
+	var owned []schedule
+	for _, val := range schedules {
+		if val.User == currentUser {
+			owned = append(owned, val)
+		}
+	}
+	if len(owned) != 1 {
+		return schedules
+	}
+	return owned
+}
+
 // This function is the simple version of RequestSchedulesExtended and does not allow ShiftsOff = 0 to be queried.
 func (sm SampleModel) RequestSchedules(currentUser string, schedules []schedule) ([]schedule, error) {
 	retrievedSchedules, err := sm.RequestSchedulesExtended(currentUser, schedules, false)
@@ -710,7 +1205,7 @@ func (sm SampleModel) RequestSchedules(currentUser string, schedules []schedule)
 
 // This version of RequestSchedules allows ShiftsOff = 0 to be queried, but any default schedule structs will have ShiftsOff: 0 implicitly, so ShiftsOff must be set to a desired value or to -1 to be ignored.
 func (sm SampleModel) RequestSchedulesExtended(currentUser string, schedules []schedule, includeShiftsOff0 bool) ([]schedule, error) {
-	schedulesQuery := fmt.Sprintf(`select * from Schedules where User = "%s"`, currentUser)
+	schedulesQuery := fmt.Sprintf(`select ScheduleID, ScheduleName, ShiftsOff, VolunteersPerShift, User, StartDate, EndDate from Schedules where %s`, scheduleAccess("ScheduleID", currentUser, ScheduleViewer))
 	if !includeShiftsOff0 { // I have to check for this edge case
 		for _, val := range schedules {
 			if val.ShiftsOff <= -1 {
@@ -834,10 +1329,10 @@ func (sm SampleModel) UpdateSchedulesExtended(currentUser string, toUpdate []sch
 		return fmt.Errorf("error in UpdateSchedulesExtended: method failed because one of the values in toUpdate had an empty/default values schedule struct: %+v", failed)
 	}
 	head := `update Schedules set`
-	tail := fmt.Sprintf(`where User="%s" and ScheduleID=?`, currentUser)
-	tx, err := sm.DB.Begin()
+	tail := fmt.Sprintf(`where %s and ScheduleID=?`, scheduleAccess("ScheduleID", currentUser, ScheduleEditor))
+	tx, err := sm.beginAudited(currentUser)
 	if err != nil {
-		return fmt.Errorf("error in UpdateSchedulesExtended: sql.DB.Begin error: %w", err)
+		return fmt.Errorf("error in UpdateSchedulesExtended: %w", err)
 	}
 	defer tx.Rollback()
 	checkDuplicates := []schedule{}
@@ -845,7 +1340,7 @@ func (sm SampleModel) UpdateSchedulesExtended(currentUser string, toUpdate []sch
 		if val.ScheduleID == 0 {
 			return fmt.Errorf("error in UpdateSchedulesExtended: method failed because one of the values in toUpdate had an empty/default value for ScheduleID: %+v", val)
 		}
-		currentSchedule, err := sm.RequestSchedule(currentUser, schedule{ScheduleID: val.ScheduleID})
+		currentSchedule, err := sm.authorizeSchedule(currentUser, val.ScheduleID, ScheduleEditor)
 		if err != nil {
 			return fmt.Errorf("error in UpdateSchedulesExtended: %w", err)
 		}
@@ -919,7 +1414,7 @@ func (sm SampleModel) UpdateSchedulesExtended(currentUser string, toUpdate []sch
 		updateSchedulesString = fmt.Sprintf(`%s %s`, updateSchedulesString, tail)
 		//fmt.Println(count)
 		//fmt.Println(updateSchedulesString)
-		if check, err := sm.RequestSchedulesExtended(currentUser, []schedule{currentSchedule}, includeShiftsOff0); err != nil {
+		if check, err := sm.RequestSchedulesExtended(currentSchedule.User, []schedule{currentSchedule}, includeShiftsOff0); err != nil { // duplicates are checked among the owner's schedules
 			return fmt.Errorf("error in UpdateSchedulesExtended: %w", err)
 		} else if len(check) > 0 {
 			return fmt.Errorf("error in UpdateSchedulesExtended: method failed because it would create a duplicate schedule: %+v", val)
@@ -942,23 +1437,24 @@ func (sm SampleModel) UpdateSchedulesExtended(currentUser string, toUpdate []sch
 }
 
 // Will delete Schedule database entries that match the ScheduleID or that match the ScheduleName provided in each schedule struct. If a ScheduleID > 0 is provided, the value for ScheduleName is ignored for that schedule struct.
+// Only schedules currentUser has the owner role on are deleted, see ScheduleMembers.
 func (sm SampleModel) DeleteSchedules(currentUser string, toDelete []schedule) error {
 	for _, val := range toDelete {
 		if val.ScheduleID < 1 && len(val.ScheduleName) == 0 {
 			return fmt.Errorf("error in DeleteSchedules: method failed because one of the schedule structs did not have a value for ScheduleID or ScheduleName (at least one must be provided): %+v", val)
 		}
 	}
-	tx, err := sm.DB.Begin()
+	tx, err := sm.beginAudited(currentUser)
 	if err != nil {
-		return fmt.Errorf("error in DeleteSchedules: sql.DB.Begin error: %w", err)
+		return fmt.Errorf("error in DeleteSchedules: %w", err)
 	}
 	defer tx.Rollback()
 	for _, val := range toDelete {
 		var deleteScheduleString string
 		if val.ScheduleID > 0 {
-			deleteScheduleString = fmt.Sprintf(`delete from Schedules where User="%s" and ScheduleID=%d`, currentUser, val.ScheduleID)
+			deleteScheduleString = fmt.Sprintf(`delete from Schedules where %s and ScheduleID=%d`, scheduleAccess("ScheduleID", currentUser, ScheduleOwner), val.ScheduleID)
 		} else {
-			deleteScheduleString = fmt.Sprintf(`delete from Schedules where User="%s" and ScheduleName="%s"`, currentUser, val.ScheduleName)
+			deleteScheduleString = fmt.Sprintf(`delete from Schedules where %s and ScheduleName="%s"`, scheduleAccess("ScheduleID", currentUser, ScheduleOwner), val.ScheduleName)
 		}
 		_, err := tx.Exec(deleteScheduleString)
 		if err != nil {
@@ -981,6 +1477,8 @@ func (sm SampleModel) CreateWFS(currentUser string, toCreate []weekdayForSchedul
 		return fmt.Errorf("error in CreateWFS: method failed because at least one of the weekdayForSchedule entries to be created already exists in the database. Existing weekdayForSchedule(s): %+v", check)
 	}
 	checkDuplicates := []weekdayForSchedule{}
+	// ScheduleID to the User new rows are stored under
+	owners := map[int]string{}
 	for _, val := range toCreate { // User and WFSID do not need to be provided in the weekdayForSchedule structs
 		if val.Weekday == (weekdayForSchedule{}.Weekday) {
 			return fmt.Errorf("error in CreateWFS: method failed because at least one of the weekdayForSchedule structs in toCreate did not have a value for Weekday: %+v", val)
@@ -993,10 +1491,15 @@ func (sm SampleModel) CreateWFS(currentUser string, toCreate []weekdayForSchedul
 		} else {
 			return fmt.Errorf("error in CreateWFS: method failed because at least one of the weekdayForSchedule structs in toCreate was a duplicate of another weekdayForSchedule struct in toCreate: %+v", val)
 		}
+		scheduleStruct, err := sm.authorizeSchedule(currentUser, val.Schedule, ScheduleEditor)
+		if err != nil {
+			return fmt.Errorf("error in CreateWFS: %w", err)
+		}
+		owners[val.Schedule] = scheduleStruct.User
 	}
-	tx, err := sm.DB.Begin()
+	tx, err := sm.beginAudited(currentUser)
 	if err != nil {
-		return fmt.Errorf("error in CreateWFS: sql.DB.Begin error: %w", err)
+		return fmt.Errorf("error in CreateWFS: %w", err)
 	}
 	defer tx.Rollback()
 	fillWFSTableString := `insert into WeekdaysForSchedule (User, Weekday, Schedule) values (?, ?, ?)`
@@ -1006,7 +1509,7 @@ func (sm SampleModel) CreateWFS(currentUser string, toCreate []weekdayForSchedul
 	}
 	defer fillWFSTableStmt.Close()
 	for i := 0; i < len(toCreate); i++ {
-		_, err = fillWFSTableStmt.Exec(currentUser, toCreate[i].Weekday, toCreate[i].Schedule)
+		_, err = fillWFSTableStmt.Exec(owners[toCreate[i].Schedule], toCreate[i].Weekday, toCreate[i].Schedule)
 		if err != nil {
 			return fmt.Errorf("error in CreateWFS: sql.Stmt.Exec error: %w. Value of toCreate[i] is `%+v`", err, toCreate[i])
 		}
@@ -1030,7 +1533,7 @@ func (sm SampleModel) RequestWFSSingle(currentUser string, volunteerForScheduleS
 }
 
 func (sm SampleModel) RequestWFS(currentUser string, weekdaysForSchedule []weekdayForSchedule) ([]weekdayForSchedule, error) {
-	weekdaysForScheduleQuery := fmt.Sprintf(`select * from WeekdaysForSchedule where User = "%s"`, currentUser)
+	weekdaysForScheduleQuery := fmt.Sprintf(`select * from WeekdaysForSchedule where %s`, scheduleAccess("Schedule", currentUser, ScheduleViewer))
 	if len(weekdaysForSchedule) > 0 {
 		if check, failed := testEmpty(weekdaysForSchedule, weekdayForSchedule{}); check {
 			return []weekdayForSchedule{}, fmt.Errorf("error in RequestWFS: method failed because one of the values in weekdaysForSchedule had an empty/default values weekdayForSchedule struct: %+v", failed)
@@ -1103,10 +1606,10 @@ func (sm SampleModel) UpdateWFS(currentUser string, toUpdate []weekdayForSchedul
 		return fmt.Errorf("error in UpdateWFS: method failed because one of the values in toUpdate had an empty/default values weekdayForSchedule struct: %+v", failed)
 	}
 	head := `update WeekdaysForSchedule set`
-	tail := fmt.Sprintf(`where User="%s" and WFSID=?`, currentUser)
-	tx, err := sm.DB.Begin()
+	tail := fmt.Sprintf(`where %s and WFSID=?`, scheduleAccess("Schedule", currentUser, ScheduleEditor))
+	tx, err := sm.beginAudited(currentUser)
 	if err != nil {
-		return fmt.Errorf("error in UpdateWFS: sql.DB.Begin error: %w", err)
+		return fmt.Errorf("error in UpdateWFS: %w", err)
 	}
 	defer tx.Rollback()
 	checkDuplicates := []weekdayForSchedule{}
@@ -1118,6 +1621,13 @@ func (sm SampleModel) UpdateWFS(currentUser string, toUpdate []weekdayForSchedul
 		if err != nil {
 			return fmt.Errorf("error in UpdateWFS: %w", err)
 		}
+		for _, scheduleID := range []int{currentWFS.Schedule, val.Schedule} {
+			if scheduleID > 0 {
+				if _, err := sm.authorizeSchedule(currentUser, scheduleID, ScheduleEditor); err != nil {
+					return fmt.Errorf("error in UpdateWFS: %w", err)
+				}
+			}
+		}
 		if !slices.Contains(checkDuplicates, weekdayForSchedule{Weekday: val.Weekday, Schedule: val.Schedule}) {
 			checkDuplicates = append(checkDuplicates, weekdayForSchedule{Weekday: val.Weekday, Schedule: val.Schedule})
 		} else {
@@ -1175,23 +1685,24 @@ func (sm SampleModel) UpdateWFS(currentUser string, toUpdate []weekdayForSchedul
 }
 
 // Will delete WFS database entries that match the WFSID or that match the Weekday and Schedule provided in each WFS struct. If a WFSID > 0 is provided, the values for Weekday and Schedule are ignored for that WFS struct.
+// Rows of schedules currentUser has no editor role on are skipped.
 func (sm SampleModel) DeleteWFS(currentUser string, toDelete []weekdayForSchedule) error {
 	for _, val := range toDelete {
 		if val.WFSID < 1 && (len(val.Weekday) == 0 || val.Schedule < 1) {
 			return fmt.Errorf("error in DeleteWFS: method failed because one of the weekdayForSchedule structs did not have a value for WFSID or Weekday and Schedule: %+v", val)
 		}
 	}
-	tx, err := sm.DB.Begin()
+	tx, err := sm.beginAudited(currentUser)
 	if err != nil {
-		return fmt.Errorf("error in DeleteWFS: sql.DB.Begin error: %w", err)
+		return fmt.Errorf("error in DeleteWFS: %w", err)
 	}
 	defer tx.Rollback()
 	for _, val := range toDelete {
 		var deleteWFSString string
 		if val.WFSID > 0 {
-			deleteWFSString = fmt.Sprintf(`delete from WeekdaysForSchedule where User="%s" and WFSID=%d`, currentUser, val.WFSID)
+			deleteWFSString = fmt.Sprintf(`delete from WeekdaysForSchedule where %s and WFSID=%d`, scheduleAccess("Schedule", currentUser, ScheduleEditor), val.WFSID)
 		} else {
-			deleteWFSString = fmt.Sprintf(`delete from WeekdaysForSchedule where User="%s" and Weekday="%s" and Schedule=%d`, currentUser, val.Weekday, val.Schedule)
+			deleteWFSString = fmt.Sprintf(`delete from WeekdaysForSchedule where %s and Weekday="%s" and Schedule=%d`, scheduleAccess("Schedule", currentUser, ScheduleEditor), val.Weekday, val.Schedule)
 		}
 		_, err := tx.Exec(deleteWFSString)
 		if err != nil {
@@ -1228,12 +1739,12 @@ func (sm SampleModel) CleanOrphanedWFS(currentUser string, correctWFS []map[sche
 				}
 			}
 		}
-		tx, err := sm.DB.Begin()
+		tx, err := sm.beginAudited(currentUser)
 		if err != nil {
-			return fmt.Errorf("error in CleanOrphanedWFS: sql.DB.Begin error: %w", err)
+			return fmt.Errorf("error in CleanOrphanedWFS: %w", err)
 		}
 		defer tx.Rollback()
-		deleteWFSQuery := fmt.Sprintf(`delete from WeekdaysForSchedule where User = "%s" and WFSID in (%s)`, currentUser, CsvSlice(WFSToDelete, true))
+		deleteWFSQuery := fmt.Sprintf(`delete from WeekdaysForSchedule where %s and WFSID in (%s)`, scheduleAccess("Schedule", currentUser, ScheduleEditor), CsvSlice(WFSToDelete, true))
 		//fmt.Println(deleteWFSQuery)
 		_, err = tx.Exec(deleteWFSQuery)
 		if err != nil {
@@ -1256,6 +1767,8 @@ func (sm SampleModel) CreateVFS(currentUser string, toCreate []volunteerForSched
 		return fmt.Errorf("error in CreateVFS: method failed because at least one of the volunteerForSchedule entries to be created already exists in the database. Existing volunteerForSchedule entry(s): %+v", check)
 	}
 	checkDuplicates := []volunteerForSchedule{}
+	// ScheduleID to the User new rows are stored under
+	owners := map[int]string{}
 	for _, val := range toCreate { // User and VFSID do not need to be provided in the volunteerForSchedule structs
 		if val.Schedule == (volunteerForSchedule{}.Schedule) {
 			return fmt.Errorf("error in CreateVFS: method failed because at least one of the volunteerForSchedule structs in toCreate did not have a value for Schedule: %+v", val)
@@ -1268,10 +1781,19 @@ func (sm SampleModel) CreateVFS(currentUser string, toCreate []volunteerForSched
 		} else {
 			return fmt.Errorf("error in CreateVFS: method failed because at least one of the volunteerForSchedule structs in toCreate was a duplicate of another volunteerForSchedule struct in toCreate: %+v", val)
 		}
+		scheduleStruct, err := sm.authorizeSchedule(currentUser, val.Schedule, ScheduleEditor)
+		if err != nil {
+			return fmt.Errorf("error in CreateVFS: %w", err)
+		}
+		err = sm.checkInPool(scheduleStruct.User, val.Volunteer)
+		if err != nil {
+			return fmt.Errorf("error in CreateVFS: %w", err)
+		}
+		owners[val.Schedule] = scheduleStruct.User
 	}
-	tx, err := sm.DB.Begin()
+	tx, err := sm.beginAudited(currentUser)
 	if err != nil {
-		return fmt.Errorf("error in CreateVFS: sql.DB.Begin error: %w", err)
+		return fmt.Errorf("error in CreateVFS: %w", err)
 	}
 	defer tx.Rollback()
 	fillVFSTableString := `insert into VolunteersForSchedule (User, Schedule, Volunteer) values (?, ?, ?)`
@@ -1281,7 +1803,7 @@ func (sm SampleModel) CreateVFS(currentUser string, toCreate []volunteerForSched
 	}
 	defer fillVFSTableStmt.Close()
 	for i := 0; i < len(toCreate); i++ {
-		_, err = fillVFSTableStmt.Exec(currentUser, toCreate[i].Schedule, toCreate[i].Volunteer)
+		_, err = fillVFSTableStmt.Exec(owners[toCreate[i].Schedule], toCreate[i].Schedule, toCreate[i].Volunteer)
 		if err != nil {
 			return fmt.Errorf("error in CreateVFS: sql.Stmt.Exec error: %w. Value of toCreate[i] is `%+v`", err, toCreate[i])
 		}
@@ -1305,7 +1827,7 @@ func (sm SampleModel) RequestVFSSingle(currentUser string, volunteerForScheduleS
 }
 
 func (sm SampleModel) RequestVFS(currentUser string, volunteersForSchedule []volunteerForSchedule) ([]volunteerForSchedule, error) {
-	VFSQuery := fmt.Sprintf(`select * from VolunteersForSchedule where User = "%s"`, currentUser)
+	VFSQuery := fmt.Sprintf(`select * from VolunteersForSchedule where %s`, scheduleAccess("Schedule", currentUser, ScheduleViewer))
 	if len(volunteersForSchedule) > 0 {
 		if check, failed := testEmpty(volunteersForSchedule, volunteerForSchedule{}); check {
 			return []volunteerForSchedule{}, fmt.Errorf("error in RequestVFS: method failed because one of the values in volunteersForSchedule had an empty/default values volunteerForSchedule struct: %+v", failed)
@@ -1376,10 +1898,10 @@ func (sm SampleModel) UpdateVFS(currentUser string, toUpdate []volunteerForSched
 		return fmt.Errorf("error in UpdateVFS: method failed because one of the values in toUpdate had an empty/default values volunteerForSchedule struct: %+v", failed)
 	}
 	head := `update VolunteersForSchedule set`
-	tail := fmt.Sprintf(`where User="%s" and VFSID=?`, currentUser)
-	tx, err := sm.DB.Begin()
+	tail := fmt.Sprintf(`where %s and VFSID=?`, scheduleAccess("Schedule", currentUser, ScheduleEditor))
+	tx, err := sm.beginAudited(currentUser)
 	if err != nil {
-		return fmt.Errorf("error in UpdateVFS: sql.DB.Begin error: %w", err)
+		return fmt.Errorf("error in UpdateVFS: %w", err)
 	}
 	defer tx.Rollback()
 	checkDuplicates := []volunteerForSchedule{}
@@ -1391,6 +1913,16 @@ func (sm SampleModel) UpdateVFS(currentUser string, toUpdate []volunteerForSched
 		if err != nil {
 			return fmt.Errorf("error in UpdateWFS: %w", err)
 		}
+		owner := ""
+		for _, scheduleID := range []int{currentVFS.Schedule, val.Schedule} {
+			if scheduleID > 0 {
+				scheduleStruct, err := sm.authorizeSchedule(currentUser, scheduleID, ScheduleEditor)
+				if err != nil {
+					return fmt.Errorf("error in UpdateVFS: %w", err)
+				}
+				owner = scheduleStruct.User // the owner of the schedule the row ends up on
+			}
+		}
 		if !slices.Contains(checkDuplicates, volunteerForSchedule{Schedule: val.Schedule, Volunteer: val.Volunteer}) {
 			checkDuplicates = append(checkDuplicates, volunteerForSchedule{Schedule: val.Schedule, Volunteer: val.Volunteer})
 		} else {
@@ -1425,6 +1957,10 @@ func (sm SampleModel) UpdateVFS(currentUser string, toUpdate []volunteerForSched
 		updateVFSString = fmt.Sprintf(`%s %s`, updateVFSString, tail)
 		//fmt.Println(count)
 		//fmt.Println(updateVFSString)
+		err = sm.checkInPool(owner, currentVFS.Volunteer)
+		if err != nil {
+			return fmt.Errorf("error in UpdateVFS: %w", err)
+		}
 		if check, err := sm.RequestVFS(currentUser, []volunteerForSchedule{currentVFS}); err != nil {
 			return fmt.Errorf("error in UpdateVFS: %w", err)
 		} else if len(check) > 0 {
@@ -1448,23 +1984,24 @@ func (sm SampleModel) UpdateVFS(currentUser string, toUpdate []volunteerForSched
 }
 
 // Will delete VFS database entries that match the VFSID or that match the Schedule and Volunteer provided in each VFS struct. If a VFSID > 0 is provided, the values for Schedule and Volunteer are ignored for that VFS struct.
+// Rows of schedules currentUser has no editor role on are skipped.
 func (sm SampleModel) DeleteVFS(currentUser string, toDelete []volunteerForSchedule) error {
 	for _, val := range toDelete {
 		if val.VFSID < 1 && (val.Schedule < 1 || val.Volunteer < 1) {
 			return fmt.Errorf("error in DeleteVFS: method failed because one of the volunteerForSchedule structs did not have a value for VFSID or Schedule and Volunteer: %+v", val)
 		}
 	}
-	tx, err := sm.DB.Begin()
+	tx, err := sm.beginAudited(currentUser)
 	if err != nil {
-		return fmt.Errorf("error in DeleteVFS: sql.DB.Begin error: %w", err)
+		return fmt.Errorf("error in DeleteVFS: %w", err)
 	}
 	defer tx.Rollback()
 	for _, val := range toDelete {
 		var deleteVFSString string
 		if val.VFSID > 0 {
-			deleteVFSString = fmt.Sprintf(`delete from VolunteersForSchedule where User="%s" and VFSID=%d`, currentUser, val.VFSID)
+			deleteVFSString = fmt.Sprintf(`delete from VolunteersForSchedule where %s and VFSID=%d`, scheduleAccess("Schedule", currentUser, ScheduleEditor), val.VFSID)
 		} else {
-			deleteVFSString = fmt.Sprintf(`delete from VolunteersForSchedule where User="%s" and Schedule=%d and Volunteer=%d`, currentUser, val.Schedule, val.Volunteer)
+			deleteVFSString = fmt.Sprintf(`delete from VolunteersForSchedule where %s and Schedule=%d and Volunteer=%d`, scheduleAccess("Schedule", currentUser, ScheduleEditor), val.Schedule, val.Volunteer)
 		}
 		_, err := tx.Exec(deleteVFSString)
 		if err != nil {
@@ -1508,12 +2045,12 @@ func (sm SampleModel) CleanOrphanedVFS(currentUser string, correctVFS []map[sche
 				}
 			}
 		}
-		tx, err := sm.DB.Begin()
+		tx, err := sm.beginAudited(currentUser)
 		if err != nil {
-			return fmt.Errorf("error in CleanOrphanedVFS: sql.DB.begin error: %w", err)
+			return fmt.Errorf("error in CleanOrphanedVFS: %w", err)
 		}
 		defer tx.Rollback()
-		deleteVFSQuery := fmt.Sprintf(`delete from VolunteersForSchedule where User = "%s" and VFSID in (%s)`, currentUser, CsvSlice(VFSToDelete, true))
+		deleteVFSQuery := fmt.Sprintf(`delete from VolunteersForSchedule where %s and VFSID in (%s)`, scheduleAccess("Schedule", currentUser, ScheduleEditor), CsvSlice(VFSToDelete, true))
 		//fmt.Println(deleteVFSQuery)
 		_, err = tx.Exec(deleteVFSQuery)
 		if err != nil {
@@ -1536,6 +2073,8 @@ func (sm SampleModel) CreateUFS(currentUser string, toCreate []unavailabilityFor
 		return fmt.Errorf("error in CreateUFS: method failed because at least one of the unavailabilityForSchedule entries to be created already exists in the database. Existing unavailabilityForSchedule entry(s): %+v", check)
 	}
 	checkDuplicates := []unavailabilityForSchedule{}
+	// VFSID to the User new rows are stored under
+	owners := map[int]string{}
 	for _, val := range toCreate { // User and UFSID do not need to be provided in the unavailabilityForSchedule structs
 		if val.VolunteerForSchedule == (unavailabilityForSchedule{}.VolunteerForSchedule) {
 			return fmt.Errorf("error in CreateUFS: method failed because at least one of the unavailabilityForSchedule structs in toCreate did not have a value for VolunteerForSchedule: %+v", val)
@@ -1548,10 +2087,15 @@ func (sm SampleModel) CreateUFS(currentUser string, toCreate []unavailabilityFor
 		} else {
 			return fmt.Errorf("error in CreateUFS: method failed because at least one of the unavailabilityForSchedule structs in toCreate was a duplicate of another unavailabilityForSchedule struct in toCreate: %+v", val)
 		}
+		scheduleStruct, err := sm.authorizeVFS(currentUser, val.VolunteerForSchedule, ScheduleEditor)
+		if err != nil {
+			return fmt.Errorf("error in CreateUFS: %w", err)
+		}
+		owners[val.VolunteerForSchedule] = scheduleStruct.User
 	}
-	tx, err := sm.DB.Begin()
+	tx, err := sm.beginAudited(currentUser)
 	if err != nil {
-		return fmt.Errorf("error in CreateUFS: sql.DB.Begin error: %w", err)
+		return fmt.Errorf("error in CreateUFS: %w", err)
 	}
 	defer tx.Rollback()
 	fillUFSTableString := `insert into UnavailabilitiesForSchedule (User, VolunteerForSchedule, Date) values (?, ?, ?)`
@@ -1561,7 +2105,7 @@ func (sm SampleModel) CreateUFS(currentUser string, toCreate []unavailabilityFor
 	}
 	defer fillUFSTableStmt.Close()
 	for i := 0; i < len(toCreate); i++ {
-		_, err = fillUFSTableStmt.Exec(currentUser, toCreate[i].VolunteerForSchedule, toCreate[i].Date)
+		_, err = fillUFSTableStmt.Exec(owners[toCreate[i].VolunteerForSchedule], toCreate[i].VolunteerForSchedule, toCreate[i].Date)
 		if err != nil {
 			return fmt.Errorf("error in CreateUFS: sql.Stmt.Exec error: %w. Value of toCreate[i] is `%+v`", err, toCreate[i])
 		}
@@ -1585,7 +2129,7 @@ func (sm SampleModel) RequestUFSSingle(currentUser string, unavailabilityForSche
 }
 
 func (sm SampleModel) RequestUFS(currentUser string, unavailabilitiesForSchedule []unavailabilityForSchedule) ([]unavailabilityForSchedule, error) {
-	UFSQuery := fmt.Sprintf(`select * from UnavailabilitiesForSchedule where User = "%s"`, currentUser)
+	UFSQuery := fmt.Sprintf(`select * from UnavailabilitiesForSchedule where %s`, unavailabilityAccess(currentUser, ScheduleViewer))
 	if len(unavailabilitiesForSchedule) > 0 {
 		if check, failed := testEmpty(unavailabilitiesForSchedule, unavailabilityForSchedule{}); check {
 			return []unavailabilityForSchedule{}, fmt.Errorf("error in RequestUFS: method failed because one of the values in unavailabilitiesForSchedule had an empty/default values unavailabilityForSchedule struct: %+v", failed)
@@ -1658,10 +2202,10 @@ func (sm SampleModel) UpdateUFS(currentUser string, toUpdate []unavailabilityFor
 		return fmt.Errorf("error in UpdateUFS: method failed because one of the values in toUpdate had an empty/default values unavailabilityForSchedule struct: %+v", failed)
 	}
 	head := `update UnavailabilitiesForSchedule set`
-	tail := fmt.Sprintf(`where User="%s" and UFSID=?`, currentUser)
-	tx, err := sm.DB.Begin()
+	tail := fmt.Sprintf(`where %s and UFSID=?`, unavailabilityAccess(currentUser, ScheduleEditor))
+	tx, err := sm.beginAudited(currentUser)
 	if err != nil {
-		return fmt.Errorf("error in UpdateUFS: sql.DB.Begin error: %w", err)
+		return fmt.Errorf("error in UpdateUFS: %w", err)
 	}
 	defer tx.Rollback()
 	checkDuplicates := []unavailabilityForSchedule{}
@@ -1673,6 +2217,13 @@ func (sm SampleModel) UpdateUFS(currentUser string, toUpdate []unavailabilityFor
 		if err != nil {
 			return fmt.Errorf("error in UpdateUFS: %w", err)
 		}
+		for _, vfsID := range []int{currentUFS.VolunteerForSchedule, val.VolunteerForSchedule} {
+			if vfsID > 0 {
+				if _, err := sm.authorizeVFS(currentUser, vfsID, ScheduleEditor); err != nil {
+					return fmt.Errorf("error in UpdateUFS: %w", err)
+				}
+			}
+		}
 		if !slices.Contains(checkDuplicates, unavailabilityForSchedule{VolunteerForSchedule: val.VolunteerForSchedule, Date: val.Date}) {
 			checkDuplicates = append(checkDuplicates, unavailabilityForSchedule{VolunteerForSchedule: val.VolunteerForSchedule, Date: val.Date})
 		} else {
@@ -1730,23 +2281,24 @@ func (sm SampleModel) UpdateUFS(currentUser string, toUpdate []unavailabilityFor
 }
 
 // Will delete UFS database entries that match the UFSID or that match the VFS and Date provided in each UFS struct. If a UFSID > 0 is provided, the values for VFS and Date are ignored for that UFS struct.
+// Rows of schedules currentUser has no editor role on are skipped.
 func (sm SampleModel) DeleteUFS(currentUser string, toDelete []unavailabilityForSchedule) error {
 	for _, val := range toDelete {
 		if val.UFSID < 1 && (val.VolunteerForSchedule < 1 || val.Date < 1) {
 			return fmt.Errorf("error in DeleteUFS: method failed because one of the unavailabilityForSchedule structs did not have a value for UFSID or VolunteerForSchedule and Date: %+v", val)
 		}
 	}
-	tx, err := sm.DB.Begin()
+	tx, err := sm.beginAudited(currentUser)
 	if err != nil {
-		return fmt.Errorf("error in DeleteUFS: sql.DB.Begin error: %w", err)
+		return fmt.Errorf("error in DeleteUFS: %w", err)
 	}
 	defer tx.Rollback()
 	for _, val := range toDelete {
 		var deleteUFSString string
 		if val.UFSID > 0 {
-			deleteUFSString = fmt.Sprintf(`delete from UnavailabilitiesForSchedule where User="%s" and UFSID=%d`, currentUser, val.UFSID)
+			deleteUFSString = fmt.Sprintf(`delete from UnavailabilitiesForSchedule where %s and UFSID=%d`, unavailabilityAccess(currentUser, ScheduleEditor), val.UFSID)
 		} else {
-			deleteUFSString = fmt.Sprintf(`delete from UnavailabilitiesForSchedule where User="%s" and VolunteerForSchedule=%d and Date=%d`, currentUser, val.VolunteerForSchedule, val.Date)
+			deleteUFSString = fmt.Sprintf(`delete from UnavailabilitiesForSchedule where %s and VolunteerForSchedule=%d and Date=%d`, unavailabilityAccess(currentUser, ScheduleEditor), val.VolunteerForSchedule, val.Date)
 		}
 		_, err := tx.Exec(deleteUFSString)
 		if err != nil {
@@ -1786,12 +2338,12 @@ func (sm SampleModel) CleanOrphanedUFS(currentUser string, correctUFS []map[volu
 				}
 			}
 		}
-		tx, err := sm.DB.Begin()
+		tx, err := sm.beginAudited(currentUser)
 		if err != nil {
-			return fmt.Errorf("error in CleanOrphanedUFS: sql.DB.Begin error: %w", err)
+			return fmt.Errorf("error in CleanOrphanedUFS: %w", err)
 		}
 		defer tx.Rollback()
-		deleteUFSQuery := fmt.Sprintf(`delete from UnavailabilitiesForSchedule where User = "%s" and UFSID in (%s)`, currentUser, CsvSlice(UFSToDelete, true))
+		deleteUFSQuery := fmt.Sprintf(`delete from UnavailabilitiesForSchedule where %s and UFSID in (%s)`, unavailabilityAccess(currentUser, ScheduleEditor), CsvSlice(UFSToDelete, true))
 		//fmt.Println(deleteUFSQuery)
 		_, err = tx.Exec(deleteUFSQuery)
 		if err != nil {
@@ -1805,12 +2357,639 @@ func (sm SampleModel) CleanOrphanedUFS(currentUser string, correctUFS []map[volu
 	return nil
 }
 
-func (sm SampleModel) CreateCompletedSchedule(currentUser string, toCreate completedSchedule) { // figure out what to return as a completed/failed value, instead of just crashing the program
-	// fill this in
+// ScheduleData of each completedSchedule in toCreate must be a roster as described by rosterShift.
+func (sm SampleModel) CreateCompletedSchedules(currentUser string, toCreate []completedSchedule) error {
+	if len(toCreate) == 0 {
+		return errors.New("error in CreateCompletedSchedules: method failed because the toCreate argument was an empty slice")
+	}
+	// ScheduleID to the User new rows are stored under
+	owners := map[int]string{}
+	for _, val := range toCreate { // User and CScheduleID do not need to be provided in the completedSchedule structs
+		if val.Schedule == (completedSchedule{}.Schedule) {
+			return fmt.Errorf("error in CreateCompletedSchedules: method failed because at least one of the completedSchedule structs in toCreate did not have a value for Schedule: %+v", val)
+		}
+		if _, err := parseScheduleData(val.ScheduleData); err != nil {
+			return fmt.Errorf("error in CreateCompletedSchedules: method failed because at least one of the completedSchedule structs in toCreate did not have a valid ScheduleData: %w", err)
+		}
+		scheduleStruct, err := sm.authorizeSchedule(currentUser, val.Schedule, ScheduleEditor)
+		if err != nil {
+			return fmt.Errorf("error in CreateCompletedSchedules: %w", err)
+		}
+		owners[val.Schedule] = scheduleStruct.User
+	}
+	tx, err := sm.beginAudited(currentUser)
+	if err != nil {
+		return fmt.Errorf("error in CreateCompletedSchedules: %w", err)
+	}
+	defer tx.Rollback()
+	fillCompletedSchedulesTableString := `insert into CompletedSchedules (ScheduleData, User, Schedule) values (?, ?, ?)`
+	fillCompletedSchedulesTableStmt, err := tx.Prepare(fillCompletedSchedulesTableString)
+	if err != nil {
+		return fmt.Errorf("error in CreateCompletedSchedules: sql.Tx.Prepare error: %w. Value of fillCompletedSchedulesTableString is `%s`", err, fillCompletedSchedulesTableString)
+	}
+	defer fillCompletedSchedulesTableStmt.Close()
+	for i := 0; i < len(toCreate); i++ {
+		_, err = fillCompletedSchedulesTableStmt.Exec(toCreate[i].ScheduleData, owners[toCreate[i].Schedule], toCreate[i].Schedule)
+		if err != nil {
+			return fmt.Errorf("error in CreateCompletedSchedules: sql.Stmt.Exec error: %w. Value of toCreate[i] is `%+v`", err, toCreate[i])
+		}
+	}
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("error in CreateCompletedSchedules: sql.Tx.Commit error: %w", err)
+	}
+	return nil
+}
+
+func (sm SampleModel) RequestCompletedSchedules(currentUser string, completedSchedules []completedSchedule) ([]completedSchedule, error) {
+	completedSchedulesQuery := fmt.Sprintf(`select CScheduleID, ScheduleData, User, Schedule from CompletedSchedules where %s`, scheduleAccess("Schedule", currentUser, ScheduleViewer))
+	if len(completedSchedules) > 0 {
+		if check, failed := testEmpty(completedSchedules, completedSchedule{}); check {
+			return []completedSchedule{}, fmt.Errorf("error in RequestCompletedSchedules: method failed because one of the values in completedSchedules had an empty/default values completedSchedule struct: %+v", failed)
+		}
+		completedSchedulesQuery = fmt.Sprintf(`%s and (`, completedSchedulesQuery)
+	}
+	var args []any
+	for i := 0; i < len(completedSchedules); i++ {
+		count := countGTZero([]int{completedSchedules[i].CScheduleID, len(completedSchedules[i].ScheduleData), len(completedSchedules[i].User), completedSchedules[i].Schedule})
+		// count must be at least 1 because the testEmpty check passed
+		completedSchedulesQuery = fmt.Sprintf(`%s(`, completedSchedulesQuery)
+		if completedSchedules[i].CScheduleID > 0 {
+			completedSchedulesQuery = fmt.Sprintf(`%sCScheduleID = %d`, completedSchedulesQuery, completedSchedules[i].CScheduleID)
+			count--
+			if count > 0 {
+				completedSchedulesQuery = fmt.Sprintf(`%s and `, completedSchedulesQuery)
+			}
+		}
+		if len(completedSchedules[i].ScheduleData) > 0 {
+			completedSchedulesQuery = fmt.Sprintf(`%sScheduleData = ?`, completedSchedulesQuery)
+			args = append(args, completedSchedules[i].ScheduleData) // the JSON may contain quotes from volunteer or schedule names
+			count--
+			if count > 0 {
+				completedSchedulesQuery = fmt.Sprintf(`%s and `, completedSchedulesQuery)
+			}
+		}
+		if len(completedSchedules[i].User) > 0 {
+			completedSchedulesQuery = fmt.Sprintf(`%sUser = "%s"`, completedSchedulesQuery, completedSchedules[i].User)
+			count--
+			if count > 0 {
+				completedSchedulesQuery = fmt.Sprintf(`%s and `, completedSchedulesQuery)
+			}
+		}
+		if completedSchedules[i].Schedule > 0 {
+			completedSchedulesQuery = fmt.Sprintf(`%sSchedule = %d`, completedSchedulesQuery, completedSchedules[i].Schedule)
+		}
+		completedSchedulesQuery = fmt.Sprintf(`%s)`, completedSchedulesQuery)
+		if i+1 < len(completedSchedules) {
+			completedSchedulesQuery = fmt.Sprintf(`%s or `, completedSchedulesQuery)
+		}
+	}
+	if len(completedSchedules) > 0 {
+		completedSchedulesQuery = fmt.Sprintf(`%s)`, completedSchedulesQuery)
+	}
+	var result []completedSchedule
+	rows, err := sm.DB.Query(completedSchedulesQuery, args...)
+	if err != nil {
+		return []completedSchedule{}, fmt.Errorf("error in RequestCompletedSchedules: sql.DB.Query error: %w. Value of completedSchedulesQuery is `%s`", err, completedSchedulesQuery)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var completedScheduleStruct completedSchedule
+		err = rows.Scan(&completedScheduleStruct.CScheduleID, &completedScheduleStruct.ScheduleData, &completedScheduleStruct.User, &completedScheduleStruct.Schedule)
+		if err != nil {
+			return []completedSchedule{}, fmt.Errorf("error in RequestCompletedSchedules: sql.Rows.Scan error: %w. Value of completedScheduleStruct is `%+v`", err, completedScheduleStruct)
+		}
+		result = append(result, completedScheduleStruct)
+	}
+	err = rows.Err()
+	if err != nil {
+		return []completedSchedule{}, fmt.Errorf("error in RequestCompletedSchedules: sql.Rows.Err error: %w", err)
+	}
+	return result, nil
+}
+
+// A completedSchedule in toDelete identifies either a single row by CScheduleID or every row of a schedule by Schedule. Rows of schedules currentUser has no editor role on are skipped.
+func (sm SampleModel) DeleteCompletedSchedules(currentUser string, toDelete []completedSchedule) error {
+	for _, val := range toDelete {
+		if val.CScheduleID < 1 && val.Schedule < 1 {
+			return fmt.Errorf("error in DeleteCompletedSchedules: method failed because one of the completedSchedule structs did not have a value for CScheduleID or Schedule: %+v", val)
+		}
+	}
+	tx, err := sm.beginAudited(currentUser)
+	if err != nil {
+		return fmt.Errorf("error in DeleteCompletedSchedules: %w", err)
+	}
+	defer tx.Rollback()
+	for _, val := range toDelete {
+		var deleteCompletedScheduleString string
+		if val.CScheduleID > 0 {
+			deleteCompletedScheduleString = fmt.Sprintf(`delete from CompletedSchedules where %s and CScheduleID=%d`, scheduleAccess("Schedule", currentUser, ScheduleEditor), val.CScheduleID)
+		} else {
+			deleteCompletedScheduleString = fmt.Sprintf(`delete from CompletedSchedules where %s and Schedule=%d`, scheduleAccess("Schedule", currentUser, ScheduleEditor), val.Schedule)
+		}
+		_, err := tx.Exec(deleteCompletedScheduleString)
+		if err != nil {
+			return fmt.Errorf("error in DeleteCompletedSchedules: sql.Tx.Exec error: %w. Value of deleteCompletedScheduleString is `%s`", err, deleteCompletedScheduleString)
+		}
+	}
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("error in DeleteCompletedSchedules: sql.Tx.Commit error: %w", err)
+	}
+	return nil
+}
+
+type UserErrorKind int
+
+const (
+	UserNameInvalid UserErrorKind = iota
+	UserAlreadyExists
+	UserNotFound
+	UserStillReferenced // rows of other tables still belong to the user
+	UserPasswordInvalid
+	UserCredentialsInvalid // the UserName or the password given to Login is wrong
+	UserSessionInvalid     // the session token is unknown, revoked, or expired
+	UserForbidden          // the user lacks the role a schedule operation needs, see ScheduleMembers
+	UserLoginThrottled     // too many logins of the user or from its source failed recently, see loginPolicy
+	UserAPIKeyInvalid      // the API key is unknown, revoked, or expired
+)
+
+// UserError is returned by the user methods when the request itself can't be carried out, as opposed to a database failure.
+type UserError struct {
+	Kind       UserErrorKind
+	UserName   string
+	Detail     string
+	RetryAfter time.Duration // how long to wait before trying again, for UserLoginThrottled
+}
+
+func (e *UserError) Error() string {
+	switch e.Kind {
+	case UserAlreadyExists:
+		return fmt.Sprintf("user `%s` already exists", e.UserName)
+	case UserNotFound:
+		return fmt.Sprintf("failed to locate user `%s`", e.UserName)
+	case UserStillReferenced:
+		return fmt.Sprintf("user `%s` can't be deleted because it still owns rows: %s", e.UserName, e.Detail)
+	case UserPasswordInvalid:
+		return fmt.Sprintf("password of user `%s` is invalid: %s", e.UserName, e.Detail)
+	case UserCredentialsInvalid:
+		return "invalid credentials"
+	case UserSessionInvalid:
+		return "invalid or expired session"
+	case UserForbidden:
+		return fmt.Sprintf("user `%s` is not allowed to do this: %s", e.UserName, e.Detail)
+	case UserLoginThrottled:
+		return fmt.Sprintf("too many failed logins: %s", e.Detail)
+	case UserAPIKeyInvalid:
+		return "invalid, revoked, or expired API key"
+	default:
+		return fmt.Sprintf("user name `%s` is invalid: %s", e.UserName, e.Detail)
+	}
+}
+
+// Most queries interpolate the user name between double quotes, so names with quotes or control characters are rejected rather than escaped.
+func validateUserName(userName string) error {
+	switch {
+	case len(userName) == 0:
+		return &UserError{Kind: UserNameInvalid, UserName: userName, Detail: "it is empty"}
+	case len(userName) > 64:
+		return &UserError{Kind: UserNameInvalid, UserName: userName, Detail: "it is longer than 64 bytes"}
+	case strings.TrimSpace(userName) != userName:
+		return &UserError{Kind: UserNameInvalid, UserName: userName, Detail: "it starts or ends with whitespace"}
+	case strings.ContainsFunc(userName, func(char rune) bool { return char == '"' || char == '`' || unicode.IsControl(char) }):
+		return &UserError{Kind: UserNameInvalid, UserName: userName, Detail: "it contains a quote or a control character"}
+	}
+	return nil
+}
+
+// A column whose foreign key references Users(UserName).
+type userReference struct {
+	Table  string
+	Column string
+}
+
+// Lists every column that references Users(UserName), including those added by migrations, by reading the foreign keys of each table.
+func userReferences(tx *sql.Tx) ([]userReference, error) {
+	rows, err := tx.Query(`select m.name, f."from" from sqlite_master m join pragma_foreign_key_list(m.name) f where m.type = 'table' and f."table" = 'Users' order by m.name`)
+	if err != nil {
+		return nil, fmt.Errorf("error in userReferences: sql.Tx.Query error: %w", err)
+	}
+	defer rows.Close()
+	var result []userReference
+	for rows.Next() {
+		var reference userReference
+		err = rows.Scan(&reference.Table, &reference.Column)
+		if err != nil {
+			return nil, fmt.Errorf("error in userReferences: sql.Rows.Scan error: %w", err)
+		}
+		result = append(result, reference)
+	}
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("error in userReferences: sql.Rows.Err error: %w", err)
+	}
+	return result, nil
+}
+
+// Password is stored as given, so it must already be a hash.
+func (sm SampleModel) CreateUser(toCreate user) error {
+	err := validateUserName(toCreate.UserName)
+	if err != nil {
+		return fmt.Errorf("error in CreateUser: %w", err)
+	}
+	_, err = sm.RequestUser(user{UserName: toCreate.UserName})
+	var userErr *UserError
+	if err == nil {
+		return fmt.Errorf("error in CreateUser: %w", &UserError{Kind: UserAlreadyExists, UserName: toCreate.UserName})
+	} else if !errors.As(err, &userErr) || userErr.Kind != UserNotFound {
+		return fmt.Errorf("error in CreateUser: %w", err)
+	}
+	_, err = sm.execAudited(toCreate.UserName, `insert into Users (UserName, Password) values (?, ?)`, toCreate.UserName, toCreate.Password)
+	if err != nil {
+		return fmt.Errorf("error in CreateUser: %w. Value of toCreate.UserName is `%s`", err, toCreate.UserName)
+	}
+	return nil
+}
+
+// Returns the Users row named userStruct.UserName, including its Password.
+func (sm SampleModel) RequestUser(userStruct user) (user, error) {
+	if len(userStruct.UserName) == 0 {
+		return user{}, fmt.Errorf("error in RequestUser: %w", &UserError{Kind: UserNameInvalid, Detail: "it is empty"})
+	}
+	var result user
+	err := sm.DB.QueryRow(`select UserName, Password from Users where UserName = ?`, userStruct.UserName).Scan(&result.UserName, &result.Password)
+	if errors.Is(err, sql.ErrNoRows) {
+		return user{}, fmt.Errorf("error in RequestUser: %w", &UserError{Kind: UserNotFound, UserName: userStruct.UserName})
+	}
+	if err != nil {
+		return user{}, fmt.Errorf("error in RequestUser: sql.DB.QueryRow error: %w. Value of userStruct.UserName is `%s`", err, userStruct.UserName)
+	}
+	return result, nil
 }
 
-func (sm SampleModel) DeleteCompletedSchedule(currentUser string, existingSchedule string, toDelete string) { // how to identify what to delete? Figure out what to return as a completed/failed value, instead of just crashing the program
-	// fill this in
+// Renames currentUser to toUpdate.UserName and, if toUpdate.Password is set, replaces its Password. A rename moves every row that belongs to currentUser to the new name in the same transaction, including its AuditLog and login history.
+func (sm SampleModel) UpdateUser(currentUser string, toUpdate user) error {
+	if len(toUpdate.UserName) == 0 && len(toUpdate.Password) == 0 {
+		return fmt.Errorf("error in UpdateUser: method failed because toUpdate had neither a UserName nor a Password")
+	}
+	existing, err := sm.RequestUser(user{UserName: currentUser})
+	if err != nil {
+		return fmt.Errorf("error in UpdateUser: %w", err)
+	}
+	newName := currentUser
+	if len(toUpdate.UserName) > 0 && toUpdate.UserName != currentUser {
+		newName = toUpdate.UserName
+		err = validateUserName(newName)
+		if err != nil {
+			return fmt.Errorf("error in UpdateUser: %w", err)
+		}
+		_, err = sm.RequestUser(user{UserName: newName})
+		var userErr *UserError
+		if err == nil {
+			return fmt.Errorf("error in UpdateUser: %w", &UserError{Kind: UserAlreadyExists, UserName: newName})
+		} else if !errors.As(err, &userErr) || userErr.Kind != UserNotFound {
+			return fmt.Errorf("error in UpdateUser: %w", err)
+		}
+	}
+	password := existing.Password
+	if len(toUpdate.Password) > 0 {
+		password = toUpdate.Password
+	}
+	tx, err := sm.beginAudited(currentUser)
+	if err != nil {
+		return fmt.Errorf("error in UpdateUser: %w", err)
+	}
+	defer tx.Rollback()
+	if newName == currentUser {
+		_, err = tx.Exec(`update Users set Password = ? where UserName = ?`, password, currentUser)
+		if err != nil {
+			return fmt.Errorf("error in UpdateUser: sql.Tx.Exec error: %w", err)
+		}
+	} else {
+		// the new row is inserted before and the old one deleted after the references move, so no foreign key is ever broken
+		_, err = tx.Exec(`insert into Users (UserName, Password) values (?, ?)`, newName, password)
+		if err != nil {
+			return fmt.Errorf("error in UpdateUser: sql.Tx.Exec error: %w", err)
+		}
+		references, err := userReferences(tx.Tx)
+		if err != nil {
+			return fmt.Errorf("error in UpdateUser: %w", err)
+		}
+		for _, val := range references {
+			updateReferenceString := fmt.Sprintf(`update %s set %s = ? where %s = ?`, val.Table, val.Column, val.Column)
+			_, err = tx.Exec(updateReferenceString, newName, currentUser)
+			if err != nil {
+				return fmt.Errorf("error in UpdateUser: sql.Tx.Exec error: %w. Value of updateReferenceString is `%s`", err, updateReferenceString)
+			}
+		}
+		_, err = tx.Exec(`delete from Users where UserName = ?`, currentUser)
+		if err != nil {
+			return fmt.Errorf("error in UpdateUser: sql.Tx.Exec error: %w", err)
+		}
+		// the history tables have no foreign keys, so userReferences misses them. AuditLog is renamed last to also move the rows the rename itself just logged
+		for _, historyString := range []string{
+			`update LoginAttempts set User = ? where User = ?`,
+			`update LoginThrottles set Subject = ? where Kind = 'user' and Subject = ?`,
+			`update AuditLog set User = ? where User = ?`,
+			`update AuditLog set Owner = ? where Owner = ?`,
+		} {
+			_, err = tx.Exec(historyString, newName, currentUser)
+			if err != nil {
+				return fmt.Errorf("error in UpdateUser: sql.Tx.Exec error: %w. Value of historyString is `%s`", err, historyString)
+			}
+		}
+	}
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("error in UpdateUser: sql.Tx.Commit error: %w", err)
+	}
+	return nil
+}
+
+// Deletes the Users row of currentUser with its Sessions and APIKeys. It fails with a UserError if any other row still belongs to the user, see DeleteUserCascade for deleting those too.
+func (sm SampleModel) DeleteUser(currentUser string) error {
+	_, err := sm.RequestUser(user{UserName: currentUser})
+	if err != nil {
+		return fmt.Errorf("error in DeleteUser: %w", err)
+	}
+	tx, err := sm.beginAudited(currentUser)
+	if err != nil {
+		return fmt.Errorf("error in DeleteUser: %w", err)
+	}
+	defer tx.Rollback()
+	for _, table := range []string{"Sessions", "APIKeys"} {
+		_, err = tx.Exec(fmt.Sprintf(`delete from %s where User = ?`, table), currentUser)
+		if err != nil {
+			return fmt.Errorf("error in DeleteUser: sql.Tx.Exec error: %w", err)
+		}
+	}
+	references, err := userReferences(tx.Tx)
+	if err != nil {
+		return fmt.Errorf("error in DeleteUser: %w", err)
+	}
+	counts, err := countUserRows(tx.Tx, currentUser, references)
+	if err != nil {
+		return fmt.Errorf("error in DeleteUser: %w", err)
+	}
+	var owned []string
+	for _, val := range counts {
+		if val.Rows > 0 {
+			owned = append(owned, fmt.Sprintf("%d in %s", val.Rows, val.Table))
+		}
+	}
+	if len(owned) > 0 {
+		return fmt.Errorf("error in DeleteUser: %w", &UserError{Kind: UserStillReferenced, UserName: currentUser, Detail: strings.Join(owned, ", ")})
+	}
+	_, err = tx.Exec(`delete from Users where UserName = ?`, currentUser)
+	if err != nil {
+		return fmt.Errorf("error in DeleteUser: sql.Tx.Exec error: %w", err)
+	}
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("error in DeleteUser: sql.Tx.Commit error: %w", err)
+	}
+	return nil
+}
+
+// The number of rows of one table that belong to a user, see PreviewDeleteUserCascade.
+type userRowCount struct {
+	Table string
+	Rows  int
+}
+
+// Orders the references of userReferences so every table comes before the tables its foreign keys point to, which is the order their rows can be deleted in. Ties keep the order of userReferences.
+func userDeletionOrder(tx *sql.Tx) ([]userReference, error) {
+	references, err := userReferences(tx)
+	if err != nil {
+		return nil, fmt.Errorf("error in userDeletionOrder: %w", err)
+	}
+	dependsOn := map[string][]string{} // tables each table's foreign keys point to, other than itself
+	for _, val := range references {
+		if _, ok := dependsOn[val.Table]; ok {
+			continue
+		}
+		dependsOn[val.Table] = []string{}
+		rows, err := tx.Query(`select distinct "table" from pragma_foreign_key_list(?)`, val.Table)
+		if err != nil {
+			return nil, fmt.Errorf("error in userDeletionOrder: sql.Tx.Query error: %w", err)
+		}
+		for rows.Next() {
+			var table string
+			err = rows.Scan(&table)
+			if err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("error in userDeletionOrder: sql.Rows.Scan error: %w", err)
+			}
+			if table != val.Table {
+				dependsOn[val.Table] = append(dependsOn[val.Table], table)
+			}
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return nil, fmt.Errorf("error in userDeletionOrder: sql.Rows.Err error: %w", err)
+		}
+	}
+	var result []userReference
+	remaining := references
+	for len(remaining) > 0 {
+		var next []userReference
+		for _, val := range remaining {
+			if slices.ContainsFunc(remaining, func(other userReference) bool { return slices.Contains(dependsOn[other.Table], val.Table) }) {
+				next = append(next, val)
+			} else {
+				result = append(result, val)
+			}
+		}
+		if len(next) == len(remaining) {
+			return nil, fmt.Errorf("error in userDeletionOrder: method failed because the foreign keys of these tables form a cycle: %+v", remaining)
+		}
+		remaining = next
+	}
+	return result, nil
+}
+
+func countUserRows(tx *sql.Tx, currentUser string, references []userReference) ([]userRowCount, error) {
+	var result []userRowCount
+	for _, val := range references {
+		var count int
+		countString := fmt.Sprintf(`select count(*) from %s where %s = ?`, val.Table, val.Column)
+		err := tx.QueryRow(countString, currentUser).Scan(&count)
+		if err != nil {
+			return nil, fmt.Errorf("error in countUserRows: sql.Tx.QueryRow error: %w. Value of countString is `%s`", err, countString)
+		}
+		result = append(result, userRowCount{Table: val.Table, Rows: count})
+	}
+	return result, nil
+}
+
+// The rows of one table that the foreign key cascades of DeleteUserCascade delete along with the rows of Parent, without them belonging to the user themselves.
+type cascadedRowCount struct {
+	Table  string
+	Parent string // the first table in deletion order whose rows take these along
+	Rows   int
+}
+
+// Counts the rows that "on delete cascade" foreign keys would delete with the rows of references that belong to currentUser, for example other users' memberships of its schedules. Rows a reference of references counts directly are left out.
+func countCascadedRows(tx *sql.Tx, currentUser string, references []userReference) ([]cascadedRowCount, error) {
+	owned := map[string][]string{} // user columns of every table in references
+	var tables []string
+	for _, val := range references {
+		if _, ok := owned[val.Table]; !ok {
+			tables = append(tables, val.Table)
+		}
+		owned[val.Table] = append(owned[val.Table], val.Column)
+	}
+	ownedCondition := func(table string, args *[]any) string {
+		var conditions []string
+		for _, column := range owned[table] {
+			conditions = append(conditions, fmt.Sprintf(`%s = ?`, column))
+			*args = append(*args, currentUser)
+		}
+		return strings.Join(conditions, " or ")
+	}
+	rows, err := tx.Query(`select m.name, f."from", f."table", f."to" from sqlite_master m join pragma_foreign_key_list(m.name) f where m.type = 'table' and f.on_delete = 'CASCADE' order by m.name`)
+	if err != nil {
+		return nil, fmt.Errorf("error in countCascadedRows: sql.Tx.Query error: %w", err)
+	}
+	type cascade struct{ table, column, parent, parentColumn string }
+	var cascades []cascade
+	var children []string
+	for rows.Next() {
+		var val cascade
+		err = rows.Scan(&val.table, &val.column, &val.parent, &val.parentColumn)
+		if err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("error in countCascadedRows: sql.Rows.Scan error: %w", err)
+		}
+		if _, ok := owned[val.parent]; ok {
+			cascades = append(cascades, val)
+			if !slices.Contains(children, val.table) {
+				children = append(children, val.table)
+			}
+		}
+	}
+	err = rows.Err()
+	rows.Close()
+	if err != nil {
+		return nil, fmt.Errorf("error in countCascadedRows: sql.Rows.Err error: %w", err)
+	}
+	var result []cascadedRowCount
+	for _, table := range children {
+		var args []any
+		var conditions []string
+		parent := ""
+		for _, val := range cascades {
+			if val.table != table {
+				continue
+			}
+			conditions = append(conditions, fmt.Sprintf(`%s in (select %s from %s where %s)`, val.column, val.parentColumn, val.parent, ownedCondition(val.parent, &args)))
+			if parent == "" || slices.Index(tables, val.parent) < slices.Index(tables, parent) {
+				parent = val.parent
+			}
+		}
+		countString := fmt.Sprintf(`select count(*) from %s where (%s)`, table, strings.Join(conditions, " or "))
+		for _, column := range owned[table] {
+			countString += fmt.Sprintf(` and %s is not ?`, column)
+			args = append(args, currentUser)
+		}
+		var count int
+		err = tx.QueryRow(countString, args...).Scan(&count)
+		if err != nil {
+			return nil, fmt.Errorf("error in countCascadedRows: sql.Tx.QueryRow error: %w. Value of countString is `%s`", err, countString)
+		}
+		result = append(result, cascadedRowCount{Table: table, Parent: parent, Rows: count})
+	}
+	return result, nil
+}
+
+// Adds cascaded to the counts of counts. Tables without a count of their own are inserted right before their Parent, where their rows are deleted.
+func addCascadedRows(counts []userRowCount, cascaded []cascadedRowCount) []userRowCount {
+	for _, val := range cascaded {
+		if i := slices.IndexFunc(counts, func(count userRowCount) bool { return count.Table == val.Table }); i >= 0 {
+			counts[i].Rows += val.Rows
+			continue
+		}
+		i := slices.IndexFunc(counts, func(count userRowCount) bool { return count.Table == val.Parent })
+		counts = slices.Insert(counts, max(i, 0), userRowCount{Table: val.Table, Rows: val.Rows})
+	}
+	return counts
+}
+
+// Counts the rows DeleteUserCascade would delete for currentUser, table by table in the order they would be deleted, ending with the Users row itself. Rows that go away with the user's rows through foreign key cascades, like other users' memberships of its schedules, are counted too. Nothing is changed.
+func (sm SampleModel) PreviewDeleteUserCascade(currentUser string) ([]userRowCount, error) {
+	_, err := sm.RequestUser(user{UserName: currentUser})
+	if err != nil {
+		return nil, fmt.Errorf("error in PreviewDeleteUserCascade: %w", err)
+	}
+	tx, err := sm.DB.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("error in PreviewDeleteUserCascade: sql.DB.Begin error: %w", err)
+	}
+	defer tx.Rollback()
+	references, err := userDeletionOrder(tx)
+	if err != nil {
+		return nil, fmt.Errorf("error in PreviewDeleteUserCascade: %w", err)
+	}
+	result, err := countUserRows(tx, currentUser, references)
+	if err != nil {
+		return nil, fmt.Errorf("error in PreviewDeleteUserCascade: %w", err)
+	}
+	cascaded, err := countCascadedRows(tx, currentUser, references)
+	if err != nil {
+		return nil, fmt.Errorf("error in PreviewDeleteUserCascade: %w", err)
+	}
+	return append(addCascadedRows(result, cascaded), userRowCount{Table: "Users", Rows: 1}), nil
+}
+
+// Deletes currentUser and every row it owns in a single transaction, in the order of userDeletionOrder, and returns how many rows were deleted from each table like PreviewDeleteUserCascade.
+// If archive is not nil, ExportBackup writes everything the user owns to it first, and nothing is deleted if that fails. Other users' memberships of the user's schedules are deleted with the schedules. Deleting fails if schedules of other users still use one of the user's volunteers through an organization.
+func (sm SampleModel) DeleteUserCascade(currentUser string, archive io.Writer) ([]userRowCount, error) {
+	_, err := sm.RequestUser(user{UserName: currentUser})
+	if err != nil {
+		return nil, fmt.Errorf("error in DeleteUserCascade: %w", err)
+	}
+	if archive != nil {
+		err = sm.ExportBackup(currentUser, archive)
+		if err != nil {
+			return nil, fmt.Errorf("error in DeleteUserCascade: %w", err)
+		}
+	}
+	tx, err := sm.beginAudited(currentUser)
+	if err != nil {
+		return nil, fmt.Errorf("error in DeleteUserCascade: %w", err)
+	}
+	defer tx.Rollback()
+	references, err := userDeletionOrder(tx.Tx)
+	if err != nil {
+		return nil, fmt.Errorf("error in DeleteUserCascade: %w", err)
+	}
+	cascaded, err := countCascadedRows(tx.Tx, currentUser, references) // RowsAffected leaves out the rows deleted by cascades, so they're counted first
+	if err != nil {
+		return nil, fmt.Errorf("error in DeleteUserCascade: %w", err)
+	}
+	var result []userRowCount
+	for _, val := range references {
+		deleteString := fmt.Sprintf(`delete from %s where %s = ?`, val.Table, val.Column)
+		res, err := tx.Exec(deleteString, currentUser)
+		if err != nil {
+			return nil, fmt.Errorf("error in DeleteUserCascade: sql.Tx.Exec error: %w. Value of deleteString is `%s`", err, deleteString)
+		}
+		count, err := res.RowsAffected()
+		if err != nil {
+			return nil, fmt.Errorf("error in DeleteUserCascade: sql.Result.RowsAffected error: %w", err)
+		}
+		result = append(result, userRowCount{Table: val.Table, Rows: int(count)})
+	}
+	_, err = tx.Exec(`delete from Users where UserName = ?`, currentUser)
+	if err != nil {
+		return nil, fmt.Errorf("error in DeleteUserCascade: sql.Tx.Exec error: %w", err)
+	}
+	err = tx.Commit()
+	if err != nil {
+		return nil, fmt.Errorf("error in DeleteUserCascade: sql.Tx.Commit error: %w", err)
+	}
+	return append(addCascadedRows(result, cascaded), userRowCount{Table: "Users", Rows: 1}), nil
 }
 
 /*
@@ -1832,28 +3011,15 @@ What Delete options are needed?
 	Delete Schedule should delete a single row on Schedules and multiple rows on WFS, VFS, UFS, and CompletedSchedules
 	Delete Completed Schedule should delete a single row on CompletedSchedules
 
-This does not contemplate CRUDing users yet.
+Users are CRUDed by CreateUser, RequestUser, UpdateUser, and DeleteUser, or DeleteUserCascade to delete everything they own.
 */
 
 func main() {
-	dbExists := false
-	if _, err := os.Stat(dbName); err == nil {
-		dbExists = true
-	}
-	db, err := sql.Open("sqlite3", dbName)
-	if err != nil {
-		log.Fatal(err)
-	}
-	env := &Env{
-		sample:       SampleModel{DB: db},
-		loggedInUser: "Seth",
-	}
-	defer env.sample.DB.Close()
-	if !dbExists {
-		if err = env.sample.CreateDatabase(); err != nil {
-			log.Fatalf("Crashed in main() with error: %v", err)
-		}
-	}
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// Fills the database with the sample schedules test1, test2, and test3 and their volunteers. It fails if the sample data was already loaded.
+func loadDemoData(env *Env) error {
 	schedules := []schedule{
 		{
 			ScheduleName:       "test1",
@@ -1880,7 +3046,9 @@ func main() {
 			User:               env.loggedInUser,
 		},
 	}
-	env.sample.CreateSchedules(env.loggedInUser, schedules)
+	if err := env.sample.CreateSchedules(env.loggedInUser, schedules); err != nil {
+		return fmt.Errorf("error in loadDemoData: %w", err)
+	}
 	weekdaysForSchedule := []weekdayForSchedule{
 		{
 			User:     env.loggedInUser,
@@ -1898,7 +3066,9 @@ func main() {
 			Schedule: Must(env.sample.RequestSchedule(env.loggedInUser, schedule{ScheduleName: "test3"})).ScheduleID,
 		},
 	}
-	env.sample.CreateWFS(env.loggedInUser, weekdaysForSchedule)
+	if err := env.sample.CreateWFS(env.loggedInUser, weekdaysForSchedule); err != nil {
+		return fmt.Errorf("error in loadDemoData: %w", err)
+	}
 	volunteers := []volunteer{
 		{
 			VolunteerName: "Tim",
@@ -1929,7 +3099,9 @@ func main() {
 			User:          env.loggedInUser,
 		},
 	}
-	env.sample.CreateVolunteers(env.loggedInUser, volunteers)
+	if err := env.sample.CreateVolunteers(env.loggedInUser, volunteers); err != nil {
+		return fmt.Errorf("error in loadDemoData: %w", err)
+	}
 	volunteersForSchedule := []volunteerForSchedule{
 		{
 			User:      env.loggedInUser,
@@ -1987,7 +3159,9 @@ func main() {
 			Volunteer: Must(env.sample.RequestVolunteer(env.loggedInUser, volunteer{VolunteerName: "George"})).VolunteerID,
 		},
 	}
-	env.sample.CreateVFS(env.loggedInUser, volunteersForSchedule)
+	if err := env.sample.CreateVFS(env.loggedInUser, volunteersForSchedule); err != nil {
+		return fmt.Errorf("error in loadDemoData: %w", err)
+	}
 	unavailabilitiesForSchedule := []unavailabilityForSchedule{
 		{
 			User: env.loggedInUser,
@@ -2038,9 +3212,8 @@ func main() {
 			Date: Must(env.sample.RequestDate(date{Month: 8, Day: 18, Year: 2024})).DateID,
 		},
 	}
-	env.sample.CreateUFS(env.loggedInUser, unavailabilitiesForSchedule)
-
-	fmt.Println("Done. Press enter to exit executable.")
-	_, _ = bufio.NewReader(os.Stdin).ReadString('\n')
-	fmt.Print(month{}, user{})
+	if err := env.sample.CreateUFS(env.loggedInUser, unavailabilitiesForSchedule); err != nil {
+		return fmt.Errorf("error in loadDemoData: %w", err)
+	}
+	return nil
 }
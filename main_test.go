@@ -1,15 +1,20 @@
 package main
 
 import (
+	"bytes"
 	"crypto/sha256"
 	"database/sql"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
+	"reflect"
 	"slices"
 	"strings"
 	"testing"
+	"time"
 
 	_ "github.com/mattn/go-sqlite3"
 )
@@ -232,6 +237,9 @@ func simulateUpdatedSampleVFS(currentUser string, generatedVFS []volunteerForSch
 	return
 }
 
+// A roster for test1 with the sample volunteers Tim (1), Bill (2), Jack (3), and George (4), listed out of date order
+const sampleScheduleData = `[{"Date":379,"Volunteers":[2,3,4]},{"Date":372,"Volunteers":[1,2,3]},{"Date":386,"Volunteers":[1,3]}]`
+
 func generateSampleUFS(currentUser string, sm SampleModel) (result []unavailabilityForSchedule) {
 	result = append(result, []unavailabilityForSchedule{
 		{
@@ -367,6 +375,7 @@ func setUpDatabaseModel(t *testing.T) (SampleModel, func(t *testing.T)) {
 	testDbPath := fmt.Sprintf("%s\\%s", t.TempDir(), testDbName)
 	model, teardown := setUpDatabase(t, testDbPath)
 	model.CreateDatabase()
+	model.MigrateDatabase()
 	return model, teardown
 }
 
@@ -391,6 +400,36 @@ func setUpDatabase(t *testing.T, testDbPath string) (SampleModel, func(t *testin
 	}
 }
 
+// Fills the database with the sample schedules, WFS, volunteers, VFS, and UFS used throughout these tests.
+func setUpSampleData(t *testing.T, env *Env) {
+	t.Log("running setUpSampleData")
+	err := env.sample.CreateSchedulesExtended(env.loggedInUser, generateSampleSchedules(env.sample), true)
+	if err != nil {
+		t.Errorf("Error setting up test (CreateSchedulesExtended failed): %v", err)
+		t.FailNow()
+	}
+	err = env.sample.CreateWFS(env.loggedInUser, generateSampleWFS(env.loggedInUser, env.sample))
+	if err != nil {
+		t.Errorf("Error setting up test (CreateWFS failed): %v", err)
+		t.FailNow()
+	}
+	err = env.sample.CreateVolunteers(env.loggedInUser, sampleVolunteers)
+	if err != nil {
+		t.Errorf("Error setting up test (CreateVolunteers failed): %v", err)
+		t.FailNow()
+	}
+	err = env.sample.CreateVFS(env.loggedInUser, generateSampleVFS(env.loggedInUser, env.sample))
+	if err != nil {
+		t.Errorf("Error setting up test (CreateVFS failed): %v", err)
+		t.FailNow()
+	}
+	err = env.sample.CreateUFS(env.loggedInUser, generateSampleUFS(env.loggedInUser, env.sample))
+	if err != nil {
+		t.Errorf("Error setting up test (CreateUFS failed): %v", err)
+		t.FailNow()
+	}
+}
+
 func TestCreateDatabase(t *testing.T) {
 	testDbPath := fmt.Sprintf("%s\\%s", t.TempDir(), testDbName)
 	testSample, tearDownDatabaseModel := setUpDatabase(t, testDbPath)
@@ -421,6 +460,50 @@ func TestCreateDatabase(t *testing.T) {
 	}
 }
 
+func TestMigrateDatabase(t *testing.T) {
+	testDbPath := fmt.Sprintf("%s\\%s", t.TempDir(), testDbName)
+	testSample, tearDownDatabaseModel := setUpDatabase(t, testDbPath)
+	defer tearDownDatabaseModel(t)
+	err := testSample.CreateDatabase()
+	if err != nil {
+		t.Errorf("Error setting up test (CreateDatabase failed): %v", err)
+		t.FailNow()
+	}
+	// A schedule created before any migration was applied must survive the migrations.
+	_, err = testSample.DB.Exec(`insert into Schedules (ScheduleName, ShiftsOff, VolunteersPerShift, User, StartDate, EndDate) values ("old", 1, 1, "Seth", 1, 2)`)
+	if err != nil {
+		t.Errorf("Error setting up test (inserting a schedule failed): %v", err)
+		t.FailNow()
+	}
+	tests := []struct {
+		name string
+	}{
+		{name: "Migrate a database created by CreateDatabase"},
+		{name: "Migrate an up to date database"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := testSample.MigrateDatabase()
+			if err != nil {
+				t.Errorf("got error: `%v`", err)
+			}
+			var version int
+			if err := testSample.DB.QueryRow(`pragma user_version`).Scan(&version); err != nil || version != len(migrations) {
+				t.Errorf("got user_version %d (error: %v), want %d", version, err, len(migrations))
+			}
+			revision, err := testSample.RequestScheduleRevision("Seth", 1)
+			checkResults(t, revision, 0, 0, err)
+		})
+	}
+	_, err = testSample.DB.Exec(fmt.Sprintf(`pragma user_version = %d`, len(migrations)+1))
+	if err != nil {
+		t.Errorf("Error setting up test (setting user_version failed): %v", err)
+	}
+	if err := testSample.MigrateDatabase(); err == nil {
+		t.Errorf("got no error, want an error for a database newer than the latest migration")
+	}
+}
+
 func TestRequestWeekday(t *testing.T) {
 	testSample, tearDownDatabaseModel := setUpDatabaseModel(t)
 	defer tearDownDatabaseModel(t)
@@ -495,6 +578,32 @@ func TestRequestDates(t *testing.T) {
 	}
 }
 
+func TestRequestDatesBetween(t *testing.T) {
+	sampleModel, tearDownDatabase := setUpDatabaseModel(t)
+	defer tearDownDatabase(t)
+	tests := []struct {
+		name      string
+		startDate int
+		endDate   int
+		want      []date
+	}{
+		{name: "Request three dates", startDate: 365, endDate: 367, want: []date{
+			{DateID: 365, Month: 12, Day: 31, Year: 2023, Weekday: "Sunday"},
+			{DateID: 366, Month: 1, Day: 1, Year: 2024, Weekday: "Monday"},
+			{DateID: 367, Month: 1, Day: 2, Year: 2024, Weekday: "Tuesday"},
+		}},
+		{name: "Request a single date", startDate: 1, endDate: 1, want: []date{{DateID: 1, Month: 1, Day: 1, Year: 2023, Weekday: "Sunday"}}},
+		{name: "Fail by providing an endDate before the startDate", startDate: 367, endDate: 365, want: []date{}},
+		{name: "Fail by providing a nonexistent endDate", startDate: 1, endDate: 100000, want: []date{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ans, err := sampleModel.RequestDatesBetween(tt.startDate, tt.endDate)
+			checkResultsSlice(t, ans, tt.want, []date{{DateID: tt.startDate}, {DateID: tt.endDate}}, err)
+		})
+	}
+}
+
 func TestCreateVolunteers(t *testing.T) {
 	env, tearDownEnvironment := setUpEnvironment(t)
 	defer tearDownEnvironment(t)
@@ -553,6 +662,7 @@ func TestRequestVolunteers(t *testing.T) {
 		t.Errorf("Error setting up test (CreateVolunteers failed): %v", err)
 		t.FailNow()
 	}
+	deactivatedAt := time.Now()
 	tests := []struct {
 		name  string
 		input []volunteer
@@ -570,6 +680,7 @@ func TestRequestVolunteers(t *testing.T) {
 			{VolunteerID: 6, VolunteerName: "Lance", User: env.loggedInUser},
 			{VolunteerID: 7, VolunteerName: "Larry", User: env.loggedInUser},
 		}},
+		{name: "Fail by filtering only by DeactivatedAt, which filters ignore", input: []volunteer{{DeactivatedAt: &deactivatedAt}}},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -598,7 +709,7 @@ func TestUpdateVolunteers(t *testing.T) {
 		{name: "Fail to update because of an empty volunteer struct", input: []volunteer{{}}, want: simulateUpdatedSampleVolunteers(env.loggedInUser)},
 		{name: "Update volunteers but don't provide any volunteers", input: []volunteer{}, want: simulateUpdatedSampleVolunteers(env.loggedInUser)},
 		{name: "Fail to create a duplicate volunteer (same User and VolunteerName, different VolunteerID)", input: []volunteer{{VolunteerID: 2, VolunteerName: "Timmy"}}, want: simulateUpdatedSampleVolunteers(env.loggedInUser)},
-		{name: "Try to update a nonexistent volunteer", input: []volunteer{{VolunteerID: 10, VolunteerName: "Timmy"}}, want: simulateUpdatedSampleVolunteers(env.loggedInUser)}, // the query gets no matches; so no error, but also no output
+		{name: "Fail to update a nonexistent volunteer", input: []volunteer{{VolunteerID: 10, VolunteerName: "Timmy"}}, want: simulateUpdatedSampleVolunteers(env.loggedInUser)},
 		{name: "Fail to update because it would create a duplicate Volunteer (0 existing, 2 proposed)", input: []volunteer{{VolunteerID: 2, VolunteerName: "test2a"}, {VolunteerID: 2, VolunteerName: "test2a"}}, want: simulateUpdatedSampleVolunteers(env.loggedInUser)},
 	}
 	for _, tt := range tests {
@@ -609,6 +720,94 @@ func TestUpdateVolunteers(t *testing.T) {
 	}
 }
 
+func TestVolunteerProfiles(t *testing.T) {
+	env, tearDownEnvironment := setUpEnvironment(t)
+	defer tearDownEnvironment(t)
+	err := env.sample.CreateVolunteers(env.loggedInUser, sampleVolunteers)
+	if err != nil {
+		t.Errorf("Error setting up test (CreateVolunteers failed): %v", err)
+		t.FailNow()
+	}
+	ann := volunteer{VolunteerName: "Ann", Email: "ann@example.com", Phone: "555-0100", PreferredContact: "sms", Notes: `prefers "early" shifts`}
+	t.Run("Create a volunteer with a profile", func(t *testing.T) {
+		err := env.sample.CreateVolunteers(env.loggedInUser, []volunteer{ann})
+		if err != nil {
+			t.Errorf("CreateVolunteers failed: %v", err)
+		}
+		ann.VolunteerID, ann.User = 8, env.loggedInUser
+		ans, err := env.sample.RequestVolunteer(env.loggedInUser, volunteer{VolunteerName: "Ann"})
+		checkResults(t, ans, ann, volunteer{}, err)
+	})
+	t.Run("Filter by profile fields", func(t *testing.T) {
+		ans, err := env.sample.RequestVolunteers(env.loggedInUser, []volunteer{{Email: "ann@example.com"}, {Notes: `prefers "early" shifts`, PreferredContact: "sms"}})
+		checkResultsSlice(t, ans, []volunteer{ann}, nil, err)
+	})
+	t.Run("Fail to create or update with an invalid profile", func(t *testing.T) {
+		invalid := []volunteer{
+			{VolunteerName: "Bea", Email: "not an address"},
+			{VolunteerName: "Bea", PreferredContact: "fax"},
+			{VolunteerName: "Bea", PreferredContact: "email", Phone: "555-0101"},
+		}
+		for i, val := range invalid {
+			err := env.sample.CreateVolunteers(env.loggedInUser, []volunteer{val})
+			if err == nil {
+				t.Errorf("CreateVolunteers accepted %+v", val)
+			}
+			if i == 2 { // Ann has an Email already, so an update may prefer it
+				continue
+			}
+			val.VolunteerID = ann.VolunteerID
+			err = env.sample.UpdateVolunteers(env.loggedInUser, []volunteer{val})
+			if err == nil {
+				t.Errorf("UpdateVolunteers accepted %+v", val)
+			}
+		}
+	})
+	t.Run("Update only the given fields", func(t *testing.T) {
+		err := env.sample.UpdateVolunteers(env.loggedInUser, []volunteer{{VolunteerID: ann.VolunteerID, Email: "ann@example.org", PreferredContact: "email"}})
+		if err != nil {
+			t.Errorf("UpdateVolunteers failed: %v", err)
+		}
+		ann.Email, ann.PreferredContact = "ann@example.org", "email"
+		ans, err := env.sample.RequestVolunteer(env.loggedInUser, volunteer{VolunteerID: ann.VolunteerID})
+		checkResults(t, ans, ann, volunteer{}, err)
+	})
+	t.Run("Keep a deactivated volunteer inactive when renaming it", func(t *testing.T) {
+		err := env.sample.DeleteVolunteers(env.loggedInUser, []volunteer{{VolunteerID: ann.VolunteerID}})
+		if err != nil {
+			t.Errorf("DeleteVolunteers failed: %v", err)
+		}
+		err = env.sample.UpdateVolunteers(env.loggedInUser, []volunteer{{VolunteerID: ann.VolunteerID, VolunteerName: "Anne", Inactive: false}})
+		if err != nil {
+			t.Errorf("UpdateVolunteers failed: %v", err)
+		}
+		ans, err := env.sample.RequestVolunteers(env.loggedInUser, []volunteer{{Inactive: true}})
+		if len(ans) == 1 && ans[0].DeactivatedAt == nil {
+			t.Errorf("got DeactivatedAt nil for an inactive volunteer")
+		} else if len(ans) == 1 {
+			ans[0].DeactivatedAt = nil
+		}
+		ann.VolunteerName, ann.Inactive = "Anne", true
+		checkResultsSlice(t, ans, []volunteer{ann}, nil, err)
+	})
+	t.Run("Reactivate a volunteer", func(t *testing.T) {
+		err := env.sample.ReactivateVolunteers(env.loggedInUser, []volunteer{{VolunteerID: ann.VolunteerID}})
+		if err != nil {
+			t.Errorf("ReactivateVolunteers failed: %v", err)
+		}
+		ans, err := env.sample.RequestVolunteers(env.loggedInUser, []volunteer{{Inactive: true}})
+		checkResults(t, len(ans), 0, 0, err)
+	})
+	t.Run("Clear profile fields by replacing the profile", func(t *testing.T) {
+		err := env.sample.replaceVolunteerProfile(env.loggedInUser, volunteer{VolunteerID: ann.VolunteerID, Phone: "555-0100"})
+		if err != nil {
+			t.Errorf("replaceVolunteerProfile failed: %v", err)
+		}
+		ans, err := env.sample.RequestVolunteer(env.loggedInUser, volunteer{VolunteerID: ann.VolunteerID})
+		checkResults(t, ans, volunteer{VolunteerID: ann.VolunteerID, VolunteerName: "Anne", User: env.loggedInUser, Phone: "555-0100"}, volunteer{}, err)
+	})
+}
+
 func TestDeleteVolunteers(t *testing.T) {
 	env, tearDownEnvironment := setUpEnvironment(t)
 	defer tearDownEnvironment(t)
@@ -631,7 +830,7 @@ func TestDeleteVolunteers(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			err := env.sample.DeleteVolunteers(env.loggedInUser, tt.input)
-			checkResultsErrOnly(t, tt.input, err, tt.want, env.sample.RequestVolunteers, env.loggedInUser, []volunteer{})
+			checkResultsErrOnly(t, tt.input, err, tt.want, env.sample.RequestActiveVolunteers, env.loggedInUser, []volunteer{})
 		})
 	}
 }
@@ -654,11 +853,76 @@ func TestCleanOrphanedVolunteers(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			err := env.sample.CleanOrphanedVolunteers(env.loggedInUser)
-			checkResultsErrOnly(t, tt.input, err, tt.want, env.sample.RequestVolunteers, env.loggedInUser, []volunteer{})
+			checkResultsErrOnly(t, tt.input, err, tt.want, env.sample.RequestActiveVolunteers, env.loggedInUser, []volunteer{})
 		})
 	}
 }
 
+func TestDeactivatedVolunteers(t *testing.T) {
+	env, tearDownEnvironment := setUpEnvironment(t)
+	defer tearDownEnvironment(t)
+	setUpSampleData(t, env)
+	err := env.sample.CreateCompletedSchedules(env.loggedInUser, []completedSchedule{{ScheduleData: sampleScheduleData, Schedule: 2}})
+	if err != nil {
+		t.Errorf("Error setting up test (CreateCompletedSchedules failed): %v", err)
+		t.FailNow()
+	}
+	err = env.sample.DeleteVolunteers(env.loggedInUser, []volunteer{{VolunteerName: "Tim"}})
+	if err != nil {
+		t.Errorf("Error setting up test (DeleteVolunteers failed): %v", err)
+		t.FailNow()
+	}
+	t.Run("Keep the row with a timestamp", func(t *testing.T) {
+		ans, err := env.sample.RequestVolunteer(env.loggedInUser, volunteer{VolunteerName: "Tim"})
+		checkResultsSlice(t, []bool{ans.Inactive, ans.DeactivatedAt != nil}, []bool{true, true}, nil, err)
+	})
+	t.Run("Leave inactive volunteers out of active lists", func(t *testing.T) {
+		ans, err := env.sample.RequestActiveVolunteers(env.loggedInUser, []volunteer{})
+		checkResultsSlice(t, ans, simulateCreatedSampleVolunteers(env.loggedInUser)[1:], nil, err)
+	})
+	t.Run("Keep the names of schedules and completed schedules", func(t *testing.T) {
+		data, err := env.sample.FetchAndSendData(env.loggedInUser, "test1")
+		checkResults(t, slices.ContainsFunc(data.VolunteerAvailabilityData, func(val map[string][]string) bool { _, ok := val["Tim"]; return ok }), true, false, err)
+		ans, err := env.sample.RequestRoster(env.loggedInUser, 1)
+		checkResults(t, ans.Shifts[0].Volunteers[0].VolunteerName, "Tim", "", err)
+	})
+	t.Run("Fail by adding an inactive volunteer to a schedule", func(t *testing.T) {
+		err := env.sample.CreateVFS(env.loggedInUser, []volunteerForSchedule{{Schedule: 4, Volunteer: 1}})
+		if err == nil || !strings.Contains(err.Error(), "inactive volunteers can't be added") {
+			t.Errorf("got error `%v`, want the inactive volunteer to be rejected", err)
+		}
+	})
+	t.Run("Leave an inactive volunteer out of received data", func(t *testing.T) {
+		data, err := env.sample.FetchAndSendData(env.loggedInUser, "test3")
+		if err != nil {
+			t.Errorf("Error setting up test (FetchAndSendData failed): %v", err)
+			t.FailNow()
+		}
+		data.VolunteerAvailabilityData = append(data.VolunteerAvailabilityData, map[string][]string{"Tim": {"2024-06-09"}})
+		plan, err := env.sample.PlanReceivedData(data)
+		checkResultsSlice(t, plan.VolunteersRejected, []string{"Tim"}, nil, err)
+		checkResults(t, len(plan.VolunteersToAdd)+len(plan.UnavailabilitiesToAdd), 0, 0, nil)
+		err = env.sample.RecieveAndStoreData(data)
+		if err != nil {
+			t.Errorf("RecieveAndStoreData failed: %v", err)
+		}
+		stored, err := env.sample.FetchAndSendData(env.loggedInUser, "test3")
+		checkResults(t, slices.ContainsFunc(stored.VolunteerAvailabilityData, func(val map[string][]string) bool { _, ok := val["Tim"]; return ok }), false, true, err)
+	})
+	t.Run("Reactivate a volunteer", func(t *testing.T) {
+		err := env.sample.ReactivateVolunteers(env.loggedInUser, []volunteer{{VolunteerID: 1}})
+		if err != nil {
+			t.Errorf("ReactivateVolunteers failed: %v", err)
+		}
+		ans, err := env.sample.RequestVolunteer(env.loggedInUser, volunteer{VolunteerID: 1})
+		checkResultsSlice(t, []volunteer{ans}, simulateCreatedSampleVolunteers(env.loggedInUser)[:1], nil, err)
+		err = env.sample.CreateVFS(env.loggedInUser, []volunteerForSchedule{{Schedule: 4, Volunteer: 1}})
+		if err != nil {
+			t.Errorf("CreateVFS failed for a reactivated volunteer: %v", err)
+		}
+	})
+}
+
 func TestCreateSchedulesExtended(t *testing.T) {
 	env, tearDownEnvironment := setUpEnvironment(t)
 	defer tearDownEnvironment(t)
@@ -1730,18 +1994,399 @@ func TestCleanOrphanedUFS(t *testing.T) {
 	}
 }
 
+func TestCreateCompletedSchedules(t *testing.T) {
+	env, tearDownEnvironment := setUpEnvironment(t)
+	defer tearDownEnvironment(t)
+	setUpSampleData(t, env)
+	want := []completedSchedule{{CScheduleID: 1, ScheduleData: sampleScheduleData, User: env.loggedInUser, Schedule: 2}}
+	tests := []struct {
+		name  string
+		input []completedSchedule
+		want  []completedSchedule
+	}{
+		{name: "Create a completed schedule", input: []completedSchedule{{ScheduleData: sampleScheduleData, Schedule: 2}}, want: want},
+		{name: "Fail by not providing a Schedule", input: []completedSchedule{{ScheduleData: sampleScheduleData}}, want: want},
+		{name: "Fail by providing a nonexistent Schedule", input: []completedSchedule{{ScheduleData: sampleScheduleData, Schedule: 100}}, want: want},
+		{name: "Fail by providing ScheduleData that is not a roster", input: []completedSchedule{{ScheduleData: `{"Date":372}`, Schedule: 2}}, want: want},
+		{name: "Fail by providing a shift without a Date", input: []completedSchedule{{ScheduleData: `[{"Volunteers":[1]}]`, Schedule: 2}}, want: want},
+		{name: "Fail by providing no input", input: []completedSchedule{}, want: want},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := env.sample.CreateCompletedSchedules(env.loggedInUser, tt.input)
+			checkResultsErrOnly(t, tt.input, err, tt.want, env.sample.RequestCompletedSchedules, env.loggedInUser, []completedSchedule{})
+		})
+	}
+}
+
+func TestRequestCompletedSchedules(t *testing.T) {
+	env, tearDownEnvironment := setUpEnvironment(t)
+	defer tearDownEnvironment(t)
+	setUpSampleData(t, env)
+	err := env.sample.CreateCompletedSchedules(env.loggedInUser, []completedSchedule{{ScheduleData: sampleScheduleData, Schedule: 2}, {ScheduleData: `[]`, Schedule: 3}})
+	if err != nil {
+		t.Errorf("Error setting up test (CreateCompletedSchedules failed): %v", err)
+		t.FailNow()
+	}
+	tests := []struct {
+		name  string
+		input []completedSchedule
+		want  []completedSchedule
+	}{
+		{name: "Request by ScheduleData", input: []completedSchedule{{ScheduleData: `[]`}}, want: []completedSchedule{{CScheduleID: 2, ScheduleData: `[]`, User: env.loggedInUser, Schedule: 3}}},
+		{name: "Request by ScheduleData with a quote", input: []completedSchedule{{ScheduleData: `[{"Name":"O'Brien"}]`}}, want: nil},
+		{name: "Request nothing by ScheduleData that tries to widen the query", input: []completedSchedule{{ScheduleData: `' or '1' = '1`}}, want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ans, err := env.sample.RequestCompletedSchedules(env.loggedInUser, tt.input)
+			checkResultsSlice(t, ans, tt.want, tt.input, err)
+		})
+	}
+}
+
+func TestDeleteCompletedSchedules(t *testing.T) {
+	env, tearDownEnvironment := setUpEnvironment(t)
+	defer tearDownEnvironment(t)
+	setUpSampleData(t, env)
+	err := env.sample.CreateCompletedSchedules(env.loggedInUser, []completedSchedule{
+		{ScheduleData: sampleScheduleData, Schedule: 2},
+		{ScheduleData: `[]`, Schedule: 2},
+		{ScheduleData: `[]`, Schedule: 3},
+	})
+	if err != nil {
+		t.Errorf("Error setting up test (CreateCompletedSchedules failed): %v", err)
+		t.FailNow()
+	}
+	tests := []struct {
+		name  string
+		input []completedSchedule
+		want  []completedSchedule
+	}{
+		{name: "Fail by not providing a CScheduleID or Schedule", input: []completedSchedule{{ScheduleData: `[]`}}, want: []completedSchedule{
+			{CScheduleID: 1, ScheduleData: sampleScheduleData, User: env.loggedInUser, Schedule: 2},
+			{CScheduleID: 2, ScheduleData: `[]`, User: env.loggedInUser, Schedule: 2},
+			{CScheduleID: 3, ScheduleData: `[]`, User: env.loggedInUser, Schedule: 3},
+		}},
+		{name: "Delete by CScheduleID", input: []completedSchedule{{CScheduleID: 1}}, want: []completedSchedule{
+			{CScheduleID: 2, ScheduleData: `[]`, User: env.loggedInUser, Schedule: 2},
+			{CScheduleID: 3, ScheduleData: `[]`, User: env.loggedInUser, Schedule: 3},
+		}},
+		{name: "Delete every completed schedule of a schedule", input: []completedSchedule{{Schedule: 3}}, want: []completedSchedule{
+			{CScheduleID: 2, ScheduleData: `[]`, User: env.loggedInUser, Schedule: 2},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := env.sample.DeleteCompletedSchedules(env.loggedInUser, tt.input)
+			checkResultsErrOnly(t, tt.input, err, tt.want, env.sample.RequestCompletedSchedules, env.loggedInUser, []completedSchedule{})
+		})
+	}
+}
+
+func TestFetchAndSendData(t *testing.T) {
+	env, tearDownEnvironment := setUpEnvironment(t)
+	defer tearDownEnvironment(t)
+	setUpSampleData(t, env)
+	tests := []struct {
+		name    string
+		input   string
+		want    SendReceiveDataStruct
+		wantErr bool
+	}{
+		{name: "Fetch test1", input: "test1", want: SendReceiveDataStruct{
+			User:         env.loggedInUser,
+			ScheduleName: "test1",
+			VolunteerAvailabilityData: []map[string][]string{
+				{"Tim": {"2024-01-14"}},
+				{"Bill": {"2024-01-21"}},
+				{"Jack": {}},
+				{"George": {}},
+			},
+			StartDate:           "2024-01-01",
+			EndDate:             "2024-03-01",
+			WeekdaysForSchedule: []string{"Sunday"},
+			ShiftsOff:           3,
+			VolunteersPerShift:  3,
+			CompletedSchedules:  []string{},
+			Revision:            7, // 1 WFS, 4 VFS, and 2 UFS rows were created by setUpSampleData
+		}},
+		{name: "Fetch test0, which has no volunteers", input: "test0", want: SendReceiveDataStruct{
+			User:                      env.loggedInUser,
+			ScheduleName:              "test0",
+			VolunteerAvailabilityData: []map[string][]string{},
+			StartDate:                 "2023-08-01",
+			EndDate:                   "2023-09-01",
+			WeekdaysForSchedule:       []string{"Monday"},
+			ShiftsOff:                 0,
+			VolunteersPerShift:        1,
+			CompletedSchedules:        []string{},
+			Revision:                  1,
+		}},
+		{name: "Fail to fetch a nonexistent schedule", input: "test9", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ans, err := env.sample.FetchAndSendData(env.loggedInUser, tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("got error: `%v`, want error: %t", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(ans, tt.want) {
+				t.Errorf("got %+v, want %+v", ans, tt.want)
+			}
+		})
+	}
+}
+
+func TestCreateUser(t *testing.T) {
+	env, tearDownEnvironment := setUpEnvironment(t)
+	defer tearDownEnvironment(t)
+	tests := []struct {
+		name     string
+		input    user
+		wantKind UserErrorKind
+		wantErr  bool
+	}{
+		{name: "Create a user", input: user{UserName: "Ann"}},
+		{name: "Fail to create a user that exists", input: user{UserName: "Seth"}, wantKind: UserAlreadyExists, wantErr: true},
+		{name: "Fail to create a user without a UserName", input: user{}, wantKind: UserNameInvalid, wantErr: true},
+		{name: "Fail to create a user whose UserName has a quote", input: user{UserName: `A"nn`}, wantKind: UserNameInvalid, wantErr: true},
+		{name: "Fail to create a user whose UserName ends with a space", input: user{UserName: "Ann "}, wantKind: UserNameInvalid, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := env.sample.CreateUser(tt.input)
+			var userErr *UserError
+			if !tt.wantErr && err != nil || tt.wantErr && (!errors.As(err, &userErr) || userErr.Kind != tt.wantKind) {
+				t.Errorf("got error: `%v`, want a UserError of kind %d: %t", err, tt.wantKind, tt.wantErr)
+			}
+		})
+	}
+	ans, err := env.sample.RequestUser(user{UserName: "Ann"})
+	checkResults(t, ans.UserName, "Ann", "", err)
+}
+
+func TestRequestUser(t *testing.T) {
+	env, tearDownEnvironment := setUpEnvironment(t)
+	defer tearDownEnvironment(t)
+	ans, err := env.sample.RequestUser(user{UserName: "Seth"})
+	checkResults(t, ans.UserName, "Seth", "", err)
+	_, err = env.sample.RequestUser(user{UserName: "Nobody"})
+	var userErr *UserError
+	if !errors.As(err, &userErr) || userErr.Kind != UserNotFound {
+		t.Errorf("got error: `%v`, want a UserNotFound UserError", err)
+	}
+}
+
+func TestUpdateUser(t *testing.T) {
+	env, tearDownEnvironment := setUpEnvironment(t)
+	defer tearDownEnvironment(t)
+	setUpSampleData(t, env)
+	err := env.sample.CreateCompletedSchedules(env.loggedInUser, []completedSchedule{{ScheduleData: sampleScheduleData, Schedule: 2}})
+	if err != nil {
+		t.Errorf("Error setting up test (CreateCompletedSchedules failed): %v", err)
+		t.FailNow()
+	}
+	err = env.sample.CreateUser(user{UserName: "Ann"})
+	if err != nil {
+		t.Errorf("Error setting up test (CreateUser failed): %v", err)
+		t.FailNow()
+	}
+	before, err := env.sample.RequestBackup(env.loggedInUser)
+	if err != nil {
+		t.Errorf("Error setting up test (RequestBackup failed): %v", err)
+		t.FailNow()
+	}
+	t.Run("Fail to rename a user to an existing UserName", func(t *testing.T) {
+		err := env.sample.UpdateUser(env.loggedInUser, user{UserName: "Ann"})
+		var userErr *UserError
+		if !errors.As(err, &userErr) || userErr.Kind != UserAlreadyExists {
+			t.Errorf("got error: `%v`, want a UserAlreadyExists UserError", err)
+		}
+	})
+	t.Run("Fail to update a user without new values", func(t *testing.T) {
+		err := env.sample.UpdateUser(env.loggedInUser, user{})
+		if err == nil {
+			t.Errorf("got no error, want an error")
+		}
+	})
+	t.Run("Rename a user and every row it owns", func(t *testing.T) {
+		_, err := env.sample.Login(env.loggedInUser, "wrong horse", "")
+		if err == nil {
+			t.Errorf("Error setting up test (Login succeeded without a password)")
+		}
+		auditBefore, err := env.sample.RequestAuditLog(env.loggedInUser, auditFilter{User: env.loggedInUser})
+		if err != nil || len(auditBefore) == 0 {
+			t.Errorf("Error setting up test (RequestAuditLog returned %d rows): %v", len(auditBefore), err)
+		}
+		err = env.sample.UpdateUser(env.loggedInUser, user{UserName: "Sam"})
+		if err != nil {
+			t.Errorf("got error: `%v`", err)
+		}
+		auditAfter, err := env.sample.RequestAuditLog("Sam", auditFilter{User: "Sam"})
+		if err != nil || len(auditAfter) <= len(auditBefore) {
+			t.Errorf("got %d AuditLog rows for the new UserName (error: `%v`), want more than the %d of the old one", len(auditAfter), err, len(auditBefore))
+		}
+		attempts, err := env.sample.RequestLoginAttempts("Sam", 0)
+		checkResults(t, len(attempts), 1, 0, err)
+		attempts, err = env.sample.RequestLoginAttempts(env.loggedInUser, 0)
+		checkResults(t, len(attempts), 0, 0, err)
+		after, err := env.sample.RequestBackup("Sam")
+		if err != nil {
+			t.Errorf("got error: `%v`", err)
+		}
+		before.User, before.CreatedAt = after.User, after.CreatedAt
+		if !reflect.DeepEqual(after, before) {
+			t.Errorf("got %+v, want %+v", after, before)
+		}
+		_, err = env.sample.RequestUser(user{UserName: env.loggedInUser})
+		if err == nil {
+			t.Errorf("got no error requesting the old UserName, want an error")
+		}
+	})
+	t.Run("Replace a Password without renaming", func(t *testing.T) {
+		err := env.sample.UpdateUser("Ann", user{Password: []byte("hash")})
+		ans, requestErr := env.sample.RequestUser(user{UserName: "Ann"})
+		if err != nil || requestErr != nil || string(ans.Password) != "hash" {
+			t.Errorf("got %+v (errors: `%v` and `%v`), want Password `hash`", ans, err, requestErr)
+		}
+	})
+}
+
+func TestDeleteUser(t *testing.T) {
+	env, tearDownEnvironment := setUpEnvironment(t)
+	defer tearDownEnvironment(t)
+	setUpSampleData(t, env)
+	err := env.sample.CreateUser(user{UserName: "Ann"})
+	if err != nil {
+		t.Errorf("Error setting up test (CreateUser failed): %v", err)
+		t.FailNow()
+	}
+	tests := []struct {
+		name     string
+		input    string
+		wantKind UserErrorKind
+		wantErr  bool
+	}{
+		{name: "Delete a user without rows", input: "Ann"},
+		{name: "Fail to delete a user that was deleted", input: "Ann", wantKind: UserNotFound, wantErr: true},
+		{name: "Fail to delete a user that still owns rows", input: "Seth", wantKind: UserStillReferenced, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := env.sample.DeleteUser(tt.input)
+			var userErr *UserError
+			if !tt.wantErr && err != nil || tt.wantErr && (!errors.As(err, &userErr) || userErr.Kind != tt.wantKind) {
+				t.Errorf("got error: `%v`, want a UserError of kind %d: %t", err, tt.wantKind, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDeleteUserCascade(t *testing.T) {
+	env, tearDownEnvironment := setUpEnvironment(t)
+	defer tearDownEnvironment(t)
+	setUpSampleData(t, env)
+	_, _, err := env.sample.IssueSession("Seth")
+	if err != nil {
+		t.Errorf("Error setting up test (IssueSession failed): %v", err)
+		t.FailNow()
+	}
+	// rows that only go away through foreign key cascades: Ann's membership of test1, the members of a group, and a volunteer of an organization
+	err = env.sample.CreateUser(user{UserName: "Ann"})
+	if err != nil {
+		t.Errorf("Error setting up test (CreateUser failed): %v", err)
+		t.FailNow()
+	}
+	err = env.sample.ShareSchedule("Seth", 2, "Ann", ScheduleViewer)
+	if err != nil {
+		t.Errorf("Error setting up test (ShareSchedule failed): %v", err)
+		t.FailNow()
+	}
+	group, err := env.sample.CreateVolunteerGroup("Seth", "regulars")
+	if err != nil {
+		t.Errorf("Error setting up test (CreateVolunteerGroup failed): %v", err)
+		t.FailNow()
+	}
+	err = env.sample.AddGroupVolunteers("Seth", group.GroupID, []volunteer{{VolunteerName: "Tim"}, {VolunteerName: "Bill"}})
+	if err != nil {
+		t.Errorf("Error setting up test (AddGroupVolunteers failed): %v", err)
+		t.FailNow()
+	}
+	organizationStruct, err := env.sample.CreateOrganization("Seth", "Shelter")
+	if err != nil {
+		t.Errorf("Error setting up test (CreateOrganization failed): %v", err)
+		t.FailNow()
+	}
+	err = env.sample.AddOrganizationVolunteers("Seth", organizationStruct.OrganizationID, []volunteer{{VolunteerName: "Jack"}})
+	if err != nil {
+		t.Errorf("Error setting up test (AddOrganizationVolunteers failed): %v", err)
+		t.FailNow()
+	}
+	want := []userRowCount{
+		{Table: "APIKeys", Rows: 0},
+		{Table: "CompletedSchedules", Rows: 0},
+		{Table: "OrganizationMembers", Rows: 1},
+		{Table: "ScheduleMembers", Rows: 1},
+		{Table: "Sessions", Rows: 1},
+		{Table: "UnavailabilitiesForSchedule", Rows: 6},
+		{Table: "VolunteerGroupMembers", Rows: 2},
+		{Table: "VolunteerGroups", Rows: 1},
+		{Table: "WeekdaysForSchedule", Rows: 4},
+		{Table: "VolunteersForSchedule", Rows: 11},
+		{Table: "Schedules", Rows: 4},
+		{Table: "OrganizationVolunteers", Rows: 1},
+		{Table: "Volunteers", Rows: 7},
+		{Table: "Users", Rows: 1},
+	}
+	ans, err := env.sample.PreviewDeleteUserCascade("Seth")
+	checkResultsSlice(t, ans, want, []userRowCount{}, err)
+	backupBefore, err := env.sample.RequestBackup("Seth")
+	if err != nil {
+		t.Errorf("Error setting up test (RequestBackup failed): %v", err)
+	}
+	var archive bytes.Buffer
+	ans, err = env.sample.DeleteUserCascade("Seth", &archive)
+	checkResultsSlice(t, ans, want, []userRowCount{}, err)
+	_, err = env.sample.RequestUser(user{UserName: "Seth"})
+	var userErr *UserError
+	if !errors.As(err, &userErr) || userErr.Kind != UserNotFound {
+		t.Errorf("got error: `%v`, want a UserNotFound UserError", err)
+	}
+	_, err = env.sample.DeleteUserCascade("Seth", nil)
+	if !errors.As(err, &userErr) || userErr.Kind != UserNotFound {
+		t.Errorf("got error: `%v`, want a UserNotFound UserError", err)
+	}
+	// the archive restores everything that was deleted, with new IDs
+	err = env.sample.CreateUser(user{UserName: "Seth"})
+	if err != nil {
+		t.Errorf("Error setting up test (CreateUser failed): %v", err)
+	}
+	_, err = env.sample.ImportBackup("Seth", &archive)
+	if err != nil {
+		t.Errorf("got error importing the archive: `%v`", err)
+	}
+	backupAfter, err := env.sample.RequestBackup("Seth")
+	wantLengths := []int{len(backupBefore.Volunteers), len(backupBefore.Schedules), len(backupBefore.WeekdaysForSchedule), len(backupBefore.VolunteersForSchedule), len(backupBefore.UnavailabilitiesForSchedule)}
+	ansLengths := []int{len(backupAfter.Volunteers), len(backupAfter.Schedules), len(backupAfter.WeekdaysForSchedule), len(backupAfter.VolunteersForSchedule), len(backupAfter.UnavailabilitiesForSchedule)}
+	checkResultsSlice(t, ansLengths, wantLengths, []int{}, err)
+}
+
 func TestMain(t *testing.T) {
 	tests := []struct {
 		name   string
 		input  any
 		output any
 	}{
-		{name: "run main", input: nil, output: nil},
+		{name: "run main", input: nil, output: 0},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			os.Remove("./sample.db")
-			main()
+			var stdout, stderr strings.Builder
+			ans := run([]string{"--db", filepath.Join(t.TempDir(), "sample.db"), "demo"}, strings.NewReader(""), &stdout, &stderr) // main() calls os.Exit, so run is called directly
+			if ans != tt.output {
+				t.Errorf("got exit code %d, want %v. stderr: %s", ans, tt.output, stderr.String())
+			}
 		})
 	}
 }
@@ -0,0 +1,368 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+type organization struct {
+	OrganizationID   int
+	OrganizationName string
+}
+
+// Returns an SQL condition that holds for the Organization column values of the organizations currentUser is a member of.
+func organizationAccess(column string, currentUser string) string {
+	return fmt.Sprintf(`%s in (select Organization from OrganizationMembers where User = "%s")`, column, currentUser)
+}
+
+// Creates an organization named organizationName with currentUser as its first member.
+func (sm SampleModel) CreateOrganization(currentUser string, organizationName string) (organization, error) {
+	if len(strings.TrimSpace(organizationName)) == 0 {
+		return organization{}, errors.New("error in CreateOrganization: method failed because organizationName is empty")
+	}
+	tx, err := sm.beginAudited(currentUser)
+	if err != nil {
+		return organization{}, fmt.Errorf("error in CreateOrganization: %w", err)
+	}
+	defer tx.Rollback()
+	var exists bool
+	err = tx.QueryRow(`select exists (select 1 from Organizations where OrganizationName = ?)`, organizationName).Scan(&exists)
+	if err != nil {
+		return organization{}, fmt.Errorf("error in CreateOrganization: sql.Tx.QueryRow error: %w", err)
+	}
+	if exists {
+		return organization{}, fmt.Errorf("error in CreateOrganization: method failed because organization `%s` already exists in the database", organizationName)
+	}
+	res, err := tx.Exec(`insert into Organizations (OrganizationName) values (?)`, organizationName)
+	if err != nil {
+		return organization{}, fmt.Errorf("error in CreateOrganization: sql.Tx.Exec error: %w", err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return organization{}, fmt.Errorf("error in CreateOrganization: sql.Result.LastInsertId error: %w", err)
+	}
+	_, err = tx.Exec(`insert into OrganizationMembers (Organization, User) values (?, ?)`, id, currentUser)
+	if err != nil {
+		return organization{}, fmt.Errorf("error in CreateOrganization: sql.Tx.Exec error: %w", err)
+	}
+	err = tx.Commit()
+	if err != nil {
+		return organization{}, fmt.Errorf("error in CreateOrganization: sql.Tx.Commit error: %w", err)
+	}
+	return organization{OrganizationID: int(id), OrganizationName: organizationName}, nil
+}
+
+// Lists the organizations currentUser is a member of, ordered by OrganizationID.
+func (sm SampleModel) RequestOrganizations(currentUser string) ([]organization, error) {
+	rows, err := sm.DB.Query(fmt.Sprintf(`select OrganizationID, OrganizationName from Organizations where %s order by OrganizationID`, organizationAccess("OrganizationID", currentUser)))
+	if err != nil {
+		return []organization{}, fmt.Errorf("error in RequestOrganizations: sql.DB.Query error: %w", err)
+	}
+	defer rows.Close()
+	result := []organization{}
+	for rows.Next() {
+		var organizationStruct organization
+		err = rows.Scan(&organizationStruct.OrganizationID, &organizationStruct.OrganizationName)
+		if err != nil {
+			return []organization{}, fmt.Errorf("error in RequestOrganizations: sql.Rows.Scan error: %w", err)
+		}
+		result = append(result, organizationStruct)
+	}
+	err = rows.Err()
+	if err != nil {
+		return []organization{}, fmt.Errorf("error in RequestOrganizations: sql.Rows.Err error: %w", err)
+	}
+	return result, nil
+}
+
+// Returns the organization currentUser is a member of that matches the OrganizationID or, if that is 0, the OrganizationName of organizationStruct.
+func (sm SampleModel) RequestOrganization(currentUser string, organizationStruct organization) (organization, error) {
+	organizations, err := sm.RequestOrganizations(currentUser)
+	if err != nil {
+		return organization{}, fmt.Errorf("error in RequestOrganization: %w", err)
+	}
+	for _, val := range organizations {
+		if val.OrganizationID == organizationStruct.OrganizationID || (organizationStruct.OrganizationID == 0 && val.OrganizationName == organizationStruct.OrganizationName) {
+			return val, nil
+		}
+	}
+	return organization{}, fmt.Errorf("error in RequestOrganization: method failed to locate organization %+v among the organizations of user `%s`", organizationStruct, currentUser)
+}
+
+// Deletes the organization with organizationID. Its volunteers stay with the users that created them. It fails while a member's schedule still uses a volunteer of another member, see checkPoolInUse.
+func (sm SampleModel) DeleteOrganization(currentUser string, organizationID int) error {
+	_, err := sm.RequestOrganization(currentUser, organization{OrganizationID: organizationID})
+	if err != nil {
+		return fmt.Errorf("error in DeleteOrganization: %w", err)
+	}
+	tx, err := sm.beginAudited(currentUser)
+	if err != nil {
+		return fmt.Errorf("error in DeleteOrganization: %w", err)
+	}
+	defer tx.Rollback()
+	_, err = tx.Exec(`delete from Organizations where OrganizationID = ?`, organizationID)
+	if err != nil {
+		return fmt.Errorf("error in DeleteOrganization: sql.Tx.Exec error: %w", err)
+	}
+	err = checkPoolInUse(tx.Tx)
+	if err != nil {
+		return fmt.Errorf("error in DeleteOrganization: %w", err)
+	}
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("error in DeleteOrganization: sql.Tx.Commit error: %w", err)
+	}
+	return nil
+}
+
+// Fails if a VolunteersForSchedule row inside tx points to a volunteer that is no longer in the pool of the schedule's owner, see volunteerPool. Methods that take volunteers out of a pool call it before committing, because the owner's schedule could no longer resolve the volunteer's name.
+func checkPoolInUse(tx *sql.Tx) error {
+	var volunteerName, scheduleName, owner string
+	err := tx.QueryRow(`select v.VolunteerName, s.ScheduleName, s.User from VolunteersForSchedule f
+		join Schedules s on s.ScheduleID = f.Schedule
+		join Volunteers v on v.VolunteerID = f.Volunteer
+		where v.User != s.User and not exists (select 1 from OrganizationVolunteers o join OrganizationMembers m on m.Organization = o.Organization where o.Volunteer = v.VolunteerID and m.User = s.User)
+		limit 1`).Scan(&volunteerName, &scheduleName, &owner)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error in checkPoolInUse: sql.Tx.QueryRow error: %w", err)
+	}
+	return fmt.Errorf("error in checkPoolInUse: method failed because volunteer `%s` is still on schedule `%s` of user `%s`", volunteerName, scheduleName, owner)
+}
+
+// Adds member to the organization with organizationID. Every member may add others.
+func (sm SampleModel) AddOrganizationMember(currentUser string, organizationID int, member string) error {
+	organizationStruct, err := sm.RequestOrganization(currentUser, organization{OrganizationID: organizationID})
+	if err != nil {
+		return fmt.Errorf("error in AddOrganizationMember: %w", err)
+	}
+	_, err = sm.RequestUser(user{UserName: member})
+	if err != nil {
+		return fmt.Errorf("error in AddOrganizationMember: %w", err)
+	}
+	res, err := sm.execAudited(currentUser, `insert into OrganizationMembers (Organization, User) values (?, ?) on conflict (Organization, User) do nothing`, organizationID, member)
+	if err != nil {
+		return fmt.Errorf("error in AddOrganizationMember: %w", err)
+	}
+	count, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error in AddOrganizationMember: sql.Result.RowsAffected error: %w", err)
+	}
+	if count == 0 {
+		return fmt.Errorf("error in AddOrganizationMember: method failed because user `%s` is already a member of organization `%s`", member, organizationStruct.OrganizationName)
+	}
+	return nil
+}
+
+// Removes member from the organization with organizationID, taking the volunteers it created out of the organization's pool. Every member may remove others, but the last member can't leave; delete the organization instead. Like DeleteOrganization it fails while a schedule still uses a volunteer that would leave its owner's pool.
+func (sm SampleModel) RemoveOrganizationMember(currentUser string, organizationID int, member string) error {
+	organizationStruct, err := sm.RequestOrganization(currentUser, organization{OrganizationID: organizationID})
+	if err != nil {
+		return fmt.Errorf("error in RemoveOrganizationMember: %w", err)
+	}
+	members, err := sm.RequestOrganizationMembers(currentUser, organizationID)
+	if err != nil {
+		return fmt.Errorf("error in RemoveOrganizationMember: %w", err)
+	}
+	found := false
+	for _, val := range members {
+		found = found || val == member
+	}
+	if !found {
+		return fmt.Errorf("error in RemoveOrganizationMember: method failed to locate user `%s` among the members of organization `%s`", member, organizationStruct.OrganizationName)
+	}
+	if len(members) == 1 {
+		return fmt.Errorf("error in RemoveOrganizationMember: method failed because user `%s` is the last member of organization `%s`", member, organizationStruct.OrganizationName)
+	}
+	tx, err := sm.beginAudited(currentUser)
+	if err != nil {
+		return fmt.Errorf("error in RemoveOrganizationMember: %w", err)
+	}
+	defer tx.Rollback()
+	_, err = tx.Exec(`delete from OrganizationVolunteers where Organization = ? and Volunteer in (select VolunteerID from Volunteers where User = ?)`, organizationID, member)
+	if err != nil {
+		return fmt.Errorf("error in RemoveOrganizationMember: sql.Tx.Exec error: %w", err)
+	}
+	_, err = tx.Exec(`delete from OrganizationMembers where Organization = ? and User = ?`, organizationID, member)
+	if err != nil {
+		return fmt.Errorf("error in RemoveOrganizationMember: sql.Tx.Exec error: %w", err)
+	}
+	err = checkPoolInUse(tx.Tx)
+	if err != nil {
+		return fmt.Errorf("error in RemoveOrganizationMember: %w", err)
+	}
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("error in RemoveOrganizationMember: sql.Tx.Commit error: %w", err)
+	}
+	return nil
+}
+
+// Lists the UserNames of the members of the organization with organizationID, ordered by when they joined.
+func (sm SampleModel) RequestOrganizationMembers(currentUser string, organizationID int) ([]string, error) {
+	_, err := sm.RequestOrganization(currentUser, organization{OrganizationID: organizationID})
+	if err != nil {
+		return []string{}, fmt.Errorf("error in RequestOrganizationMembers: %w", err)
+	}
+	rows, err := sm.DB.Query(`select User from OrganizationMembers where Organization = ? order by MemberID`, organizationID)
+	if err != nil {
+		return []string{}, fmt.Errorf("error in RequestOrganizationMembers: sql.DB.Query error: %w", err)
+	}
+	defer rows.Close()
+	result := []string{}
+	for rows.Next() {
+		var member string
+		err = rows.Scan(&member)
+		if err != nil {
+			return []string{}, fmt.Errorf("error in RequestOrganizationMembers: sql.Rows.Scan error: %w", err)
+		}
+		result = append(result, member)
+	}
+	err = rows.Err()
+	if err != nil {
+		return []string{}, fmt.Errorf("error in RequestOrganizationMembers: sql.Rows.Err error: %w", err)
+	}
+	return result, nil
+}
+
+// Moves volunteers created by currentUser into the pool of the organization with organizationID. A volunteer belongs to at most one organization.
+func (sm SampleModel) AddOrganizationVolunteers(currentUser string, organizationID int, volunteers []volunteer) error {
+	_, err := sm.RequestOrganization(currentUser, organization{OrganizationID: organizationID})
+	if err != nil {
+		return fmt.Errorf("error in AddOrganizationVolunteers: %w", err)
+	}
+	var toAdd []volunteer
+	for _, val := range volunteers {
+		volunteerStruct, err := sm.RequestVolunteer(currentUser, val)
+		if err != nil {
+			return fmt.Errorf("error in AddOrganizationVolunteers: %w", err)
+		}
+		toAdd = append(toAdd, volunteerStruct)
+	}
+	tx, err := sm.beginAudited(currentUser)
+	if err != nil {
+		return fmt.Errorf("error in AddOrganizationVolunteers: %w", err)
+	}
+	defer tx.Rollback()
+	for _, val := range toAdd {
+		var current int
+		err = tx.QueryRow(`select Organization from OrganizationVolunteers where Volunteer = ?`, val.VolunteerID).Scan(&current)
+		if err == nil && current != organizationID {
+			return fmt.Errorf("error in AddOrganizationVolunteers: method failed because volunteer `%s` already belongs to another organization", val.VolunteerName)
+		}
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("error in AddOrganizationVolunteers: sql.Tx.QueryRow error: %w", err)
+		}
+		_, err = tx.Exec(`insert into OrganizationVolunteers (Volunteer, Organization) values (?, ?) on conflict (Volunteer) do nothing`, val.VolunteerID, organizationID)
+		if err != nil {
+			return fmt.Errorf("error in AddOrganizationVolunteers: sql.Tx.Exec error: %w", err)
+		}
+	}
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("error in AddOrganizationVolunteers: sql.Tx.Commit error: %w", err)
+	}
+	return nil
+}
+
+// Takes volunteers created by currentUser out of the pool of the organization with organizationID. It fails while a schedule of another member still uses one of them; that member has to remove the volunteer from the schedule first.
+func (sm SampleModel) RemoveOrganizationVolunteers(currentUser string, organizationID int, volunteers []volunteer) error {
+	_, err := sm.RequestOrganization(currentUser, organization{OrganizationID: organizationID})
+	if err != nil {
+		return fmt.Errorf("error in RemoveOrganizationVolunteers: %w", err)
+	}
+	tx, err := sm.beginAudited(currentUser)
+	if err != nil {
+		return fmt.Errorf("error in RemoveOrganizationVolunteers: %w", err)
+	}
+	defer tx.Rollback()
+	for _, val := range volunteers {
+		volunteerStruct, err := sm.RequestVolunteer(currentUser, val)
+		if err != nil {
+			return fmt.Errorf("error in RemoveOrganizationVolunteers: %w", err)
+		}
+		res, err := tx.Exec(`delete from OrganizationVolunteers where Volunteer = ? and Organization = ?`, volunteerStruct.VolunteerID, organizationID)
+		if err != nil {
+			return fmt.Errorf("error in RemoveOrganizationVolunteers: sql.Tx.Exec error: %w", err)
+		}
+		count, err := res.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("error in RemoveOrganizationVolunteers: sql.Result.RowsAffected error: %w", err)
+		}
+		if count == 0 {
+			return fmt.Errorf("error in RemoveOrganizationVolunteers: method failed to locate volunteer `%s` in the organization", volunteerStruct.VolunteerName)
+		}
+	}
+	err = checkPoolInUse(tx.Tx)
+	if err != nil {
+		return fmt.Errorf("error in RemoveOrganizationVolunteers: %w", err)
+	}
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("error in RemoveOrganizationVolunteers: sql.Tx.Commit error: %w", err)
+	}
+	return nil
+}
+
+// The organization-scoped variant of RequestVolunteers: filters the pool of the organization with organizationID instead of the volunteers currentUser created.
+func (sm SampleModel) RequestOrganizationVolunteers(currentUser string, organizationID int, volunteers []volunteer) ([]volunteer, error) {
+	_, err := sm.RequestOrganization(currentUser, organization{OrganizationID: organizationID})
+	if err != nil {
+		return []volunteer{}, fmt.Errorf("error in RequestOrganizationVolunteers: %w", err)
+	}
+	return sm.requestVolunteersWhere("RequestOrganizationVolunteers", fmt.Sprintf(`VolunteerID in (select Volunteer from OrganizationVolunteers where Organization = %d)`, organizationID), volunteers)
+}
+
+// Returns an SQL condition that holds for the rows of Volunteers a schedule owned by currentUser may draw from: those currentUser created and those in the pools of its organizations.
+func volunteerPool(currentUser string) string {
+	return fmt.Sprintf(`(User = "%s" or VolunteerID in (select Volunteer from OrganizationVolunteers where %s))`, currentUser, organizationAccess("Organization", currentUser))
+}
+
+// Fails unless the volunteer with volunteerID is in the pool of owner, so it may be put on owner's schedules.
+func (sm SampleModel) checkInPool(owner string, volunteerID int) error {
+	var inPool bool
+	err := sm.DB.QueryRow(fmt.Sprintf(`select exists (select 1 from Volunteers where VolunteerID = ? and %s)`, volunteerPool(owner)), volunteerID).Scan(&inPool)
+	if err != nil {
+		return fmt.Errorf("error in checkInPool: sql.DB.QueryRow error: %w", err)
+	}
+	if !inPool {
+		return fmt.Errorf("error in checkInPool: method failed to locate VolunteerID %d in the pool of user `%s`", volunteerID, owner)
+	}
+	return nil
+}
+
+// Like RequestVolunteers, but also filters the pools of every organization currentUser is a member of, see volunteerPool.
+func (sm SampleModel) RequestPoolVolunteers(currentUser string, volunteers []volunteer) ([]volunteer, error) {
+	return sm.requestVolunteersWhere("RequestPoolVolunteers", volunteerPool(currentUser), volunteers)
+}
+
+// The organization-scoped variant of RequestSchedules: filters the schedules owned by the members of the organization with organizationID. Changing them still needs a role, see ShareSchedule.
+func (sm SampleModel) RequestOrganizationSchedules(currentUser string, organizationID int) ([]schedule, error) {
+	_, err := sm.RequestOrganization(currentUser, organization{OrganizationID: organizationID})
+	if err != nil {
+		return []schedule{}, fmt.Errorf("error in RequestOrganizationSchedules: %w", err)
+	}
+	rows, err := sm.DB.Query(`select ScheduleID, ScheduleName, ShiftsOff, VolunteersPerShift, User, StartDate, EndDate from Schedules where User in (select User from OrganizationMembers where Organization = ?) order by ScheduleID`, organizationID)
+	if err != nil {
+		return []schedule{}, fmt.Errorf("error in RequestOrganizationSchedules: sql.DB.Query error: %w", err)
+	}
+	defer rows.Close()
+	result := []schedule{}
+	for rows.Next() {
+		var scheduleStruct schedule
+		err = rows.Scan(&scheduleStruct.ScheduleID, &scheduleStruct.ScheduleName, &scheduleStruct.ShiftsOff, &scheduleStruct.VolunteersPerShift, &scheduleStruct.User, &scheduleStruct.StartDate, &scheduleStruct.EndDate)
+		if err != nil {
+			return []schedule{}, fmt.Errorf("error in RequestOrganizationSchedules: sql.Rows.Scan error: %w", err)
+		}
+		result = append(result, scheduleStruct)
+	}
+	err = rows.Err()
+	if err != nil {
+		return []schedule{}, fmt.Errorf("error in RequestOrganizationSchedules: sql.Rows.Err error: %w", err)
+	}
+	return result, nil
+}
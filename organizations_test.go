@@ -0,0 +1,158 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestOrganizations(t *testing.T) {
+	env, tearDownEnvironment := setUpEnvironment(t)
+	defer tearDownEnvironment(t)
+	setUpSampleData(t, env)
+	err := env.sample.CreateUser(user{UserName: "Ann"})
+	if err != nil {
+		t.Errorf("Error setting up test (CreateUser failed): %v", err)
+		t.FailNow()
+	}
+	var foodBank organization
+	t.Run("Create an organization", func(t *testing.T) {
+		foodBank, err = env.sample.CreateOrganization("Seth", "Food bank")
+		checkResults(t, foodBank, organization{OrganizationID: 1, OrganizationName: "Food bank"}, organization{}, err)
+		_, err = env.sample.CreateOrganization("Ann", "Food bank")
+		if err == nil {
+			t.Errorf("CreateOrganization created a second organization named Food bank")
+		}
+	})
+	t.Run("Fail to see an organization before joining it", func(t *testing.T) {
+		_, err := env.sample.RequestOrganizationVolunteers("Ann", foodBank.OrganizationID, []volunteer{})
+		if err == nil {
+			t.Errorf("RequestOrganizationVolunteers succeeded for a user that is not a member")
+		}
+		ans, err := env.sample.RequestOrganizations("Ann")
+		checkResultsSlice(t, ans, []organization{}, nil, err)
+	})
+	t.Run("Add a member", func(t *testing.T) {
+		err := env.sample.AddOrganizationMember("Seth", foodBank.OrganizationID, "Ann")
+		if err != nil {
+			t.Errorf("AddOrganizationMember failed: %v", err)
+		}
+		ans, err := env.sample.RequestOrganizationMembers("Ann", foodBank.OrganizationID)
+		checkResultsSlice(t, ans, []string{"Seth", "Ann"}, nil, err)
+	})
+	t.Run("Share volunteers through the pool", func(t *testing.T) {
+		err := env.sample.AddOrganizationVolunteers("Seth", foodBank.OrganizationID, []volunteer{{VolunteerName: "Tim"}, {VolunteerName: "Bill"}})
+		if err != nil {
+			t.Errorf("AddOrganizationVolunteers failed: %v", err)
+		}
+		tim := Must(env.sample.RequestVolunteer("Seth", volunteer{VolunteerName: "Tim"}))
+		bill := Must(env.sample.RequestVolunteer("Seth", volunteer{VolunteerName: "Bill"}))
+		ans, err := env.sample.RequestOrganizationVolunteers("Ann", foodBank.OrganizationID, []volunteer{})
+		checkResultsSlice(t, ans, []volunteer{tim, bill}, nil, err)
+		ans, err = env.sample.RequestPoolVolunteers("Ann", []volunteer{{VolunteerName: "Tim"}})
+		checkResultsSlice(t, ans, []volunteer{tim}, nil, err)
+		ans, err = env.sample.RequestVolunteers("Ann", []volunteer{})
+		checkResults(t, len(ans), 0, 0, err)
+	})
+	t.Run("Fail to add a volunteer of another member", func(t *testing.T) {
+		err := env.sample.AddOrganizationVolunteers("Ann", foodBank.OrganizationID, []volunteer{{VolunteerName: "Jack"}})
+		if err == nil {
+			t.Errorf("AddOrganizationVolunteers added a volunteer the user did not create")
+		}
+	})
+	t.Run("Draw from the pool in a member's schedule", func(t *testing.T) {
+		data := SendReceiveDataStruct{
+			User:                      "Ann",
+			ScheduleName:              "ann0",
+			VolunteerAvailabilityData: []map[string][]string{{"Tim": {"2024-10-06"}}},
+			StartDate:                 "2024-10-01",
+			EndDate:                   "2024-10-31",
+			WeekdaysForSchedule:       []string{"Sunday"},
+			ShiftsOff:                 1,
+			VolunteersPerShift:        2,
+		}
+		plan, err := env.sample.PlanReceivedData(data)
+		checkResults(t, len(plan.VolunteersToCreate), 0, 0, err)
+		err = env.sample.RecieveAndStoreData(data)
+		if err != nil {
+			t.Errorf("RecieveAndStoreData failed: %v", err)
+			t.FailNow()
+		}
+		ans, err := env.sample.FetchAndSendData("Ann", "ann0")
+		checkResults(t, len(ans.VolunteerAvailabilityData), 1, 0, err)
+		_, ok := ans.VolunteerAvailabilityData[0]["Tim"]
+		checkResults(t, ok, true, false, nil)
+		schedules, err := env.sample.RequestOrganizationSchedules("Seth", foodBank.OrganizationID)
+		checkResults(t, len(schedules), 5, 0, err)
+	})
+	t.Run("Fail to schedule a volunteer outside the owner's pool", func(t *testing.T) {
+		ann0 := Must(env.sample.RequestSchedule("Ann", schedule{ScheduleName: "ann0"}))
+		jack := Must(env.sample.RequestVolunteer("Seth", volunteer{VolunteerName: "Jack"}))
+		err := env.sample.CreateVFS("Ann", []volunteerForSchedule{{Schedule: ann0.ScheduleID, Volunteer: jack.VolunteerID}})
+		if err == nil {
+			t.Errorf("CreateVFS put Jack on schedule ann0, though he is not in the pool")
+		}
+		enrolled := Must(env.sample.RequestVFS("Ann", []volunteerForSchedule{{Schedule: ann0.ScheduleID}}))
+		err = env.sample.UpdateVFS("Ann", []volunteerForSchedule{{VFSID: enrolled[0].VFSID, Volunteer: jack.VolunteerID}})
+		if err == nil {
+			t.Errorf("UpdateVFS put Jack on schedule ann0, though he is not in the pool")
+		}
+	})
+	t.Run("Keep volunteers in the pool while a member's schedule uses them", func(t *testing.T) {
+		err := env.sample.RemoveOrganizationVolunteers("Seth", foodBank.OrganizationID, []volunteer{{VolunteerName: "Tim"}})
+		if err == nil {
+			t.Errorf("RemoveOrganizationVolunteers took Tim out of the pool while schedule ann0 uses him")
+		}
+		err = env.sample.RemoveOrganizationMember("Ann", foodBank.OrganizationID, "Seth")
+		if err == nil {
+			t.Errorf("RemoveOrganizationMember took Tim out of the pool while schedule ann0 uses him")
+		}
+		err = env.sample.DeleteOrganization("Ann", foodBank.OrganizationID)
+		if err == nil {
+			t.Errorf("DeleteOrganization took Tim out of the pool while schedule ann0 uses him")
+		}
+		ans, err := env.sample.RequestOrganizationVolunteers("Ann", foodBank.OrganizationID, []volunteer{})
+		checkResults(t, len(ans), 2, 0, err)
+	})
+	t.Run("Keep the last member in the organization", func(t *testing.T) {
+		ann0 := Must(env.sample.RequestSchedule("Ann", schedule{ScheduleName: "ann0"}))
+		tim := Must(env.sample.RequestVolunteer("Seth", volunteer{VolunteerName: "Tim"}))
+		october6 := Must(env.sample.RequestDate(date{Month: 10, Day: 6, Year: 2024}))
+		err := env.sample.CreateCompletedSchedules("Ann", []completedSchedule{{ScheduleData: fmt.Sprintf(`[{"Date":%d,"Volunteers":[%d]}]`, october6.DateID, tim.VolunteerID), Schedule: ann0.ScheduleID}})
+		if err != nil {
+			t.Errorf("Error setting up test (CreateCompletedSchedules failed): %v", err)
+			t.FailNow()
+		}
+		data := Must(env.sample.FetchAndSendData("Ann", "ann0"))
+		data.VolunteerAvailabilityData = []map[string][]string{}
+		err = env.sample.RecieveAndStoreData(data)
+		if err != nil {
+			t.Errorf("Error setting up test (RecieveAndStoreData failed): %v", err)
+			t.FailNow()
+		}
+		err = env.sample.RemoveOrganizationMember("Ann", foodBank.OrganizationID, "Seth")
+		if err != nil {
+			t.Errorf("RemoveOrganizationMember failed: %v", err)
+		}
+		ans, err := env.sample.RequestOrganizationVolunteers("Ann", foodBank.OrganizationID, []volunteer{})
+		checkResults(t, len(ans), 0, 0, err)
+		fetched, err := env.sample.FetchAndSendData("Ann", "ann0")
+		checkResults(t, len(fetched.VolunteerAvailabilityData), 0, 0, err)
+		completed := Must(env.sample.RequestCompletedSchedules("Ann", []completedSchedule{{Schedule: ann0.ScheduleID}}))
+		rosterStruct, err := env.sample.RequestRoster("Ann", completed[0].CScheduleID)
+		if err != nil || len(rosterStruct.Shifts) != 1 || len(rosterStruct.Shifts[0].Volunteers) != 1 || rosterStruct.Shifts[0].Volunteers[0].VolunteerName != "Tim" {
+			t.Errorf("got roster %+v (error: `%v`), want Tim on October 6", rosterStruct, err)
+		}
+		err = env.sample.RemoveOrganizationMember("Ann", foodBank.OrganizationID, "Ann")
+		if err == nil {
+			t.Errorf("RemoveOrganizationMember removed the last member")
+		}
+	})
+	t.Run("Delete an organization", func(t *testing.T) {
+		err := env.sample.DeleteOrganization("Ann", foodBank.OrganizationID)
+		if err != nil {
+			t.Errorf("DeleteOrganization failed: %v", err)
+		}
+		ans, err := env.sample.RequestOrganizations("Ann")
+		checkResultsSlice(t, ans, []organization{}, nil, err)
+	})
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// Argon2id parameters for new password hashes. Every hash records the parameters it was made with, so these can be raised later: hashes made with weaker ones still verify and are replaced by Login.
+type passwordParams struct {
+	Memory     uint32 // KiB
+	Time       uint32 // passes over the memory
+	Threads    uint8
+	SaltLength uint32 // bytes
+	KeyLength  uint32 // bytes
+}
+
+var defaultPasswordParams = passwordParams{Memory: 64 * 1024, Time: 3, Threads: 2, SaltLength: 16, KeyLength: 32}
+
+const (
+	minPasswordLength = 8
+	maxPasswordLength = 1024 // hashing cost does not depend on the length, but reading huge passwords from requests should not be allowed
+)
+
+// Checked against when the user does not exist, so a failed Login takes as long whether or not the UserName is known.
+var dummyPasswordHash = sync.OnceValue(func() []byte {
+	hash, _ := hashPassword("dummy password", defaultPasswordParams)
+	return hash
+})
+
+// Hashes password with Argon2id and a random salt. The result is stored in Users.Password as the text `$argon2id$v=19$m=MEMORY,t=TIME,p=THREADS$SALT$KEY`, with SALT and KEY in unpadded base64.
+func hashPassword(password string, params passwordParams) ([]byte, error) {
+	salt := make([]byte, params.SaltLength)
+	_, err := rand.Read(salt)
+	if err != nil {
+		return nil, fmt.Errorf("error in hashPassword: rand.Read error: %w", err)
+	}
+	key := argon2.IDKey([]byte(password), salt, params.Time, params.Memory, params.Threads, params.KeyLength)
+	encoding := base64.RawStdEncoding
+	return []byte(fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version, params.Memory, params.Time, params.Threads, encoding.EncodeToString(salt), encoding.EncodeToString(key))), nil
+}
+
+// Reads back the parameters, salt, and key of a hash made by hashPassword.
+func decodePasswordHash(encoded []byte) (passwordParams, []byte, []byte, error) {
+	parts := strings.Split(string(encoded), "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return passwordParams{}, nil, nil, errors.New("error in decodePasswordHash: method failed because the hash is not an argon2id hash")
+	}
+	var version int
+	_, err := fmt.Sscanf(parts[2], "v=%d", &version)
+	if err != nil || version != argon2.Version {
+		return passwordParams{}, nil, nil, fmt.Errorf("error in decodePasswordHash: method failed because `%s` is not a supported argon2 version", parts[2])
+	}
+	var params passwordParams
+	_, err = fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Time, &params.Threads)
+	if err != nil {
+		return passwordParams{}, nil, nil, fmt.Errorf("error in decodePasswordHash: fmt.Sscanf error: %w. Value of parts[3] is `%s`", err, parts[3])
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return passwordParams{}, nil, nil, fmt.Errorf("error in decodePasswordHash: base64.Encoding.DecodeString error: %w", err)
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return passwordParams{}, nil, nil, fmt.Errorf("error in decodePasswordHash: base64.Encoding.DecodeString error: %w", err)
+	}
+	params.SaltLength, params.KeyLength = uint32(len(salt)), uint32(len(key))
+	return params, salt, key, nil
+}
+
+// Reports whether password matches encoded, comparing the keys in constant time, and whether encoded was made with parameters other than defaultPasswordParams and should be replaced.
+func verifyPassword(password string, encoded []byte) (bool, bool, error) {
+	params, salt, key, err := decodePasswordHash(encoded)
+	if err != nil {
+		return false, false, fmt.Errorf("error in verifyPassword: %w", err)
+	}
+	candidate := argon2.IDKey([]byte(password), salt, params.Time, params.Memory, params.Threads, params.KeyLength)
+	return subtle.ConstantTimeCompare(candidate, key) == 1, params != defaultPasswordParams, nil
+}
+
+func validatePassword(userName, password string) error {
+	switch {
+	case len(password) < minPasswordLength:
+		return &UserError{Kind: UserPasswordInvalid, UserName: userName, Detail: fmt.Sprintf("it is shorter than %d bytes", minPasswordLength)}
+	case len(password) > maxPasswordLength:
+		return &UserError{Kind: UserPasswordInvalid, UserName: userName, Detail: fmt.Sprintf("it is longer than %d bytes", maxPasswordLength)}
+	}
+	return nil
+}
+
+// Hashes password and stores it as the Password of currentUser, replacing any previous one. Every session and API key of currentUser is revoked in the same transaction, so a stolen credential does not survive a password reset.
+func (sm SampleModel) SetPassword(currentUser string, password string) error {
+	err := validatePassword(currentUser, password)
+	if err != nil {
+		return fmt.Errorf("error in SetPassword: %w", err)
+	}
+	hash, err := hashPassword(password, defaultPasswordParams)
+	if err != nil {
+		return fmt.Errorf("error in SetPassword: %w", err)
+	}
+	tx, err := sm.beginAudited(currentUser)
+	if err != nil {
+		return fmt.Errorf("error in SetPassword: %w", err)
+	}
+	defer tx.Rollback()
+	res, err := tx.Exec(`update Users set Password = ? where UserName = ?`, hash, currentUser)
+	if err != nil {
+		return fmt.Errorf("error in SetPassword: sql.Tx.Exec error: %w", err)
+	}
+	count, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error in SetPassword: sql.Result.RowsAffected error: %w", err)
+	}
+	if count == 0 {
+		return fmt.Errorf("error in SetPassword: %w", &UserError{Kind: UserNotFound, UserName: currentUser})
+	}
+	_, err = tx.Exec(`delete from Sessions where User = ?`, currentUser)
+	if err != nil {
+		return fmt.Errorf("error in SetPassword: sql.Tx.Exec error: %w", err)
+	}
+	_, err = tx.Exec(`update APIKeys set RevokedAt = ? where User = ? and RevokedAt is null`, time.Now().Unix(), currentUser)
+	if err != nil {
+		return fmt.Errorf("error in SetPassword: sql.Tx.Exec error: %w", err)
+	}
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("error in SetPassword: sql.Tx.Commit error: %w", err)
+	}
+	return nil
+}
+
+// Returns the user named userName if password is its password. Unknown users, users without a password, and wrong passwords all fail with the same UserCredentialsInvalid UserError, so callers can't tell them apart. A hash made with outdated parameters is replaced on a successful login.
+// source is where the attempt comes from, for example the remote address of a request, or empty if unknown. Every attempt is recorded in LoginAttempts, and while userName or source has failed too often recently the password is not checked at all and Login fails with a UserLoginThrottled UserError, see loginPolicy. Attempts count as failures until the password turns out right, so concurrent guesses can't all get past the throttle, see beginLoginAttempt.
+func (sm SampleModel) Login(userName string, password string, source string) (user, error) {
+	attemptID, err := sm.beginLoginAttempt(userName, source, time.Now())
+	if err != nil {
+		return user{}, fmt.Errorf("error in Login: %w", err)
+	}
+	fail := func() (user, error) { // the failure was recorded by beginLoginAttempt already
+		return user{}, fmt.Errorf("error in Login: %w", &UserError{Kind: UserCredentialsInvalid, UserName: userName})
+	}
+	if len(password) > maxPasswordLength {
+		return fail()
+	}
+	found, err := sm.RequestUser(user{UserName: userName})
+	var userErr *UserError
+	if err != nil && !errors.As(err, &userErr) {
+		return user{}, fmt.Errorf("error in Login: %w", err)
+	}
+	if err != nil || len(found.Password) == 0 {
+		_, _, _ = verifyPassword(password, dummyPasswordHash())
+		return fail()
+	}
+	match, outdated, err := verifyPassword(password, found.Password)
+	if err != nil {
+		return user{}, fmt.Errorf("error in Login: %w", err)
+	}
+	if !match {
+		return fail()
+	}
+	if outdated { // validatePassword is skipped so passwords set under older rules still get upgraded
+		hash, err := hashPassword(password, defaultPasswordParams)
+		if err != nil {
+			return user{}, fmt.Errorf("error in Login: %w", err)
+		}
+		err = sm.UpdateUser(found.UserName, user{Password: hash})
+		if err != nil {
+			return user{}, fmt.Errorf("error in Login: %w", err)
+		}
+	}
+	err = sm.recordLoginSuccess(attemptID, userName, source)
+	if err != nil {
+		return user{}, fmt.Errorf("error in Login: %w", err)
+	}
+	return user{UserName: found.UserName}, nil
+}
+
+// Replaces the password of userName with newPassword after checking oldPassword, so a password can't be changed by whoever finds an unattended session. source is passed on to Login.
+func (sm SampleModel) ChangePassword(userName string, oldPassword string, newPassword string, source string) error {
+	_, err := sm.Login(userName, oldPassword, source)
+	if err != nil {
+		return fmt.Errorf("error in ChangePassword: %w", err)
+	}
+	err = sm.SetPassword(userName, newPassword)
+	if err != nil {
+		return fmt.Errorf("error in ChangePassword: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHashPassword(t *testing.T) {
+	first, err := hashPassword("correct horse", defaultPasswordParams)
+	if err != nil {
+		t.Errorf("got error: `%v`", err)
+	}
+	second, _ := hashPassword("correct horse", defaultPasswordParams)
+	if bytes.Equal(first, second) {
+		t.Errorf("got the same hash twice, want different salts")
+	}
+	if !strings.HasPrefix(string(first), "$argon2id$v=19$m=65536,t=3,p=2$") {
+		t.Errorf("got %s, want the parameters at the start", first)
+	}
+	tests := []struct {
+		name         string
+		password     string
+		encoded      []byte
+		wantMatch    bool
+		wantOutdated bool
+		wantErr      bool
+	}{
+		{name: "Verify the right password", password: "correct horse", encoded: first, wantMatch: true},
+		{name: "Reject a wrong password", password: "correct horsE", encoded: first},
+		{name: "Report a hash with outdated parameters", password: "correct horse", encoded: Must(hashPassword("correct horse", passwordParams{Memory: 1024, Time: 1, Threads: 1, SaltLength: 16, KeyLength: 32})), wantMatch: true, wantOutdated: true},
+		{name: "Fail with something that is not a hash", password: "correct horse", encoded: []byte("correct horse"), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			match, outdated, err := verifyPassword(tt.password, tt.encoded)
+			if match != tt.wantMatch || outdated != tt.wantOutdated || (err != nil) != tt.wantErr {
+				t.Errorf("got %t, %t, and error `%v`, want %t, %t, and an error: %t", match, outdated, err, tt.wantMatch, tt.wantOutdated, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLogin(t *testing.T) {
+	env, tearDownEnvironment := setUpEnvironment(t)
+	defer tearDownEnvironment(t)
+	err := env.sample.CreateUser(user{UserName: "Ann"})
+	if err != nil {
+		t.Errorf("Error setting up test (CreateUser failed): %v", err)
+		t.FailNow()
+	}
+	err = env.sample.SetPassword("Ann", "correct horse")
+	if err != nil {
+		t.Errorf("Error setting up test (SetPassword failed): %v", err)
+		t.FailNow()
+	}
+	tests := []struct {
+		name     string
+		userName string
+		password string
+		wantErr  bool
+	}{
+		{name: "Log in", userName: "Ann", password: "correct horse"},
+		{name: "Fail with a wrong password", userName: "Ann", password: "wrong horse", wantErr: true},
+		{name: "Fail with an unknown user", userName: "Nobody", password: "correct horse", wantErr: true},
+		{name: "Fail with a user without a password", userName: "Seth", password: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ans, err := env.sample.Login(tt.userName, tt.password, "")
+			var userErr *UserError
+			if tt.wantErr && (!errors.As(err, &userErr) || userErr.Kind != UserCredentialsInvalid || err.Error() != "error in Login: invalid credentials") {
+				t.Errorf("got error: `%v`, want a UserCredentialsInvalid UserError", err)
+			}
+			if !tt.wantErr && (err != nil || ans.UserName != tt.userName) {
+				t.Errorf("got %+v (error: `%v`), want user %s", ans, err, tt.userName)
+			}
+		})
+	}
+	t.Run("Upgrade a hash with outdated parameters", func(t *testing.T) {
+		weak := Must(hashPassword("correct horse", passwordParams{Memory: 1024, Time: 1, Threads: 1, SaltLength: 16, KeyLength: 32}))
+		err := env.sample.UpdateUser("Ann", user{Password: weak})
+		if err != nil {
+			t.Errorf("Error setting up test (UpdateUser failed): %v", err)
+		}
+		_, err = env.sample.Login("Ann", "correct horse", "")
+		stored, requestErr := env.sample.RequestUser(user{UserName: "Ann"})
+		if err != nil || requestErr != nil || !strings.HasPrefix(string(stored.Password), "$argon2id$v=19$m=65536,t=3,p=2$") {
+			t.Errorf("got Password %s (errors: `%v` and `%v`), want a hash with the default parameters", stored.Password, err, requestErr)
+		}
+	})
+}
+
+func TestChangePassword(t *testing.T) {
+	env, tearDownEnvironment := setUpEnvironment(t)
+	defer tearDownEnvironment(t)
+	err := env.sample.SetPassword("Seth", "correct horse")
+	if err != nil {
+		t.Errorf("Error setting up test (SetPassword failed): %v", err)
+		t.FailNow()
+	}
+	err = env.sample.ChangePassword("Seth", "wrong horse", "battery staple", "")
+	if err == nil {
+		t.Errorf("got no error with a wrong old password, want an error")
+	}
+	err = env.sample.ChangePassword("Seth", "correct horse", "short", "")
+	var userErr *UserError
+	if !errors.As(err, &userErr) || userErr.Kind != UserPasswordInvalid {
+		t.Errorf("got error: `%v`, want a UserPasswordInvalid UserError", err)
+	}
+	err = env.sample.ChangePassword("Seth", "correct horse", "battery staple", "")
+	if err != nil {
+		t.Errorf("got error: `%v`", err)
+	}
+	_, err = env.sample.Login("Seth", "battery staple", "")
+	if err != nil {
+		t.Errorf("got error logging in with the new password: `%v`", err)
+	}
+}
+
+func TestSetPasswordRevokesCredentials(t *testing.T) {
+	env, tearDownEnvironment := setUpEnvironment(t)
+	defer tearDownEnvironment(t)
+	sessionToken, _, err := env.sample.IssueSession("Seth")
+	if err != nil {
+		t.Errorf("Error setting up test (IssueSession failed): %v", err)
+		t.FailNow()
+	}
+	keyToken, _, err := env.sample.CreateAPIKey("Seth", "script", APIKeyAdmin, time.Time{})
+	if err != nil {
+		t.Errorf("Error setting up test (CreateAPIKey failed): %v", err)
+		t.FailNow()
+	}
+	err = env.sample.SetPassword("Seth", "correct horse")
+	if err != nil {
+		t.Errorf("got error: `%v`", err)
+	}
+	_, err = env.sample.ValidateSession(sessionToken)
+	if err == nil {
+		t.Errorf("ValidateSession accepted a session issued before the password was set")
+	}
+	_, err = env.sample.ValidateAPIKey(keyToken)
+	if err == nil {
+		t.Errorf("ValidateAPIKey accepted a key created before the password was set")
+	}
+	err = env.sample.SetPassword("Nobody", "correct horse")
+	var userErr *UserError
+	if !errors.As(err, &userErr) || userErr.Kind != UserNotFound {
+		t.Errorf("got error: `%v`, want a UserNotFound UserError", err)
+	}
+}
@@ -0,0 +1,455 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+const replHelp = `commands:
+  user NAME                          act as another user
+  schedule NAME                      select the schedule the commands below work on
+  add VOLUNTEER...                   add volunteers to the schedule
+  remove VOLUNTEER...                remove volunteers from the schedule
+  weekdays WEEKDAY...                set the weekdays of the schedule
+  mark VOLUNTEER out|in DATE...      mark a volunteer unavailable (out) or available again (in) on YYYY-MM-DD dates
+  show schedule|changes|roster       show the selected schedule, the uncommitted changes, or its latest roster
+  generate                           generate and store a roster for the schedule
+  commit                             store the uncommitted changes in one transaction
+  discard                            drop the uncommitted changes
+  quit                               leave the shell
+Any command of the command line (for example "volunteers list") also works and is stored at once.
+End a line with a tab or "?" to list the names that can complete its last word.
+`
+
+var errQuit = errors.New("quit")
+
+// Command line commands the shell does not forward: they would start another shell or server, or read stdin, which the shell is reading.
+var shellExcludedCommands = []string{"shell", "serve", "demo", "users"}
+
+// The interactive shell started by the shell command. Edits to the selected schedule are staged in a copy of its FetchAndSendData result and only stored by commit, through RecieveAndStoreData, so they are applied in one transaction and rejected if someone else changed the schedule in the meantime.
+type repl struct {
+	cli          cli
+	scheduleName string                 // the selected schedule, empty if none is selected
+	pending      *SendReceiveDataStruct // the selected schedule with the uncommitted edits, nil if there are none
+}
+
+func (c cli) shell(args []string) error {
+	_, err := parseCommandArgs(flag.NewFlagSet("shell", flag.ContinueOnError), args, 0, 0)
+	if err != nil {
+		return err
+	}
+	r := &repl{cli: c}
+	scanner := bufio.NewScanner(c.stdin)
+	for {
+		fmt.Fprint(c.stdout, r.prompt())
+		if !scanner.Scan() {
+			break
+		}
+		err := r.execute(scanner.Text())
+		if errors.Is(err, errQuit) {
+			return nil
+		}
+		if err != nil {
+			fmt.Fprintln(c.stderr, err)
+		}
+	}
+	fmt.Fprintln(c.stdout)
+	if r.pending != nil {
+		fmt.Fprintln(c.stderr, "uncommitted changes were discarded")
+	}
+	err = scanner.Err()
+	if err != nil {
+		return fmt.Errorf("error in shell: bufio.Scanner.Scan error: %w", err)
+	}
+	return nil
+}
+
+// Shows the user, the selected schedule, and a * if there are uncommitted changes.
+func (r *repl) prompt() string {
+	context := r.cli.env.loggedInUser
+	if len(r.scheduleName) > 0 {
+		context = fmt.Sprintf("%s/%s", context, r.scheduleName)
+	}
+	if r.pending != nil {
+		context += "*"
+	}
+	return context + "> "
+}
+
+// Splits line into words at spaces. Double quotes group words, so `add "Mary Ann"` adds one volunteer.
+func splitWords(line string) ([]string, error) {
+	var words []string
+	var current strings.Builder
+	inWord, quoted := false, false
+	for _, char := range line {
+		switch {
+		case char == '"':
+			quoted, inWord = !quoted, true
+		case !quoted && (char == ' ' || char == '\t'):
+			if inWord {
+				words = append(words, current.String())
+				current.Reset()
+				inWord = false
+			}
+		default:
+			current.WriteRune(char)
+			inWord = true
+		}
+	}
+	if quoted {
+		return nil, fmt.Errorf("error in splitWords: method failed because a quote in `%s` was not closed", line)
+	}
+	if inWord {
+		words = append(words, current.String())
+	}
+	return words, nil
+}
+
+func (r *repl) execute(line string) error {
+	if strings.HasSuffix(line, "\t") || strings.HasSuffix(line, "?") {
+		candidates, err := r.complete(strings.TrimSuffix(strings.TrimSuffix(line, "\t"), "?"))
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(r.cli.stdout, strings.Join(candidates, "  "))
+		return nil
+	}
+	words, err := splitWords(line)
+	if err != nil {
+		return err
+	}
+	if len(words) == 0 {
+		return nil
+	}
+	switch words[0] {
+	case "help":
+		fmt.Fprint(r.cli.stdout, replHelp)
+		return nil
+	case "quit", "exit":
+		if r.pending != nil {
+			return errors.New("there are uncommitted changes. Use commit or discard first")
+		}
+		return errQuit
+	case "user":
+		return r.selectUser(words[1:])
+	case "schedule":
+		if len(words) > 2 { // schedule weekdays set and schedule volunteers add of the command line
+			break
+		}
+		return r.selectSchedule(words[1:])
+	case "add":
+		return r.addVolunteers(words[1:])
+	case "remove":
+		return r.removeVolunteers(words[1:])
+	case "weekdays":
+		return r.setWeekdays(words[1:])
+	case "mark":
+		return r.mark(words[1:])
+	case "show":
+		return r.show(words[1:])
+	case "generate":
+		return r.generate(words[1:])
+	case "commit":
+		return r.commit(words[1:])
+	case "discard":
+		if r.pending == nil {
+			return errors.New("there are no uncommitted changes")
+		}
+		r.pending = nil
+		return nil
+	}
+	for _, val := range cliCommands {
+		if slices.Contains(shellExcludedCommands, val.path[0]) {
+			continue
+		}
+		if len(words) >= len(val.path) && slices.Equal(words[:len(val.path)], val.path) {
+			return val.run(r.cli, words[len(val.path):])
+		}
+	}
+	return fmt.Errorf("unknown command `%s`. Type help for a list of commands", words[0])
+}
+
+// Switching user or schedule would silently drop the staged edits, so it is refused until they are committed or discarded.
+func (r *repl) requireNoPending() error {
+	if r.pending != nil {
+		return errors.New("there are uncommitted changes. Use commit or discard first")
+	}
+	return nil
+}
+
+func (r *repl) selectUser(args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: user NAME")
+	}
+	if err := r.requireNoPending(); err != nil {
+		return err
+	}
+	_, err := r.cli.env.sample.RequestUser(user{UserName: args[0]})
+	if err != nil {
+		return err
+	}
+	r.cli.env = &Env{sample: r.cli.env.sample, loggedInUser: args[0]}
+	r.scheduleName = ""
+	return nil
+}
+
+func (r *repl) selectSchedule(args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: schedule NAME")
+	}
+	if err := r.requireNoPending(); err != nil {
+		return err
+	}
+	scheduleStruct, err := r.cli.env.sample.RequestSchedule(r.cli.env.loggedInUser, schedule{ScheduleName: args[0]})
+	if err != nil {
+		return err
+	}
+	r.scheduleName = scheduleStruct.ScheduleName
+	return nil
+}
+
+// Returns the staged copy of the selected schedule, fetching it on the first edit.
+func (r *repl) working() (*SendReceiveDataStruct, error) {
+	if len(r.scheduleName) == 0 {
+		return nil, errors.New("no schedule is selected. Use schedule NAME first")
+	}
+	if r.pending == nil {
+		data, err := r.cli.env.sample.FetchAndSendData(r.cli.env.loggedInUser, r.scheduleName)
+		if err != nil {
+			return nil, err
+		}
+		r.pending = &data
+	}
+	return r.pending, nil
+}
+
+func (r *repl) addVolunteers(args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: add VOLUNTEER...")
+	}
+	data, err := r.working()
+	if err != nil {
+		return err
+	}
+	for _, val := range args {
+		if availabilityIndex(data, val) < 0 {
+			data.VolunteerAvailabilityData = append(data.VolunteerAvailabilityData, map[string][]string{val: {}})
+		}
+	}
+	return nil
+}
+
+func (r *repl) removeVolunteers(args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: remove VOLUNTEER...")
+	}
+	data, err := r.working()
+	if err != nil {
+		return err
+	}
+	for _, val := range args {
+		i := availabilityIndex(data, val)
+		if i < 0 {
+			return fmt.Errorf("error in removeVolunteers: method failed to locate volunteer `%s` in schedule `%s`", val, r.scheduleName)
+		}
+		data.VolunteerAvailabilityData = slices.Delete(data.VolunteerAvailabilityData, i, i+1)
+	}
+	return nil
+}
+
+func (r *repl) setWeekdays(args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: weekdays WEEKDAY...")
+	}
+	var weekdays []string
+	for _, val := range args {
+		weekdayStruct, err := r.cli.env.sample.RequestWeekday(weekday{WeekdayName: val})
+		if err != nil {
+			return err
+		}
+		if !slices.Contains(weekdays, weekdayStruct.WeekdayName) {
+			weekdays = append(weekdays, weekdayStruct.WeekdayName)
+		}
+	}
+	data, err := r.working()
+	if err != nil {
+		return err
+	}
+	data.WeekdaysForSchedule = weekdays
+	return nil
+}
+
+func (r *repl) mark(args []string) error {
+	if len(args) < 3 || args[1] != "out" && args[1] != "in" {
+		return errors.New("usage: mark VOLUNTEER out|in DATE...")
+	}
+	var dates []string
+	for _, val := range args[2:] {
+		dateStruct, err := r.cli.resolveDate(val)
+		if err != nil {
+			return err
+		}
+		dates = append(dates, isoDate(dateStruct))
+	}
+	data, err := r.working()
+	if err != nil {
+		return err
+	}
+	i := availabilityIndex(data, args[0])
+	if i < 0 {
+		return fmt.Errorf("error in mark: method failed to locate volunteer `%s` in schedule `%s`", args[0], r.scheduleName)
+	}
+	unavailable := data.VolunteerAvailabilityData[i][args[0]]
+	for _, val := range dates {
+		j := slices.Index(unavailable, val)
+		switch {
+		case args[1] == "out" && j < 0:
+			unavailable = append(unavailable, val)
+		case args[1] == "in" && j >= 0:
+			unavailable = slices.Delete(unavailable, j, j+1)
+		}
+	}
+	data.VolunteerAvailabilityData[i][args[0]] = unavailable
+	return nil
+}
+
+func (r *repl) show(args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: show schedule|changes|roster")
+	}
+	if len(r.scheduleName) == 0 {
+		return errors.New("no schedule is selected. Use schedule NAME first")
+	}
+	switch args[0] {
+	case "schedule":
+		data := r.pending
+		if data == nil {
+			fetched, err := r.cli.env.sample.FetchAndSendData(r.cli.env.loggedInUser, r.scheduleName)
+			if err != nil {
+				return err
+			}
+			data = &fetched
+		}
+		fmt.Fprintf(r.cli.stdout, "%s from %s to %s on %s, %d per shift with %d shifts off\n", data.ScheduleName, data.StartDate, data.EndDate, strings.Join(data.WeekdaysForSchedule, ", "), data.VolunteersPerShift, data.ShiftsOff)
+		rows := [][]string{}
+		for _, entry := range data.VolunteerAvailabilityData {
+			for name, dates := range entry {
+				rows = append(rows, []string{name, strings.Join(dates, " ")})
+			}
+		}
+		return r.cli.write([]string{"Volunteer", "Unavailable"}, rows, data)
+	case "changes":
+		if r.pending == nil {
+			fmt.Fprintln(r.cli.stdout, "no uncommitted changes")
+			return nil
+		}
+		plan, err := r.cli.env.sample.PlanReceivedData(*r.pending)
+		if err != nil {
+			return err
+		}
+		for _, val := range plan.Summary() {
+			fmt.Fprintln(r.cli.stdout, val)
+		}
+		return nil
+	case "roster":
+		return r.cli.rosterShow([]string{r.scheduleName})
+	}
+	return fmt.Errorf("cannot show `%s`. Use show schedule, show changes, or show roster", args[0])
+}
+
+// The generator reads the stored schedule, so it would ignore staged edits. They have to be committed first.
+func (r *repl) generate(args []string) error {
+	if len(args) != 0 {
+		return errors.New("usage: generate")
+	}
+	if len(r.scheduleName) == 0 {
+		return errors.New("no schedule is selected. Use schedule NAME first")
+	}
+	if err := r.requireNoPending(); err != nil {
+		return err
+	}
+	return r.cli.rosterGenerate([]string{r.scheduleName})
+}
+
+func (r *repl) commit(args []string) error {
+	if len(args) != 0 {
+		return errors.New("usage: commit")
+	}
+	if r.pending == nil {
+		return errors.New("there are no uncommitted changes")
+	}
+	plan, err := r.cli.env.sample.PlanReceivedData(*r.pending)
+	if err != nil {
+		return err
+	}
+	err = r.cli.env.sample.RecieveAndStoreData(*r.pending)
+	if err != nil {
+		return err
+	}
+	r.pending = nil
+	fmt.Fprintf(r.cli.stdout, "committed %d changes\n", len(plan.Summary()))
+	return nil
+}
+
+// Returns the words that can complete the last word of line, based on the words before it. Volunteer and schedule names come from RequestVolunteers and RequestSchedules.
+func (r *repl) complete(line string) ([]string, error) {
+	words, err := splitWords(line)
+	if err != nil {
+		return nil, err
+	}
+	prefix := ""
+	if len(words) > 0 && !strings.HasSuffix(line, " ") {
+		prefix, words = words[len(words)-1], words[:len(words)-1]
+	}
+	var options []string
+	switch {
+	case len(words) == 0:
+		options = []string{"user", "schedule", "add", "remove", "weekdays", "mark", "show", "generate", "commit", "discard", "help", "quit"}
+		for _, val := range cliCommands {
+			if !slices.Contains(options, val.path[0]) && !slices.Contains(shellExcludedCommands, val.path[0]) {
+				options = append(options, val.path[0])
+			}
+		}
+	case words[0] == "schedule" && len(words) == 1:
+		schedules, err := r.cli.env.sample.RequestSchedules(r.cli.env.loggedInUser, []schedule{})
+		if err != nil {
+			return nil, err
+		}
+		for _, val := range schedules {
+			options = append(options, val.ScheduleName)
+		}
+	case words[0] == "add" || words[0] == "remove" || words[0] == "mark" && len(words) == 1:
+		request := r.cli.env.sample.RequestVolunteers
+		if words[0] == "add" { // inactive volunteers can't be added, but may still be on the schedule
+			request = r.cli.env.sample.RequestActiveVolunteers
+		}
+		volunteers, err := request(r.cli.env.loggedInUser, []volunteer{})
+		if err != nil {
+			return nil, err
+		}
+		for _, val := range volunteers {
+			options = append(options, val.VolunteerName)
+		}
+	case words[0] == "mark" && len(words) == 2:
+		options = []string{"out", "in"}
+	case words[0] == "show" && len(words) == 1:
+		options = []string{"schedule", "changes", "roster"}
+	case words[0] == "weekdays":
+		_, options, err = r.cli.env.sample.requestCalendarNames()
+		if err != nil {
+			return nil, err
+		}
+	}
+	var result []string
+	for _, val := range options {
+		if strings.HasPrefix(strings.ToLower(val), strings.ToLower(prefix)) {
+			result = append(result, val)
+		}
+	}
+	slices.Sort(result)
+	return result, nil
+}
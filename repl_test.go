@@ -0,0 +1,88 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestShell(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "shell.db")
+	runCLI(t, dbPath, 0, "demo")
+	t.Run("Stage edits and commit them", func(t *testing.T) {
+		input := strings.Join([]string{
+			"schedule test1",
+			"mark Tim out 2024-01-28 2024-02-04",
+			"mark Tim in 2024-01-14",
+			`add "Mary Ann"`,
+			"show changes",
+			"quit",
+			"commit",
+			"show schedule",
+			"quit",
+		}, "\n")
+		var stdout, stderr strings.Builder
+		ans := run([]string{"--db", dbPath, "shell"}, strings.NewReader(input), &stdout, &stderr)
+		if ans != 0 {
+			t.Errorf("got exit code %d, want 0", ans)
+		}
+		wantChanges := "remove unavailability of Tim on 2024-01-14\ncreate volunteer Mary Ann\nadd volunteer Mary Ann to the schedule\nadd unavailability of Tim on 2024-01-28\nadd unavailability of Tim on 2024-02-04\n"
+		if !strings.Contains(stdout.String(), wantChanges) || !strings.Contains(stdout.String(), "committed 5 changes") {
+			t.Errorf("got %q, want it to contain %q", stdout.String(), wantChanges)
+		}
+		if !strings.Contains(stdout.String(), "Seth/test1*> ") || !strings.Contains(stdout.String(), "Tim        2024-01-28 2024-02-04") {
+			t.Errorf("got %q", stdout.String())
+		}
+		if stderr.String() != "there are uncommitted changes. Use commit or discard first\n" {
+			t.Errorf("got stderr %q", stderr.String())
+		}
+	})
+	t.Run("Discard staged edits at the end of input", func(t *testing.T) {
+		var stdout, stderr strings.Builder
+		run([]string{"--db", dbPath, "shell"}, strings.NewReader("schedule test1\nremove Tim\n"), &stdout, &stderr)
+		if stderr.String() != "uncommitted changes were discarded\n" {
+			t.Errorf("got stderr %q", stderr.String())
+		}
+		ans, _ := runCLI(t, dbPath, 0, "--format", "csv", "roster", "generate", "test1")
+		if !strings.Contains(ans, "Tim") {
+			t.Errorf("got %q, want Tim to still be in the roster", ans)
+		}
+	})
+}
+
+func TestComplete(t *testing.T) {
+	env, tearDownEnvironment := setUpEnvironment(t)
+	defer tearDownEnvironment(t)
+	setUpSampleData(t, env)
+	r := &repl{cli: cli{env: env}}
+	tests := []struct {
+		name   string
+		input  string
+		output []string
+	}{
+		{name: "Complete a command", input: "sh", output: []string{"show"}},
+		{name: "Complete a schedule name", input: "schedule te", output: []string{"test0", "test1", "test2", "test3"}},
+		{name: "Complete a volunteer name", input: "mark b", output: []string{"Bill", "Bob"}},
+		{name: "Complete out or in", input: "mark Bill ", output: []string{"in", "out"}},
+		{name: "Complete a weekday", input: "weekdays Monday T", output: []string{"Thursday", "Tuesday"}},
+		{name: "Complete nothing after a date", input: "mark Bill out 2024", output: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ans, err := r.complete(tt.input)
+			if err != nil || !reflect.DeepEqual(ans, tt.output) {
+				t.Errorf("got %v (error: %v), want %v", ans, err, tt.output)
+			}
+		})
+	}
+}
+
+func TestSplitWords(t *testing.T) {
+	ans, err := splitWords(`add "Mary Ann"  Tim`)
+	checkResultsSlice(t, ans, []string{"add", "Mary Ann", "Tim"}, []string{}, err)
+	_, err = splitWords(`add "Mary`)
+	if err == nil {
+		t.Errorf("got no error for an unclosed quote, want an error")
+	}
+}
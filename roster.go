@@ -0,0 +1,183 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+)
+
+// ScheduleData of a CompletedSchedules row is the JSON encoding of a []rosterShift, for example `[{"Date":372,"Volunteers":[1,2,3]}]`.
+type rosterShift struct {
+	Date       int   // DateID
+	Volunteers []int // VolunteerIDs assigned to the shift
+}
+
+// A completed schedule with its DateIDs and VolunteerIDs resolved through Dates and Volunteers.
+type roster struct {
+	CScheduleID int
+	Schedule    schedule
+	Shifts      []resolvedShift // ordered by DateID
+}
+
+type resolvedShift struct {
+	Date       date
+	Volunteers []volunteer
+}
+
+func parseScheduleData(scheduleData string) ([]rosterShift, error) {
+	var shifts []rosterShift
+	err := json.Unmarshal([]byte(scheduleData), &shifts)
+	if err != nil {
+		return []rosterShift{}, fmt.Errorf("error in parseScheduleData: json.Unmarshal error: %w", err)
+	}
+	for _, shift := range shifts {
+		if shift.Date < 1 {
+			return []rosterShift{}, fmt.Errorf("error in parseScheduleData: method failed because a shift did not have a value for Date: %+v", shift)
+		}
+		if slices.Contains(shift.Volunteers, 0) {
+			return []rosterShift{}, fmt.Errorf("error in parseScheduleData: method failed because a shift had an empty VolunteerID: %+v", shift)
+		}
+	}
+	return shifts, nil
+}
+
+func (sm SampleModel) RequestRoster(currentUser string, cScheduleID int) (roster, error) {
+	completedSchedules, err := sm.RequestCompletedSchedules(currentUser, []completedSchedule{{CScheduleID: cScheduleID}})
+	if err != nil {
+		return roster{}, fmt.Errorf("error in RequestRoster: %w", err)
+	}
+	if len(completedSchedules) != 1 {
+		return roster{}, fmt.Errorf("error in RequestRoster: method failed to locate exactly one completed schedule with CScheduleID %d. Found %d matches", cScheduleID, len(completedSchedules))
+	}
+	scheduleStruct, err := sm.RequestSchedule(currentUser, schedule{ScheduleID: completedSchedules[0].Schedule})
+	if err != nil {
+		return roster{}, fmt.Errorf("error in RequestRoster: %w", err)
+	}
+	shifts, err := parseScheduleData(completedSchedules[0].ScheduleData)
+	if err != nil {
+		return roster{}, fmt.Errorf("error in RequestRoster: %w", err)
+	}
+	result := roster{CScheduleID: cScheduleID, Schedule: scheduleStruct, Shifts: []resolvedShift{}}
+	if len(shifts) == 0 {
+		return result, nil
+	}
+	var datesToRequest []date
+	var volunteersToRequest []volunteer
+	for _, shift := range shifts {
+		datesToRequest = append(datesToRequest, date{DateID: shift.Date})
+		for _, val := range shift.Volunteers {
+			if !slices.Contains(volunteersToRequest, volunteer{VolunteerID: val}) {
+				volunteersToRequest = append(volunteersToRequest, volunteer{VolunteerID: val})
+			}
+		}
+	}
+	dates, err := sm.RequestDates(datesToRequest)
+	if err != nil {
+		return roster{}, fmt.Errorf("error in RequestRoster: %w", err)
+	}
+	foundDates := map[int]date{}
+	for _, val := range dates {
+		foundDates[val.DateID] = val
+	}
+	foundVolunteers := map[int]volunteer{}
+	if len(volunteersToRequest) > 0 {
+		// a roster may name volunteers that have since left the owner's pool, so they're looked up by VolunteerID alone. Viewing the roster already needed access to its schedule
+		volunteers, err := sm.requestVolunteersWhere("RequestRoster", "1 = 1", volunteersToRequest)
+		if err != nil {
+			return roster{}, fmt.Errorf("error in RequestRoster: %w", err)
+		}
+		for _, val := range volunteers {
+			foundVolunteers[val.VolunteerID] = val
+		}
+	}
+	for _, shift := range shifts {
+		dateStruct, ok := foundDates[shift.Date]
+		if !ok {
+			return roster{}, fmt.Errorf("error in RequestRoster: method failed to locate DateID %d", shift.Date)
+		}
+		resolved := resolvedShift{Date: dateStruct, Volunteers: []volunteer{}}
+		for _, val := range shift.Volunteers {
+			volunteerStruct, ok := foundVolunteers[val]
+			if !ok {
+				return roster{}, fmt.Errorf("error in RequestRoster: method failed to locate VolunteerID %d", val)
+			}
+			resolved.Volunteers = append(resolved.Volunteers, volunteerStruct)
+		}
+		result.Shifts = append(result.Shifts, resolved)
+	}
+	slices.SortStableFunc(result.Shifts, func(a, b resolvedShift) int { return a.Date.DateID - b.Date.DateID })
+	return result, nil
+}
+
+// Assigns VolunteersPerShift of the schedule's volunteers to every date from StartDate to EndDate that falls on one of its WeekdaysForSchedule, without storing anything.
+// Volunteers are never assigned on a date they are unavailable. Each shift prefers volunteers who have had at least ShiftsOff shifts off, then the fewest shifts so far, then the longest time since their last shift, then VFSID order. If too few volunteers are rested, unavailable ones are still skipped but rested ones are not required, and if too few are available the shift stays short.
+func (sm SampleModel) GenerateRoster(currentUser string, scheduleID int) ([]rosterShift, error) {
+	scheduleStruct, err := sm.RequestSchedule(currentUser, schedule{ScheduleID: scheduleID})
+	if err != nil {
+		return []rosterShift{}, fmt.Errorf("error in GenerateRoster: %w", err)
+	}
+	state, err := sm.requestScheduleState(currentUser, scheduleStruct)
+	if err != nil {
+		return []rosterShift{}, fmt.Errorf("error in GenerateRoster: %w", err)
+	}
+	if len(state.wfs) == 0 || len(state.vfs) == 0 {
+		return []rosterShift{}, fmt.Errorf("error in GenerateRoster: method failed because schedule `%s` needs at least one weekday and one volunteer", scheduleStruct.ScheduleName)
+	}
+	dates, err := sm.RequestDatesBetween(scheduleStruct.StartDate, scheduleStruct.EndDate)
+	if err != nil {
+		return []rosterShift{}, fmt.Errorf("error in GenerateRoster: %w", err)
+	}
+	var weekdays []string
+	for _, val := range state.wfs {
+		weekdays = append(weekdays, val.Weekday)
+	}
+	type candidate struct {
+		vfs     volunteerForSchedule
+		shifts  int
+		last    int // index of the last shift, -1 if none yet
+		rested  bool
+		ordinal int
+	}
+	candidates := []*candidate{}
+	for i, val := range state.vfs {
+		candidates = append(candidates, &candidate{vfs: val, last: -1, ordinal: i})
+	}
+	result := []rosterShift{}
+	for _, dateStruct := range dates {
+		if !slices.Contains(weekdays, dateStruct.Weekday) {
+			continue
+		}
+		index := len(result)
+		var available []*candidate
+		for _, val := range candidates {
+			if slices.ContainsFunc(state.ufs[val.vfs.VFSID], func(ufs unavailabilityForSchedule) bool { return ufs.Date == dateStruct.DateID }) {
+				continue
+			}
+			val.rested = val.last < 0 || index-val.last-1 >= scheduleStruct.ShiftsOff
+			available = append(available, val)
+		}
+		slices.SortStableFunc(available, func(a, b *candidate) int {
+			switch {
+			case a.rested != b.rested:
+				if a.rested {
+					return -1
+				}
+				return 1
+			case a.shifts != b.shifts:
+				return a.shifts - b.shifts
+			case a.last != b.last:
+				return a.last - b.last
+			default:
+				return a.ordinal - b.ordinal
+			}
+		})
+		shift := rosterShift{Date: dateStruct.DateID, Volunteers: []int{}}
+		for _, val := range available[:min(len(available), scheduleStruct.VolunteersPerShift)] {
+			shift.Volunteers = append(shift.Volunteers, val.vfs.Volunteer)
+			val.shifts++
+			val.last = index
+		}
+		result = append(result, shift)
+	}
+	return result, nil
+}
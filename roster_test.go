@@ -0,0 +1,69 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseScheduleData(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    []rosterShift
+		wantErr bool
+	}{
+		{name: "Parse a roster", input: `[{"Date":372,"Volunteers":[1,2]},{"Date":379,"Volunteers":[]}]`, want: []rosterShift{{Date: 372, Volunteers: []int{1, 2}}, {Date: 379, Volunteers: []int{}}}},
+		{name: "Parse an empty roster", input: `[]`, want: []rosterShift{}},
+		{name: "Fail to parse a shift without a Date", input: `[{"Volunteers":[1]}]`, want: []rosterShift{}, wantErr: true},
+		{name: "Fail to parse a VolunteerID of 0", input: `[{"Date":372,"Volunteers":[0]}]`, want: []rosterShift{}, wantErr: true},
+		{name: "Fail to parse something other than JSON", input: `372: Tim, Bill`, want: []rosterShift{}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ans, err := parseScheduleData(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("got error: `%v`, want error: %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(ans, tt.want) {
+				t.Errorf("got %+v, want %+v", ans, tt.want)
+			}
+		})
+	}
+}
+
+func TestRequestRoster(t *testing.T) {
+	env, tearDownEnvironment := setUpEnvironment(t)
+	defer tearDownEnvironment(t)
+	setUpSampleData(t, env)
+	err := env.sample.CreateCompletedSchedules(env.loggedInUser, []completedSchedule{
+		{ScheduleData: sampleScheduleData, Schedule: 2},
+		{ScheduleData: `[{"Date":372,"Volunteers":[100]}]`, Schedule: 2},
+	})
+	if err != nil {
+		t.Errorf("Error setting up test (CreateCompletedSchedules failed): %v", err)
+		t.FailNow()
+	}
+	volunteers := simulateCreatedSampleVolunteers(env.loggedInUser)
+	tim, bill, jack, george := volunteers[0], volunteers[1], volunteers[2], volunteers[3]
+	want := roster{
+		CScheduleID: 1,
+		Schedule:    Must(env.sample.RequestSchedule(env.loggedInUser, schedule{ScheduleName: "test1"})),
+		Shifts: []resolvedShift{
+			{Date: date{DateID: 372, Month: 1, Day: 7, Year: 2024, Weekday: "Sunday"}, Volunteers: []volunteer{tim, bill, jack}},
+			{Date: date{DateID: 379, Month: 1, Day: 14, Year: 2024, Weekday: "Sunday"}, Volunteers: []volunteer{bill, jack, george}},
+			{Date: date{DateID: 386, Month: 1, Day: 21, Year: 2024, Weekday: "Sunday"}, Volunteers: []volunteer{tim, jack}},
+		},
+	}
+	ans, err := env.sample.RequestRoster(env.loggedInUser, 1)
+	if err != nil || !reflect.DeepEqual(ans, want) {
+		t.Errorf("got %+v (error: %v), want %+v", ans, err, want)
+	}
+	_, err = env.sample.RequestRoster(env.loggedInUser, 2)
+	if err == nil {
+		t.Errorf("got no error, want an error for a roster with a nonexistent VolunteerID")
+	}
+	_, err = env.sample.RequestRoster("Nobody", 1)
+	if err == nil {
+		t.Errorf("got no error, want an error for a roster of another user")
+	}
+}
@@ -0,0 +1,942 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const apiPrefix = "/api/v1"
+
+type contextKey string
+
+const userContextKey contextKey = "user"
+
+// Maps the errors returned by SampleModel methods to HTTP status codes. The methods report problems through their messages, so the messages are matched here.
+func statusForError(err error) int {
+	var conflict *RevisionConflictError
+	var userErr *UserError
+	message := strings.ToLower(err.Error())
+	switch {
+	case errors.As(err, &conflict):
+		return http.StatusConflict
+	case errors.As(err, &userErr):
+		return map[UserErrorKind]int{UserNameInvalid: http.StatusBadRequest, UserAlreadyExists: http.StatusConflict, UserNotFound: http.StatusNotFound, UserStillReferenced: http.StatusConflict, UserPasswordInvalid: http.StatusBadRequest, UserCredentialsInvalid: http.StatusUnauthorized, UserSessionInvalid: http.StatusUnauthorized, UserForbidden: http.StatusForbidden, UserLoginThrottled: http.StatusTooManyRequests, UserAPIKeyInvalid: http.StatusUnauthorized}[userErr.Kind]
+	case strings.Contains(message, "foreign key constraint failed"): // e.g. deleting a volunteer that is still on a schedule
+		return http.StatusConflict
+	case strings.Contains(message, "inactive volunteers can't be added"): // raised by the triggers on VolunteersForSchedule
+		return http.StatusConflict
+	case strings.Contains(message, "sql."):
+		return http.StatusInternalServerError
+	case strings.Contains(message, "already exists") || strings.Contains(message, "would create a duplicate"):
+		return http.StatusConflict
+	case strings.Contains(message, "failed to locate"):
+		return http.StatusNotFound
+	default:
+		return http.StatusBadRequest
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	err := json.NewEncoder(w).Encode(value)
+	if err != nil {
+		log.Printf("error in writeJSON: json.Encoder.Encode error: %v", err)
+	}
+}
+
+// Writes err as {"error": "..."}, with a Retry-After header for throttled logins. Database errors are logged instead of being sent to the client.
+func writeError(w http.ResponseWriter, err error) {
+	var userErr *UserError
+	if errors.As(err, &userErr) && userErr.RetryAfter > 0 {
+		w.Header().Set("Retry-After", fmt.Sprint(int(userErr.RetryAfter.Seconds())))
+	}
+	status := statusForError(err)
+	message := err.Error()
+	if status == http.StatusInternalServerError {
+		log.Printf("internal server error: %v", err)
+		message = http.StatusText(status)
+	}
+	writeJSON(w, status, map[string]string{"error": message})
+}
+
+func readJSON(w http.ResponseWriter, r *http.Request, value any) error {
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20))
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(value)
+	if err != nil {
+		return fmt.Errorf("error in readJSON: the request body is not valid JSON for %T: %w", value, err)
+	}
+	return nil
+}
+
+// Returns the host of the remote address of r, which Login throttles failures from. Forwarding headers are ignored because any client can set them.
+func requestSource(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+func currentUser(r *http.Request) string {
+	return r.Context().Value(userContextKey).(string)
+}
+
+// Returns the token of an `Authorization: Bearer TOKEN` header, or false if the request has none.
+func bearerToken(r *http.Request) (string, bool) {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return token, ok && len(token) > 0
+}
+
+// Identifies the Users row making the request and the scope it was granted, either from an API key (see CreateAPIKey), from a session token (see IssueSession), or from HTTP Basic authentication, checking the password with Login. Sessions and passwords are granted APIKeyAdmin.
+func (env *Env) authenticate(r *http.Request) (string, apiKeyScope, error) {
+	if token, ok := bearerToken(r); ok {
+		var userErr *UserError
+		if strings.HasPrefix(token, apiKeyTokenPrefix) {
+			key, err := env.sample.ValidateAPIKey(token)
+			if err == nil {
+				scope, err := parseAPIKeyScope(key.Scope)
+				if err != nil {
+					return "", 0, fmt.Errorf("error in authenticate: %w", err)
+				}
+				return key.User, scope, nil
+			}
+			if !errors.As(err, &userErr) {
+				return "", 0, fmt.Errorf("error in authenticate: %w", err)
+			}
+			// session tokens are random, so a few of them start with apiKeyTokenPrefix too
+		}
+		found, err := env.sample.ValidateSession(token)
+		if errors.As(err, &userErr) {
+			return "", 0, errors.New("invalid or expired session or API key")
+		}
+		if err != nil {
+			return "", 0, fmt.Errorf("error in authenticate: %w", err)
+		}
+		return found.User, APIKeyAdmin, nil
+	}
+	userName, password, ok := r.BasicAuth()
+	if !ok {
+		return "", 0, errors.New("missing credentials")
+	}
+	found, err := env.sample.Login(userName, password, requestSource(r))
+	var userErr *UserError
+	if errors.As(err, &userErr) && userErr.Kind == UserLoginThrottled {
+		return "", 0, err
+	}
+	if errors.As(err, &userErr) {
+		return "", 0, errors.New("invalid credentials")
+	}
+	if err != nil {
+		return "", 0, fmt.Errorf("error in authenticate: %w", err)
+	}
+	return found.UserName, APIKeyAdmin, nil
+}
+
+// Returns the scope an API key needs for r. Account routes and changes to who may access what need APIKeyAdmin, other reads including searches and plans need APIKeyReadOnly, and other changes need APIKeyRosterWrite.
+func requiredScope(r *http.Request) apiKeyScope {
+	path := strings.TrimPrefix(r.URL.Path, apiPrefix)
+	switch {
+	case strings.HasPrefix(path, "/users/") || strings.HasPrefix(path, "/sessions") || strings.HasPrefix(path, "/api-keys"):
+		return APIKeyAdmin
+	case r.Method == http.MethodGet || r.Method == http.MethodHead || r.Method == http.MethodPost && (strings.HasSuffix(path, "/search") || strings.HasSuffix(path, "/plan")):
+		return APIKeyReadOnly
+	case strings.HasPrefix(path, "/organizations") || strings.Contains(path, "/members"):
+		return APIKeyAdmin
+	default:
+		return APIKeyRosterWrite
+	}
+}
+
+// Rejects requests that can't be authenticated or whose API key lacks the requiredScope, and stores the authenticated user in the request context for currentUser.
+func (env *Env) requireUser(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userName, scope, err := env.authenticate(r)
+		if err != nil {
+			var userErr *UserError
+			if strings.Contains(err.Error(), "sql.") || errors.As(err, &userErr) {
+				writeError(w, err)
+				return
+			}
+			w.Header().Add("WWW-Authenticate", `Basic realm="SampleDatabase"`)
+			w.Header().Add("WWW-Authenticate", `Bearer realm="SampleDatabase"`)
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+			return
+		}
+		if required := requiredScope(r); scope < required {
+			writeError(w, &UserError{Kind: UserForbidden, UserName: userName, Detail: fmt.Sprintf("the API key has scope %s, but this needs %s", scope, required)})
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), userContextKey, userName)))
+	})
+}
+
+// GET: every row of the current user.
+func handleList[T any](request func(string, []T) ([]T, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		result, err := request(currentUser(r), []T{})
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		if result == nil {
+			result = []T{}
+		}
+		writeJSON(w, http.StatusOK, result)
+	}
+}
+
+// POST .../search: the rows matching any of the structs in the body, as the Request* methods filter them.
+func handleSearch[T any](request func(string, []T) ([]T, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var filters []T
+		err := readJSON(w, r, &filters)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		if len(filters) == 0 {
+			writeError(w, errors.New("error in handleSearch: at least one filter must be provided"))
+			return
+		}
+		result, err := request(currentUser(r), filters)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		if result == nil {
+			result = []T{}
+		}
+		writeJSON(w, http.StatusOK, result)
+	}
+}
+
+// POST, PUT, and DELETE: passes the structs in the body to a Create*, Update*, or Delete* method.
+func handleMutation[T any](mutate func(string, []T) error, status int) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var values []T
+		err := readJSON(w, r, &values)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		err = mutate(currentUser(r), values)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		w.WriteHeader(status)
+	}
+}
+
+func (env *Env) handleListScheduleMembers(w http.ResponseWriter, r *http.Request) {
+	scheduleStruct, err := env.sample.RequestSchedule(currentUser(r), schedule{ScheduleName: r.PathValue("name")})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	members, err := env.sample.RequestScheduleMembers(currentUser(r), scheduleStruct.ScheduleID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, members)
+}
+
+// Gives the User in the body the Role in the body on the schedule named in the path, for example {"User":"Ann","Role":"editor"}.
+func (env *Env) handleShareSchedule(w http.ResponseWriter, r *http.Request) {
+	var member scheduleMember
+	err := readJSON(w, r, &member)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	role, err := parseScheduleRole(member.Role)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	scheduleStruct, err := env.sample.RequestSchedule(currentUser(r), schedule{ScheduleName: r.PathValue("name")})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	err = env.sample.ShareSchedule(currentUser(r), scheduleStruct.ScheduleID, member.User, role)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (env *Env) handleUnshareSchedule(w http.ResponseWriter, r *http.Request) {
+	scheduleStruct, err := env.sample.RequestSchedule(currentUser(r), schedule{ScheduleName: r.PathValue("name")})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	err = env.sample.UnshareSchedule(currentUser(r), scheduleStruct.ScheduleID, r.PathValue("user"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (env *Env) handleListOrganizations(w http.ResponseWriter, r *http.Request) {
+	organizations, err := env.sample.RequestOrganizations(currentUser(r))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, organizations)
+}
+
+// Creates the organization named in the body, for example {"OrganizationName":"Food bank"}, with the current user as its first member.
+func (env *Env) handleCreateOrganization(w http.ResponseWriter, r *http.Request) {
+	var body organization
+	err := readJSON(w, r, &body)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	result, err := env.sample.CreateOrganization(currentUser(r), body.OrganizationName)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, result)
+}
+
+// Looks up the organization named by the {org} path value among those of the current user, writing the error if there is none.
+func (env *Env) pathOrganization(w http.ResponseWriter, r *http.Request) (organization, bool) {
+	result, err := env.sample.RequestOrganization(currentUser(r), organization{OrganizationName: r.PathValue("org")})
+	if err != nil {
+		writeError(w, err)
+		return organization{}, false
+	}
+	return result, true
+}
+
+func (env *Env) handleDeleteOrganization(w http.ResponseWriter, r *http.Request) {
+	organizationStruct, ok := env.pathOrganization(w, r)
+	if !ok {
+		return
+	}
+	err := env.sample.DeleteOrganization(currentUser(r), organizationStruct.OrganizationID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (env *Env) handleListOrganizationMembers(w http.ResponseWriter, r *http.Request) {
+	organizationStruct, ok := env.pathOrganization(w, r)
+	if !ok {
+		return
+	}
+	members, err := env.sample.RequestOrganizationMembers(currentUser(r), organizationStruct.OrganizationID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, members)
+}
+
+func (env *Env) handleAddOrganizationMember(w http.ResponseWriter, r *http.Request) {
+	organizationStruct, ok := env.pathOrganization(w, r)
+	if !ok {
+		return
+	}
+	err := env.sample.AddOrganizationMember(currentUser(r), organizationStruct.OrganizationID, r.PathValue("user"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (env *Env) handleRemoveOrganizationMember(w http.ResponseWriter, r *http.Request) {
+	organizationStruct, ok := env.pathOrganization(w, r)
+	if !ok {
+		return
+	}
+	err := env.sample.RemoveOrganizationMember(currentUser(r), organizationStruct.OrganizationID, r.PathValue("user"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (env *Env) handleListOrganizationVolunteers(w http.ResponseWriter, r *http.Request) {
+	organizationStruct, ok := env.pathOrganization(w, r)
+	if !ok {
+		return
+	}
+	volunteers, err := env.sample.RequestOrganizationVolunteers(currentUser(r), organizationStruct.OrganizationID, []volunteer{})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if volunteers == nil {
+		volunteers = []volunteer{}
+	}
+	writeJSON(w, http.StatusOK, volunteers)
+}
+
+// Moves the current user's volunteer named in the path into the organization's pool.
+func (env *Env) handleAddOrganizationVolunteer(w http.ResponseWriter, r *http.Request) {
+	organizationStruct, ok := env.pathOrganization(w, r)
+	if !ok {
+		return
+	}
+	err := env.sample.AddOrganizationVolunteers(currentUser(r), organizationStruct.OrganizationID, []volunteer{{VolunteerName: r.PathValue("volunteer")}})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (env *Env) handleRemoveOrganizationVolunteer(w http.ResponseWriter, r *http.Request) {
+	organizationStruct, ok := env.pathOrganization(w, r)
+	if !ok {
+		return
+	}
+	err := env.sample.RemoveOrganizationVolunteers(currentUser(r), organizationStruct.OrganizationID, []volunteer{{VolunteerName: r.PathValue("volunteer")}})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (env *Env) handleListOrganizationSchedules(w http.ResponseWriter, r *http.Request) {
+	organizationStruct, ok := env.pathOrganization(w, r)
+	if !ok {
+		return
+	}
+	schedules, err := env.sample.RequestOrganizationSchedules(currentUser(r), organizationStruct.OrganizationID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, schedules)
+}
+
+func (env *Env) handleFindDuplicateVolunteers(w http.ResponseWriter, r *http.Request) {
+	duplicates, err := env.sample.FindDuplicateVolunteers(currentUser(r))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, duplicates)
+}
+
+// Merges the volunteers with the VolunteerIDs in the body, for example {"Survivor":1,"Duplicate":8}, and writes the mergeReport.
+func (env *Env) handleMergeVolunteers(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Survivor  int
+		Duplicate int
+	}
+	err := readJSON(w, r, &body)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	report, err := env.sample.MergeVolunteers(currentUser(r), body.Survivor, body.Duplicate)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, report)
+}
+
+func (env *Env) handleListVolunteerGroups(w http.ResponseWriter, r *http.Request) {
+	groups, err := env.sample.RequestVolunteerGroups(currentUser(r))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, groups)
+}
+
+// Creates the group named in the body, for example {"GroupName":"Sunday team"}.
+func (env *Env) handleCreateVolunteerGroup(w http.ResponseWriter, r *http.Request) {
+	var body volunteerGroup
+	err := readJSON(w, r, &body)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	result, err := env.sample.CreateVolunteerGroup(currentUser(r), body.GroupName)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, result)
+}
+
+// Looks up the group named by the {group} path value among those of the current user, writing the error if there is none.
+func (env *Env) pathVolunteerGroup(w http.ResponseWriter, r *http.Request) (volunteerGroup, bool) {
+	result, err := env.sample.RequestVolunteerGroup(currentUser(r), volunteerGroup{GroupName: r.PathValue("group")})
+	if err != nil {
+		writeError(w, err)
+		return volunteerGroup{}, false
+	}
+	return result, true
+}
+
+// Renames the group to the GroupName in the body.
+func (env *Env) handleRenameVolunteerGroup(w http.ResponseWriter, r *http.Request) {
+	groupStruct, ok := env.pathVolunteerGroup(w, r)
+	if !ok {
+		return
+	}
+	var body volunteerGroup
+	err := readJSON(w, r, &body)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	err = env.sample.RenameVolunteerGroup(currentUser(r), groupStruct.GroupID, body.GroupName)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (env *Env) handleDeleteVolunteerGroup(w http.ResponseWriter, r *http.Request) {
+	groupStruct, ok := env.pathVolunteerGroup(w, r)
+	if !ok {
+		return
+	}
+	err := env.sample.DeleteVolunteerGroup(currentUser(r), groupStruct.GroupID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (env *Env) handleListGroupVolunteers(w http.ResponseWriter, r *http.Request) {
+	groupStruct, ok := env.pathVolunteerGroup(w, r)
+	if !ok {
+		return
+	}
+	volunteers, err := env.sample.RequestGroupVolunteers(currentUser(r), groupStruct.GroupID, []volunteer{})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if volunteers == nil {
+		volunteers = []volunteer{}
+	}
+	writeJSON(w, http.StatusOK, volunteers)
+}
+
+func (env *Env) handleAddGroupVolunteer(w http.ResponseWriter, r *http.Request) {
+	groupStruct, ok := env.pathVolunteerGroup(w, r)
+	if !ok {
+		return
+	}
+	err := env.sample.AddGroupVolunteers(currentUser(r), groupStruct.GroupID, []volunteer{{VolunteerName: r.PathValue("volunteer")}})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (env *Env) handleRemoveGroupVolunteer(w http.ResponseWriter, r *http.Request) {
+	groupStruct, ok := env.pathVolunteerGroup(w, r)
+	if !ok {
+		return
+	}
+	err := env.sample.RemoveGroupVolunteers(currentUser(r), groupStruct.GroupID, []volunteer{{VolunteerName: r.PathValue("volunteer")}})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Enrolls the group into the schedule named in the body, for example {"ScheduleName":"test1"}, and writes the enrollReport.
+func (env *Env) handleEnrollVolunteerGroup(w http.ResponseWriter, r *http.Request) {
+	groupStruct, ok := env.pathVolunteerGroup(w, r)
+	if !ok {
+		return
+	}
+	var body schedule
+	err := readJSON(w, r, &body)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	scheduleStruct, err := env.sample.RequestSchedule(currentUser(r), schedule{ScheduleName: body.ScheduleName})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	report, err := env.sample.EnrollVolunteerGroup(currentUser(r), groupStruct.GroupID, scheduleStruct.ScheduleID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, report)
+}
+
+// GET /api/v1/audit?schedule=ID&volunteer=ID&user=NAME&table=TABLE&limit=N, where every parameter is optional, see auditFilter.
+func (env *Env) handleAuditLog(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := auditFilter{User: query.Get("user"), TableName: query.Get("table")}
+	for name, value := range map[string]*int{"schedule": &filter.Schedule, "volunteer": &filter.Volunteer, "limit": &filter.Limit} {
+		if !query.Has(name) {
+			continue
+		}
+		number, err := strconv.Atoi(query.Get(name))
+		if err != nil || number < 0 {
+			writeError(w, fmt.Errorf("error in handleAuditLog: method failed because the %s parameter `%s` is not a non-negative number", name, query.Get(name)))
+			return
+		}
+		*value = number
+	}
+	entries, err := env.sample.RequestAuditLog(currentUser(r), filter)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, entries)
+}
+
+// Lists the latest login attempts for the current user, so users can notice someone guessing their password.
+func (env *Env) handleListLoginAttempts(w http.ResponseWriter, r *http.Request) {
+	attempts, err := env.sample.RequestLoginAttempts(currentUser(r), 100)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, attempts)
+}
+
+func (env *Env) handleListAPIKeys(w http.ResponseWriter, r *http.Request) {
+	keys, err := env.sample.RequestAPIKeys(currentUser(r))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, keys)
+}
+
+type apiKeyRequest struct {
+	Name      string
+	Scope     string    // read-only, roster-write, or admin
+	ExpiresAt time.Time // the key does not expire if this is missing
+}
+
+type apiKeyResponse struct {
+	Token string // only sent when the key is created
+	Key   apiKey
+}
+
+// Creates an API key for the current user and returns its token, which can't be requested again.
+func (env *Env) handleCreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	var body apiKeyRequest
+	err := readJSON(w, r, &body)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	scope, err := parseAPIKeyScope(body.Scope)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	token, created, err := env.sample.CreateAPIKey(currentUser(r), body.Name, scope, body.ExpiresAt)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, apiKeyResponse{Token: token, Key: created})
+}
+
+func (env *Env) handleRevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	keyID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeError(w, fmt.Errorf("error in handleRevokeAPIKey: method failed because `%s` is not a KeyID", r.PathValue("id")))
+		return
+	}
+	err = env.sample.RevokeAPIKey(currentUser(r), keyID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type sessionCredentials struct {
+	UserName string
+	Password string
+}
+
+type sessionResponse struct {
+	Token     string `json:",omitempty"` // only sent when the session is created
+	ExpiresAt time.Time
+}
+
+// Logs in with the UserName and Password in the body and issues a session token. This is the only route that needs no authentication.
+func (env *Env) handleCreateSession(w http.ResponseWriter, r *http.Request) {
+	var credentials sessionCredentials
+	err := readJSON(w, r, &credentials)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	found, err := env.sample.Login(credentials.UserName, credentials.Password, requestSource(r))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	token, created, err := env.sample.IssueSession(found.UserName)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, sessionResponse{Token: token, ExpiresAt: created.ExpiresAt.UTC()})
+}
+
+func (env *Env) handleRefreshSession(w http.ResponseWriter, r *http.Request) {
+	token, ok := bearerToken(r)
+	if !ok {
+		writeError(w, errors.New("error in handleRefreshSession: only session tokens can be refreshed"))
+		return
+	}
+	refreshed, err := env.sample.RefreshSession(token)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, sessionResponse{ExpiresAt: refreshed.ExpiresAt.UTC()})
+}
+
+func (env *Env) handleRevokeSession(w http.ResponseWriter, r *http.Request) {
+	token, ok := bearerToken(r)
+	if !ok {
+		writeError(w, errors.New("error in handleRevokeSession: only session tokens can be revoked"))
+		return
+	}
+	err := env.sample.RevokeSession(token)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (env *Env) handleRevokeSessions(w http.ResponseWriter, r *http.Request) {
+	count, err := env.sample.RevokeSessions(currentUser(r))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]int{"revoked": count})
+}
+
+type passwordChange struct {
+	OldPassword string
+	NewPassword string
+}
+
+func (env *Env) handleChangePassword(w http.ResponseWriter, r *http.Request) {
+	var change passwordChange
+	err := readJSON(w, r, &change)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	err = env.sample.ChangePassword(currentUser(r), change.OldPassword, change.NewPassword, requestSource(r))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (env *Env) handleFetchScheduleData(w http.ResponseWriter, r *http.Request) {
+	data, err := env.sample.FetchAndSendData(currentUser(r), r.PathValue("name"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, data)
+}
+
+// Stores a SendReceiveDataStruct for the schedule named in the path. A stale Revision is answered with 409 and the RevisionConflictError.
+func (env *Env) handleStoreScheduleData(w http.ResponseWriter, r *http.Request) {
+	var data SendReceiveDataStruct
+	err := readJSON(w, r, &data)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	data.User = currentUser(r)
+	if len(data.ScheduleName) == 0 {
+		data.ScheduleName = r.PathValue("name")
+	}
+	if data.ScheduleName != r.PathValue("name") {
+		writeError(w, fmt.Errorf("error in handleStoreScheduleData: ScheduleName `%s` does not match the path", data.ScheduleName))
+		return
+	}
+	err = env.sample.RecieveAndStoreData(data)
+	var conflict *RevisionConflictError
+	if errors.As(err, &conflict) {
+		writeJSON(w, http.StatusConflict, map[string]any{"error": err.Error(), "conflict": conflict})
+		return
+	}
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (env *Env) handlePlanScheduleData(w http.ResponseWriter, r *http.Request) {
+	var data SendReceiveDataStruct
+	err := readJSON(w, r, &data)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	data.User = currentUser(r)
+	data.ScheduleName = r.PathValue("name")
+	plan, err := env.sample.PlanReceivedData(data)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"summary": plan.Summary()})
+}
+
+// Schedules are read and written with the Extended methods, so ShiftsOff 0 is allowed. In search filters, ShiftsOff -1 means any value.
+func (env *Env) routes() http.Handler {
+	sm := env.sample
+	includeShiftsOff0 := func(request func(string, []schedule, bool) ([]schedule, error)) func(string, []schedule) ([]schedule, error) {
+		return func(currentUser string, schedules []schedule) ([]schedule, error) {
+			return request(currentUser, schedules, true)
+		}
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("PUT "+apiPrefix+"/users/me/password", env.handleChangePassword)
+	mux.HandleFunc("GET "+apiPrefix+"/users/me/login-attempts", env.handleListLoginAttempts)
+	mux.HandleFunc("POST "+apiPrefix+"/sessions/refresh", env.handleRefreshSession)
+	mux.HandleFunc("DELETE "+apiPrefix+"/sessions/current", env.handleRevokeSession)
+	mux.HandleFunc("DELETE "+apiPrefix+"/sessions", env.handleRevokeSessions)
+	mux.HandleFunc("GET "+apiPrefix+"/api-keys", env.handleListAPIKeys)
+	mux.HandleFunc("POST "+apiPrefix+"/api-keys", env.handleCreateAPIKey)
+	mux.HandleFunc("DELETE "+apiPrefix+"/api-keys/{id}", env.handleRevokeAPIKey)
+
+	mux.Handle("GET "+apiPrefix+"/volunteers", handleList(sm.RequestActiveVolunteers))
+	mux.Handle("POST "+apiPrefix+"/volunteers/search", handleSearch(sm.RequestVolunteers))
+	mux.Handle("POST "+apiPrefix+"/volunteers", handleMutation(sm.CreateVolunteers, http.StatusCreated))
+	mux.Handle("PUT "+apiPrefix+"/volunteers", handleMutation(sm.UpdateVolunteers, http.StatusNoContent))
+	mux.Handle("DELETE "+apiPrefix+"/volunteers", handleMutation(sm.DeleteVolunteers, http.StatusNoContent))
+	mux.Handle("POST "+apiPrefix+"/volunteers/reactivate", handleMutation(sm.ReactivateVolunteers, http.StatusNoContent))
+	mux.HandleFunc("GET "+apiPrefix+"/volunteers/duplicates", env.handleFindDuplicateVolunteers)
+	mux.HandleFunc("POST "+apiPrefix+"/volunteers/merge", env.handleMergeVolunteers)
+
+	mux.Handle("GET "+apiPrefix+"/schedules", handleList(includeShiftsOff0(sm.RequestSchedulesExtended)))
+	mux.Handle("POST "+apiPrefix+"/schedules/search", handleSearch(includeShiftsOff0(sm.RequestSchedulesExtended)))
+	mux.Handle("POST "+apiPrefix+"/schedules", handleMutation(func(currentUser string, toCreate []schedule) error {
+		return sm.CreateSchedulesExtended(currentUser, toCreate, true)
+	}, http.StatusCreated))
+	mux.Handle("PUT "+apiPrefix+"/schedules", handleMutation(func(currentUser string, toUpdate []schedule) error {
+		return sm.UpdateSchedulesExtended(currentUser, toUpdate, true)
+	}, http.StatusNoContent))
+	mux.Handle("DELETE "+apiPrefix+"/schedules", handleMutation(sm.DeleteSchedules, http.StatusNoContent))
+	mux.HandleFunc("GET "+apiPrefix+"/schedules/{name}/data", env.handleFetchScheduleData)
+	mux.HandleFunc("PUT "+apiPrefix+"/schedules/{name}/data", env.handleStoreScheduleData)
+	mux.HandleFunc("POST "+apiPrefix+"/schedules/{name}/plan", env.handlePlanScheduleData)
+	mux.HandleFunc("GET "+apiPrefix+"/schedules/{name}/members", env.handleListScheduleMembers)
+	mux.HandleFunc("PUT "+apiPrefix+"/schedules/{name}/members", env.handleShareSchedule)
+	mux.HandleFunc("DELETE "+apiPrefix+"/schedules/{name}/members/{user}", env.handleUnshareSchedule)
+	mux.HandleFunc("GET "+apiPrefix+"/organizations", env.handleListOrganizations)
+	mux.HandleFunc("POST "+apiPrefix+"/organizations", env.handleCreateOrganization)
+	mux.HandleFunc("DELETE "+apiPrefix+"/organizations/{org}", env.handleDeleteOrganization)
+	mux.HandleFunc("GET "+apiPrefix+"/organizations/{org}/members", env.handleListOrganizationMembers)
+	mux.HandleFunc("PUT "+apiPrefix+"/organizations/{org}/members/{user}", env.handleAddOrganizationMember)
+	mux.HandleFunc("DELETE "+apiPrefix+"/organizations/{org}/members/{user}", env.handleRemoveOrganizationMember)
+	mux.HandleFunc("GET "+apiPrefix+"/organizations/{org}/volunteers", env.handleListOrganizationVolunteers)
+	mux.HandleFunc("PUT "+apiPrefix+"/organizations/{org}/volunteers/{volunteer}", env.handleAddOrganizationVolunteer)
+	mux.HandleFunc("DELETE "+apiPrefix+"/organizations/{org}/volunteers/{volunteer}", env.handleRemoveOrganizationVolunteer)
+	mux.HandleFunc("GET "+apiPrefix+"/organizations/{org}/schedules", env.handleListOrganizationSchedules)
+	mux.HandleFunc("GET "+apiPrefix+"/groups", env.handleListVolunteerGroups)
+	mux.HandleFunc("POST "+apiPrefix+"/groups", env.handleCreateVolunteerGroup)
+	mux.HandleFunc("PUT "+apiPrefix+"/groups/{group}", env.handleRenameVolunteerGroup)
+	mux.HandleFunc("DELETE "+apiPrefix+"/groups/{group}", env.handleDeleteVolunteerGroup)
+	mux.HandleFunc("GET "+apiPrefix+"/groups/{group}/volunteers", env.handleListGroupVolunteers)
+	mux.HandleFunc("PUT "+apiPrefix+"/groups/{group}/volunteers/{volunteer}", env.handleAddGroupVolunteer)
+	mux.HandleFunc("DELETE "+apiPrefix+"/groups/{group}/volunteers/{volunteer}", env.handleRemoveGroupVolunteer)
+	mux.HandleFunc("POST "+apiPrefix+"/groups/{group}/enroll", env.handleEnrollVolunteerGroup)
+	mux.HandleFunc("GET "+apiPrefix+"/audit", env.handleAuditLog)
+
+	mux.Handle("GET "+apiPrefix+"/wfs", handleList(sm.RequestWFS))
+	mux.Handle("POST "+apiPrefix+"/wfs/search", handleSearch(sm.RequestWFS))
+	mux.Handle("POST "+apiPrefix+"/wfs", handleMutation(sm.CreateWFS, http.StatusCreated))
+	mux.Handle("PUT "+apiPrefix+"/wfs", handleMutation(sm.UpdateWFS, http.StatusNoContent))
+	mux.Handle("DELETE "+apiPrefix+"/wfs", handleMutation(sm.DeleteWFS, http.StatusNoContent))
+
+	mux.Handle("GET "+apiPrefix+"/vfs", handleList(sm.RequestVFS))
+	mux.Handle("POST "+apiPrefix+"/vfs/search", handleSearch(sm.RequestVFS))
+	mux.Handle("POST "+apiPrefix+"/vfs", handleMutation(sm.CreateVFS, http.StatusCreated))
+	mux.Handle("PUT "+apiPrefix+"/vfs", handleMutation(sm.UpdateVFS, http.StatusNoContent))
+	mux.Handle("DELETE "+apiPrefix+"/vfs", handleMutation(sm.DeleteVFS, http.StatusNoContent))
+
+	mux.Handle("GET "+apiPrefix+"/ufs", handleList(sm.RequestUFS))
+	mux.Handle("POST "+apiPrefix+"/ufs/search", handleSearch(sm.RequestUFS))
+	mux.Handle("POST "+apiPrefix+"/ufs", handleMutation(sm.CreateUFS, http.StatusCreated))
+	mux.Handle("PUT "+apiPrefix+"/ufs", handleMutation(sm.UpdateUFS, http.StatusNoContent))
+	mux.Handle("DELETE "+apiPrefix+"/ufs", handleMutation(sm.DeleteUFS, http.StatusNoContent))
+
+	mux.Handle("GET "+apiPrefix+"/completed-schedules", handleList(sm.RequestCompletedSchedules))
+	mux.Handle("POST "+apiPrefix+"/completed-schedules/search", handleSearch(sm.RequestCompletedSchedules))
+	mux.Handle("POST "+apiPrefix+"/completed-schedules", handleMutation(sm.CreateCompletedSchedules, http.StatusCreated))
+	mux.Handle("DELETE "+apiPrefix+"/completed-schedules", handleMutation(sm.DeleteCompletedSchedules, http.StatusNoContent))
+
+	public := http.NewServeMux()
+	public.HandleFunc("POST "+apiPrefix+"/sessions", env.handleCreateSession)
+	public.Handle("/", env.requireUser(mux))
+	return public
+}
+
+// Serves the JSON API on addr until the server fails.
+func (env *Env) serve(addr string) error {
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           env.routes(),
+		ReadHeaderTimeout: 10 * time.Second,
+		ReadTimeout:       30 * time.Second,
+		WriteTimeout:      30 * time.Second,
+		IdleTimeout:       2 * time.Minute,
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go env.sample.cleanUpSessions(ctx, time.Hour)
+	log.Printf("serving %s on %s", apiPrefix, addr)
+	err := server.ListenAndServe()
+	if err != nil {
+		return fmt.Errorf("error in serve: http.Server.ListenAndServe error: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,263 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStatusForError(t *testing.T) {
+	tests := []struct {
+		name  string
+		input error
+		want  int
+	}{
+		{name: "Map a revision conflict", input: fmt.Errorf("error in RecieveAndStoreData: %w", &RevisionConflictError{ScheduleName: "test1", Revision: 1, CurrentRevision: 2}), want: http.StatusConflict},
+		{name: "Map a foreign key failure", input: errors.New("error in DeleteVolunteers: sql.Tx.Exec error: FOREIGN KEY constraint failed"), want: http.StatusConflict},
+		{name: "Map an inactive volunteer", input: errors.New("error in CreateVFS: sql.Stmt.Exec error: inactive volunteers can't be added to a schedule"), want: http.StatusConflict},
+		{name: "Map a database error", input: errors.New("error in RequestVolunteers: sql.DB.Query error: no such table"), want: http.StatusInternalServerError},
+		{name: "Map an existing row", input: errors.New("error in CreateVFS: method failed because at least one of the volunteerForSchedule entries to be created already exists in the database"), want: http.StatusConflict},
+		{name: "Map a missing row", input: errors.New("error in RequestSchedule: method failed to locate exactly one schedule matching {}. Found 0 matches"), want: http.StatusNotFound},
+		{name: "Map a user that still owns rows", input: fmt.Errorf("error in DeleteUser: %w", &UserError{Kind: UserStillReferenced, UserName: "Seth"}), want: http.StatusConflict},
+		{name: "Map a user without the needed role", input: fmt.Errorf("error in CreateWFS: %w", &UserError{Kind: UserForbidden, UserName: "Ann"}), want: http.StatusForbidden},
+		{name: "Map a throttled login", input: fmt.Errorf("error in Login: %w", &UserError{Kind: UserLoginThrottled, UserName: "Ann", RetryAfter: time.Second}), want: http.StatusTooManyRequests},
+		{name: "Map invalid input", input: errors.New("error in CreateVFS: method failed because at least one of the volunteerForSchedule structs in toCreate did not have a value for Schedule"), want: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ans := statusForError(tt.input)
+			checkResults(t, ans, tt.want, 0, nil)
+		})
+	}
+}
+
+func TestRoutes(t *testing.T) {
+	env, tearDownEnvironment := setUpEnvironment(t)
+	defer tearDownEnvironment(t)
+	setUpSampleData(t, env)
+	err := env.sample.SetPassword("Seth", "correct horse")
+	if err != nil {
+		t.Errorf("Error setting up test (SetPassword failed): %v", err)
+		t.FailNow()
+	}
+	handler := env.routes()
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		user       string // empty for no credentials
+		password   string // "correct horse" if empty
+		body       string
+		wantStatus int
+		wantBody   string // substring of the response body
+	}{
+		{name: "Fail without credentials", method: "GET", path: "/api/v1/volunteers", wantStatus: http.StatusUnauthorized},
+		{name: "Fail with an unknown user", method: "GET", path: "/api/v1/volunteers", user: "Nobody", wantStatus: http.StatusUnauthorized},
+		{name: "Fail with a wrong password", method: "GET", path: "/api/v1/volunteers", user: "Seth", password: "wrong horse", wantStatus: http.StatusUnauthorized},
+		{name: "List volunteers", method: "GET", path: "/api/v1/volunteers", user: "Seth", wantStatus: http.StatusOK, wantBody: `{"VolunteerID":7,"VolunteerName":"Larry","User":"Seth"}]`},
+		{name: "Search volunteers", method: "POST", path: "/api/v1/volunteers/search", user: "Seth", body: `[{"VolunteerName":"Bill"}]`, wantStatus: http.StatusOK, wantBody: `[{"VolunteerID":2,"VolunteerName":"Bill","User":"Seth"}]`},
+		{name: "Create a volunteer", method: "POST", path: "/api/v1/volunteers", user: "Seth", body: `[{"VolunteerName":"Zed"}]`, wantStatus: http.StatusCreated},
+		{name: "Fail by creating an existing volunteer", method: "POST", path: "/api/v1/volunteers", user: "Seth", body: `[{"VolunteerName":"Zed"}]`, wantStatus: http.StatusConflict},
+		{name: "Rename a volunteer", method: "PUT", path: "/api/v1/volunteers", user: "Seth", body: `[{"VolunteerID":8,"VolunteerName":"Zack"}]`, wantStatus: http.StatusNoContent},
+		{name: "Deactivate a volunteer on a schedule", method: "DELETE", path: "/api/v1/volunteers", user: "Seth", body: `[{"VolunteerID":1}]`, wantStatus: http.StatusNoContent},
+		{name: "Fail by adding an inactive volunteer to a schedule", method: "POST", path: "/api/v1/vfs", user: "Seth", body: `[{"Volunteer":1,"Schedule":2}]`, wantStatus: http.StatusConflict},
+		{name: "Reactivate a volunteer", method: "POST", path: "/api/v1/volunteers/reactivate", user: "Seth", body: `[{"VolunteerID":1}]`, wantStatus: http.StatusNoContent},
+		{name: "Delete a volunteer", method: "DELETE", path: "/api/v1/volunteers", user: "Seth", body: `[{"VolunteerID":8}]`, wantStatus: http.StatusNoContent},
+		{name: "List duplicate volunteers", method: "GET", path: "/api/v1/volunteers/duplicates", user: "Seth", wantStatus: http.StatusOK, wantBody: `"Duplicate":{"VolunteerID":8,"VolunteerName":"Zack","User":"Seth","Inactive":true`},
+		{name: "Fail by merging a volunteer into itself", method: "POST", path: "/api/v1/volunteers/merge", user: "Seth", body: `{"Survivor":3,"Duplicate":3}`, wantStatus: http.StatusBadRequest},
+		{name: "Merge duplicate volunteers", method: "POST", path: "/api/v1/volunteers/merge", user: "Seth", body: `{"Survivor":3,"Duplicate":8}`, wantStatus: http.StatusOK, wantBody: `"CompletedSchedulesUpdated":0`},
+		{name: "Create a volunteer group", method: "POST", path: "/api/v1/groups", user: "Seth", body: `{"GroupName":"Sunday"}`, wantStatus: http.StatusCreated, wantBody: `{"GroupID":1,"GroupName":"Sunday","User":"Seth"}`},
+		{name: "Fail by creating an existing volunteer group", method: "POST", path: "/api/v1/groups", user: "Seth", body: `{"GroupName":"Sunday"}`, wantStatus: http.StatusConflict},
+		{name: "Rename a volunteer group", method: "PUT", path: "/api/v1/groups/Sunday", user: "Seth", body: `{"GroupName":"Youth"}`, wantStatus: http.StatusNoContent},
+		{name: "Add a volunteer to a group", method: "PUT", path: "/api/v1/groups/Youth/volunteers/Bill", user: "Seth", wantStatus: http.StatusNoContent},
+		{name: "Add another volunteer to a group", method: "PUT", path: "/api/v1/groups/Youth/volunteers/Bob", user: "Seth", wantStatus: http.StatusNoContent},
+		{name: "Fail by adding a volunteer to a nonexistent group", method: "PUT", path: "/api/v1/groups/Sunday/volunteers/Tim", user: "Seth", wantStatus: http.StatusNotFound},
+		{name: "List the volunteers of a group", method: "GET", path: "/api/v1/groups/Youth/volunteers", user: "Seth", wantStatus: http.StatusOK, wantBody: `{"VolunteerID":5,"VolunteerName":"Bob","User":"Seth"}]`},
+		{name: "Enroll a volunteer group", method: "POST", path: "/api/v1/groups/Youth/enroll", user: "Seth", body: `{"ScheduleName":"test3"}`, wantStatus: http.StatusOK, wantBody: `{"Enrolled":["Bob"],"AlreadyEnrolled":["Bill"],"Inactive":[],"NotInPool":[]}`},
+		{name: "Remove a volunteer from a group", method: "DELETE", path: "/api/v1/groups/Youth/volunteers/Bob", user: "Seth", wantStatus: http.StatusNoContent},
+		{name: "Delete a volunteer group", method: "DELETE", path: "/api/v1/groups/Youth", user: "Seth", wantStatus: http.StatusNoContent},
+		{name: "List no volunteer groups", method: "GET", path: "/api/v1/groups", user: "Seth", wantStatus: http.StatusOK, wantBody: `[]`},
+		{name: "Fail by sending an unknown field", method: "POST", path: "/api/v1/volunteers", user: "Seth", body: `[{"Name":"Zed"}]`, wantStatus: http.StatusBadRequest},
+		{name: "Fail by searching without filters", method: "POST", path: "/api/v1/volunteers/search", user: "Seth", body: `[]`, wantStatus: http.StatusBadRequest},
+		{name: "List schedules including ShiftsOff 0", method: "GET", path: "/api/v1/schedules", user: "Seth", wantStatus: http.StatusOK, wantBody: `"ScheduleName":"test0","ShiftsOff":0`},
+		{name: "Fetch the data of a schedule", method: "GET", path: "/api/v1/schedules/test1/data", user: "Seth", wantStatus: http.StatusOK, wantBody: `"VolunteerAvailabilityData":[{"Tim":["2024-01-14"]},{"Bill":["2024-01-21"]},{"Jack":[]},{"George":[]}]`},
+		{name: "Fail by fetching a nonexistent schedule", method: "GET", path: "/api/v1/schedules/nope/data", user: "Seth", wantStatus: http.StatusNotFound},
+		{name: "Plan a change", method: "POST", path: "/api/v1/schedules/test0/plan", user: "Seth", body: `{"StartDate":"2023-08-01","EndDate":"2023-09-01","WeekdaysForSchedule":["Monday"],"ShiftsOff":0,"VolunteersPerShift":2,"VolunteerAvailabilityData":[],"Revision":1}`, wantStatus: http.StatusOK, wantBody: `{"summary":["change VolunteersPerShift of schedule \"test0\" from 1 to 2"]}`},
+		{name: "Store a change", method: "PUT", path: "/api/v1/schedules/test0/data", user: "Seth", body: `{"StartDate":"2023-08-01","EndDate":"2023-09-01","WeekdaysForSchedule":["Monday"],"ShiftsOff":0,"VolunteersPerShift":2,"VolunteerAvailabilityData":[],"Revision":1}`, wantStatus: http.StatusNoContent},
+		{name: "Fail by storing a stale change", method: "PUT", path: "/api/v1/schedules/test0/data", user: "Seth", body: `{"StartDate":"2023-08-01","EndDate":"2023-09-01","WeekdaysForSchedule":["Monday"],"ShiftsOff":1,"VolunteersPerShift":2,"VolunteerAvailabilityData":[],"Revision":1}`, wantStatus: http.StatusConflict, wantBody: `"CurrentRevision":2`},
+		{name: "Fail by storing data for another schedule", method: "PUT", path: "/api/v1/schedules/test0/data", user: "Seth", body: `{"ScheduleName":"test1"}`, wantStatus: http.StatusBadRequest},
+		{name: "List UFS", method: "GET", path: "/api/v1/ufs", user: "Seth", wantStatus: http.StatusOK, wantBody: `{"UFSID":1,"User":"Seth","VolunteerForSchedule":1,"Date":379}`},
+		{name: "Create a completed schedule", method: "POST", path: "/api/v1/completed-schedules", user: "Seth", body: `[{"ScheduleData":"[{\"Date\":372,\"Volunteers\":[1]}]","Schedule":2}]`, wantStatus: http.StatusCreated},
+		{name: "List completed schedules", method: "GET", path: "/api/v1/completed-schedules", user: "Seth", wantStatus: http.StatusOK, wantBody: `"CScheduleID":1`},
+		{name: "Delete a completed schedule", method: "DELETE", path: "/api/v1/completed-schedules", user: "Seth", body: `[{"CScheduleID":1}]`, wantStatus: http.StatusNoContent},
+		{name: "List no completed schedules as an empty array", method: "GET", path: "/api/v1/completed-schedules", user: "Seth", wantStatus: http.StatusOK, wantBody: `[]`},
+		{name: "Filter the audit log", method: "GET", path: "/api/v1/audit?table=CompletedSchedules&limit=1", user: "Seth", wantStatus: http.StatusOK, wantBody: `"TableName":"CompletedSchedules","PrimaryKey":"1","Operation":"delete"`},
+		{name: "Fail by filtering the audit log with a malformed limit", method: "GET", path: "/api/v1/audit?limit=many", user: "Seth", wantStatus: http.StatusBadRequest},
+		{name: "Fail by using an unknown path", method: "GET", path: "/api/v2/volunteers", user: "Seth", wantStatus: http.StatusNotFound},
+		{name: "Fail by changing the password with a wrong old password", method: "PUT", path: "/api/v1/users/me/password", user: "Seth", body: `{"OldPassword":"wrong horse","NewPassword":"battery staple"}`, wantStatus: http.StatusUnauthorized},
+		{name: "Fail by changing the password to a short one", method: "PUT", path: "/api/v1/users/me/password", user: "Seth", body: `{"OldPassword":"correct horse","NewPassword":"short"}`, wantStatus: http.StatusBadRequest},
+		{name: "Change the password", method: "PUT", path: "/api/v1/users/me/password", user: "Seth", body: `{"OldPassword":"correct horse","NewPassword":"battery staple"}`, wantStatus: http.StatusNoContent},
+		{name: "Fail with the old password", method: "GET", path: "/api/v1/volunteers", user: "Seth", wantStatus: http.StatusUnauthorized},
+		{name: "Use the new password", method: "GET", path: "/api/v1/volunteers", user: "Seth", password: "battery staple", wantStatus: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			if len(tt.user) > 0 {
+				password := tt.password
+				if len(password) == 0 {
+					password = "correct horse"
+				}
+				request.SetBasicAuth(tt.user, password)
+			}
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, request)
+			if recorder.Code != tt.wantStatus {
+				t.Errorf("got status %d with body `%s`, want %d", recorder.Code, recorder.Body.String(), tt.wantStatus)
+			}
+			if !strings.Contains(recorder.Body.String(), tt.wantBody) {
+				t.Errorf("got body `%s`, want it to contain `%s`", recorder.Body.String(), tt.wantBody)
+			}
+			if recorder.Code >= 400 && recorder.Code != http.StatusNotFound || recorder.Code == http.StatusNotFound && strings.HasPrefix(tt.path, apiPrefix) {
+				var body map[string]any
+				if err := json.Unmarshal(recorder.Body.Bytes(), &body); err != nil || body["error"] == nil {
+					t.Errorf("got error body `%s`, want a JSON object with an error", recorder.Body.String())
+				}
+			}
+		})
+	}
+}
+
+func TestSessionRoutes(t *testing.T) {
+	env, tearDownEnvironment := setUpEnvironment(t)
+	defer tearDownEnvironment(t)
+	err := env.sample.SetPassword("Seth", "correct horse")
+	if err != nil {
+		t.Errorf("Error setting up test (SetPassword failed): %v", err)
+		t.FailNow()
+	}
+	handler := env.routes()
+	serve := func(method, path, token, body string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(method, path, strings.NewReader(body))
+		if len(token) > 0 {
+			request.Header.Set("Authorization", "Bearer "+token)
+		}
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+		return recorder
+	}
+	login := func(t *testing.T) string {
+		t.Helper()
+		recorder := serve("POST", "/api/v1/sessions", "", `{"UserName":"Seth","Password":"correct horse"}`)
+		var created sessionResponse
+		err := json.Unmarshal(recorder.Body.Bytes(), &created)
+		if recorder.Code != http.StatusCreated || err != nil || len(created.Token) == 0 {
+			t.Errorf("got status %d with body `%s`, want %d with a token", recorder.Code, recorder.Body.String(), http.StatusCreated)
+			t.FailNow()
+		}
+		return created.Token
+	}
+	token := login(t)
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		token      string
+		body       string
+		wantStatus int
+	}{
+		{name: "Fail to log in with a wrong password", method: "POST", path: "/api/v1/sessions", body: `{"UserName":"Seth","Password":"wrong horse"}`, wantStatus: http.StatusUnauthorized},
+		{name: "Use a session", method: "GET", path: "/api/v1/volunteers", token: token, wantStatus: http.StatusOK},
+		{name: "Fail with an unknown token", method: "GET", path: "/api/v1/volunteers", token: "nope", wantStatus: http.StatusUnauthorized},
+		{name: "Refresh a session", method: "POST", path: "/api/v1/sessions/refresh", token: token, wantStatus: http.StatusOK},
+		{name: "Log out", method: "DELETE", path: "/api/v1/sessions/current", token: token, wantStatus: http.StatusNoContent},
+		{name: "Fail with a revoked session", method: "GET", path: "/api/v1/volunteers", token: token, wantStatus: http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := serve(tt.method, tt.path, tt.token, tt.body)
+			if recorder.Code != tt.wantStatus {
+				t.Errorf("got status %d with body `%s`, want %d", recorder.Code, recorder.Body.String(), tt.wantStatus)
+			}
+		})
+	}
+	t.Run("Revoke every session", func(t *testing.T) {
+		first, second := login(t), login(t)
+		recorder := serve("DELETE", "/api/v1/sessions", first, "")
+		if recorder.Code != http.StatusOK || !strings.Contains(recorder.Body.String(), `"revoked":2`) {
+			t.Errorf("got status %d with body `%s`, want %d revoking 2 sessions", recorder.Code, recorder.Body.String(), http.StatusOK)
+		}
+		recorder = serve("GET", "/api/v1/volunteers", second, "")
+		if recorder.Code != http.StatusUnauthorized {
+			t.Errorf("got status %d, want %d", recorder.Code, http.StatusUnauthorized)
+		}
+	})
+	t.Run("Revoke every session when the password changes", func(t *testing.T) {
+		token := login(t)
+		err := env.sample.ChangePassword("Seth", "correct horse", "battery staple", "")
+		if err != nil {
+			t.Errorf("Error setting up test (ChangePassword failed): %v", err)
+		}
+		recorder := serve("GET", "/api/v1/volunteers", token, "")
+		if recorder.Code != http.StatusUnauthorized {
+			t.Errorf("got status %d, want %d", recorder.Code, http.StatusUnauthorized)
+		}
+	})
+}
+
+func TestAPIKeyRoutes(t *testing.T) {
+	env, tearDownEnvironment := setUpEnvironment(t)
+	defer tearDownEnvironment(t)
+	setUpSampleData(t, env)
+	handler := env.routes()
+	tokens := map[apiKeyScope]string{}
+	for _, scope := range []apiKeyScope{APIKeyReadOnly, APIKeyRosterWrite, APIKeyAdmin} {
+		token, _, err := env.sample.CreateAPIKey("Seth", scope.String(), scope, time.Time{})
+		if err != nil {
+			t.Errorf("Error setting up test (CreateAPIKey failed): %v", err)
+			t.FailNow()
+		}
+		tokens[scope] = token
+	}
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		scope      apiKeyScope
+		body       string
+		wantStatus int
+		wantBody   string // substring of the response body
+	}{
+		{name: "Read with a read-only key", method: "GET", path: "/api/v1/volunteers", scope: APIKeyReadOnly, wantStatus: http.StatusOK},
+		{name: "Search with a read-only key", method: "POST", path: "/api/v1/volunteers/search", scope: APIKeyReadOnly, body: `[{"VolunteerName":"Bill"}]`, wantStatus: http.StatusOK},
+		{name: "Fail to write with a read-only key", method: "POST", path: "/api/v1/volunteers", scope: APIKeyReadOnly, body: `[{"VolunteerName":"Zed"}]`, wantStatus: http.StatusForbidden, wantBody: "needs roster-write"},
+		{name: "Write with a roster-write key", method: "POST", path: "/api/v1/volunteers", scope: APIKeyRosterWrite, body: `[{"VolunteerName":"Zed"}]`, wantStatus: http.StatusCreated},
+		{name: "Fail to share a schedule with a roster-write key", method: "PUT", path: "/api/v1/schedules/test1/members", scope: APIKeyRosterWrite, body: `{"User":"Seth","Role":"viewer"}`, wantStatus: http.StatusForbidden},
+		{name: "Fail to list keys with a roster-write key", method: "GET", path: "/api/v1/api-keys", scope: APIKeyRosterWrite, wantStatus: http.StatusForbidden},
+		{name: "List keys with an admin key", method: "GET", path: "/api/v1/api-keys", scope: APIKeyAdmin, wantStatus: http.StatusOK, wantBody: `"Scope":"roster-write"`},
+		{name: "Create a key", method: "POST", path: "/api/v1/api-keys", scope: APIKeyAdmin, body: `{"Name":"sync","Scope":"read-only"}`, wantStatus: http.StatusCreated, wantBody: `"Token":"sdb_`},
+		{name: "Fail to create a key with an unknown scope", method: "POST", path: "/api/v1/api-keys", scope: APIKeyAdmin, body: `{"Name":"sync","Scope":"root"}`, wantStatus: http.StatusBadRequest},
+		{name: "Revoke a key", method: "DELETE", path: "/api/v1/api-keys/1", scope: APIKeyAdmin, wantStatus: http.StatusNoContent},
+		{name: "Fail with a revoked key", method: "GET", path: "/api/v1/volunteers", scope: APIKeyReadOnly, wantStatus: http.StatusUnauthorized},
+		{name: "Fail to revoke an unknown key", method: "DELETE", path: "/api/v1/api-keys/99", scope: APIKeyAdmin, wantStatus: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			request.Header.Set("Authorization", "Bearer "+tokens[tt.scope])
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, request)
+			if recorder.Code != tt.wantStatus {
+				t.Errorf("got status %d with body `%s`, want %d", recorder.Code, recorder.Body.String(), tt.wantStatus)
+			}
+			if !strings.Contains(recorder.Body.String(), tt.wantBody) {
+				t.Errorf("got body `%s`, want it to contain `%s`", recorder.Body.String(), tt.wantBody)
+			}
+		})
+	}
+}
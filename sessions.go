@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+const (
+	sessionLifetime = 24 * time.Hour      // how long a session lasts after it is issued or refreshed
+	maxSessionAge   = 30 * 24 * time.Hour // no refresh extends a session beyond this long after it was issued
+)
+
+// A row of Sessions. The token itself is only known to the client it was issued to.
+type session struct {
+	SessionID  int
+	User       string
+	CreatedAt  time.Time
+	ExpiresAt  time.Time
+	LastSeenAt time.Time
+}
+
+func hashSessionToken(token string) []byte {
+	hash := sha256.Sum256([]byte(token))
+	return hash[:]
+}
+
+// Creates a session for currentUser and returns its token, 32 random bytes in unpadded URL-safe base64. The token is not stored, so it can't be recovered later.
+func (sm SampleModel) IssueSession(currentUser string) (string, session, error) {
+	_, err := sm.RequestUser(user{UserName: currentUser})
+	if err != nil {
+		return "", session{}, fmt.Errorf("error in IssueSession: %w", err)
+	}
+	random := make([]byte, 32)
+	_, err = rand.Read(random)
+	if err != nil {
+		return "", session{}, fmt.Errorf("error in IssueSession: rand.Read error: %w", err)
+	}
+	token := base64.RawURLEncoding.EncodeToString(random)
+	now := time.Now().Truncate(time.Second)
+	result := session{User: currentUser, CreatedAt: now, ExpiresAt: now.Add(sessionLifetime), LastSeenAt: now}
+	res, err := sm.DB.Exec(`insert into Sessions (TokenHash, User, CreatedAt, ExpiresAt, LastSeenAt) values (?, ?, ?, ?, ?)`,
+		hashSessionToken(token), currentUser, result.CreatedAt.Unix(), result.ExpiresAt.Unix(), result.LastSeenAt.Unix())
+	if err != nil {
+		return "", session{}, fmt.Errorf("error in IssueSession: sql.DB.Exec error: %w", err)
+	}
+	lastID, err := res.LastInsertId()
+	if err != nil {
+		return "", session{}, fmt.Errorf("error in IssueSession: sql.Result.LastInsertId error: %w", err)
+	}
+	result.SessionID = int(lastID)
+	return token, result, nil
+}
+
+// Returns the unexpired session with token. Unknown, revoked, and expired tokens fail with the same UserSessionInvalid UserError.
+func (sm SampleModel) requestSession(token string) (session, error) {
+	var result session
+	var createdAt, expiresAt, lastSeenAt int64
+	err := sm.DB.QueryRow(`select SessionID, User, CreatedAt, ExpiresAt, LastSeenAt from Sessions where TokenHash = ? and ExpiresAt > ?`, hashSessionToken(token), time.Now().Unix()).
+		Scan(&result.SessionID, &result.User, &createdAt, &expiresAt, &lastSeenAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return session{}, fmt.Errorf("error in requestSession: %w", &UserError{Kind: UserSessionInvalid})
+	}
+	if err != nil {
+		return session{}, fmt.Errorf("error in requestSession: sql.DB.QueryRow error: %w", err)
+	}
+	result.CreatedAt, result.ExpiresAt, result.LastSeenAt = time.Unix(createdAt, 0), time.Unix(expiresAt, 0), time.Unix(lastSeenAt, 0)
+	return result, nil
+}
+
+// Returns the session with token if it has not expired or been revoked, and records that it was seen now.
+func (sm SampleModel) ValidateSession(token string) (session, error) {
+	result, err := sm.requestSession(token)
+	if err != nil {
+		return session{}, fmt.Errorf("error in ValidateSession: %w", err)
+	}
+	result.LastSeenAt = time.Now().Truncate(time.Second)
+	_, err = sm.DB.Exec(`update Sessions set LastSeenAt = ? where SessionID = ?`, result.LastSeenAt.Unix(), result.SessionID)
+	if err != nil {
+		return session{}, fmt.Errorf("error in ValidateSession: sql.DB.Exec error: %w", err)
+	}
+	return result, nil
+}
+
+// Extends the session with token to sessionLifetime from now, but not beyond maxSessionAge after it was issued.
+func (sm SampleModel) RefreshSession(token string) (session, error) {
+	result, err := sm.requestSession(token)
+	if err != nil {
+		return session{}, fmt.Errorf("error in RefreshSession: %w", err)
+	}
+	now := time.Now().Truncate(time.Second)
+	result.LastSeenAt = now
+	result.ExpiresAt = now.Add(sessionLifetime)
+	if limit := result.CreatedAt.Add(maxSessionAge); result.ExpiresAt.After(limit) {
+		result.ExpiresAt = limit
+	}
+	_, err = sm.DB.Exec(`update Sessions set ExpiresAt = ?, LastSeenAt = ? where SessionID = ?`, result.ExpiresAt.Unix(), result.LastSeenAt.Unix(), result.SessionID)
+	if err != nil {
+		return session{}, fmt.Errorf("error in RefreshSession: sql.DB.Exec error: %w", err)
+	}
+	return result, nil
+}
+
+// Ends the session with token. Revoking a token that is unknown or already revoked is not an error, so logging out twice is harmless.
+func (sm SampleModel) RevokeSession(token string) error {
+	_, err := sm.DB.Exec(`delete from Sessions where TokenHash = ?`, hashSessionToken(token))
+	if err != nil {
+		return fmt.Errorf("error in RevokeSession: sql.DB.Exec error: %w", err)
+	}
+	return nil
+}
+
+// Ends every session of currentUser, for example after a password change, and returns how many there were.
+func (sm SampleModel) RevokeSessions(currentUser string) (int, error) {
+	res, err := sm.DB.Exec(`delete from Sessions where User = ?`, currentUser)
+	if err != nil {
+		return 0, fmt.Errorf("error in RevokeSessions: sql.DB.Exec error: %w", err)
+	}
+	count, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("error in RevokeSessions: sql.Result.RowsAffected error: %w", err)
+	}
+	return int(count), nil
+}
+
+// Deletes the sessions that expired before now and returns how many there were.
+func (sm SampleModel) DeleteExpiredSessions(now time.Time) (int, error) {
+	res, err := sm.DB.Exec(`delete from Sessions where ExpiresAt <= ?`, now.Unix())
+	if err != nil {
+		return 0, fmt.Errorf("error in DeleteExpiredSessions: sql.DB.Exec error: %w", err)
+	}
+	count, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("error in DeleteExpiredSessions: sql.Result.RowsAffected error: %w", err)
+	}
+	return int(count), nil
+}
+
+// Calls DeleteExpiredSessions, and DeleteLoginAttempts for attempts older than loginAttemptRetention, every interval until ctx is done. Expired sessions are already rejected by ValidateSession, so this only keeps the tables small and failures are just logged.
+func (sm SampleModel) cleanUpSessions(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if _, err := sm.DeleteExpiredSessions(now); err != nil {
+				log.Printf("cleanUpSessions: %v", err)
+			}
+			if _, err := sm.DeleteLoginAttempts(now.Add(-loginAttemptRetention)); err != nil {
+				log.Printf("cleanUpSessions: %v", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSessions(t *testing.T) {
+	env, tearDownEnvironment := setUpEnvironment(t)
+	defer tearDownEnvironment(t)
+	token, issued, err := env.sample.IssueSession("Seth")
+	if err != nil {
+		t.Errorf("Error setting up test (IssueSession failed): %v", err)
+		t.FailNow()
+	}
+	otherToken, _, err := env.sample.IssueSession("Seth")
+	if err != nil {
+		t.Errorf("Error setting up test (IssueSession failed): %v", err)
+		t.FailNow()
+	}
+	wantInvalid := func(t *testing.T, err error) {
+		t.Helper()
+		var userErr *UserError
+		if !errors.As(err, &userErr) || userErr.Kind != UserSessionInvalid {
+			t.Errorf("got error `%v`, want a UserSessionInvalid UserError", err)
+		}
+	}
+	t.Run("Fail to issue a session for an unknown user", func(t *testing.T) {
+		_, _, err := env.sample.IssueSession("Nobody")
+		var userErr *UserError
+		if !errors.As(err, &userErr) || userErr.Kind != UserNotFound {
+			t.Errorf("got error `%v`, want a UserNotFound UserError", err)
+		}
+	})
+	t.Run("Validate a session", func(t *testing.T) {
+		ans, err := env.sample.ValidateSession(token)
+		checkResults(t, ans.User, "Seth", "", err)
+		checkResults(t, ans.SessionID, issued.SessionID, 0, nil)
+	})
+	t.Run("Fail to validate an unknown token", func(t *testing.T) {
+		_, err := env.sample.ValidateSession("not a token")
+		wantInvalid(t, err)
+	})
+	t.Run("Refresh a session no further than maxSessionAge", func(t *testing.T) {
+		createdAt := time.Now().Add(-maxSessionAge + time.Hour).Unix()
+		_, err := env.sample.DB.Exec(`update Sessions set CreatedAt = ? where SessionID = ?`, createdAt, issued.SessionID)
+		if err != nil {
+			t.Errorf("Error setting up test (sql.DB.Exec failed): %v", err)
+		}
+		ans, err := env.sample.RefreshSession(token)
+		checkResults(t, ans.ExpiresAt.Unix(), createdAt+int64(maxSessionAge/time.Second), 0, err)
+	})
+	t.Run("Fail to validate an expired session", func(t *testing.T) {
+		_, err := env.sample.DB.Exec(`update Sessions set ExpiresAt = ? where SessionID = ?`, time.Now().Add(-time.Minute).Unix(), issued.SessionID)
+		if err != nil {
+			t.Errorf("Error setting up test (sql.DB.Exec failed): %v", err)
+		}
+		_, err = env.sample.ValidateSession(token)
+		wantInvalid(t, err)
+		_, err = env.sample.RefreshSession(token)
+		wantInvalid(t, err)
+	})
+	t.Run("Delete expired sessions", func(t *testing.T) {
+		ans, err := env.sample.DeleteExpiredSessions(time.Now())
+		checkResults(t, ans, 1, 0, err)
+		_, err = env.sample.ValidateSession(otherToken)
+		if err != nil {
+			t.Errorf("got error: `%v`", err)
+		}
+	})
+	t.Run("Revoke a session twice", func(t *testing.T) {
+		err := env.sample.RevokeSession(otherToken)
+		if err != nil {
+			t.Errorf("got error: `%v`", err)
+		}
+		err = env.sample.RevokeSession(otherToken)
+		if err != nil {
+			t.Errorf("got error: `%v`", err)
+		}
+		_, err = env.sample.ValidateSession(otherToken)
+		wantInvalid(t, err)
+	})
+	t.Run("Revoke every session of a user", func(t *testing.T) {
+		for range 2 {
+			_, _, err := env.sample.IssueSession("Seth")
+			if err != nil {
+				t.Errorf("Error setting up test (IssueSession failed): %v", err)
+			}
+		}
+		ans, err := env.sample.RevokeSessions("Seth")
+		checkResults(t, ans, 2, 0, err)
+	})
+	t.Run("Delete a user with sessions", func(t *testing.T) {
+		err := env.sample.CreateUser(user{UserName: "Ann"})
+		if err != nil {
+			t.Errorf("Error setting up test (CreateUser failed): %v", err)
+		}
+		annToken, _, err := env.sample.IssueSession("Ann")
+		if err != nil {
+			t.Errorf("Error setting up test (IssueSession failed): %v", err)
+		}
+		err = env.sample.DeleteUser("Ann")
+		if err != nil {
+			t.Errorf("got error: `%v`", err)
+		}
+		_, err = env.sample.ValidateSession(annToken)
+		wantInvalid(t, err)
+	})
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// How much a user may do with a schedule it does not own through Schedules.User. Each role includes the ones before it.
+type scheduleRole int
+
+const (
+	ScheduleViewer scheduleRole = iota + 1 // may request the schedule and its WFS, VFS, UFS, and CompletedSchedules rows
+	ScheduleEditor                         // may also create, update, and delete those rows and update the schedule
+	ScheduleOwner                          // may also delete the schedule and change who it is shared with
+)
+
+var scheduleRoleNames = map[scheduleRole]string{ScheduleViewer: "viewer", ScheduleEditor: "editor", ScheduleOwner: "owner"}
+
+func (r scheduleRole) String() string {
+	if name, ok := scheduleRoleNames[r]; ok {
+		return name
+	}
+	return "none"
+}
+
+func parseScheduleRole(name string) (scheduleRole, error) {
+	for key, val := range scheduleRoleNames {
+		if val == name {
+			return key, nil
+		}
+	}
+	return 0, fmt.Errorf("error in parseScheduleRole: method failed because `%s` is not one of viewer, editor, or owner", name)
+}
+
+// A row of ScheduleMembers.
+type scheduleMember struct {
+	MemberID int
+	Schedule int
+	User     string
+	Role     string // viewer, editor, or owner
+}
+
+// Returns an SQL condition that holds for the ScheduleIDs in column that currentUser may access with at least role: the schedules it owns through Schedules.User and those shared with it through ScheduleMembers.
+func scheduleAccess(column string, currentUser string, role scheduleRole) string {
+	var roles []string
+	for val := max(role, ScheduleViewer); val <= ScheduleOwner; val++ {
+		roles = append(roles, fmt.Sprintf(`'%s'`, val))
+	}
+	return fmt.Sprintf(`%s in (select ScheduleID from Schedules where User = "%s" union select Schedule from ScheduleMembers where User = "%s" and Role in (%s))`, column, currentUser, currentUser, strings.Join(roles, ", "))
+}
+
+// Like scheduleAccess for the VolunteerForSchedule column of UnavailabilitiesForSchedule, whose schedule is only known through VolunteersForSchedule.
+func unavailabilityAccess(currentUser string, role scheduleRole) string {
+	return fmt.Sprintf(`VolunteerForSchedule in (select VFSID from VolunteersForSchedule where %s)`, scheduleAccess("Schedule", currentUser, role))
+}
+
+// Returns the role currentUser has on scheduleStruct, or 0 if it has none.
+func (sm SampleModel) requestScheduleRole(currentUser string, scheduleStruct schedule) (scheduleRole, error) {
+	if scheduleStruct.User == currentUser {
+		return ScheduleOwner, nil
+	}
+	var name string
+	err := sm.DB.QueryRow(`select Role from ScheduleMembers where Schedule = ? and User = ?`, scheduleStruct.ScheduleID, currentUser).Scan(&name)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("error in requestScheduleRole: sql.DB.QueryRow error: %w", err)
+	}
+	role, err := parseScheduleRole(name)
+	if err != nil {
+		return 0, fmt.Errorf("error in requestScheduleRole: %w", err)
+	}
+	return role, nil
+}
+
+// Returns the schedule with scheduleID if currentUser has at least role on it. Schedules currentUser can't see at all fail as if they did not exist, and schedules it can see but not change fail with a UserForbidden UserError.
+// Rows created by members are stored under the owner in the returned schedule's User, so everything belonging to a schedule has the same User.
+func (sm SampleModel) authorizeSchedule(currentUser string, scheduleID int, role scheduleRole) (schedule, error) {
+	var result schedule
+	err := sm.DB.QueryRow(`select ScheduleID, ScheduleName, ShiftsOff, VolunteersPerShift, User, StartDate, EndDate from Schedules where ScheduleID = ?`, scheduleID).
+		Scan(&result.ScheduleID, &result.ScheduleName, &result.ShiftsOff, &result.VolunteersPerShift, &result.User, &result.StartDate, &result.EndDate)
+	if errors.Is(err, sql.ErrNoRows) {
+		return schedule{}, fmt.Errorf("error in authorizeSchedule: method failed to locate schedule with ScheduleID %d", scheduleID)
+	}
+	if err != nil {
+		return schedule{}, fmt.Errorf("error in authorizeSchedule: sql.DB.QueryRow error: %w", err)
+	}
+	current, err := sm.requestScheduleRole(currentUser, result)
+	if err != nil {
+		return schedule{}, fmt.Errorf("error in authorizeSchedule: %w", err)
+	}
+	if current == 0 {
+		return schedule{}, fmt.Errorf("error in authorizeSchedule: method failed to locate schedule with ScheduleID %d", scheduleID)
+	}
+	if current < role {
+		return schedule{}, fmt.Errorf("error in authorizeSchedule: %w", &UserError{Kind: UserForbidden, UserName: currentUser, Detail: fmt.Sprintf("schedule `%s` needs the %s role, but the user is a %s", result.ScheduleName, role, current)})
+	}
+	return result, nil
+}
+
+// Like authorizeSchedule for the schedule of the VFS row with vfsID.
+func (sm SampleModel) authorizeVFS(currentUser string, vfsID int, role scheduleRole) (schedule, error) {
+	vfsStruct, err := sm.RequestVFSSingle(currentUser, volunteerForSchedule{VFSID: vfsID})
+	if err != nil {
+		return schedule{}, fmt.Errorf("error in authorizeVFS: %w", err)
+	}
+	result, err := sm.authorizeSchedule(currentUser, vfsStruct.Schedule, role)
+	if err != nil {
+		return schedule{}, fmt.Errorf("error in authorizeVFS: %w", err)
+	}
+	return result, nil
+}
+
+// Gives member the role on the schedule with scheduleID, replacing any role it had. Only owners may share a schedule, and the owner in Schedules.User can't be given a role.
+func (sm SampleModel) ShareSchedule(currentUser string, scheduleID int, member string, role scheduleRole) error {
+	if _, ok := scheduleRoleNames[role]; !ok {
+		return fmt.Errorf("error in ShareSchedule: method failed because %d is not a role", role)
+	}
+	scheduleStruct, err := sm.authorizeSchedule(currentUser, scheduleID, ScheduleOwner)
+	if err != nil {
+		return fmt.Errorf("error in ShareSchedule: %w", err)
+	}
+	_, err = sm.RequestUser(user{UserName: member})
+	if err != nil {
+		return fmt.Errorf("error in ShareSchedule: %w", err)
+	}
+	if member == scheduleStruct.User {
+		return fmt.Errorf("error in ShareSchedule: method failed because user `%s` already owns schedule `%s`", member, scheduleStruct.ScheduleName)
+	}
+	_, err = sm.execAudited(currentUser, `insert into ScheduleMembers (Schedule, User, Role) values (?, ?, ?) on conflict (Schedule, User) do update set Role = excluded.Role`, scheduleID, member, role.String())
+	if err != nil {
+		return fmt.Errorf("error in ShareSchedule: %w", err)
+	}
+	return nil
+}
+
+// Takes away member's role on the schedule with scheduleID. Owners may remove anyone, and every member may remove itself.
+func (sm SampleModel) UnshareSchedule(currentUser string, scheduleID int, member string) error {
+	required := ScheduleOwner
+	if member == currentUser {
+		required = ScheduleViewer
+	}
+	_, err := sm.authorizeSchedule(currentUser, scheduleID, required)
+	if err != nil {
+		return fmt.Errorf("error in UnshareSchedule: %w", err)
+	}
+	res, err := sm.execAudited(currentUser, `delete from ScheduleMembers where Schedule = ? and User = ?`, scheduleID, member)
+	if err != nil {
+		return fmt.Errorf("error in UnshareSchedule: %w", err)
+	}
+	count, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error in UnshareSchedule: sql.Result.RowsAffected error: %w", err)
+	}
+	if count == 0 {
+		return fmt.Errorf("error in UnshareSchedule: method failed to locate user `%s` among the members of schedule %d", member, scheduleID)
+	}
+	return nil
+}
+
+// Lists who the schedule with scheduleID is shared with, ordered by MemberID. The owner in Schedules.User is not included.
+func (sm SampleModel) RequestScheduleMembers(currentUser string, scheduleID int) ([]scheduleMember, error) {
+	_, err := sm.authorizeSchedule(currentUser, scheduleID, ScheduleViewer)
+	if err != nil {
+		return []scheduleMember{}, fmt.Errorf("error in RequestScheduleMembers: %w", err)
+	}
+	rows, err := sm.DB.Query(`select MemberID, Schedule, User, Role from ScheduleMembers where Schedule = ? order by MemberID`, scheduleID)
+	if err != nil {
+		return []scheduleMember{}, fmt.Errorf("error in RequestScheduleMembers: sql.DB.Query error: %w", err)
+	}
+	defer rows.Close()
+	result := []scheduleMember{}
+	for rows.Next() {
+		var member scheduleMember
+		err = rows.Scan(&member.MemberID, &member.Schedule, &member.User, &member.Role)
+		if err != nil {
+			return []scheduleMember{}, fmt.Errorf("error in RequestScheduleMembers: sql.Rows.Scan error: %w", err)
+		}
+		result = append(result, member)
+	}
+	err = rows.Err()
+	if err != nil {
+		return []scheduleMember{}, fmt.Errorf("error in RequestScheduleMembers: sql.Rows.Err error: %w", err)
+	}
+	return result, nil
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestScheduleSharing(t *testing.T) {
+	env, tearDownEnvironment := setUpEnvironment(t)
+	defer tearDownEnvironment(t)
+	setUpSampleData(t, env)
+	err := env.sample.CreateUser(user{UserName: "Ann"})
+	if err != nil {
+		t.Errorf("Error setting up test (CreateUser failed): %v", err)
+		t.FailNow()
+	}
+	test1 := Must(env.sample.RequestSchedule("Seth", schedule{ScheduleName: "test1"}))
+	wantForbidden := func(t *testing.T, err error) {
+		t.Helper()
+		var userErr *UserError
+		if !errors.As(err, &userErr) || userErr.Kind != UserForbidden {
+			t.Errorf("got error `%v`, want a UserForbidden UserError", err)
+		}
+	}
+	t.Run("Fail to find a schedule that is not shared", func(t *testing.T) {
+		_, err := env.sample.RequestSchedule("Ann", schedule{ScheduleName: "test1"})
+		if err == nil {
+			t.Errorf("RequestSchedule found schedule test1 for a user it is not shared with")
+		}
+		_, err = env.sample.RequestScheduleMembers("Ann", test1.ScheduleID)
+		if err == nil {
+			t.Errorf("RequestScheduleMembers succeeded for a user schedule test1 is not shared with")
+		}
+	})
+	t.Run("Fail to share a schedule with its owner", func(t *testing.T) {
+		err := env.sample.ShareSchedule("Seth", test1.ScheduleID, "Seth", ScheduleEditor)
+		if err == nil {
+			t.Errorf("ShareSchedule gave the owner of schedule test1 a role")
+		}
+	})
+	t.Run("Fail to share a schedule with an unknown user", func(t *testing.T) {
+		err := env.sample.ShareSchedule("Seth", test1.ScheduleID, "Nobody", ScheduleViewer)
+		var userErr *UserError
+		if !errors.As(err, &userErr) || userErr.Kind != UserNotFound {
+			t.Errorf("got error `%v`, want a UserNotFound UserError", err)
+		}
+	})
+	t.Run("View a schedule as a viewer", func(t *testing.T) {
+		err := env.sample.ShareSchedule("Seth", test1.ScheduleID, "Ann", ScheduleViewer)
+		if err != nil {
+			t.Errorf("ShareSchedule failed: %v", err)
+			t.FailNow()
+		}
+		ans, err := env.sample.RequestSchedule("Ann", schedule{ScheduleName: "test1"})
+		checkResults(t, ans, test1, schedule{ScheduleName: "test1"}, err)
+		wfs, err := env.sample.RequestWFS("Ann", []weekdayForSchedule{{Schedule: test1.ScheduleID}})
+		checkResults(t, len(wfs), 1, test1.ScheduleID, err)
+		vfs, err := env.sample.RequestVFS("Ann", []volunteerForSchedule{{Schedule: test1.ScheduleID}})
+		ownerVFS, _ := env.sample.RequestVFS("Seth", []volunteerForSchedule{{Schedule: test1.ScheduleID}})
+		checkResultsSlice(t, vfs, ownerVFS, nil, err)
+		_, err = env.sample.RequestSchedule("Ann", schedule{ScheduleName: "test0"})
+		if err == nil {
+			t.Errorf("RequestSchedule found schedule test0, which was not shared")
+		}
+	})
+	t.Run("Fail to change a schedule as a viewer", func(t *testing.T) {
+		changed := test1
+		changed.VolunteersPerShift = 5
+		wantForbidden(t, env.sample.UpdateSchedules("Ann", []schedule{changed}))
+		wantForbidden(t, env.sample.CreateWFS("Ann", []weekdayForSchedule{{Weekday: "Monday", Schedule: test1.ScheduleID}}))
+		wantForbidden(t, env.sample.ShareSchedule("Ann", test1.ScheduleID, "Ann", ScheduleOwner))
+	})
+	t.Run("Change a schedule as an editor", func(t *testing.T) {
+		err := env.sample.ShareSchedule("Seth", test1.ScheduleID, "Ann", ScheduleEditor)
+		if err != nil {
+			t.Errorf("ShareSchedule failed: %v", err)
+			t.FailNow()
+		}
+		err = env.sample.CreateWFS("Ann", []weekdayForSchedule{{Weekday: "Monday", Schedule: test1.ScheduleID}})
+		if err != nil {
+			t.Errorf("CreateWFS failed: %v", err)
+		}
+		ans, err := env.sample.RequestWFSSingle("Seth", weekdayForSchedule{Weekday: "Monday", Schedule: test1.ScheduleID})
+		checkResults(t, ans.User, "Seth", "Monday", err)
+		members, err := env.sample.RequestScheduleMembers("Seth", test1.ScheduleID)
+		checkResultsSlice(t, members, []scheduleMember{{MemberID: 1, Schedule: test1.ScheduleID, User: "Ann", Role: "editor"}}, nil, err)
+	})
+	t.Run("Leave a schedule undeleted as an editor", func(t *testing.T) {
+		err := env.sample.DeleteSchedules("Ann", []schedule{{ScheduleID: test1.ScheduleID}})
+		if err != nil {
+			t.Errorf("DeleteSchedules failed: %v", err)
+		}
+		ans, err := env.sample.RequestSchedule("Seth", schedule{ScheduleName: "test1"})
+		checkResults(t, ans, test1, schedule{ScheduleName: "test1"}, err)
+	})
+	t.Run("Keep shared rows out of the member's backup", func(t *testing.T) {
+		ans, err := env.sample.RequestBackup("Ann")
+		checkResults(t, len(ans.Schedules)+len(ans.WeekdaysForSchedule)+len(ans.VolunteersForSchedule), 0, 0, err)
+	})
+	t.Run("Leave a schedule as a member", func(t *testing.T) {
+		err := env.sample.UnshareSchedule("Ann", test1.ScheduleID, "Ann")
+		if err != nil {
+			t.Errorf("UnshareSchedule failed: %v", err)
+		}
+		_, err = env.sample.RequestSchedule("Ann", schedule{ScheduleName: "test1"})
+		if err == nil {
+			t.Errorf("RequestSchedule found schedule test1 after the user left it")
+		}
+		err = env.sample.UnshareSchedule("Seth", test1.ScheduleID, "Ann")
+		if err == nil {
+			t.Errorf("UnshareSchedule removed a user that was no longer a member")
+		}
+	})
+	t.Run("Prefer the user's own schedule over a shared one with the same name", func(t *testing.T) {
+		annSchedule := generateSampleSchedules(env.sample)[1]
+		err := env.sample.CreateSchedules("Ann", []schedule{annSchedule})
+		if err != nil {
+			t.Errorf("Error setting up test (CreateSchedules failed): %v", err)
+			t.FailNow()
+		}
+		annTest1 := Must(env.sample.RequestSchedule("Ann", schedule{ScheduleName: "test1"}))
+		err = env.sample.ShareSchedule("Ann", annTest1.ScheduleID, "Seth", ScheduleEditor)
+		if err != nil {
+			t.Errorf("ShareSchedule failed: %v", err)
+			t.FailNow()
+		}
+		ans, err := env.sample.RequestSchedule("Seth", schedule{ScheduleName: "test1"})
+		checkResults(t, ans, test1, schedule{ScheduleName: "test1"}, err)
+		data, err := env.sample.FetchAndSendData("Seth", "test1")
+		if err != nil {
+			t.Errorf("FetchAndSendData failed: %v", err)
+			t.FailNow()
+		}
+		plan, err := env.sample.PlanReceivedData(data)
+		checkResults(t, plan.Schedule.ScheduleID, test1.ScheduleID, test1.ScheduleID, err)
+	})
+}